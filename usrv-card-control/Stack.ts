@@ -6,12 +6,14 @@ import {
     KushkiStack,
     PatternEnum,
     PluginsEnum,
-    ResourceEnum
+    ResourceEnum,
+    SQSActions
 } from "@kushki/cdk";
 import {Schedule} from "aws-cdk-lib/aws-events";
 import * as cdk from 'aws-cdk-lib';
 import {Duration} from 'aws-cdk-lib';
 import {AttributeType, StreamViewType} from "aws-cdk-lib/aws-dynamodb";
+import {Alarm, ComparisonOperator, Metric, TreatMissingData} from "aws-cdk-lib/aws-cloudwatch";
 import {IResourceService} from "@kushki/cdk/lib/lib/repository/IResourceService";
 import {SQSQueueResource} from "@kushki/cdk/lib/lib/repository/ResourceProps";
 import {AccountEnvEnum} from "@kushki/cdk/lib/common/infraestructure/AccountEnvEnum";
@@ -47,6 +49,7 @@ const DYNAMO_BLOCKED_CARD = STACK.setResource({
         pointInTimeRecovery: true,
         stream: StreamViewType.NEW_AND_OLD_IMAGES,
         tableName: "blockedCard",
+        timeToLiveAttribute: "expiresAt",
     },
     type: ResourceEnum.DynamoDB,
 });
@@ -59,11 +62,64 @@ const DYNAMO_CARD_RETRY = STACK.setResource({
                 indexName: "cardIdMerchantIndex",
                 partitionKey: {name: "cardID", type: AttributeType.STRING},
                 sortKey: {name: "merchantID", type: AttributeType.STRING}
+            },
+            {
+                indexName: "merchantIdCardIndex",
+                partitionKey: {name: "merchantID", type: AttributeType.STRING},
+                sortKey: {name: "cardID", type: AttributeType.STRING}
             }
         ],
         pointInTimeRecovery: true,
         stream: StreamViewType.NEW_AND_OLD_IMAGES,
         tableName: "cardRetry",
+        timeToLiveAttribute: "expiresAt",
+    },
+    type: ResourceEnum.DynamoDB,
+});
+
+const DYNAMO_RETRY_POLICY = STACK.setResource({
+    props: {
+        partitionKey: {name: "policyID", type: AttributeType.STRING},
+        pointInTimeRecovery: true,
+        tableName: "retryPolicy",
+    },
+    type: ResourceEnum.DynamoDB,
+});
+
+const DYNAMO_MERCHANT_SETTINGS = STACK.setResource({
+    props: {
+        partitionKey: {name: "merchantID", type: AttributeType.STRING},
+        pointInTimeRecovery: true,
+        tableName: "merchantSettings",
+    },
+    type: ResourceEnum.DynamoDB,
+});
+
+const DYNAMO_SHADOW_REPORT = STACK.setResource({
+    props: {
+        partitionKey: {name: "policyID", type: AttributeType.STRING},
+        sortKey: {name: "reportKey", type: AttributeType.STRING},
+        pointInTimeRecovery: true,
+        tableName: "shadowPolicyReport",
+    },
+    type: ResourceEnum.DynamoDB,
+});
+
+const DYNAMO_BLOCK_HISTORY = STACK.setResource({
+    props: {
+        partitionKey: {name: "cardID", type: AttributeType.STRING},
+        sortKey: {name: "eventKey", type: AttributeType.STRING},
+        pointInTimeRecovery: true,
+        tableName: "blockHistory",
+    },
+    type: ResourceEnum.DynamoDB,
+});
+
+const DYNAMO_PROCESSED_MESSAGE = STACK.setResource({
+    props: {
+        partitionKey: {name: "idempotencyKey", type: AttributeType.STRING},
+        tableName: "processedMessage",
+        timeToLiveAttribute: "expiresAt",
     },
     type: ResourceEnum.DynamoDB,
 });
@@ -174,6 +230,79 @@ const CARD_INFO_PROCESSING_QUEUE: IResourceService<SQSQueueResource> = STACK.set
     },
 })
 
+const DYNAMO_CARD_INFO_WEBHOOK_CONFIG = STACK.setResource({
+    props: {
+        partitionKey: { name: "merchantId", type: AttributeType.STRING },
+        pointInTimeRecovery: true,
+        tableName: "cardInfoWebhookConfig",
+    },
+    type: ResourceEnum.DynamoDB,
+});
+
+const DYNAMO_CARD_INFO_WEBHOOK_DELIVERY = STACK.setResource({
+    props: {
+        partitionKey: { name: "externalReferenceId", type: AttributeType.STRING },
+        pointInTimeRecovery: true,
+        tableName: "cardInfoWebhookDelivery",
+        timeToLiveAttribute: "expiresAt"
+    },
+    type: ResourceEnum.DynamoDB,
+});
+
+// The public keys the card data of each merchant is encrypted with, replaces the MERCHANT_<id>_PUBLIC_KEY variables
+const DYNAMO_CARD_INFO_MERCHANT_KEY = STACK.setResource({
+    props: {
+        partitionKey: { name: "merchantId", type: AttributeType.STRING },
+        sortKey: { name: "keyId", type: AttributeType.STRING },
+        pointInTimeRecovery: true,
+        tableName: "cardInfoMerchantKey",
+    },
+    type: ResourceEnum.DynamoDB,
+});
+
+const DEAD_LETTER_CARD_INFO_WEBHOOK_QUEUE: IResourceService<SQSQueueResource> = STACK.setResource<SQSQueueResource>({
+    type: ResourceEnum.SQSQueue,
+    props: {
+        deliveryDelay: Duration.seconds(0),
+        queueName: "cardInfoWebhookDeadLetterQueue",
+        visibilityTimeout: Duration.seconds(300),
+        retentionPeriod: Duration.days(14), // the maximum, a message the DLQ handler cannot process is kept for the investigation
+    },
+})
+
+// The webhook DLQ only grows when its handler cannot mark the deliveries as failed
+new Alarm(STACK, "cardInfoWebhookDeadLetterQueueAlarm", {
+    alarmName: "cardInfoWebhookDeadLetterQueueDepth",
+    alarmDescription: "Card info webhook deliveries reached the dead letter queue",
+    metric: new Metric({
+        namespace: "AWS/SQS",
+        metricName: "ApproximateNumberOfMessagesVisible",
+        dimensionsMap: {
+            QueueName: STACK.utils.getEnvResource(DEAD_LETTER_CARD_INFO_WEBHOOK_QUEUE, AttributeTypeEnum.NAME),
+        },
+        period: Duration.minutes(5),
+        statistic: "Maximum",
+    }),
+    threshold: 0,
+    evaluationPeriods: 1,
+    comparisonOperator: ComparisonOperator.GREATER_THAN_THRESHOLD,
+    treatMissingData: TreatMissingData.NOT_BREACHING,
+});
+
+// The delivery retries are scheduled with the delay of each message, the DLQ only gets infrastructure failures
+const CARD_INFO_WEBHOOK_QUEUE: IResourceService<SQSQueueResource> = STACK.setResource<SQSQueueResource>({
+    type: ResourceEnum.SQSQueue,
+    props: {
+        deadLetterQueue: {
+            maxReceiveCount: 3,
+            queue: DEAD_LETTER_CARD_INFO_WEBHOOK_QUEUE,
+        },
+        queueName: "cardInfoWebhookQueue",
+        visibilityTimeout: Duration.seconds(300), // 5 minutes
+        retentionPeriod: Duration.seconds(2800)   // 46+ minutes
+    },
+})
+
 // Environment
 STACK.setEnvironment({
     DYNAMO_BLOCKED_CARD: STACK.utils.getEnvResource(
@@ -184,11 +313,52 @@ STACK.setEnvironment({
         DYNAMO_CARD_RETRY,
         AttributeTypeEnum.NAME
     ),
+    DYNAMO_RETRY_POLICY: STACK.utils.getEnvResource(
+        DYNAMO_RETRY_POLICY,
+        AttributeTypeEnum.NAME
+    ),
+    DYNAMO_MERCHANT_SETTINGS: STACK.utils.getEnvResource(
+        DYNAMO_MERCHANT_SETTINGS,
+        AttributeTypeEnum.NAME
+    ),
+    DYNAMO_SHADOW_REPORT: STACK.utils.getEnvResource(
+        DYNAMO_SHADOW_REPORT,
+        AttributeTypeEnum.NAME
+    ),
+    DYNAMO_BLOCK_HISTORY: STACK.utils.getEnvResource(
+        DYNAMO_BLOCK_HISTORY,
+        AttributeTypeEnum.NAME
+    ),
+    DYNAMO_PROCESSED_MESSAGE: STACK.utils.getEnvResource(
+        DYNAMO_PROCESSED_MESSAGE,
+        AttributeTypeEnum.NAME
+    ),
     DYNAMO_CARD_INFO_TABLE: STACK.utils.getEnvResource(
         DYNAMO_CARD_INFO,
         AttributeTypeEnum.NAME
     ),
+    DYNAMO_CARD_INFO_WEBHOOK_CONFIG_TABLE: STACK.utils.getEnvResource(
+        DYNAMO_CARD_INFO_WEBHOOK_CONFIG,
+        AttributeTypeEnum.NAME
+    ),
+    DYNAMO_CARD_INFO_WEBHOOK_DELIVERY_TABLE: STACK.utils.getEnvResource(
+        DYNAMO_CARD_INFO_WEBHOOK_DELIVERY,
+        AttributeTypeEnum.NAME
+    ),
+    DYNAMO_CARD_INFO_MERCHANT_KEY_TABLE: STACK.utils.getEnvResource(
+        DYNAMO_CARD_INFO_MERCHANT_KEY,
+        AttributeTypeEnum.NAME
+    ),
+    SQS_CARD_INFO_WEBHOOK_QUEUE: STACK.utils.getEnvResource(
+        CARD_INFO_WEBHOOK_QUEUE,
+        AttributeTypeEnum.NAME
+    ),
     ROLLBAR_TOKEN: STACK.utils.getEnvDynamodb("ROLLBAR_TOKEN"),
+    CARD_STATUS_FAIL_MODE: STACK.utils.getEnvDynamodb("CARD_STATUS_FAIL_MODE"),
+    CARD_STATUS_MERCHANT_FAIL_MODES: STACK.utils.getEnvDynamodb("CARD_STATUS_MERCHANT_FAIL_MODES"),
+    SWEEPER_DRY_RUN: STACK.utils.getEnvDynamodb("SWEEPER_DRY_RUN"),
+    CARD_VELOCITY_RULES: STACK.utils.getEnvDynamodb("CARD_VELOCITY_RULES"),
+    CARD_VELOCITY_ALLOWLIST: STACK.utils.getEnvDynamodb("CARD_VELOCITY_ALLOWLIST"),
 });
 
 // Plugins
@@ -210,7 +380,8 @@ STACK.setPattern(PatternEnum.SQS_LAMBDA)
             type: EventsEnum.QueueEvent,
             props: {
                 source: BLOCK_CARD_QUEUE,
-                batchSize: 1
+                batchSize: 10,
+                reportBatchItemFailures: true
             }
         }
     ])
@@ -220,13 +391,33 @@ STACK.setPattern(PatternEnum.SQS_LAMBDA)
             "block_card_handler"
         )
     }).setAccess([
+    {
+        actions: [DynamoActions.PutItem, DynamoActions.DeleteItem],
+        resource: DYNAMO_PROCESSED_MESSAGE
+    },
     {
         actions: [DynamoActions.UpdateItem, DynamoActions.GetItem, DynamoActions.PutItem],
         resource: DYNAMO_BLOCKED_CARD
     },
     {
-        actions: [DynamoActions.UpdateItem, DynamoActions.GetItem],
+        actions: [DynamoActions.UpdateItem, DynamoActions.GetItem, DynamoActions.Query, DynamoActions.DeleteItem],
         resource: DYNAMO_CARD_RETRY
+    },
+    {
+        actions: [DynamoActions.GetItem],
+        resource: DYNAMO_RETRY_POLICY
+    },
+    {
+        actions: [DynamoActions.GetItem],
+        resource: DYNAMO_MERCHANT_SETTINGS
+    },
+    {
+        actions: [DynamoActions.UpdateItem],
+        resource: DYNAMO_SHADOW_REPORT
+    },
+    {
+        actions: [DynamoActions.PutItem],
+        resource: DYNAMO_BLOCK_HISTORY
     }
 ]);
 
@@ -236,7 +427,8 @@ STACK.setPattern(PatternEnum.SQS_LAMBDA)
             type: EventsEnum.QueueEvent,
             props: {
                 source: RESTORE_RETRY_QUEUE,
-                batchSize: 1
+                batchSize: 10,
+                reportBatchItemFailures: true
             }
         }
     ])
@@ -253,6 +445,10 @@ STACK.setPattern(PatternEnum.SQS_LAMBDA)
     {
         actions: [DynamoActions.Query, DynamoActions.DeleteItem],
         resource: DYNAMO_CARD_RETRY
+    },
+    {
+        actions: [DynamoActions.PutItem],
+        resource: DYNAMO_BLOCK_HISTORY
     }
 ]);
 
@@ -303,6 +499,100 @@ STACK.setPattern(PatternEnum.SINGLE_LAMBDA)
         actions: [DynamoActions.GetItem],
         resource: DYNAMO_BLOCKED_CARD
     },
+    {
+        actions: [DynamoActions.GetItem],
+        resource: DYNAMO_CARD_RETRY
+    },
+    {
+        actions: [DynamoActions.GetItem],
+        resource: DYNAMO_RETRY_POLICY
+    },
+    {
+        actions: [DynamoActions.GetItem],
+        resource: DYNAMO_MERCHANT_SETTINGS
+    },
+])
+
+STACK.setPattern(PatternEnum.SINGLE_LAMBDA)
+    .setLambda({
+        ...LAMBDA_PROPS(
+            "batchCheckCardStatus",
+            "batch_check_card_status_handler"
+        ),
+        ...VPC_PCI_SUBNETS,
+        crossAccount: true
+    }).setAccess([
+    {
+        actions: [DynamoActions.BatchGetItem],
+        resource: DYNAMO_BLOCKED_CARD
+    },
+    {
+        actions: [DynamoActions.GetItem],
+        resource: DYNAMO_MERCHANT_SETTINGS
+    },
+])
+
+STACK.setPattern(PatternEnum.SINGLE_LAMBDA)
+    .setEvents([
+        {
+            type: EventsEnum.ApiEvent,
+            props: {
+                method: "POST",
+                path: "/card/v1/admin",
+                authorizer: {
+                    arn: STACK.utils.getEnvDynamodb("CARD_ADMIN_AUTHORIZER_ARN"),
+                    identitySource: "method.request.header.Authorization"
+                }
+            }
+        }
+    ])
+    .setLambda({
+        ...LAMBDA_PROPS(
+            "cardAdmin",
+            "card_admin_handler"
+        ),
+        ...VPC_PCI_SUBNETS
+    }).setAccess([
+    {
+        actions: [DynamoActions.UpdateItem, DynamoActions.GetItem, DynamoActions.PutItem],
+        resource: DYNAMO_BLOCKED_CARD
+    },
+    {
+        actions: [DynamoActions.Query, DynamoActions.DeleteItem],
+        resource: DYNAMO_CARD_RETRY
+    },
+    {
+        actions: [DynamoActions.PutItem],
+        resource: DYNAMO_BLOCK_HISTORY
+    }
+])
+
+STACK.setPattern(PatternEnum.SINGLE_LAMBDA)
+    .setLambda({
+        ...LAMBDA_PROPS(
+            "blockHistory",
+            "block_history_handler"
+        ),
+        crossAccount: true
+    }).setAccess([
+    {
+        actions: [DynamoActions.Query],
+        resource: DYNAMO_BLOCK_HISTORY
+    }
+])
+
+STACK.setPattern(PatternEnum.SINGLE_LAMBDA)
+    .setLambda({
+        ...LAMBDA_PROPS(
+            "shadowPolicyReport",
+            "shadow_report_handler"
+        ),
+        crossAccount: true
+    }).setAccess([
+    {
+        actions: [DynamoActions.Query],
+        resource: DYNAMO_SHADOW_REPORT
+    }
 ])
 
 STACK.setPattern(PatternEnum.SQS_LAMBDA)
@@ -311,7 +601,8 @@ STACK.setPattern(PatternEnum.SQS_LAMBDA)
             type: EventsEnum.QueueEvent,
             props: {
                 source: CARD_INFO_PROCESSING_QUEUE,
-                batchSize: 1
+                batchSize: 10,
+                reportBatchItemFailures: true
             }
         }
     ])
@@ -326,6 +617,22 @@ STACK.setPattern(PatternEnum.SQS_LAMBDA)
         {
             actions: [DynamoActions.PutItem, DynamoActions.GetItem],
             resource: DYNAMO_CARD_INFO
+        },
+        {
+            actions: [DynamoActions.Query],
+            resource: DYNAMO_CARD_INFO_MERCHANT_KEY
+        },
+        {
+            actions: [DynamoActions.GetItem],
+            resource: DYNAMO_CARD_INFO_WEBHOOK_CONFIG
+        },
+        {
+            actions: [DynamoActions.PutItem, DynamoActions.GetItem],
+            resource: DYNAMO_CARD_INFO_WEBHOOK_DELIVERY
+        },
+        {
+            actions: [SQSActions.GetQueueUrl, SQSActions.SendMessage],
+            resource: CARD_INFO_WEBHOOK_QUEUE
         }
     ]);
 
@@ -346,5 +653,186 @@ STACK.setPattern(PatternEnum.SQS_LAMBDA)
         )
     });
 
+STACK.setPattern(PatternEnum.SQS_LAMBDA)
+    .setEvents([
+        {
+            type: EventsEnum.QueueEvent,
+            props: {
+                source: CARD_INFO_WEBHOOK_QUEUE,
+                batchSize: 10,
+                reportBatchItemFailures: true
+            }
+        }
+    ])
+    .setLambda({
+        ...LAMBDA_PROPS(
+            "cardInfoWebhook",
+            "card_info_webhook_handler"
+        ),
+        timeout: Duration.seconds(120),
+    })
+    .setAccess([
+        {
+            actions: [DynamoActions.GetItem],
+            resource: DYNAMO_CARD_INFO
+        },
+        {
+            actions: [DynamoActions.GetItem],
+            resource: DYNAMO_CARD_INFO_WEBHOOK_CONFIG
+        },
+        {
+            actions: [DynamoActions.PutItem, DynamoActions.GetItem],
+            resource: DYNAMO_CARD_INFO_WEBHOOK_DELIVERY
+        },
+        {
+            actions: [SQSActions.GetQueueUrl, SQSActions.SendMessage],
+            resource: CARD_INFO_WEBHOOK_QUEUE
+        }
+    ]);
+
+// Marks the deliveries of the webhook DLQ as failed and notifies them to Rollbar
+STACK.setPattern(PatternEnum.SQS_LAMBDA)
+    .setEvents([
+        {
+            type: EventsEnum.QueueEvent,
+            props: {
+                source: DEAD_LETTER_CARD_INFO_WEBHOOK_QUEUE,
+                batchSize: 1
+            }
+        }
+    ])
+    .setLambda({
+        ...LAMBDA_PROPS(
+            "cardInfoWebhookDLQ",
+            "card_info_webhook_dlq_handler"
+        )
+    })
+    .setAccess([
+        {
+            actions: [DynamoActions.PutItem, DynamoActions.GetItem],
+            resource: DYNAMO_CARD_INFO_WEBHOOK_DELIVERY
+        }
+    ]);
+
+STACK.setPattern(PatternEnum.SINGLE_LAMBDA)
+    .setEvents([
+        {
+            type: EventsEnum.ApiEvent,
+            props: {
+                method: "GET",
+                path: "/analytics/v1/card-info/{externalReferenceId}",
+                authorizer: {
+                    arn: STACK.utils.getEnvDynamodb("CARD_INFO_AUTHORIZER_ARN"),
+                    identitySource: "method.request.header.Private-Merchant-Id"
+                }
+            }
+        }
+    ])
+    .setLambda({
+        ...LAMBDA_PROPS(
+            "cardInfoRetrieval",
+            "card_info_retrieval_handler"
+        ),
+        timeout: Duration.seconds(3),
+    })
+    .setAccess([
+        {
+            actions: [DynamoActions.GetItem],
+            resource: DYNAMO_CARD_INFO
+        }
+    ]);
+
+STACK.setPattern(PatternEnum.SINGLE_LAMBDA)
+    .setEvents([
+        {
+            type: EventsEnum.ApiEvent,
+            props: {
+                method: "POST",
+                path: "/analytics/v1/card-info/keys",
+                authorizer: {
+                    arn: STACK.utils.getEnvDynamodb("CARD_INFO_AUTHORIZER_ARN"),
+                    identitySource: "method.request.header.Private-Merchant-Id"
+                }
+            }
+        }
+    ])
+    .setLambda({
+        ...LAMBDA_PROPS(
+            "cardInfoKeyRegistration",
+            "card_info_key_registration_handler"
+        ),
+        timeout: Duration.seconds(6),
+    })
+    .setAccess([
+        {
+            actions: [DynamoActions.PutItem],
+            resource: DYNAMO_CARD_INFO_MERCHANT_KEY
+        }
+    ]);
+
+// Promotes the copy of the card data stored while a merchant key was pending once that key activates.
+// It never re-encrypts, the records stored before the key was registered keep the previous key and are only reported
+STACK.setPattern(PatternEnum.SINGLE_LAMBDA)
+    .setEvents([
+        {
+            type: EventsEnum.ScheduleEvent,
+            props: {
+                schedule: Schedule.rate(cdk.Duration.hours(1))
+            }
+        }
+    ])
+    .setLambda({
+        ...LAMBDA_PROPS(
+            "cardInfoKeyPromotion",
+            "card_info_key_promotion_handler"
+        ),
+        timeout: Duration.minutes(15)
+    })
+    .setAccess([
+        {
+            actions: [DynamoActions.Scan],
+            resource: DYNAMO_CARD_INFO_MERCHANT_KEY
+        },
+        {
+            actions: [DynamoActions.Query, DynamoActions.UpdateItem],
+            resource: DYNAMO_CARD_INFO
+        }
+    ]);
+
+STACK.setPattern(PatternEnum.SINGLE_LAMBDA)
+    .setEvents([
+        {
+            type: EventsEnum.ScheduleEvent,
+            props: {
+                schedule: Schedule.rate(cdk.Duration.hours(1)),
+                input: {dryRun: false}
+            }
+        }
+    ])
+    .setLambda({
+        ...LAMBDA_PROPS(
+            "sweeper",
+            "sweeper_handler"
+        ),
+        timeout: Duration.minutes(15)
+    }).setAccess([
+    {
+        actions: [DynamoActions.Scan, DynamoActions.UpdateItem, DynamoActions.GetItem],
+        resource: DYNAMO_BLOCKED_CARD
+    },
+    {
+        actions: [DynamoActions.Scan, DynamoActions.DeleteItem],
+        resource: DYNAMO_CARD_RETRY
+    },
+    {
+        actions: [DynamoActions.GetItem],
+        resource: DYNAMO_RETRY_POLICY
+    },
+    {
+        actions: [DynamoActions.PutItem],
+        resource: DYNAMO_BLOCK_HISTORY
+    }
+]);
+
 // Build
 STACK.build();
\ No newline at end of file
@@ -0,0 +1,35 @@
+// Build batch check card status lambda.
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"bitbucket.org/kushki/usrv-card-control/service"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	"bitbucket.org/kushki/usrv-go-core/middleware"
+	"bitbucket.org/kushki/usrv-go-core/rollbar"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mefellows/vesper"
+)
+
+const required = "required"
+
+func batchCheckCardStatusHandler(ctx context.Context, event events.APIGatewayProxyRequest) (types.BatchCheckCardStatusResponse, error) {
+	return service.InitializeBatchCheckCardStatus(ctx, event)
+}
+
+func main() {
+
+	baseRules := map[string]interface{}{
+		"cards": required,
+	}
+
+	m := vesper.New(batchCheckCardStatusHandler).
+		Use(rollbar.WrapRollbar()).
+		Use(middleware.ErrorAPIMiddleware(false)).
+		Use(middleware.InputOutputLogsMiddleware()).
+		Use(middleware.SchemaValidationMiddleware(types.BatchCheckCardStatusRequest{}, baseRules)).
+		Use(middleware.APIGatewayMiddleware(middleware.ContentTypeJSON, middleware.ContentTypeJSON, true, http.StatusOK))
+	m.Start()
+}
@@ -10,8 +10,8 @@ import (
 	"github.com/mefellows/vesper"
 )
 
-func blockCardHandler(ctx context.Context, event events.SQSEvent) (bool, error) {
-	return true, service.InitBlockService(ctx, event)
+func blockCardHandler(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
+	return service.InitBlockService(ctx, event)
 }
 
 func main() {
@@ -0,0 +1,35 @@
+// Card block history lambda.
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"bitbucket.org/kushki/usrv-card-control/service"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	"bitbucket.org/kushki/usrv-go-core/middleware"
+	"bitbucket.org/kushki/usrv-go-core/rollbar"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mefellows/vesper"
+)
+
+const required = "required"
+
+func blockHistoryHandler(ctx context.Context, event events.APIGatewayProxyRequest) (types.BlockHistoryResponse, error) {
+	return service.InitializeBlockHistory(ctx, event)
+}
+
+func main() {
+
+	baseRules := map[string]interface{}{
+		"cardId": required,
+	}
+
+	m := vesper.New(blockHistoryHandler).
+		Use(rollbar.WrapRollbar()).
+		Use(middleware.ErrorAPIMiddleware(false)).
+		Use(middleware.InputOutputLogsMiddleware()).
+		Use(middleware.SchemaValidationMiddleware(types.BlockHistoryRequest{}, baseRules)).
+		Use(middleware.APIGatewayMiddleware(middleware.ContentTypeJSON, middleware.ContentTypeJSON, true, http.StatusOK))
+	m.Start()
+}
@@ -0,0 +1,37 @@
+// Card administration lambda.
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"bitbucket.org/kushki/usrv-card-control/service"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	"bitbucket.org/kushki/usrv-go-core/middleware"
+	"bitbucket.org/kushki/usrv-go-core/rollbar"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mefellows/vesper"
+)
+
+const required = "required"
+
+func cardAdminHandler(ctx context.Context, event events.APIGatewayProxyRequest) (types.CardAdminResponse, error) {
+	return service.InitializeCardAdmin(ctx, event)
+}
+
+func main() {
+
+	baseRules := map[string]interface{}{
+		"operation": required,
+		"cardId":    required,
+		"reason":    required,
+	}
+
+	m := vesper.New(cardAdminHandler).
+		Use(rollbar.WrapRollbar()).
+		Use(middleware.ErrorAPIMiddleware(false)).
+		Use(middleware.InputOutputLogsMiddleware()).
+		Use(middleware.SchemaValidationMiddleware(types.CardAdminRequest{}, baseRules)).
+		Use(middleware.APIGatewayMiddleware(middleware.ContentTypeJSON, middleware.ContentTypeJSON, true, http.StatusOK))
+	m.Start()
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"context"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/application/use_cases"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/infrastructure/config"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/infrastructure/handlers"
+	"bitbucket.org/kushki/usrv-go-core/middleware"
+	"bitbucket.org/kushki/usrv-go-core/rollbar"
+	"github.com/mefellows/vesper"
+)
+
+func cardInfoKeyPromotionHandler(ctx context.Context) (*use_cases.PromoteCardInfoKeysResponse, error) {
+	// Initialize dependencies
+	dependencies, err := config.NewMerchantKeyDependencyContainer(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	// Create handler
+	handler := handlers.NewScheduledKeyPromotionHandler(dependencies)
+
+	return handler.HandleScheduledEvent(ctx)
+}
+
+func main() {
+	m := vesper.New(cardInfoKeyPromotionHandler).
+		Use(rollbar.WrapRollbar()).
+		Use(middleware.InputOutputLogsMiddleware())
+
+	m.Start()
+}
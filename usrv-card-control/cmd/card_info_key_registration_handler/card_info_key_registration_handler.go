@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/infrastructure/config"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/infrastructure/handlers"
+	"bitbucket.org/kushki/usrv-go-core/rollbar"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mefellows/vesper"
+)
+
+func cardInfoKeyRegistrationHandler(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	// Initialize dependencies
+	dependencies, err := config.NewMerchantKeyDependencyContainer(ctx)
+	if err != nil {
+		return events.APIGatewayProxyResponse{}, err
+	}
+
+	// Create handler
+	handler := handlers.NewAPICardInfoHandler(dependencies)
+
+	// Every outcome is answered with its documented status code
+	return handler.HandleRegisterMerchantKey(ctx, event), nil
+}
+
+func main() {
+	m := vesper.New(cardInfoKeyRegistrationHandler).
+		Use(rollbar.WrapRollbar())
+
+	m.Start()
+}
@@ -1,4 +1,4 @@
-package card_info_processor_dlq_handler
+package main
 
 import (
 	"context"
@@ -31,7 +31,7 @@ func RunCardInfoDLQ(_ context.Context, event events.SQSEvent) (bool, error) {
 	rollbar.SetCodeVersion(os.Getenv(constants.EnvUsrvCommit))
 	rollbar.SetServerRoot(os.Getenv(constants.EnvUsrvStage))
 
-	var request entities.CardInfoMessage
+	var request entities.PxpCardInfoMessage
 	if err = json.Unmarshal([]byte(event.Records[0].Body), &request); err != nil {
 		return false, err
 	}
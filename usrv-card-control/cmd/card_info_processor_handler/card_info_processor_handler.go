@@ -11,23 +11,18 @@ import (
 	"github.com/mefellows/vesper"
 )
 
-func cardInfoProcessorHandler(ctx context.Context, event events.SQSEvent) (bool, error) {
+func cardInfoProcessorHandler(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
 	// Initialize dependencies
 	dependencies, err := config.NewDependencyContainer(ctx)
 	if err != nil {
-		return false, err
+		return events.SQSEventResponse{}, err
 	}
 
 	// Create handler
 	handler := handlers.NewSQSCardInfoHandler(dependencies)
 
-	// Process the SQS event
-	err = handler.HandleSQSEvent(ctx, event)
-	if err != nil {
-		return false, err
-	}
-
-	return true, nil
+	// Process the SQS event, failed records are reported back to be retried
+	return handler.HandleSQSEvent(ctx, event), nil
 }
 
 func main() {
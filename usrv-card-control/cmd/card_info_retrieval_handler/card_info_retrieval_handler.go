@@ -0,0 +1,53 @@
+package main
+
+import (
+	"context"
+	"sync"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/infrastructure/config"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/infrastructure/handlers"
+	"bitbucket.org/kushki/usrv-go-core/rollbar"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mefellows/vesper"
+)
+
+var (
+	retrievalHandler *handlers.APICardInfoHandler
+	retrievalMutex   sync.Mutex
+)
+
+// getRetrievalHandler builds the dependencies on the cold start and reuses them on the warm invocations,
+// a failed initialization is retried by the next invocation
+func getRetrievalHandler(ctx context.Context) (*handlers.APICardInfoHandler, error) {
+	retrievalMutex.Lock()
+	defer retrievalMutex.Unlock()
+
+	if retrievalHandler != nil {
+		return retrievalHandler, nil
+	}
+
+	dependencies, err := config.NewRetrievalDependencyContainer(ctx)
+	if err != nil {
+		return nil, err
+	}
+	retrievalHandler = handlers.NewAPICardInfoHandler(dependencies)
+
+	return retrievalHandler, nil
+}
+
+func cardInfoRetrievalHandler(ctx context.Context, event events.APIGatewayProxyRequest) (events.APIGatewayProxyResponse, error) {
+	handler, err := getRetrievalHandler(ctx)
+	if err != nil {
+		return events.APIGatewayProxyResponse{}, err
+	}
+
+	// Every outcome is answered with its documented status code
+	return handler.HandleGetCardInfo(ctx, event), nil
+}
+
+func main() {
+	m := vesper.New(cardInfoRetrievalHandler).
+		Use(rollbar.WrapRollbar())
+
+	m.Start()
+}
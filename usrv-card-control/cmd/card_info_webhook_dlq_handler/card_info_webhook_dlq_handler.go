@@ -0,0 +1,60 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	constants "bitbucket.org/kushki/usrv-card-control"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/application/use_cases"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/entities"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/infrastructure/config"
+	"bitbucket.org/kushki/usrv-go-core/middleware"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mefellows/vesper"
+	"github.com/rollbar/rollbar-go"
+)
+
+func RunCardInfoWebhookDLQ(ctx context.Context, event events.SQSEvent) (bool, error) {
+	const cardInfoWebhookDLQServiceTag = "cardInfoWebhookDLQ | %s"
+
+	dependencies, err := config.NewWebhookDLQDependencyContainer(ctx)
+	if err != nil {
+		return false, err
+	}
+
+	rollbar.SetToken(os.Getenv(constants.EnvRollbarToken))
+	rollbar.SetEnvironment(os.Getenv(constants.EnvUsrvStage))
+	rollbar.SetCodeVersion(os.Getenv(constants.EnvUsrvCommit))
+	rollbar.SetServerRoot(os.Getenv(constants.EnvUsrvStage))
+
+	source := fmt.Sprintf(cardInfoWebhookDLQServiceTag, "NotifyRollbar")
+	for _, record := range event.Records {
+		// The message only carries the external reference ID, the card data is never in the queue
+		delivery, err := dependencies.FailCardInfoWebhookUseCase.Execute(ctx, use_cases.FailCardInfoWebhookRequest{
+			SQSMessageBody: record.Body,
+		})
+		if err != nil {
+			return false, err
+		}
+		if delivery == nil || delivery.Status != entities.WebhookDeliveryFailed {
+			continue
+		}
+
+		errorMessage := fmt.Errorf("error delivering card info webhook for externalReferenceId %s and merchant %s after %d attempts",
+			delivery.ExternalReferenceID, delivery.MerchantID, delivery.Attempts)
+		dependencies.Logger.Error(source, errorMessage.Error())
+		rollbar.Error(errorMessage)
+	}
+	rollbar.Wait()
+
+	return true, nil
+}
+
+func main() {
+	m := vesper.New(RunCardInfoWebhookDLQ).
+		Use(middleware.InputOutputLogsMiddleware()).
+		Use(middleware.DynamoParamsMiddleware(false))
+
+	m.Start()
+}
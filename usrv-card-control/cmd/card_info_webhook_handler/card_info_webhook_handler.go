@@ -0,0 +1,32 @@
+package main
+
+import (
+	"context"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/infrastructure/config"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/infrastructure/handlers"
+	"bitbucket.org/kushki/usrv-go-core/rollbar"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mefellows/vesper"
+)
+
+func cardInfoWebhookHandler(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
+	// Initialize dependencies
+	dependencies, err := config.NewDependencyContainer(ctx)
+	if err != nil {
+		return events.SQSEventResponse{}, err
+	}
+
+	// Create handler
+	handler := handlers.NewSQSWebhookHandler(dependencies)
+
+	// The payload carries encrypted card data, so the input and output are not logged
+	return handler.HandleSQSEvent(ctx, event), nil
+}
+
+func main() {
+	m := vesper.New(cardInfoWebhookHandler).
+		Use(rollbar.WrapRollbar())
+
+	m.Start()
+}
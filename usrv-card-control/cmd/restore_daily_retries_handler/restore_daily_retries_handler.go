@@ -10,8 +10,8 @@ import (
 	"github.com/mefellows/vesper"
 )
 
-func restoreDailyRetriesHandler(ctx context.Context, event events.SQSEvent) (bool, error) {
-	return true, service.InitRestoreService(ctx, event)
+func restoreDailyRetriesHandler(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
+	return service.InitRestoreService(ctx, event)
 }
 
 func main() {
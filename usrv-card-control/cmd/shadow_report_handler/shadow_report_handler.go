@@ -0,0 +1,35 @@
+// Shadow policy report lambda.
+package main
+
+import (
+	"context"
+	"net/http"
+
+	"bitbucket.org/kushki/usrv-card-control/service"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	"bitbucket.org/kushki/usrv-go-core/middleware"
+	"bitbucket.org/kushki/usrv-go-core/rollbar"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/mefellows/vesper"
+)
+
+const required = "required"
+
+func shadowReportHandler(ctx context.Context, event events.APIGatewayProxyRequest) (types.ShadowReportResponse, error) {
+	return service.InitializeShadowReport(ctx, event)
+}
+
+func main() {
+
+	baseRules := map[string]interface{}{
+		"policyId": required,
+	}
+
+	m := vesper.New(shadowReportHandler).
+		Use(rollbar.WrapRollbar()).
+		Use(middleware.ErrorAPIMiddleware(false)).
+		Use(middleware.InputOutputLogsMiddleware()).
+		Use(middleware.SchemaValidationMiddleware(types.ShadowReportRequest{}, baseRules)).
+		Use(middleware.APIGatewayMiddleware(middleware.ContentTypeJSON, middleware.ContentTypeJSON, true, http.StatusOK))
+	m.Start()
+}
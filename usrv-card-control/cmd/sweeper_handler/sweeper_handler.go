@@ -0,0 +1,23 @@
+package main
+
+import (
+	"context"
+
+	"bitbucket.org/kushki/usrv-card-control/service"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	"bitbucket.org/kushki/usrv-go-core/middleware"
+	"bitbucket.org/kushki/usrv-go-core/rollbar"
+	"github.com/mefellows/vesper"
+)
+
+func sweeperHandler(ctx context.Context, request types.SweepRequest) (types.SweepResult, error) {
+	return service.InitSweeperService(ctx, request)
+}
+
+func main() {
+	m := vesper.New(sweeperHandler).
+		Use(rollbar.WrapRollbar()).
+		Use(middleware.InputOutputLogsMiddleware())
+
+	m.Start()
+}
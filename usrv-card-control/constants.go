@@ -1,56 +1,186 @@
 // Package constants.
 package constants
 
-import core "bitbucket.org/kushki/usrv-go-core"
-
 // Operations and frequencies.
 const (
-	BlockCardOperation = "block"
-	RetryCardOperation = "retry"
+	BlockCardOperation   = "block"
+	RetryCardOperation   = "retry"
+	ApproveCardOperation = "approve"
+
+	DailyFrequency      = "daily"
+	MonthlyFrequency    = "monthly"
+	RetryAfterFrequency = "retryAfter"
 
-	DailyFrequency   = "daily"
-	MonthlyFrequency = "monthly"
+	// CalendarDay and CalendarMonth align a rule window to the calendar of its time zone, rules without calendar roll.
+	CalendarDay   = "day"
+	CalendarMonth = "month"
 )
 
-var (
-	// Frequencies franchise frequencies to be applied.
-	Frequencies = map[string][]string{
-		core.BrandVisa: {
-			MonthlyFrequency,
-		},
-		core.BrandMasterCard: {
-			DailyFrequency, MonthlyFrequency,
-		},
-	}
+// Retry policy fields.
+const (
+	DynamoRetryPolicy    = "DYNAMO_RETRY_POLICY"
+	PolicyIDField        = "policyID"
+	PolicyWildcard       = "*"
+	PolicyCacheMinutes   = 5
+	DefaultPolicyVersion = "default-v1"
+
+	// FallbackPolicyVersion version of the built-in policy for brands without a default, stored as PolicyWildcard#*#*.
+	FallbackPolicyVersion = "fallback-v1"
+)
 
-	// Limits max retries per frequency.
-	Limits = map[string]map[string]int{
-		core.BrandVisa: {
-			MonthlyFrequency: 15,
-		},
-		core.BrandMasterCard: {
-			MonthlyFrequency: 35,
-			DailyFrequency:   7,
-		},
-	}
+// Shadow policies, stored next to the active ones and evaluated without writing blocks.
+const (
+	ShadowPolicyPrefix   = "SHADOW#"
+	ShadowRetryPrefix    = "shadow"
+	DynamoShadowReport   = "DYNAMO_SHADOW_REPORT"
+	ReportKeyField       = "reportKey"
+	ReportKeyFormat      = "%s#%s#%s"
+	EvaluationsField     = "evaluations"
+	ActiveBlocksField    = "activeBlocks"
+	ShadowBlocksField    = "shadowBlocks"
+	ExtraBlocksField     = "extraBlocks"
+	FewerBlocksField     = "fewerBlocks"
+	VersionField         = "version"
+	ShadowReportPageSize = 100
 )
 
 // Block and Retry card fields.
 const (
 	CardIdField         = "cardID"
 	LastRetryField      = "lastRetry"
+	RetriesEndField     = "retriesEnd"
 	BlockedMerchants    = "blockedMerchants"
 	MerchantIDField     = "merchantID"
+	BrandField          = "brand"
+	ProcessorField      = "processor"
 	RetryKeyField       = "retryKey"
 	RetriesField        = "retries"
 	CardIdMerchantIndex = "cardIdMerchantIndex"
+	MerchantIdCardIndex = "merchantIdCardIndex"
 	TimeStamp           = "timeStamp"
+	ExpiresAtField      = "expiresAt"
+	CardBlockField      = "cardBlock"
 
 	DynamoBlockedCard = "DYNAMO_BLOCKED_CARD"
 	DynamoCardRetry   = "DYNAMO_CARD_RETRY"
 	DayHours          = 24
 	MonthDays         = 30
-	Daily             = "daily"
+
+	// BlockedCardRetentionHours a card without blocks outlives its last update by the longest retry window.
+	BlockedCardRetentionHours = MonthDays * DayHours
+)
+
+// Card velocity across merchants, disabled until its rules are configured.
+const (
+	EnvCardVelocityRules     = "CARD_VELOCITY_RULES"
+	EnvCardVelocityAllowlist = "CARD_VELOCITY_ALLOWLIST"
+	VelocityMerchantID       = "*"
+)
+
+// Merchant settings, read once per merchant for the lambda lifetime.
+const (
+	DynamoMerchantSettings = "DYNAMO_MERCHANT_SETTINGS"
+)
+
+// Restore scopes, by default the daily retries of a card and merchant are restored.
+const (
+	RestoreCardMerchantScope = "cardMerchant"
+	RestoreCardScope         = "card"
+	RestoreMerchantScope     = "merchant"
+	RestoreAllFrequencies    = "all"
+	RestorePageSize          = 100
+)
+
+// Card administration operations.
+const (
+	AdminUnblockOperation      = "unblock"
+	AdminUnblockAllOperation   = "unblockAll"
+	AdminBlockOperation        = "block"
+	AdminResetRetriesOperation = "resetRetries"
+
+	LastAdminActionField = "lastAdminAction"
+	PrincipalIDField     = "principalId"
+	ClaimsField          = "claims"
+	EmailClaim           = "email"
+)
+
+// Decline categories, Visa reattempt categories and Mastercard Merchant Advice Codes.
+const (
+	VisaIssuerWillNeverApprove  = "VISA_CATEGORY_1"
+	VisaIssuerCannotApproveNow  = "VISA_CATEGORY_2"
+	VisaDataQuality             = "VISA_CATEGORY_3"
+	VisaGenericDecline          = "VISA_CATEGORY_4"
+	MasterCardUpdateAccount     = "MC_MAC_UPDATE_ACCOUNT"
+	MasterCardTryAgainLater     = "MC_MAC_TRY_AGAIN_LATER"
+	MasterCardRetryAfter1Hour   = "MC_MAC_RETRY_AFTER_1H"
+	MasterCardRetryAfter24Hours = "MC_MAC_RETRY_AFTER_24H"
+	MasterCardRetryAfter2Days   = "MC_MAC_RETRY_AFTER_2D"
+	MasterCardRetryAfter4Days   = "MC_MAC_RETRY_AFTER_4D"
+	MasterCardRetryAfter6Days   = "MC_MAC_RETRY_AFTER_6D"
+	MasterCardRetryAfter8Days   = "MC_MAC_RETRY_AFTER_8D"
+	MasterCardRetryAfter10Days  = "MC_MAC_RETRY_AFTER_10D"
+	MasterCardDoNotTryAgain     = "MC_MAC_DO_NOT_TRY_AGAIN"
+	UnclassifiedDeclineCategory = "UNCLASSIFIED"
+)
+
+// Block history fields and operations.
+const (
+	DynamoBlockHistory = "DYNAMO_BLOCK_HISTORY"
+	EventKeyField      = "eventKey"
+	EventKeyFormat     = "%013d#%s"
+	HistoryPageSize    = 50
+
+	HistoryRetryIncrement = "RETRY_INCREMENT"
+	HistoryTemporaryBlock = "TEMPORARY_BLOCK"
+	HistoryPermanentBlock = "PERMANENT_BLOCK"
+	HistoryExpired        = "EXPIRED"
+	HistoryRestore        = "RESTORE"
+	HistoryApproved       = "APPROVED"
+	HistoryCardBlock      = "CARD_BLOCK"
+	HistoryShadowBlock    = "SHADOW_BLOCK"
+	HistoryShadowPolicy   = "SHADOW_POLICY_BLOCK"
+	HistoryAdminPrefix    = "ADMIN_"
+)
+
+// Optimistic concurrency retries.
+const (
+	ConflictMaxRetries  = 5
+	ConflictBaseDelayMs = 25
+	ConflictMaxDelayMs  = 400
+)
+
+// Card status answer when the blocked card cannot be read.
+const (
+	EnvCardStatusFailMode          = "CARD_STATUS_FAIL_MODE"
+	EnvCardStatusMerchantFailModes = "CARD_STATUS_MERCHANT_FAIL_MODES"
+
+	FailOpen   = "OPEN"
+	FailClosed = "CLOSED"
+	FailError  = "ERROR"
+)
+
+// Batch card status limits, BatchGetItem reads up to 100 keys per call.
+const (
+	BatchCheckMaxCards  = 500
+	BatchGetMaxKeys     = 100
+	BatchGetMaxAttempts = 3
+)
+
+// Sweeper of expired blocks and stale retries, retries out of the window of their rule are stale.
+const (
+	EnvSweeperDryRun = "SWEEPER_DRY_RUN"
+	SweeperPageSize  = 100
+)
+
+// Dedup records of the block messages, long enough to cover SQS redeliveries and DLQ redrives.
+// A claim in progress expires with the visibility timeout of the block queue, so a crashed message is processed on redelivery.
+const (
+	DynamoProcessedMessage       = "DYNAMO_PROCESSED_MESSAGE"
+	IdempotencyKeyField          = "idempotencyKey"
+	IdempotencyTTLHours          = 24
+	IdempotencyInProgressMinutes = 5
+	ProcessedMessageInProgress   = "IN_PROGRESS"
+	ProcessedMessageCompleted    = "COMPLETED"
 )
 
 // BLOCK TYPE
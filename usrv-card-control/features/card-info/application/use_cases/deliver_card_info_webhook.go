@@ -0,0 +1,221 @@
+package use_cases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/entities"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/repositories"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/services"
+	"bitbucket.org/kushki/usrv-card-control/features/shared/constants"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+)
+
+// DeliverCardInfoWebhookUseCase delivers the stored card info to the webhook registered by the merchant
+// Deliveries are at least once, the receiver identifies duplicates by externalReferenceId
+type DeliverCardInfoWebhookUseCase struct {
+	cardInfoRepo repositories.CardInfoRepository
+	webhookRepo  repositories.WebhookRepository
+	webhookQueue services.WebhookQueue
+	sender       services.WebhookSender
+	logger       logger.KushkiLogger
+	now          func() time.Time
+}
+
+// NewDeliverCardInfoWebhookUseCase creates a new instance of the use case
+func NewDeliverCardInfoWebhookUseCase(
+	cardInfoRepo repositories.CardInfoRepository,
+	webhookRepo repositories.WebhookRepository,
+	webhookQueue services.WebhookQueue,
+	sender services.WebhookSender,
+	logger logger.KushkiLogger,
+) *DeliverCardInfoWebhookUseCase {
+	return &DeliverCardInfoWebhookUseCase{
+		cardInfoRepo: cardInfoRepo,
+		webhookRepo:  webhookRepo,
+		webhookQueue: webhookQueue,
+		sender:       sender,
+		logger:       logger,
+		now:          time.Now,
+	}
+}
+
+// DeliverCardInfoWebhookRequest represents the input for the use case
+type DeliverCardInfoWebhookRequest struct {
+	SQSMessageBody string
+}
+
+// Schedule queues the first delivery attempt when the merchant has an active webhook
+func (uc *DeliverCardInfoWebhookUseCase) Schedule(ctx context.Context, merchantID, externalReferenceID string) error {
+	const useCase = "ScheduleCardInfoWebhook"
+
+	// Step 1: The delivery is optional, only merchants with an active webhook get it
+	webhook, err := uc.webhookRepo.FindConfig(ctx, merchantID)
+	if errors.Is(err, dynamoerror.ErrItemNotFound) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to find webhook config: %w", err)
+	}
+	if !webhook.IsActive() {
+		return nil
+	}
+
+	// Step 2: A redelivered card info message must not schedule the delivery twice
+	delivery, err := uc.webhookRepo.FindDelivery(ctx, externalReferenceID)
+	if err != nil && !errors.Is(err, dynamoerror.ErrItemNotFound) {
+		return fmt.Errorf("failed to find webhook delivery: %w", err)
+	}
+	if delivery != nil && delivery.IsScheduled() {
+		return nil
+	}
+
+	// Step 3: The status is stored before queueing, the attempt always finds it
+	currentTime := uc.now()
+	delivery = &entities.WebhookDelivery{
+		ExternalReferenceID: externalReferenceID,
+		MerchantID:          merchantID,
+		Status:              entities.WebhookDeliveryPending,
+		CreatedAt:           currentTime.UnixMilli(),
+		UpdatedAt:           currentTime.UnixMilli(),
+		ExpiresAt:           currentTime.AddDate(0, 0, constants.CardInfoTableTTLDays).Unix(),
+	}
+	if err := uc.webhookRepo.SaveDelivery(ctx, delivery); err != nil {
+		return fmt.Errorf("failed to save webhook delivery: %w", err)
+	}
+
+	if err := uc.webhookQueue.Enqueue(ctx, entities.WebhookDeliveryMessage{ExternalReferenceID: externalReferenceID}, 0); err != nil {
+		return fmt.Errorf("failed to queue webhook delivery: %w", err)
+	}
+
+	uc.logger.Info(fmt.Sprintf("%s | Scheduled", useCase),
+		fmt.Sprintf("ExternalReferenceID: %s, MerchantID: %s", externalReferenceID, merchantID))
+
+	return nil
+}
+
+// Execute makes a delivery attempt and queues the next one with backoff when it fails
+// Only infrastructure errors are returned, so SQS retries the message
+func (uc *DeliverCardInfoWebhookUseCase) Execute(ctx context.Context, request DeliverCardInfoWebhookRequest) error {
+	const useCase = "DeliverCardInfoWebhook"
+
+	// Step 1: Parse the SQS message
+	var message entities.WebhookDeliveryMessage
+	if err := json.Unmarshal([]byte(request.SQSMessageBody), &message); err != nil {
+		uc.logger.Error(fmt.Sprintf("%s | ParseError", useCase), err)
+		return fmt.Errorf("failed to parse SQS message: %w", err)
+	}
+
+	uc.logger.Info(fmt.Sprintf("%s | Starting", useCase),
+		fmt.Sprintf("ExternalReferenceID: %s", message.ExternalReferenceID))
+
+	// Step 2: Find the delivery status
+	delivery, err := uc.webhookRepo.FindDelivery(ctx, message.ExternalReferenceID)
+	if errors.Is(err, dynamoerror.ErrItemNotFound) {
+		uc.logger.Info(fmt.Sprintf("%s | DeliveryNotFound", useCase),
+			fmt.Sprintf("ExternalReferenceID: %s", message.ExternalReferenceID))
+		return nil
+	}
+	if err != nil {
+		uc.logger.Error(fmt.Sprintf("%s | FindDeliveryError", useCase), err)
+		return fmt.Errorf("failed to find webhook delivery: %w", err)
+	}
+	if delivery.IsFinal() {
+		uc.logger.Info(fmt.Sprintf("%s | AlreadyFinished", useCase),
+			fmt.Sprintf("ExternalReferenceID: %s, Status: %s", delivery.ExternalReferenceID, delivery.Status))
+		return nil
+	}
+
+	// Step 3: The webhook may have been disabled since the delivery was scheduled
+	webhook, err := uc.webhookRepo.FindConfig(ctx, delivery.MerchantID)
+	if err != nil && !errors.Is(err, dynamoerror.ErrItemNotFound) {
+		uc.logger.Error(fmt.Sprintf("%s | FindConfigError", useCase), err)
+		return fmt.Errorf("failed to find webhook config: %w", err)
+	}
+	if webhook == nil || !webhook.IsActive() {
+		return uc.finish(ctx, delivery, entities.WebhookDeliveryFailed, "webhook is not active")
+	}
+
+	// Step 4: Build the payload from the stored card info
+	cardInfo, err := uc.cardInfoRepo.FindByMerchantIDAndExternalReferenceID(ctx, delivery.MerchantID, delivery.ExternalReferenceID)
+	if err != nil && !errors.Is(err, dynamoerror.ErrItemNotFound) {
+		uc.logger.Error(fmt.Sprintf("%s | FindCardInfoError", useCase), err)
+		return fmt.Errorf("failed to find card info: %w", err)
+	}
+	if cardInfo == nil || cardInfo.IsExpired(uc.now().UnixMilli()) {
+		return uc.finish(ctx, delivery, entities.WebhookDeliveryFailed, "card info is not available")
+	}
+
+	// The payload is a plain struct, marshaling it cannot fail
+	payload, _ := json.Marshal(newTransactionResponse(cardInfo))
+
+	// Step 5: Post the payload
+	statusCode, err := uc.sender.Send(ctx, *webhook, payload)
+	delivery.Attempts++
+	delivery.LastStatusCode = statusCode
+	if err == nil && statusCode >= http.StatusOK && statusCode < http.StatusMultipleChoices {
+		delivery.DeliveredAt = uc.now().UnixMilli()
+		return uc.finish(ctx, delivery, entities.WebhookDeliveryDelivered, "")
+	}
+
+	lastError := fmt.Sprintf("webhook answered with status %d", statusCode)
+	if err != nil {
+		lastError = err.Error()
+	}
+	uc.logger.Info(fmt.Sprintf("%s | AttemptFailed", useCase),
+		fmt.Sprintf("ExternalReferenceID: %s, Attempt: %d, Error: %s", delivery.ExternalReferenceID, delivery.Attempts, lastError))
+
+	// Step 6: Give up after the last attempt or queue the next one
+	if delivery.Attempts >= constants.WebhookMaxAttempts {
+		return uc.finish(ctx, delivery, entities.WebhookDeliveryFailed, lastError)
+	}
+
+	if err := uc.finish(ctx, delivery, entities.WebhookDeliveryRetrying, lastError); err != nil {
+		return err
+	}
+
+	if err := uc.webhookQueue.Enqueue(ctx, message, getWebhookRetryDelay(delivery.Attempts)); err != nil {
+		uc.logger.Error(fmt.Sprintf("%s | EnqueueError", useCase), err)
+		return fmt.Errorf("failed to queue webhook retry: %w", err)
+	}
+
+	return nil
+}
+
+// finish stores the outcome of the attempt
+func (uc *DeliverCardInfoWebhookUseCase) finish(
+	ctx context.Context,
+	delivery *entities.WebhookDelivery,
+	status, lastError string,
+) error {
+	const useCase = "DeliverCardInfoWebhook"
+
+	delivery.Status = status
+	delivery.LastError = lastError
+	delivery.UpdatedAt = uc.now().UnixMilli()
+
+	if err := uc.webhookRepo.SaveDelivery(ctx, delivery); err != nil {
+		uc.logger.Error(fmt.Sprintf("%s | SaveDeliveryError", useCase), err)
+		return fmt.Errorf("failed to save webhook delivery: %w", err)
+	}
+
+	uc.logger.Info(fmt.Sprintf("%s | %s", useCase, status),
+		fmt.Sprintf("ExternalReferenceID: %s, Attempts: %d", delivery.ExternalReferenceID, delivery.Attempts))
+
+	return nil
+}
+
+// getWebhookRetryDelay doubles the delay on every failed attempt
+func getWebhookRetryDelay(attempts int) time.Duration {
+	delay := constants.WebhookRetryBaseDelay
+	for i := 1; i < attempts && delay < constants.WebhookRetryMaxDelay; i++ {
+		delay *= 2
+	}
+
+	return min(delay, constants.WebhookRetryMaxDelay)
+}
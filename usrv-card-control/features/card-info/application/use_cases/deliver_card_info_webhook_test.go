@@ -0,0 +1,292 @@
+package use_cases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/entities"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/value_objects"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockWebhookRepository struct {
+	mock.Mock
+}
+
+func (m *MockWebhookRepository) FindConfig(ctx context.Context, merchantID string) (*value_objects.WebhookConfig, error) {
+	args := m.Called(ctx, merchantID)
+	return args.Get(0).(*value_objects.WebhookConfig), args.Error(1)
+}
+
+func (m *MockWebhookRepository) FindDelivery(ctx context.Context, externalReferenceID string) (*entities.WebhookDelivery, error) {
+	args := m.Called(ctx, externalReferenceID)
+	return args.Get(0).(*entities.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookRepository) SaveDelivery(ctx context.Context, delivery *entities.WebhookDelivery) error {
+	args := m.Called(ctx, delivery)
+	return args.Error(0)
+}
+
+type MockWebhookQueue struct {
+	mock.Mock
+}
+
+func (m *MockWebhookQueue) Enqueue(ctx context.Context, message entities.WebhookDeliveryMessage, delay time.Duration) error {
+	args := m.Called(ctx, message, delay)
+	return args.Error(0)
+}
+
+type MockWebhookSender struct {
+	mock.Mock
+}
+
+func (m *MockWebhookSender) Send(ctx context.Context, webhook value_objects.WebhookConfig, payload []byte) (int, error) {
+	args := m.Called(ctx, webhook, payload)
+	return args.Int(0), args.Error(1)
+}
+
+var activeWebhook = &value_objects.WebhookConfig{
+	MerchantID: "merchant-123",
+	URL:        "https://pci.example.com/webhook",
+	Secret:     "secret",
+	Enabled:    true,
+}
+
+func TestDeliverCardInfoWebhookUseCase_Schedule(t *testing.T) {
+	deliveryMessage := entities.WebhookDeliveryMessage{ExternalReferenceID: "ext-ref-123"}
+
+	tests := []struct {
+		name          string
+		webhook       *value_objects.WebhookConfig
+		configErr     error
+		delivery      *entities.WebhookDelivery
+		deliveryErr   error
+		expectedQueue bool
+		expectedErr   bool
+	}{
+		{
+			name:          "should schedule the first attempt",
+			webhook:       activeWebhook,
+			delivery:      (*entities.WebhookDelivery)(nil),
+			deliveryErr:   dynamoerror.ErrItemNotFound,
+			expectedQueue: true,
+		},
+		{
+			name:      "should skip merchants without webhook",
+			webhook:   (*value_objects.WebhookConfig)(nil),
+			configErr: dynamoerror.ErrItemNotFound,
+		},
+		{
+			name:    "should skip disabled webhooks",
+			webhook: &value_objects.WebhookConfig{URL: "https://pci.example.com/webhook", Secret: "secret"},
+		},
+		{
+			name:     "should not schedule a delivery twice",
+			webhook:  activeWebhook,
+			delivery: &entities.WebhookDelivery{Status: entities.WebhookDeliveryRetrying, Attempts: 1},
+		},
+		{
+			name:          "should queue again a pending delivery without attempts",
+			webhook:       activeWebhook,
+			delivery:      &entities.WebhookDelivery{Status: entities.WebhookDeliveryPending},
+			expectedQueue: true,
+		},
+		{
+			name:        "should return the config error",
+			webhook:     (*value_objects.WebhookConfig)(nil),
+			configErr:   errors.New("dynamo error"),
+			expectedErr: true,
+		},
+		{
+			name:        "should return the delivery error",
+			webhook:     activeWebhook,
+			delivery:    (*entities.WebhookDelivery)(nil),
+			deliveryErr: errors.New("dynamo error"),
+			expectedErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockWebhookRepo := &MockWebhookRepository{}
+			mockQueue := &MockWebhookQueue{}
+			mockLogger := &MockLogger{}
+			mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
+			mockWebhookRepo.On("FindConfig", mock.Anything, "merchant-123").Return(tt.webhook, tt.configErr)
+			mockWebhookRepo.On("FindDelivery", mock.Anything, "ext-ref-123").Return(tt.delivery, tt.deliveryErr)
+			mockWebhookRepo.On("SaveDelivery", mock.Anything, mock.MatchedBy(func(delivery *entities.WebhookDelivery) bool {
+				return delivery.Status == entities.WebhookDeliveryPending && delivery.MerchantID == "merchant-123"
+			})).Return(nil)
+			mockQueue.On("Enqueue", mock.Anything, deliveryMessage, time.Duration(0)).Return(nil)
+			useCase := NewDeliverCardInfoWebhookUseCase(&MockCardInfoRepository{}, mockWebhookRepo, mockQueue, &MockWebhookSender{}, mockLogger)
+
+			err := useCase.Schedule(context.Background(), "merchant-123", "ext-ref-123")
+
+			assert.Equal(t, tt.expectedErr, err != nil)
+			if tt.expectedQueue {
+				mockWebhookRepo.AssertCalled(t, "SaveDelivery", mock.Anything, mock.Anything)
+				mockQueue.AssertExpectations(t)
+				return
+			}
+			mockWebhookRepo.AssertNotCalled(t, "SaveDelivery", mock.Anything, mock.Anything)
+			mockQueue.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestDeliverCardInfoWebhookUseCase_Execute(t *testing.T) {
+	now := time.Now()
+	cardInfo := &entities.StoredCardInfo{
+		ExternalReferenceID: "ext-ref-123",
+		MerchantID:          "merchant-123",
+		CardBrand:           "VISA",
+		EncryptedCard:       value_objects.EncryptedCardData{EncryptedPan: "enc-pan", EncryptedDate: "enc-date"},
+		ExpiresAt:           now.Add(time.Hour).UnixMilli(),
+	}
+	expectedPayload, _ := json.Marshal(newTransactionResponse(cardInfo))
+	deliveryMessage := entities.WebhookDeliveryMessage{ExternalReferenceID: "ext-ref-123"}
+	messageBody := `{"externalReferenceId":"ext-ref-123"}`
+
+	tests := []struct {
+		name             string
+		messageBody      string
+		delivery         *entities.WebhookDelivery
+		deliveryErr      error
+		cardInfo         *entities.StoredCardInfo
+		cardInfoErr      error
+		statusCode       int
+		sendErr          error
+		enqueueErr       error
+		expectedStatus   string
+		expectedAttempts int
+		expectedDelay    time.Duration
+		expectedErr      bool
+	}{
+		{
+			name:             "should mark the delivery as delivered",
+			delivery:         &entities.WebhookDelivery{Status: entities.WebhookDeliveryPending},
+			cardInfo:         cardInfo,
+			statusCode:       http.StatusOK,
+			expectedStatus:   entities.WebhookDeliveryDelivered,
+			expectedAttempts: 1,
+		},
+		{
+			name:             "should retry with backoff when the webhook answers an error",
+			delivery:         &entities.WebhookDelivery{Status: entities.WebhookDeliveryRetrying, Attempts: 2},
+			cardInfo:         cardInfo,
+			statusCode:       http.StatusServiceUnavailable,
+			expectedStatus:   entities.WebhookDeliveryRetrying,
+			expectedAttempts: 3,
+			expectedDelay:    2 * time.Minute,
+		},
+		{
+			name:             "should retry when the request fails",
+			delivery:         &entities.WebhookDelivery{Status: entities.WebhookDeliveryPending},
+			cardInfo:         cardInfo,
+			sendErr:          errors.New("timeout"),
+			expectedStatus:   entities.WebhookDeliveryRetrying,
+			expectedAttempts: 1,
+			expectedDelay:    30 * time.Second,
+		},
+		{
+			name:             "should fail the delivery after the last attempt",
+			delivery:         &entities.WebhookDelivery{Status: entities.WebhookDeliveryRetrying, Attempts: 5},
+			cardInfo:         cardInfo,
+			statusCode:       http.StatusInternalServerError,
+			expectedStatus:   entities.WebhookDeliveryFailed,
+			expectedAttempts: 6,
+		},
+		{
+			name:           "should fail the delivery when the card info is not available",
+			delivery:       &entities.WebhookDelivery{Status: entities.WebhookDeliveryPending},
+			cardInfo:       (*entities.StoredCardInfo)(nil),
+			cardInfoErr:    dynamoerror.ErrItemNotFound,
+			expectedStatus: entities.WebhookDeliveryFailed,
+		},
+		{
+			name:     "should ignore a finished delivery",
+			delivery: &entities.WebhookDelivery{Status: entities.WebhookDeliveryDelivered, Attempts: 1},
+		},
+		{
+			name:        "should ignore a missing delivery",
+			delivery:    (*entities.WebhookDelivery)(nil),
+			deliveryErr: dynamoerror.ErrItemNotFound,
+		},
+		{
+			name:        "should return the error of an invalid message",
+			messageBody: "{",
+			expectedErr: true,
+		},
+		{
+			name:             "should return the queue error so SQS retries the message",
+			delivery:         &entities.WebhookDelivery{Status: entities.WebhookDeliveryPending},
+			cardInfo:         cardInfo,
+			statusCode:       http.StatusBadGateway,
+			enqueueErr:       errors.New("sqs error"),
+			expectedStatus:   entities.WebhookDeliveryRetrying,
+			expectedAttempts: 1,
+			expectedDelay:    30 * time.Second,
+			expectedErr:      true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockCardInfoRepository{}
+			mockWebhookRepo := &MockWebhookRepository{}
+			mockQueue := &MockWebhookQueue{}
+			mockSender := &MockWebhookSender{}
+			mockLogger := &MockLogger{}
+			mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
+			mockLogger.On("Error", mock.AnythingOfType("string"), mock.Anything).Return()
+			if tt.delivery != nil {
+				tt.delivery.ExternalReferenceID = "ext-ref-123"
+				tt.delivery.MerchantID = "merchant-123"
+			}
+			mockWebhookRepo.On("FindDelivery", mock.Anything, "ext-ref-123").Return(tt.delivery, tt.deliveryErr)
+			mockWebhookRepo.On("FindConfig", mock.Anything, "merchant-123").Return(activeWebhook, nil)
+			mockWebhookRepo.On("SaveDelivery", mock.Anything, mock.Anything).Return(nil)
+			mockRepo.On("FindByMerchantIDAndExternalReferenceID", mock.Anything, "merchant-123", "ext-ref-123").
+				Return(tt.cardInfo, tt.cardInfoErr)
+			mockSender.On("Send", mock.Anything, *activeWebhook, expectedPayload).Return(tt.statusCode, tt.sendErr)
+			mockQueue.On("Enqueue", mock.Anything, deliveryMessage, tt.expectedDelay).Return(tt.enqueueErr)
+			useCase := NewDeliverCardInfoWebhookUseCase(mockRepo, mockWebhookRepo, mockQueue, mockSender, mockLogger)
+			useCase.now = func() time.Time { return now }
+			body := messageBody
+			if tt.messageBody != "" {
+				body = tt.messageBody
+			}
+
+			err := useCase.Execute(context.Background(), DeliverCardInfoWebhookRequest{SQSMessageBody: body})
+
+			assert.Equal(t, tt.expectedErr, err != nil)
+			if tt.expectedStatus == "" {
+				mockWebhookRepo.AssertNotCalled(t, "SaveDelivery", mock.Anything, mock.Anything)
+				mockSender.AssertNotCalled(t, "Send", mock.Anything, mock.Anything, mock.Anything)
+				return
+			}
+			assert.Equal(t, tt.expectedStatus, tt.delivery.Status)
+			assert.Equal(t, tt.expectedAttempts, tt.delivery.Attempts)
+			if tt.expectedStatus == entities.WebhookDeliveryRetrying {
+				mockQueue.AssertExpectations(t)
+				return
+			}
+			mockQueue.AssertNotCalled(t, "Enqueue", mock.Anything, mock.Anything, mock.Anything)
+		})
+	}
+}
+
+func TestGetWebhookRetryDelay(t *testing.T) {
+	assert.Equal(t, 30*time.Second, getWebhookRetryDelay(1))
+	assert.Equal(t, time.Minute, getWebhookRetryDelay(2))
+	assert.Equal(t, 8*time.Minute, getWebhookRetryDelay(5))
+	assert.Equal(t, 15*time.Minute, getWebhookRetryDelay(6))
+	assert.Equal(t, 15*time.Minute, getWebhookRetryDelay(20))
+}
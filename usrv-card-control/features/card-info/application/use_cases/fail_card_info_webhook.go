@@ -0,0 +1,83 @@
+package use_cases
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/entities"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/repositories"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+)
+
+// webhookDeadLetterError last error of the deliveries whose message reached the dead letter queue
+const webhookDeadLetterError = "delivery message reached the dead letter queue"
+
+// FailCardInfoWebhookUseCase marks as failed the deliveries whose message exhausted the queue retries
+type FailCardInfoWebhookUseCase struct {
+	webhookRepo repositories.WebhookRepository
+	logger      logger.KushkiLogger
+	now         func() time.Time
+}
+
+// NewFailCardInfoWebhookUseCase creates a new instance of the use case
+func NewFailCardInfoWebhookUseCase(
+	webhookRepo repositories.WebhookRepository,
+	logger logger.KushkiLogger,
+) *FailCardInfoWebhookUseCase {
+	return &FailCardInfoWebhookUseCase{
+		webhookRepo: webhookRepo,
+		logger:      logger,
+		now:         time.Now,
+	}
+}
+
+// FailCardInfoWebhookRequest represents the input for the use case
+type FailCardInfoWebhookRequest struct {
+	SQSMessageBody string
+}
+
+// Execute marks the delivery as failed unless it already finished
+func (uc *FailCardInfoWebhookUseCase) Execute(ctx context.Context, request FailCardInfoWebhookRequest) (*entities.WebhookDelivery, error) {
+	const useCase = "FailCardInfoWebhook"
+
+	// Step 1: Parse the SQS message
+	var message entities.WebhookDeliveryMessage
+	if err := json.Unmarshal([]byte(request.SQSMessageBody), &message); err != nil {
+		uc.logger.Error(fmt.Sprintf("%s | ParseError", useCase), err)
+		return nil, fmt.Errorf("failed to parse SQS message: %w", err)
+	}
+
+	// Step 2: Find the delivery status
+	delivery, err := uc.webhookRepo.FindDelivery(ctx, message.ExternalReferenceID)
+	if errors.Is(err, dynamoerror.ErrItemNotFound) {
+		uc.logger.Info(fmt.Sprintf("%s | DeliveryNotFound", useCase),
+			fmt.Sprintf("ExternalReferenceID: %s", message.ExternalReferenceID))
+		return nil, nil
+	}
+	if err != nil {
+		uc.logger.Error(fmt.Sprintf("%s | FindDeliveryError", useCase), err)
+		return nil, fmt.Errorf("failed to find webhook delivery: %w", err)
+	}
+
+	// Step 3: A delivered message may reach the DLQ when its deletion failed
+	if delivery.IsFinal() {
+		return delivery, nil
+	}
+
+	delivery.Status = entities.WebhookDeliveryFailed
+	delivery.LastError = webhookDeadLetterError
+	delivery.UpdatedAt = uc.now().UnixMilli()
+	if err := uc.webhookRepo.SaveDelivery(ctx, delivery); err != nil {
+		uc.logger.Error(fmt.Sprintf("%s | SaveDeliveryError", useCase), err)
+		return nil, fmt.Errorf("failed to save webhook delivery: %w", err)
+	}
+
+	uc.logger.Info(fmt.Sprintf("%s | %s", useCase, delivery.Status),
+		fmt.Sprintf("ExternalReferenceID: %s, Attempts: %d", delivery.ExternalReferenceID, delivery.Attempts))
+
+	return delivery, nil
+}
@@ -0,0 +1,107 @@
+package use_cases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/entities"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestFailCardInfoWebhookUseCase_Execute(t *testing.T) {
+	now := time.Now()
+
+	tests := []struct {
+		name           string
+		body           string
+		delivery       *entities.WebhookDelivery
+		findErr        error
+		saveErr        error
+		expectedSave   bool
+		expectedStatus string
+		expectedErr    bool
+	}{
+		{
+			name:           "should mark a retrying delivery as failed",
+			body:           `{"externalReferenceId":"ext-ref-123"}`,
+			delivery:       &entities.WebhookDelivery{ExternalReferenceID: "ext-ref-123", Status: entities.WebhookDeliveryRetrying, Attempts: 2},
+			expectedSave:   true,
+			expectedStatus: entities.WebhookDeliveryFailed,
+		},
+		{
+			name:           "should mark a pending delivery as failed",
+			body:           `{"externalReferenceId":"ext-ref-123"}`,
+			delivery:       &entities.WebhookDelivery{ExternalReferenceID: "ext-ref-123", Status: entities.WebhookDeliveryPending},
+			expectedSave:   true,
+			expectedStatus: entities.WebhookDeliveryFailed,
+		},
+		{
+			name:           "should keep a delivered delivery",
+			body:           `{"externalReferenceId":"ext-ref-123"}`,
+			delivery:       &entities.WebhookDelivery{ExternalReferenceID: "ext-ref-123", Status: entities.WebhookDeliveryDelivered, Attempts: 1},
+			expectedStatus: entities.WebhookDeliveryDelivered,
+		},
+		{
+			name:     "should skip a missing delivery",
+			body:     `{"externalReferenceId":"ext-ref-123"}`,
+			delivery: (*entities.WebhookDelivery)(nil),
+			findErr:  dynamoerror.ErrItemNotFound,
+		},
+		{
+			name:        "should return the parse error",
+			body:        "{",
+			expectedErr: true,
+		},
+		{
+			name:        "should return the find error",
+			body:        `{"externalReferenceId":"ext-ref-123"}`,
+			delivery:    (*entities.WebhookDelivery)(nil),
+			findErr:     errors.New("dynamo error"),
+			expectedErr: true,
+		},
+		{
+			name:         "should return the save error",
+			body:         `{"externalReferenceId":"ext-ref-123"}`,
+			delivery:     &entities.WebhookDelivery{ExternalReferenceID: "ext-ref-123", Status: entities.WebhookDeliveryRetrying},
+			saveErr:      errors.New("dynamo error"),
+			expectedSave: true,
+			expectedErr:  true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockWebhookRepo := &MockWebhookRepository{}
+			mockLogger := &MockLogger{}
+			mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
+			mockLogger.On("Error", mock.AnythingOfType("string"), mock.Anything).Return()
+			mockWebhookRepo.On("FindDelivery", mock.Anything, "ext-ref-123").Return(tt.delivery, tt.findErr)
+			mockWebhookRepo.On("SaveDelivery", mock.Anything, mock.MatchedBy(func(delivery *entities.WebhookDelivery) bool {
+				return delivery.Status == entities.WebhookDeliveryFailed && delivery.LastError == webhookDeadLetterError &&
+					delivery.UpdatedAt == now.UnixMilli()
+			})).Return(tt.saveErr)
+			useCase := NewFailCardInfoWebhookUseCase(mockWebhookRepo, mockLogger)
+			useCase.now = func() time.Time { return now }
+
+			delivery, err := useCase.Execute(context.Background(), FailCardInfoWebhookRequest{SQSMessageBody: tt.body})
+
+			if tt.expectedErr {
+				assert.Error(t, err)
+			} else {
+				assert.NoError(t, err)
+			}
+			if tt.expectedStatus != "" {
+				assert.Equal(t, tt.expectedStatus, delivery.Status)
+			}
+			if tt.expectedSave {
+				mockWebhookRepo.AssertCalled(t, "SaveDelivery", mock.Anything, mock.Anything)
+			} else {
+				mockWebhookRepo.AssertNotCalled(t, "SaveDelivery", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+package use_cases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/entities"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/repositories"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/services"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/value_objects"
+	"bitbucket.org/kushki/usrv-card-control/features/shared/constants"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+)
+
+var (
+	// ErrInvalidGetCardInfoRequest the external reference ID is missing
+	ErrInvalidGetCardInfoRequest = errors.New("externalReferenceId is required")
+	// ErrInvalidCredential the merchant or the private credential is missing or invalid
+	ErrInvalidCredential = errors.New("invalid merchant or private credential")
+	// ErrForbiddenCredential the private credential belongs to another merchant
+	ErrForbiddenCredential = errors.New("private credential of another merchant")
+	// ErrCardInfoNotFound the record does not exist, belongs to another merchant or expired
+	ErrCardInfoNotFound = errors.New("card info not found")
+)
+
+// GetCardInfoUseCase returns the stored card info of a transaction to the merchant that owns it
+type GetCardInfoUseCase struct {
+	cardInfoRepo      repositories.CardInfoRepository
+	credentialService services.CredentialService
+	logger            logger.KushkiLogger
+	now               func() time.Time
+}
+
+// NewGetCardInfoUseCase creates a new instance of the use case
+func NewGetCardInfoUseCase(
+	cardInfoRepo repositories.CardInfoRepository,
+	credentialService services.CredentialService,
+	logger logger.KushkiLogger,
+) *GetCardInfoUseCase {
+	return &GetCardInfoUseCase{
+		cardInfoRepo:      cardInfoRepo,
+		credentialService: credentialService,
+		logger:            logger,
+		now:               time.Now,
+	}
+}
+
+// GetCardInfoRequest represents the input for the use case
+type GetCardInfoRequest struct {
+	ExternalReferenceID string
+	MerchantID          string
+	PrivateCredentialID string
+}
+
+// TransactionResponse card and transaction information as documented in pxp-card-info.yml
+type TransactionResponse struct {
+	Card                 value_objects.EncryptedCardData `json:"card"`
+	ExternalReferenceID  string                          `json:"externalReferenceId"`
+	TransactionReference string                          `json:"transactionReference"`
+	CardBrand            string                          `json:"cardBrand"`
+	TerminalID           string                          `json:"terminalId"`
+	SubMerchantCode      string                          `json:"subMerchantCode"`
+	IDAffiliation        string                          `json:"idAffiliation"`
+	MerchantID           string                          `json:"merchantId"`
+	TransactionDate      int64                           `json:"transactionDate"`
+}
+
+// Execute authenticates the merchant and returns its card info while it has not expired
+func (uc *GetCardInfoUseCase) Execute(ctx context.Context, request GetCardInfoRequest) (*TransactionResponse, error) {
+	const useCase = "GetCardInfo"
+
+	ctx, cancel := context.WithTimeout(ctx, constants.CardInfoRetrievalTimeout)
+	defer cancel()
+
+	uc.logger.Info(fmt.Sprintf("%s | Starting", useCase),
+		fmt.Sprintf("ExternalReferenceID: %s, MerchantID: %s", request.ExternalReferenceID, request.MerchantID))
+
+	// Step 1: Validate the request
+	if request.ExternalReferenceID == "" {
+		return nil, ErrInvalidGetCardInfoRequest
+	}
+
+	// Step 2: Authenticate the private credential of the merchant
+	if err := authenticateMerchant(uc.credentialService, request.MerchantID, request.PrivateCredentialID); err != nil {
+		uc.logger.Error(fmt.Sprintf("%s | CredentialError", useCase), fmt.Sprintf("MerchantID: %s", request.MerchantID))
+		return nil, err
+	}
+
+	// Step 3: Find the record of the merchant
+	cardInfo, err := uc.cardInfoRepo.FindByMerchantIDAndExternalReferenceID(ctx, request.MerchantID, request.ExternalReferenceID)
+	if errors.Is(err, dynamoerror.ErrItemNotFound) {
+		return nil, ErrCardInfoNotFound
+	}
+	if err != nil {
+		uc.logger.Error(fmt.Sprintf("%s | FindError", useCase), err)
+		return nil, fmt.Errorf("failed to find card info: %w", err)
+	}
+
+	// Step 4: The record is only available for 180 days, even before the TTL deletes it
+	if cardInfo.IsExpired(uc.now().UnixMilli()) {
+		uc.logger.Info(fmt.Sprintf("%s | Expired", useCase),
+			fmt.Sprintf("ExternalReferenceID: %s", request.ExternalReferenceID))
+		return nil, ErrCardInfoNotFound
+	}
+
+	uc.logger.Info(fmt.Sprintf("%s | Success", useCase),
+		fmt.Sprintf("ExternalReferenceID: %s", request.ExternalReferenceID))
+
+	return newTransactionResponse(cardInfo), nil
+}
+
+// authenticateMerchant the retrieval and the key registration accept only the private credential of the merchant
+// The credential of another merchant is checked first, a lenient validation must not let it through
+func authenticateMerchant(credentialService services.CredentialService, merchantID, privateCredentialID string) error {
+	if merchantID == "" {
+		return ErrInvalidCredential
+	}
+	if credentialService.BelongsToAnotherMerchant(privateCredentialID, merchantID) {
+		return ErrForbiddenCredential
+	}
+	if credentialService.ValidatePrivateCredential(privateCredentialID, merchantID) {
+		return nil
+	}
+
+	return ErrInvalidCredential
+}
+
+// newTransactionResponse the retrieval API and the webhook share the same payload
+func newTransactionResponse(cardInfo *entities.StoredCardInfo) *TransactionResponse {
+	return &TransactionResponse{
+		Card:                 cardInfo.EncryptedCard.WithDefaultAlgorithm(),
+		ExternalReferenceID:  cardInfo.ExternalReferenceID,
+		TransactionReference: cardInfo.TransactionReference,
+		CardBrand:            cardInfo.CardBrand,
+		TerminalID:           cardInfo.TerminalID,
+		SubMerchantCode:      cardInfo.SubMerchantCode,
+		IDAffiliation:        cardInfo.IDAffiliation,
+		MerchantID:           cardInfo.MerchantID,
+		TransactionDate:      cardInfo.TransactionDate,
+	}
+}
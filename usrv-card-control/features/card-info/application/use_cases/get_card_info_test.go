@@ -0,0 +1,186 @@
+package use_cases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/entities"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/value_objects"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockCredentialService struct {
+	mock.Mock
+}
+
+func (m *MockCredentialService) ValidatePrivateCredential(privateCredentialID, merchantID string) bool {
+	args := m.Called(privateCredentialID, merchantID)
+	return args.Bool(0)
+}
+
+func (m *MockCredentialService) BelongsToAnotherMerchant(privateCredentialID, merchantID string) bool {
+	args := m.Called(privateCredentialID, merchantID)
+	return args.Bool(0)
+}
+
+func TestGetCardInfoUseCase_Execute(t *testing.T) {
+	now := time.Now()
+	storedCardInfo := &entities.StoredCardInfo{
+		ExternalReferenceID:  "ext-ref-123",
+		TransactionReference: "txn-ref-456",
+		CardBrand:            "VISA",
+		TerminalID:           "terminal-001",
+		TransactionType:      "charge",
+		TransactionStatus:    "APPROVAL",
+		SubMerchantCode:      "sub-merchant-001",
+		IDAffiliation:        "affiliation-001",
+		MerchantID:           "merchant-123",
+		PrivateCredentialID:  "private-cred-456",
+		EncryptedCard:        value_objects.EncryptedCardData{EncryptedPan: "enc-pan", EncryptedDate: "enc-date"},
+		TransactionDate:      now.Add(-time.Hour).UnixMilli(),
+		ExpiresAt:            now.Add(time.Hour).UnixMilli(),
+	}
+	expiredCardInfo := *storedCardInfo
+	expiredCardInfo.ExpiresAt = now.Add(-time.Hour).UnixMilli()
+	validRequest := GetCardInfoRequest{
+		ExternalReferenceID: "ext-ref-123",
+		MerchantID:          "merchant-123",
+		PrivateCredentialID: "private-cred-456",
+	}
+
+	tests := []struct {
+		name             string
+		request          GetCardInfoRequest
+		validCredential  bool
+		otherMerchant    bool
+		cardInfo         *entities.StoredCardInfo
+		findErr          error
+		expectedResponse *TransactionResponse
+		expectedErr      error
+	}{
+		{
+			name:            "should return the documented transaction response",
+			request:         validRequest,
+			validCredential: true,
+			cardInfo:        storedCardInfo,
+			expectedResponse: &TransactionResponse{
+				Card:                 value_objects.EncryptedCardData{EncryptedPan: "enc-pan", EncryptedDate: "enc-date", Algorithm: value_objects.AlgorithmRSAPKCS1v15},
+				ExternalReferenceID:  "ext-ref-123",
+				TransactionReference: "txn-ref-456",
+				CardBrand:            "VISA",
+				TerminalID:           "terminal-001",
+				SubMerchantCode:      "sub-merchant-001",
+				IDAffiliation:        "affiliation-001",
+				MerchantID:           "merchant-123",
+				TransactionDate:      storedCardInfo.TransactionDate,
+			},
+		},
+		{
+			name:        "should reject a request without external reference ID",
+			request:     GetCardInfoRequest{MerchantID: "merchant-123", PrivateCredentialID: "private-cred-456"},
+			expectedErr: ErrInvalidGetCardInfoRequest,
+		},
+		{
+			name:        "should reject a request without merchant",
+			request:     GetCardInfoRequest{ExternalReferenceID: "ext-ref-123", PrivateCredentialID: "private-cred-456"},
+			expectedErr: ErrInvalidCredential,
+		},
+		{
+			name:        "should reject an invalid private credential",
+			request:     validRequest,
+			expectedErr: ErrInvalidCredential,
+		},
+		{
+			name:          "should forbid a private credential of another merchant",
+			request:       validRequest,
+			otherMerchant: true,
+			expectedErr:   ErrForbiddenCredential,
+		},
+		{
+			name:            "should forbid a credential of another merchant even when it validates",
+			request:         validRequest,
+			validCredential: true,
+			otherMerchant:   true,
+			expectedErr:     ErrForbiddenCredential,
+		},
+		{
+			name:            "should not find a missing record or one of another merchant",
+			request:         validRequest,
+			validCredential: true,
+			cardInfo:        (*entities.StoredCardInfo)(nil),
+			findErr:         dynamoerror.ErrItemNotFound,
+			expectedErr:     ErrCardInfoNotFound,
+		},
+		{
+			name:            "should not find an expired record",
+			request:         validRequest,
+			validCredential: true,
+			cardInfo:        &expiredCardInfo,
+			expectedErr:     ErrCardInfoNotFound,
+		},
+		{
+			name:            "should return the repository error",
+			request:         validRequest,
+			validCredential: true,
+			cardInfo:        (*entities.StoredCardInfo)(nil),
+			findErr:         errors.New("dynamo error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockCardInfoRepository{}
+			mockCredential := &MockCredentialService{}
+			mockLogger := &MockLogger{}
+			mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
+			mockLogger.On("Error", mock.AnythingOfType("string"), mock.Anything).Return()
+			mockCredential.On("ValidatePrivateCredential", "private-cred-456", "merchant-123").Return(tt.validCredential)
+			mockCredential.On("BelongsToAnotherMerchant", "private-cred-456", "merchant-123").Return(tt.otherMerchant).Maybe()
+			mockRepo.On("FindByMerchantIDAndExternalReferenceID", mock.Anything, "merchant-123", "ext-ref-123").
+				Return(tt.cardInfo, tt.findErr)
+			useCase := NewGetCardInfoUseCase(mockRepo, mockCredential, mockLogger)
+			useCase.now = func() time.Time { return now }
+
+			response, err := useCase.Execute(context.Background(), tt.request)
+
+			assert.Equal(t, tt.expectedResponse, response)
+			switch {
+			case tt.expectedErr != nil:
+				assert.ErrorIs(t, err, tt.expectedErr)
+			case tt.findErr != nil:
+				assert.ErrorIs(t, err, tt.findErr)
+			default:
+				assert.NoError(t, err)
+			}
+			if tt.cardInfo == nil && tt.findErr == nil {
+				mockRepo.AssertNotCalled(t, "FindByMerchantIDAndExternalReferenceID", mock.Anything, mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
+
+func TestGetCardInfoUseCase_Execute_Deadline(t *testing.T) {
+	mockRepo := &MockCardInfoRepository{}
+	mockCredential := &MockCredentialService{}
+	mockLogger := &MockLogger{}
+	mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
+	mockCredential.On("ValidatePrivateCredential", mock.Anything, mock.Anything).Return(true)
+	mockRepo.On("FindByMerchantIDAndExternalReferenceID", mock.MatchedBy(func(ctx context.Context) bool {
+		deadline, ok := ctx.Deadline()
+		return ok && time.Until(deadline) < 3*time.Second
+	}), "merchant-123", "ext-ref-123").
+		Return(&entities.StoredCardInfo{ExpiresAt: time.Now().Add(time.Hour).UnixMilli()}, nil)
+
+	_, err := NewGetCardInfoUseCase(mockRepo, mockCredential, mockLogger).Execute(context.Background(), GetCardInfoRequest{
+		ExternalReferenceID: "ext-ref-123",
+		MerchantID:          "merchant-123",
+		PrivateCredentialID: "private-cred-456",
+	})
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
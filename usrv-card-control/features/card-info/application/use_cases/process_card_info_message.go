@@ -20,6 +20,7 @@ type ProcessCardInfoMessageUseCase struct {
 	cardInfoRepo      repositories.CardInfoRepository
 	encryptionService services.EncryptionService
 	validationService services.ValidationService
+	webhookDelivery   *DeliverCardInfoWebhookUseCase
 	logger            logger.KushkiLogger
 }
 
@@ -38,6 +39,12 @@ func NewProcessCardInfoMessageUseCase(
 	}
 }
 
+// WithWebhookDelivery enables the delivery of the card info to the webhook registered by the merchant
+func (uc *ProcessCardInfoMessageUseCase) WithWebhookDelivery(webhookDelivery *DeliverCardInfoWebhookUseCase) *ProcessCardInfoMessageUseCase {
+	uc.webhookDelivery = webhookDelivery
+	return uc
+}
+
 // ProcessCardInfoMessageRequest represents the input for the use case
 type ProcessCardInfoMessageRequest struct {
 	SQSMessageBody string
@@ -89,6 +96,13 @@ func (uc *ProcessCardInfoMessageUseCase) Execute(
 	} else if exists {
 		uc.logger.Info(fmt.Sprintf("%s | AlreadyProcessed", useCase),
 			fmt.Sprintf("ExternalReferenceID: %s already exists", cardInfoMessage.ExternalReferenceID))
+
+		// A previous attempt may have failed after saving, before the delivery was scheduled
+		if err := uc.scheduleWebhookDelivery(ctx, cardInfoMessage); err != nil {
+			uc.logger.Error(fmt.Sprintf("%s | WebhookScheduleError", useCase), err)
+			return nil, fmt.Errorf("failed to schedule webhook delivery: %w", err)
+		}
+
 		return &ProcessCardInfoMessageResponse{
 			ExternalReferenceID: cardInfoMessage.ExternalReferenceID,
 			ProcessedAt:         time.Now().UnixMilli(),
@@ -96,8 +110,8 @@ func (uc *ProcessCardInfoMessageUseCase) Execute(
 		}, nil
 	}
 
-	// Step 5: Encrypt the card data
-	encryptedCardData, err := uc.encryptCardData(cardInfoMessage)
+	// Step 5: Encrypt the card data, also with the next key while the merchant rotates its key
+	encryptedCardData, nextEncryptedCardData, err := uc.encryptCardData(cardInfoMessage)
 	if err != nil {
 		uc.logger.Error(fmt.Sprintf("%s | EncryptionError", useCase), err)
 		return nil, fmt.Errorf("failed to encrypt card data: %w", err)
@@ -105,6 +119,7 @@ func (uc *ProcessCardInfoMessageUseCase) Execute(
 
 	// Step 6: Create the stored card info entity
 	storedCardInfo := uc.createStoredCardInfo(cardInfoMessage, encryptedCardData)
+	storedCardInfo.NextEncryptedCard = nextEncryptedCardData
 
 	// Step 7: Save to DynamoDB
 	if err := uc.saveCardInfo(ctx, storedCardInfo); err != nil {
@@ -112,6 +127,12 @@ func (uc *ProcessCardInfoMessageUseCase) Execute(
 		return nil, fmt.Errorf("failed to save card info: %w", err)
 	}
 
+	// Step 8: Schedule the delivery to the merchant webhook
+	if err := uc.scheduleWebhookDelivery(ctx, cardInfoMessage); err != nil {
+		uc.logger.Error(fmt.Sprintf("%s | WebhookScheduleError", useCase), err)
+		return nil, fmt.Errorf("failed to schedule webhook delivery: %w", err)
+	}
+
 	uc.logger.Info(fmt.Sprintf("%s | Success", useCase),
 		fmt.Sprintf("Successfully processed ExternalReferenceID: %s", cardInfoMessage.ExternalReferenceID))
 
@@ -173,13 +194,21 @@ func (uc *ProcessCardInfoMessageUseCase) checkIfAlreadyProcessed(ctx context.Con
 }
 
 // encryptCardData encrypts the card data using the merchant's public key
-func (uc *ProcessCardInfoMessageUseCase) encryptCardData(message *entities.PxpCardInfoMessage) (value_objects.EncryptedCardData, error) {
+// The copy for the next key is nil when no rotation is in progress
+func (uc *ProcessCardInfoMessageUseCase) encryptCardData(
+	message *entities.PxpCardInfoMessage,
+) (value_objects.EncryptedCardData, *value_objects.EncryptedCardData, error) {
 	encryptedData, err := uc.encryptionService.EncryptCardData(message.Card, message.MerchantID)
 	if err != nil {
-		return value_objects.EncryptedCardData{}, fmt.Errorf("encryption failed for merchant %s: %w", message.MerchantID, err)
+		return value_objects.EncryptedCardData{}, nil, fmt.Errorf("encryption failed for merchant %s: %w", message.MerchantID, err)
 	}
 
-	return encryptedData, nil
+	nextEncryptedData, err := uc.encryptionService.EncryptCardDataForNextKey(message.Card, message.MerchantID)
+	if err != nil {
+		return value_objects.EncryptedCardData{}, nil, fmt.Errorf("encryption with the next key failed for merchant %s: %w", message.MerchantID, err)
+	}
+
+	return encryptedData, nextEncryptedData, nil
 }
 
 // createStoredCardInfo creates a StoredCardInfo entity from the message and encrypted data
@@ -212,3 +241,12 @@ func (uc *ProcessCardInfoMessageUseCase) createStoredCardInfo(
 func (uc *ProcessCardInfoMessageUseCase) saveCardInfo(ctx context.Context, cardInfo *entities.StoredCardInfo) error {
 	return uc.cardInfoRepo.Save(ctx, cardInfo)
 }
+
+// scheduleWebhookDelivery schedules the webhook delivery when it is enabled
+func (uc *ProcessCardInfoMessageUseCase) scheduleWebhookDelivery(ctx context.Context, message *entities.PxpCardInfoMessage) error {
+	if uc.webhookDelivery == nil {
+		return nil
+	}
+
+	return uc.webhookDelivery.Schedule(ctx, message.MerchantID, message.ExternalReferenceID)
+}
@@ -29,6 +29,11 @@ func (m *MockCardInfoRepository) FindByExternalReferenceID(ctx context.Context,
 	return args.Get(0).(*entities.StoredCardInfo), args.Error(1)
 }
 
+func (m *MockCardInfoRepository) FindByMerchantIDAndExternalReferenceID(ctx context.Context, merchantID, externalReferenceID string) (*entities.StoredCardInfo, error) {
+	args := m.Called(ctx, merchantID, externalReferenceID)
+	return args.Get(0).(*entities.StoredCardInfo), args.Error(1)
+}
+
 func (m *MockCardInfoRepository) Delete(ctx context.Context, externalReferenceID string) error {
 	args := m.Called(ctx, externalReferenceID)
 	return args.Error(0)
@@ -39,6 +44,21 @@ func (m *MockCardInfoRepository) FindExpiredRecords(ctx context.Context, current
 	return args.Get(0).([]*entities.StoredCardInfo), args.Error(1)
 }
 
+func (m *MockCardInfoRepository) FindByNextKey(ctx context.Context, merchantID, keyID string, from, to int64) ([]*entities.StoredCardInfo, error) {
+	args := m.Called(ctx, merchantID, keyID, from, to)
+	return args.Get(0).([]*entities.StoredCardInfo), args.Error(1)
+}
+
+func (m *MockCardInfoRepository) FindByPreviousKey(ctx context.Context, merchantID, keyID string, from, to int64) ([]*entities.StoredCardInfo, error) {
+	args := m.Called(ctx, merchantID, keyID, from, to)
+	return args.Get(0).([]*entities.StoredCardInfo), args.Error(1)
+}
+
+func (m *MockCardInfoRepository) PromoteNextKey(ctx context.Context, externalReferenceID, keyID string) error {
+	args := m.Called(ctx, externalReferenceID, keyID)
+	return args.Error(0)
+}
+
 type MockEncryptionService struct {
 	mock.Mock
 }
@@ -48,6 +68,11 @@ func (m *MockEncryptionService) EncryptCardData(cardData value_objects.CardData,
 	return args.Get(0).(value_objects.EncryptedCardData), args.Error(1)
 }
 
+func (m *MockEncryptionService) EncryptCardDataForNextKey(cardData value_objects.CardData, merchantID string) (*value_objects.EncryptedCardData, error) {
+	args := m.Called(cardData, merchantID)
+	return args.Get(0).(*value_objects.EncryptedCardData), args.Error(1)
+}
+
 type MockValidationService struct {
 	mock.Mock
 }
@@ -132,6 +157,7 @@ func TestProcessCardInfoMessageUseCase_Execute_Success(t *testing.T) {
 	mockValidation.On("ValidatePrivateCredential", "private-cred-456", "merchant-123").Return(nil)
 	mockRepo.On("FindByExternalReferenceID", ctx, "ext-ref-123").Return(&entities.StoredCardInfo{}, dynamoerror.ErrItemNotFound)
 	mockEncryption.On("EncryptCardData", validMessage.Card, "merchant-123").Return(encryptedData, nil)
+	mockEncryption.On("EncryptCardDataForNextKey", validMessage.Card, "merchant-123").Return((*value_objects.EncryptedCardData)(nil), nil)
 	mockRepo.On("Save", ctx, mock.AnythingOfType("*entities.StoredCardInfo")).Return(nil)
 
 	// Act
@@ -512,6 +538,7 @@ func TestProcessCardInfoMessageUseCase_Execute_SaveError(t *testing.T) {
 	// when it encounters ErrItemNotFound, so let's simulate that directly
 	mockRepo.On("FindByExternalReferenceID", ctx, "ext-ref-123").Return((*entities.StoredCardInfo)(nil), dynamoerror.ErrItemNotFound)
 	mockEncryption.On("EncryptCardData", validMessage.Card, "merchant-123").Return(encryptedData, nil)
+	mockEncryption.On("EncryptCardDataForNextKey", validMessage.Card, "merchant-123").Return((*value_objects.EncryptedCardData)(nil), nil)
 	mockRepo.On("Save", ctx, mock.AnythingOfType("*entities.StoredCardInfo")).Return(errors.New("save failed"))
 
 	// Act
@@ -523,6 +550,80 @@ func TestProcessCardInfoMessageUseCase_Execute_SaveError(t *testing.T) {
 	assert.Contains(t, err.Error(), "failed to save card info")
 }
 
+func TestProcessCardInfoMessageUseCase_Execute_KeyRotation(t *testing.T) {
+	validMessage := entities.PxpCardInfoMessage{
+		ExternalReferenceID:  "ext-ref-123",
+		TransactionReference: "txn-ref-456",
+		CardBrand:            "VISA",
+		TerminalID:           "terminal-001",
+		TransactionType:      "charge",
+		TransactionStatus:    "APPROVAL",
+		MerchantID:           "merchant-123",
+		PrivateCredentialID:  "private-cred-456",
+		Card:                 value_objects.CardData{Pan: "4111111111111111", Date: "1225"},
+	}
+	validMessageJSON, _ := json.Marshal(validMessage)
+	encryptedData := value_objects.EncryptedCardData{EncryptedPan: "enc-pan", EncryptedDate: "enc-date", KeyID: "key-1"}
+	nextEncryptedData := &value_objects.EncryptedCardData{EncryptedPan: "next-pan", EncryptedDate: "next-date", KeyID: "key-2"}
+
+	tests := []struct {
+		name         string
+		nextData     *value_objects.EncryptedCardData
+		nextErr      error
+		expectedNext *value_objects.EncryptedCardData
+		expectErr    bool
+	}{
+		{
+			name:         "should store the copy encrypted with the next key",
+			nextData:     nextEncryptedData,
+			expectedNext: nextEncryptedData,
+		},
+		{
+			name:     "should store only the card data without rotation",
+			nextData: (*value_objects.EncryptedCardData)(nil),
+		},
+		{
+			name:      "should fail when the next key cannot encrypt",
+			nextData:  (*value_objects.EncryptedCardData)(nil),
+			nextErr:   errors.New("invalid next key"),
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			mockRepo := &MockCardInfoRepository{}
+			mockEncryption := &MockEncryptionService{}
+			mockValidation := &MockValidationService{}
+			mockLogger := &MockLogger{}
+			mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
+			mockLogger.On("Error", mock.AnythingOfType("string"), mock.Anything).Return()
+			mockValidation.On("ValidateCardInfoMessage", mock.AnythingOfType("*entities.PxpCardInfoMessage")).Return(nil)
+			mockValidation.On("ValidateMerchantAccess", "merchant-123").Return(nil)
+			mockValidation.On("ValidatePrivateCredential", "private-cred-456", "merchant-123").Return(nil)
+			mockRepo.On("FindByExternalReferenceID", ctx, "ext-ref-123").Return((*entities.StoredCardInfo)(nil), dynamoerror.ErrItemNotFound)
+			mockEncryption.On("EncryptCardData", validMessage.Card, "merchant-123").Return(encryptedData, nil)
+			mockEncryption.On("EncryptCardDataForNextKey", validMessage.Card, "merchant-123").Return(tt.nextData, tt.nextErr)
+			mockRepo.On("Save", ctx, mock.MatchedBy(func(cardInfo *entities.StoredCardInfo) bool {
+				return cardInfo.EncryptedCard == encryptedData && assert.ObjectsAreEqual(tt.expectedNext, cardInfo.NextEncryptedCard)
+			})).Return(nil)
+			useCase := NewProcessCardInfoMessageUseCase(mockRepo, mockEncryption, mockValidation, mockLogger)
+
+			response, err := useCase.Execute(ctx, ProcessCardInfoMessageRequest{SQSMessageBody: string(validMessageJSON)})
+
+			if tt.expectErr {
+				assert.Error(t, err)
+				assert.Nil(t, response)
+				mockRepo.AssertNotCalled(t, "Save", mock.Anything, mock.Anything)
+				return
+			}
+			assert.NoError(t, err)
+			mockRepo.AssertExpectations(t)
+		})
+	}
+}
+
 // Test helper to verify StoredCardInfo creation
 func TestProcessCardInfoMessageUseCase_CreateStoredCardInfo_Validation(t *testing.T) {
 	// Arrange
@@ -577,3 +678,73 @@ func TestProcessCardInfoMessageUseCase_CreateStoredCardInfo_Validation(t *testin
 	timeDiff := storedCardInfo.ExpiresAt - expectedExpiration
 	assert.True(t, timeDiff > -1000 && timeDiff < 1000, "Expiration should be approximately 180 days from now")
 }
+
+func TestProcessCardInfoMessageUseCase_Execute_SchedulesWebhookDelivery(t *testing.T) {
+	validMessage := entities.PxpCardInfoMessage{
+		ExternalReferenceID:  "ext-ref-123",
+		TransactionReference: "txn-ref-456",
+		CardBrand:            "VISA",
+		TerminalID:           "terminal-001",
+		TransactionType:      "charge",
+		TransactionStatus:    "APPROVAL",
+		MerchantID:           "merchant-123",
+		PrivateCredentialID:  "private-cred-456",
+		Card: value_objects.CardData{
+			Pan:  "4111111111111111",
+			Date: "1225",
+		},
+	}
+	validMessageJSON, _ := json.Marshal(validMessage)
+
+	tests := []struct {
+		name     string
+		existing *entities.StoredCardInfo
+		findErr  error
+	}{
+		{
+			name:     "should schedule the delivery after saving",
+			existing: &entities.StoredCardInfo{},
+			findErr:  dynamoerror.ErrItemNotFound,
+		},
+		{
+			name:     "should schedule the delivery of an already processed message",
+			existing: &entities.StoredCardInfo{ExternalReferenceID: "ext-ref-123", MerchantID: "merchant-123"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockCardInfoRepository{}
+			mockEncryption := &MockEncryptionService{}
+			mockValidation := &MockValidationService{}
+			mockWebhookRepo := &MockWebhookRepository{}
+			mockQueue := &MockWebhookQueue{}
+			mockLogger := &MockLogger{}
+			mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
+			mockValidation.On("ValidateCardInfoMessage", mock.AnythingOfType("*entities.PxpCardInfoMessage")).Return(nil)
+			mockValidation.On("ValidateMerchantAccess", "merchant-123").Return(nil)
+			mockValidation.On("ValidatePrivateCredential", "private-cred-456", "merchant-123").Return(nil)
+			mockRepo.On("FindByExternalReferenceID", mock.Anything, "ext-ref-123").Return(tt.existing, tt.findErr)
+			mockEncryption.On("EncryptCardData", validMessage.Card, "merchant-123").
+				Return(value_objects.EncryptedCardData{EncryptedPan: "enc-pan", EncryptedDate: "enc-date"}, nil)
+			mockEncryption.On("EncryptCardDataForNextKey", validMessage.Card, "merchant-123").
+				Return((*value_objects.EncryptedCardData)(nil), nil)
+			mockRepo.On("Save", mock.Anything, mock.AnythingOfType("*entities.StoredCardInfo")).Return(nil)
+			mockWebhookRepo.On("FindConfig", mock.Anything, "merchant-123").Return(activeWebhook, nil)
+			mockWebhookRepo.On("FindDelivery", mock.Anything, "ext-ref-123").
+				Return((*entities.WebhookDelivery)(nil), dynamoerror.ErrItemNotFound)
+			mockWebhookRepo.On("SaveDelivery", mock.Anything, mock.AnythingOfType("*entities.WebhookDelivery")).Return(nil)
+			mockQueue.On("Enqueue", mock.Anything, entities.WebhookDeliveryMessage{ExternalReferenceID: "ext-ref-123"}, time.Duration(0)).
+				Return(nil)
+			webhookDelivery := NewDeliverCardInfoWebhookUseCase(mockRepo, mockWebhookRepo, mockQueue, &MockWebhookSender{}, mockLogger)
+			useCase := NewProcessCardInfoMessageUseCase(mockRepo, mockEncryption, mockValidation, mockLogger).
+				WithWebhookDelivery(webhookDelivery)
+
+			response, err := useCase.Execute(context.Background(), ProcessCardInfoMessageRequest{SQSMessageBody: string(validMessageJSON)})
+
+			assert.NoError(t, err)
+			assert.True(t, response.Success)
+			mockQueue.AssertExpectations(t)
+		})
+	}
+}
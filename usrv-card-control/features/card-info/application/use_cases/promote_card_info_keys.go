@@ -0,0 +1,157 @@
+package use_cases
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/repositories"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/value_objects"
+	"bitbucket.org/kushki/usrv-card-control/features/shared/constants"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+)
+
+// PromoteCardInfoKeysUseCase promotes the copy of the card data encrypted with the next key once that key activates
+// It does not re-encrypt: the private keys belong to the merchants, so the service cannot decrypt a stored record.
+// While a key waits for its activation the processor stores a second copy encrypted with it, and the promotion
+// replaces the card data with that copy. The records stored before the key was registered have no such copy and
+// cannot be moved to the key: they stay encrypted with the previous key until the card info table TTL removes them,
+// so the run only reports them as stale
+type PromoteCardInfoKeysUseCase struct {
+	cardInfoRepo repositories.CardInfoRepository
+	keyRepo      repositories.MerchantKeyRepository
+	logger       logger.KushkiLogger
+	now          func() time.Time
+}
+
+// NewPromoteCardInfoKeysUseCase creates a new instance of the use case
+func NewPromoteCardInfoKeysUseCase(
+	cardInfoRepo repositories.CardInfoRepository,
+	keyRepo repositories.MerchantKeyRepository,
+	logger logger.KushkiLogger,
+) *PromoteCardInfoKeysUseCase {
+	return &PromoteCardInfoKeysUseCase{
+		cardInfoRepo: cardInfoRepo,
+		keyRepo:      keyRepo,
+		logger:       logger,
+		now:          time.Now,
+	}
+}
+
+// PromoteCardInfoKeysResponse represents the output of the use case
+type PromoteCardInfoKeysResponse struct {
+	Merchants int `json:"merchants"`
+	Promoted  int `json:"promoted"`
+	Stale     int `json:"stale"`
+	Failed    int `json:"failed"`
+}
+
+// Execute promotes the records of every merchant whose encryption key was registered before its activation,
+// and reports the records still encrypted with a previous key of the merchant
+// A failed merchant or record does not stop the others, the next run retries them
+func (uc *PromoteCardInfoKeysUseCase) Execute(ctx context.Context) (*PromoteCardInfoKeysResponse, error) {
+	const useCase = "PromoteCardInfoKeys"
+
+	uc.logger.Info(fmt.Sprintf("%s | Starting", useCase), "Promoting card info keys")
+
+	// Step 1: Find the keys of every merchant
+	keys, err := uc.keyRepo.FindAll(ctx)
+	if err != nil {
+		uc.logger.Error(fmt.Sprintf("%s | FindKeysError", useCase), err)
+		return nil, fmt.Errorf("failed to find merchant keys: %w", err)
+	}
+
+	keysByMerchant := make(map[string][]value_objects.MerchantKey)
+	for _, key := range keys {
+		keysByMerchant[key.MerchantID] = append(keysByMerchant[key.MerchantID], key)
+	}
+
+	merchantIDs := make([]string, 0, len(keysByMerchant))
+	for merchantID := range keysByMerchant {
+		merchantIDs = append(merchantIDs, merchantID)
+	}
+	sort.Strings(merchantIDs)
+
+	// Step 2: Promote the records of the merchants whose key activated and report the ones left behind
+	response := &PromoteCardInfoKeysResponse{}
+	currentTime := uc.now()
+	retentionStart := currentTime.AddDate(0, 0, -constants.CardInfoTableTTLDays).UnixMilli()
+	for _, merchantID := range merchantIDs {
+		key, found := value_objects.SelectEncryptionKey(keysByMerchant[merchantID], currentTime.UnixMilli())
+		if !found || key.ActivatedAt <= retentionStart {
+			continue
+		}
+
+		response.Merchants++
+		if hasPromotionWindow(key) {
+			uc.promoteMerchant(ctx, key, response)
+		}
+		uc.reportStaleRecords(ctx, key, retentionStart, response)
+	}
+
+	uc.logger.Info(fmt.Sprintf("%s | Finished", useCase),
+		fmt.Sprintf("Merchants: %d, Promoted: %d, Stale: %d, Failed: %d",
+			response.Merchants, response.Promoted, response.Stale, response.Failed))
+
+	if response.Failed > 0 {
+		return response, fmt.Errorf("failed to promote %d card info records", response.Failed)
+	}
+
+	return response, nil
+}
+
+// promoteMerchant moves the records stored while the key was pending to the key
+func (uc *PromoteCardInfoKeysUseCase) promoteMerchant(
+	ctx context.Context,
+	key value_objects.MerchantKey,
+	response *PromoteCardInfoKeysResponse,
+) {
+	const useCase = "PromoteCardInfoKeys"
+
+	records, err := uc.cardInfoRepo.FindByNextKey(ctx, key.MerchantID, key.KeyID, key.CreatedAt, key.ActivatedAt)
+	if err != nil {
+		uc.logger.Error(fmt.Sprintf("%s | FindRecordsError", useCase), err)
+		response.Failed++
+		return
+	}
+
+	for _, record := range records {
+		if err := uc.cardInfoRepo.PromoteNextKey(ctx, record.ExternalReferenceID, key.KeyID); err != nil {
+			uc.logger.Error(fmt.Sprintf("%s | PromoteError", useCase), err)
+			response.Failed++
+			continue
+		}
+		response.Promoted++
+	}
+}
+
+// reportStaleRecords counts the records stored before the key was registered, the processor never encrypted them
+// with the key and only the merchant holding the previous private key can still read them
+func (uc *PromoteCardInfoKeysUseCase) reportStaleRecords(
+	ctx context.Context,
+	key value_objects.MerchantKey,
+	retentionStart int64,
+	response *PromoteCardInfoKeysResponse,
+) {
+	const useCase = "PromoteCardInfoKeys"
+
+	records, err := uc.cardInfoRepo.FindByPreviousKey(ctx, key.MerchantID, key.KeyID, retentionStart, key.CreatedAt-1)
+	if err != nil {
+		uc.logger.Error(fmt.Sprintf("%s | FindStaleRecordsError", useCase), err)
+		response.Failed++
+		return
+	}
+	if len(records) == 0 {
+		return
+	}
+
+	uc.logger.Warning(fmt.Sprintf("%s | StaleRecords", useCase),
+		fmt.Sprintf("MerchantID: %s, KeyID: %s, Records: %d", key.MerchantID, key.KeyID, len(records)))
+	response.Stale += len(records)
+}
+
+// hasPromotionWindow only a key registered before its activation has records encrypted with it in advance
+func hasPromotionWindow(key value_objects.MerchantKey) bool {
+	return key.CreatedAt < key.ActivatedAt
+}
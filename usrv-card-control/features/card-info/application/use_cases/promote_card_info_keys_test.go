@@ -0,0 +1,116 @@
+package use_cases
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/entities"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/value_objects"
+	"bitbucket.org/kushki/usrv-card-control/features/shared/constants"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestPromoteCardInfoKeysUseCase_Execute(t *testing.T) {
+	now := time.Now()
+	hour := time.Hour.Milliseconds()
+	retentionStart := now.AddDate(0, 0, -constants.CardInfoTableTTLDays).UnixMilli()
+	promotedKey := value_objects.MerchantKey{
+		MerchantID:  "merchant-1",
+		KeyID:       "key-2",
+		Status:      value_objects.MerchantKeyActive,
+		CreatedAt:   now.UnixMilli() - 3*hour,
+		ActivatedAt: now.UnixMilli() - hour,
+	}
+	keys := []value_objects.MerchantKey{
+		{MerchantID: "merchant-1", KeyID: "key-1", Status: value_objects.MerchantKeyActive, CreatedAt: now.UnixMilli() - 10*hour, ActivatedAt: now.UnixMilli() - 10*hour},
+		promotedKey,
+		// Registered active right away, no record has a copy encrypted with it and only the older ones are stale
+		{MerchantID: "merchant-2", KeyID: "key-1", Status: value_objects.MerchantKeyActive, CreatedAt: now.UnixMilli() - hour, ActivatedAt: now.UnixMilli() - hour},
+		// Still pending, merchant-3 keeps encrypting with its current key
+		{MerchantID: "merchant-3", KeyID: "key-1", Status: value_objects.MerchantKeyActive, CreatedAt: now.UnixMilli() - 10*hour, ActivatedAt: now.UnixMilli() - 10*hour},
+		{MerchantID: "merchant-3", KeyID: "key-2", Status: value_objects.MerchantKeyActive, CreatedAt: now.UnixMilli() - hour, ActivatedAt: now.UnixMilli() + hour},
+		// Activated before every record of the table expired
+		{MerchantID: "merchant-4", KeyID: "key-1", Status: value_objects.MerchantKeyActive, CreatedAt: now.AddDate(0, 0, -200).UnixMilli(), ActivatedAt: now.AddDate(0, 0, -190).UnixMilli()},
+	}
+	records := []*entities.StoredCardInfo{{ExternalReferenceID: "ext-ref-1"}, {ExternalReferenceID: "ext-ref-2"}}
+	staleRecords := []*entities.StoredCardInfo{{ExternalReferenceID: "ext-ref-0"}}
+
+	tests := []struct {
+		name                 string
+		findKeysErr          error
+		findRecordsErr       error
+		findStaleErr         error
+		promoteErr           error
+		expectedResponse     *PromoteCardInfoKeysResponse
+		expectedLookups      int
+		expectedStaleLookups int
+		expectErr            bool
+	}{
+		{
+			name:                 "should promote the records stored while the key was pending and report the older ones",
+			expectedResponse:     &PromoteCardInfoKeysResponse{Merchants: 3, Promoted: 2, Stale: 1},
+			expectedLookups:      1,
+			expectedStaleLookups: 3,
+		},
+		{
+			name:                 "should report the records that failed",
+			promoteErr:           errors.New("dynamo error"),
+			expectedResponse:     &PromoteCardInfoKeysResponse{Merchants: 3, Stale: 1, Failed: 2},
+			expectedLookups:      1,
+			expectedStaleLookups: 3,
+			expectErr:            true,
+		},
+		{
+			name:                 "should report the merchant whose records could not be found",
+			findRecordsErr:       errors.New("dynamo error"),
+			expectedResponse:     &PromoteCardInfoKeysResponse{Merchants: 3, Stale: 1, Failed: 1},
+			expectedLookups:      1,
+			expectedStaleLookups: 3,
+			expectErr:            true,
+		},
+		{
+			name:                 "should report the merchant whose stale records could not be found",
+			findStaleErr:         errors.New("dynamo error"),
+			expectedResponse:     &PromoteCardInfoKeysResponse{Merchants: 3, Promoted: 2, Failed: 1},
+			expectedLookups:      1,
+			expectedStaleLookups: 3,
+			expectErr:            true,
+		},
+		{
+			name:        "should fail when the keys cannot be found",
+			findKeysErr: errors.New("dynamo error"),
+			expectErr:   true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockCardInfoRepository{}
+			mockKeyRepo := &MockMerchantKeyRepository{}
+			mockLogger := &MockLogger{}
+			mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
+			mockLogger.On("Error", mock.AnythingOfType("string"), mock.Anything).Return()
+			mockLogger.On("Warning", mock.AnythingOfType("string"), mock.Anything).Return()
+			mockKeyRepo.On("FindAll", mock.Anything).Return(keys, tt.findKeysErr)
+			mockRepo.On("FindByNextKey", mock.Anything, "merchant-1", "key-2", promotedKey.CreatedAt, promotedKey.ActivatedAt).
+				Return(records, tt.findRecordsErr)
+			mockRepo.On("FindByPreviousKey", mock.Anything, "merchant-1", "key-2", retentionStart, promotedKey.CreatedAt-1).
+				Return(staleRecords, tt.findStaleErr)
+			mockRepo.On("FindByPreviousKey", mock.Anything, mock.AnythingOfType("string"), "key-1", retentionStart, mock.AnythingOfType("int64")).
+				Return([]*entities.StoredCardInfo{}, nil)
+			mockRepo.On("PromoteNextKey", mock.Anything, mock.AnythingOfType("string"), "key-2").Return(tt.promoteErr)
+			useCase := NewPromoteCardInfoKeysUseCase(mockRepo, mockKeyRepo, mockLogger)
+			useCase.now = func() time.Time { return now }
+
+			response, err := useCase.Execute(context.Background())
+
+			assert.Equal(t, tt.expectedResponse, response)
+			assert.Equal(t, tt.expectErr, err != nil)
+			mockRepo.AssertNumberOfCalls(t, "FindByNextKey", tt.expectedLookups)
+			mockRepo.AssertNumberOfCalls(t, "FindByPreviousKey", tt.expectedStaleLookups)
+		})
+	}
+}
@@ -0,0 +1,153 @@
+package use_cases
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/repositories"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/services"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/value_objects"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+)
+
+var (
+	// ErrInvalidMerchantKey the key ID is missing, the PEM is not a valid RSA key, the algorithm is not supported
+	// or the dates are inconsistent
+	ErrInvalidMerchantKey = errors.New("invalid merchant key")
+	// ErrMerchantKeyExists the merchant already registered the key ID
+	ErrMerchantKeyExists = errors.New("merchant key already registered")
+)
+
+// RegisterMerchantKeyUseCase registers the RSA public key the card data of a merchant is encrypted with
+type RegisterMerchantKeyUseCase struct {
+	keyRepo           repositories.MerchantKeyRepository
+	credentialService services.CredentialService
+	logger            logger.KushkiLogger
+	now               func() time.Time
+}
+
+// NewRegisterMerchantKeyUseCase creates a new instance of the use case
+func NewRegisterMerchantKeyUseCase(
+	keyRepo repositories.MerchantKeyRepository,
+	credentialService services.CredentialService,
+	logger logger.KushkiLogger,
+) *RegisterMerchantKeyUseCase {
+	return &RegisterMerchantKeyUseCase{
+		keyRepo:           keyRepo,
+		credentialService: credentialService,
+		logger:            logger,
+		now:               time.Now,
+	}
+}
+
+// RegisterMerchantKeyRequest represents the input for the use case, the dates are in milliseconds
+// The merchant and its credential come from the authorizer and the headers, never from the body
+type RegisterMerchantKeyRequest struct {
+	MerchantID          string `json:"-"`
+	PrivateCredentialID string `json:"-"`
+	KeyID               string `json:"keyId"`
+	PublicKey           string `json:"publicKey"`
+	Algorithm           string `json:"algorithm"`
+	ActivatedAt         int64  `json:"activatedAt"`
+	ExpiresAt           int64  `json:"expiresAt"`
+}
+
+// MerchantKeyResponse the registered key without its PEM
+type MerchantKeyResponse struct {
+	KeyID       string `json:"keyId"`
+	Algorithm   string `json:"algorithm"`
+	Status      string `json:"status"`
+	ActivatedAt int64  `json:"activatedAt"`
+	ExpiresAt   int64  `json:"expiresAt,omitempty"`
+}
+
+// Execute authenticates the merchant, validates the key and stores it in the registry
+func (uc *RegisterMerchantKeyUseCase) Execute(ctx context.Context, request RegisterMerchantKeyRequest) (*MerchantKeyResponse, error) {
+	const useCase = "RegisterMerchantKey"
+
+	uc.logger.Info(fmt.Sprintf("%s | Starting", useCase),
+		fmt.Sprintf("MerchantID: %s, KeyID: %s", request.MerchantID, request.KeyID))
+
+	// Step 1: Authenticate the private credential of the merchant
+	if err := authenticateMerchant(uc.credentialService, request.MerchantID, request.PrivateCredentialID); err != nil {
+		uc.logger.Error(fmt.Sprintf("%s | CredentialError", useCase), fmt.Sprintf("MerchantID: %s", request.MerchantID))
+		return nil, err
+	}
+
+	// Step 2: Validate the key before storing it, a bad key would fail every encryption of the merchant
+	key, err := uc.createMerchantKey(request)
+	if err != nil {
+		uc.logger.Error(fmt.Sprintf("%s | ValidationError", useCase), err)
+		return nil, err
+	}
+
+	// Step 3: Store the key
+	if err := uc.keyRepo.Create(ctx, key); err != nil {
+		if errors.Is(err, repositories.ErrMerchantKeyExists) {
+			return nil, ErrMerchantKeyExists
+		}
+		uc.logger.Error(fmt.Sprintf("%s | SaveError", useCase), err)
+		return nil, fmt.Errorf("failed to save merchant key: %w", err)
+	}
+
+	uc.logger.Info(fmt.Sprintf("%s | Success", useCase),
+		fmt.Sprintf("MerchantID: %s, KeyID: %s", key.MerchantID, key.KeyID))
+
+	return &MerchantKeyResponse{
+		KeyID:       key.KeyID,
+		Algorithm:   key.Algorithm,
+		Status:      key.Status,
+		ActivatedAt: key.ActivatedAt,
+		ExpiresAt:   key.ExpiresAt,
+	}, nil
+}
+
+// createMerchantKey the key is active from the activation date, now when it is not given
+func (uc *RegisterMerchantKeyUseCase) createMerchantKey(request RegisterMerchantKeyRequest) (value_objects.MerchantKey, error) {
+	currentTime := uc.now().UnixMilli()
+
+	keyID := strings.TrimSpace(request.KeyID)
+	if keyID == "" {
+		return value_objects.MerchantKey{}, fmt.Errorf("%w: keyId is required", ErrInvalidMerchantKey)
+	}
+
+	publicKey, err := value_objects.ParseRSAPublicKey(request.PublicKey)
+	if err != nil {
+		return value_objects.MerchantKey{}, fmt.Errorf("%w: %v", ErrInvalidMerchantKey, err)
+	}
+	if publicKey.N.BitLen() < value_objects.MinRSAKeyBits {
+		return value_objects.MerchantKey{}, fmt.Errorf("%w: the key has %d bits, at least %d are required",
+			ErrInvalidMerchantKey, publicKey.N.BitLen(), value_objects.MinRSAKeyBits)
+	}
+
+	// RSA-OAEP with SHA-256 unless the merchant decrypts with PKCS#1 v1.5
+	algorithm := request.Algorithm
+	if algorithm == "" {
+		algorithm = value_objects.AlgorithmRSAOAEPSHA256
+	}
+	if !value_objects.IsSupportedAlgorithm(algorithm) {
+		return value_objects.MerchantKey{}, fmt.Errorf("%w: unsupported algorithm %s", ErrInvalidMerchantKey, algorithm)
+	}
+
+	activatedAt := request.ActivatedAt
+	if activatedAt == 0 {
+		activatedAt = currentTime
+	}
+	if request.ExpiresAt != 0 && request.ExpiresAt <= max(activatedAt, currentTime) {
+		return value_objects.MerchantKey{}, fmt.Errorf("%w: expiresAt must be after the activation and now", ErrInvalidMerchantKey)
+	}
+
+	return value_objects.MerchantKey{
+		MerchantID:  request.MerchantID,
+		KeyID:       keyID,
+		PublicKey:   request.PublicKey,
+		Algorithm:   algorithm,
+		Status:      value_objects.MerchantKeyActive,
+		ActivatedAt: activatedAt,
+		ExpiresAt:   request.ExpiresAt,
+		CreatedAt:   currentTime,
+	}, nil
+}
@@ -0,0 +1,217 @@
+package use_cases
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/repositories"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/value_objects"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockMerchantKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockMerchantKeyRepository) FindByMerchantID(ctx context.Context, merchantID string) ([]value_objects.MerchantKey, error) {
+	args := m.Called(ctx, merchantID)
+	return args.Get(0).([]value_objects.MerchantKey), args.Error(1)
+}
+
+func (m *MockMerchantKeyRepository) FindAll(ctx context.Context) ([]value_objects.MerchantKey, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]value_objects.MerchantKey), args.Error(1)
+}
+
+func (m *MockMerchantKeyRepository) Create(ctx context.Context, key value_objects.MerchantKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func encodePublicKeyPEM(t *testing.T, publicKey interface{}) string {
+	t.Helper()
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	assert.NoError(t, err)
+
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der}))
+}
+
+func generateRSAPublicKeyPEM(t *testing.T, bits int) string {
+	t.Helper()
+	privateKey, err := rsa.GenerateKey(rand.Reader, bits)
+	assert.NoError(t, err)
+
+	return encodePublicKeyPEM(t, &privateKey.PublicKey)
+}
+
+func TestRegisterMerchantKeyUseCase_Execute(t *testing.T) {
+	now := time.Now()
+	hour := time.Hour.Milliseconds()
+	validPEM := generateRSAPublicKeyPEM(t, 2048)
+	ecdsaKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	validRequest := RegisterMerchantKeyRequest{
+		MerchantID:          "merchant-123",
+		PrivateCredentialID: "private-cred-456",
+		KeyID:               "key-2025",
+		PublicKey:           validPEM,
+	}
+	withChanges := func(change func(request *RegisterMerchantKeyRequest)) RegisterMerchantKeyRequest {
+		request := validRequest
+		change(&request)
+		return request
+	}
+
+	tests := []struct {
+		name             string
+		request          RegisterMerchantKeyRequest
+		validCredential  bool
+		createErr        error
+		expectedResponse *MerchantKeyResponse
+		expectedErr      error
+	}{
+		{
+			name:            "should register the key active from now",
+			request:         validRequest,
+			validCredential: true,
+			expectedResponse: &MerchantKeyResponse{
+				KeyID:       "key-2025",
+				Algorithm:   value_objects.AlgorithmRSAOAEPSHA256,
+				Status:      value_objects.MerchantKeyActive,
+				ActivatedAt: now.UnixMilli(),
+			},
+		},
+		{
+			name: "should register a key that requests PKCS#1 v1.5",
+			request: withChanges(func(request *RegisterMerchantKeyRequest) {
+				request.Algorithm = value_objects.AlgorithmRSAPKCS1v15
+			}),
+			validCredential: true,
+			expectedResponse: &MerchantKeyResponse{
+				KeyID:       "key-2025",
+				Algorithm:   value_objects.AlgorithmRSAPKCS1v15,
+				Status:      value_objects.MerchantKeyActive,
+				ActivatedAt: now.UnixMilli(),
+			},
+		},
+		{
+			name:            "should reject an unsupported algorithm",
+			request:         withChanges(func(request *RegisterMerchantKeyRequest) { request.Algorithm = "RSA-OAEP" }),
+			validCredential: true,
+			expectedErr:     ErrInvalidMerchantKey,
+		},
+		{
+			name: "should register a key with activation and expiry dates",
+			request: withChanges(func(request *RegisterMerchantKeyRequest) {
+				request.ActivatedAt = now.UnixMilli() + hour
+				request.ExpiresAt = now.UnixMilli() + 2*hour
+			}),
+			validCredential: true,
+			expectedResponse: &MerchantKeyResponse{
+				KeyID:       "key-2025",
+				Algorithm:   value_objects.AlgorithmRSAOAEPSHA256,
+				Status:      value_objects.MerchantKeyActive,
+				ActivatedAt: now.UnixMilli() + hour,
+				ExpiresAt:   now.UnixMilli() + 2*hour,
+			},
+		},
+		{
+			name:        "should reject an invalid private credential",
+			request:     validRequest,
+			expectedErr: ErrInvalidCredential,
+		},
+		{
+			name:            "should reject a key without key ID",
+			request:         withChanges(func(request *RegisterMerchantKeyRequest) { request.KeyID = " " }),
+			validCredential: true,
+			expectedErr:     ErrInvalidMerchantKey,
+		},
+		{
+			name:            "should reject an invalid PEM",
+			request:         withChanges(func(request *RegisterMerchantKeyRequest) { request.PublicKey = "not a pem" }),
+			validCredential: true,
+			expectedErr:     ErrInvalidMerchantKey,
+		},
+		{
+			name: "should reject a key that is not RSA",
+			request: withChanges(func(request *RegisterMerchantKeyRequest) {
+				request.PublicKey = encodePublicKeyPEM(t, &ecdsaKey.PublicKey)
+			}),
+			validCredential: true,
+			expectedErr:     ErrInvalidMerchantKey,
+		},
+		{
+			name: "should reject a key shorter than 2048 bits",
+			request: withChanges(func(request *RegisterMerchantKeyRequest) {
+				request.PublicKey = generateRSAPublicKeyPEM(t, 1024)
+			}),
+			validCredential: true,
+			expectedErr:     ErrInvalidMerchantKey,
+		},
+		{
+			name: "should reject a key that expires before its activation",
+			request: withChanges(func(request *RegisterMerchantKeyRequest) {
+				request.ActivatedAt = now.UnixMilli() + 2*hour
+				request.ExpiresAt = now.UnixMilli() + hour
+			}),
+			validCredential: true,
+			expectedErr:     ErrInvalidMerchantKey,
+		},
+		{
+			name:            "should reject a key ID already registered",
+			request:         validRequest,
+			validCredential: true,
+			createErr:       fmt.Errorf("key: %w", repositories.ErrMerchantKeyExists),
+			expectedErr:     ErrMerchantKeyExists,
+		},
+		{
+			name:            "should return the registry error",
+			request:         validRequest,
+			validCredential: true,
+			createErr:       errors.New("dynamo error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockMerchantKeyRepository{}
+			mockCredential := &MockCredentialService{}
+			mockLogger := &MockLogger{}
+			mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
+			mockLogger.On("Error", mock.AnythingOfType("string"), mock.Anything).Return()
+			mockCredential.On("ValidatePrivateCredential", "private-cred-456", "merchant-123").Return(tt.validCredential)
+			mockCredential.On("BelongsToAnotherMerchant", "private-cred-456", "merchant-123").Return(false).Maybe()
+			mockRepo.On("Create", mock.Anything, mock.MatchedBy(func(key value_objects.MerchantKey) bool {
+				return key.MerchantID == "merchant-123" && key.PublicKey == tt.request.PublicKey && key.CreatedAt == now.UnixMilli() &&
+					value_objects.IsSupportedAlgorithm(key.Algorithm)
+			})).Return(tt.createErr)
+			useCase := NewRegisterMerchantKeyUseCase(mockRepo, mockCredential, mockLogger)
+			useCase.now = func() time.Time { return now }
+
+			response, err := useCase.Execute(context.Background(), tt.request)
+
+			assert.Equal(t, tt.expectedResponse, response)
+			switch {
+			case tt.expectedErr != nil:
+				assert.ErrorIs(t, err, tt.expectedErr)
+			case tt.createErr != nil:
+				assert.ErrorIs(t, err, tt.createErr)
+			default:
+				assert.NoError(t, err)
+			}
+			if tt.expectedResponse == nil && tt.createErr == nil {
+				mockRepo.AssertNotCalled(t, "Create", mock.Anything, mock.Anything)
+			}
+		})
+	}
+}
@@ -0,0 +1,39 @@
+package entities
+
+// Webhook delivery statuses
+const (
+	WebhookDeliveryPending   = "PENDING"
+	WebhookDeliveryRetrying  = "RETRYING"
+	WebhookDeliveryDelivered = "DELIVERED"
+	WebhookDeliveryFailed    = "FAILED"
+)
+
+// WebhookDelivery tracks the delivery of the card info of a transaction to the merchant webhook
+type WebhookDelivery struct {
+	ExternalReferenceID string `json:"externalReferenceId" dynamodbav:"externalReferenceId"`
+	MerchantID          string `json:"merchantId" dynamodbav:"merchantId"`
+	Status              string `json:"status" dynamodbav:"status"`
+	Attempts            int    `json:"attempts" dynamodbav:"attempts"`
+	LastStatusCode      int    `json:"lastStatusCode,omitempty" dynamodbav:"lastStatusCode,omitempty"`
+	LastError           string `json:"lastError,omitempty" dynamodbav:"lastError,omitempty"`
+	CreatedAt           int64  `json:"createdAt" dynamodbav:"createdAt"`
+	UpdatedAt           int64  `json:"updatedAt" dynamodbav:"updatedAt"`
+	DeliveredAt         int64  `json:"deliveredAt,omitempty" dynamodbav:"deliveredAt,omitempty"`
+	// ExpiresAt is in seconds as the DynamoDB TTL expects
+	ExpiresAt int64 `json:"expiresAt" dynamodbav:"expiresAt"`
+}
+
+// IsFinal checks if the delivery does not need more attempts
+func (d *WebhookDelivery) IsFinal() bool {
+	return d.Status == WebhookDeliveryDelivered || d.Status == WebhookDeliveryFailed
+}
+
+// IsScheduled checks if the delivery is already on its way, a pending delivery without attempts may not be queued yet
+func (d *WebhookDelivery) IsScheduled() bool {
+	return d.Status != WebhookDeliveryPending || d.Attempts > 0
+}
+
+// WebhookDeliveryMessage represents a message of the webhook delivery queue
+type WebhookDeliveryMessage struct {
+	ExternalReferenceID string `json:"externalReferenceId"`
+}
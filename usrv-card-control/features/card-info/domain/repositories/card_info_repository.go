@@ -13,9 +13,21 @@ type CardInfoRepository interface {
 	// FindByExternalReferenceID retrieves card information by external reference ID
 	FindByExternalReferenceID(ctx context.Context, externalReferenceID string) (*entities.StoredCardInfo, error)
 
+	// FindByMerchantIDAndExternalReferenceID retrieves card information only if it belongs to the merchant
+	FindByMerchantIDAndExternalReferenceID(ctx context.Context, merchantID, externalReferenceID string) (*entities.StoredCardInfo, error)
+
 	// Delete removes card information (for cleanup/expiration)
 	Delete(ctx context.Context, externalReferenceID string) error
 
 	// FindExpiredRecords finds records that have exceeded the 180-day limit
 	FindExpiredRecords(ctx context.Context, currentTime int64) ([]*entities.StoredCardInfo, error)
+
+	// FindByNextKey retrieves the records of the merchant created between from and to with a copy encrypted with the key
+	FindByNextKey(ctx context.Context, merchantID, keyID string, from, to int64) ([]*entities.StoredCardInfo, error)
+
+	// FindByPreviousKey retrieves the records of the merchant created between from and to encrypted with another key
+	FindByPreviousKey(ctx context.Context, merchantID, keyID string, from, to int64) ([]*entities.StoredCardInfo, error)
+
+	// PromoteNextKey replaces the card data with the copy encrypted with the key
+	PromoteNextKey(ctx context.Context, externalReferenceID, keyID string) error
 }
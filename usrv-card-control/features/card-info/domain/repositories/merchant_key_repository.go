@@ -0,0 +1,23 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/value_objects"
+)
+
+// ErrMerchantKeyExists the merchant already registered a key with the same key ID
+var ErrMerchantKeyExists = errors.New("merchant key already exists")
+
+// MerchantKeyRepository defines the contract for the merchant public key registry
+type MerchantKeyRepository interface {
+	// FindByMerchantID retrieves every key registered by the merchant
+	FindByMerchantID(ctx context.Context, merchantID string) ([]value_objects.MerchantKey, error)
+
+	// FindAll retrieves the keys of every merchant
+	FindAll(ctx context.Context) ([]value_objects.MerchantKey, error)
+
+	// Create stores a new key, ErrMerchantKeyExists when the key ID is already registered
+	Create(ctx context.Context, key value_objects.MerchantKey) error
+}
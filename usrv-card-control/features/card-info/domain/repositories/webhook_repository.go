@@ -0,0 +1,20 @@
+package repositories
+
+import (
+	"context"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/entities"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/value_objects"
+)
+
+// WebhookRepository defines the contract for webhook configuration and delivery persistence
+type WebhookRepository interface {
+	// FindConfig retrieves the webhook registered by the merchant
+	FindConfig(ctx context.Context, merchantID string) (*value_objects.WebhookConfig, error)
+
+	// FindDelivery retrieves the delivery status of the external reference ID
+	FindDelivery(ctx context.Context, externalReferenceID string) (*entities.WebhookDelivery, error)
+
+	// SaveDelivery stores the delivery status
+	SaveDelivery(ctx context.Context, delivery *entities.WebhookDelivery) error
+}
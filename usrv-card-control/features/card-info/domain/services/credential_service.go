@@ -3,4 +3,6 @@ package services
 // CredentialService defines the interface for validating private credentials
 type CredentialService interface {
 	ValidatePrivateCredential(privateCredentialID, merchantID string) bool
+	// BelongsToAnotherMerchant the credential is a private credential issued to a different merchant
+	BelongsToAnotherMerchant(privateCredentialID, merchantID string) bool
 }
@@ -8,4 +8,8 @@ import (
 type EncryptionService interface {
 	// EncryptCardData encrypts the card PAN and date using the merchant's public key
 	EncryptCardData(cardData value_objects.CardData, merchantID string) (value_objects.EncryptedCardData, error)
+
+	// EncryptCardDataForNextKey encrypts the card PAN and date with the key the merchant is rotating to,
+	// nil when no rotation is in progress
+	EncryptCardDataForNextKey(cardData value_objects.CardData, merchantID string) (*value_objects.EncryptedCardData, error)
 }
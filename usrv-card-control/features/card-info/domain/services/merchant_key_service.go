@@ -1,7 +1,15 @@
 package services
 
+import "bitbucket.org/kushki/usrv-card-control/features/card-info/domain/value_objects"
+
 // MerchantKeyService defines the interface for retrieving merchant public keys
+// A merchant may register several keys, only one of them encrypts the card data at a time
 type MerchantKeyService interface {
+	// GetMerchantPublicKey retrieves the PEM of the key the card data of the merchant is encrypted with
 	GetMerchantPublicKey(merchantID string) (string, error)
+	// GetEncryptionKey retrieves the key the card data of the merchant is encrypted with
+	GetEncryptionKey(merchantID string) (value_objects.MerchantKey, error)
+	// GetNextKey retrieves the key that replaces the encryption key, nil when no rotation is in progress
+	GetNextKey(merchantID string) (*value_objects.MerchantKey, error)
 	HasMerchantKey(merchantID string) bool
 }
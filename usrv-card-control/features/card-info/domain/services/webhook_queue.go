@@ -0,0 +1,13 @@
+package services
+
+import (
+	"context"
+	"time"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/entities"
+)
+
+// WebhookQueue defines the interface for scheduling webhook delivery attempts
+type WebhookQueue interface {
+	Enqueue(ctx context.Context, message entities.WebhookDeliveryMessage, delay time.Duration) error
+}
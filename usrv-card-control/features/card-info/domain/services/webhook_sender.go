@@ -0,0 +1,13 @@
+package services
+
+import (
+	"context"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/value_objects"
+)
+
+// WebhookSender defines the interface for posting signed payloads to a merchant webhook
+// It returns the status code answered by the webhook, the error is only for failed requests
+type WebhookSender interface {
+	Send(ctx context.Context, webhook value_objects.WebhookConfig, payload []byte) (int, error)
+}
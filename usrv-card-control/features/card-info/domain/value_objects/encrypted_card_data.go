@@ -1,9 +1,35 @@
 package value_objects
 
+// Encryption algorithms of the card data, as named by JWA (RFC 7518)
+const (
+	// AlgorithmRSAOAEPSHA256 RSA-OAEP with SHA-256 and MGF1 with SHA-256, the default
+	AlgorithmRSAOAEPSHA256 = "RSA-OAEP-256"
+	// AlgorithmRSAPKCS1v15 RSA PKCS#1 v1.5, only for the keys registered with it
+	AlgorithmRSAPKCS1v15 = "RSA1_5"
+)
+
+// IsSupportedAlgorithm checks if the card data can be encrypted with the algorithm
+func IsSupportedAlgorithm(algorithm string) bool {
+	return algorithm == AlgorithmRSAOAEPSHA256 || algorithm == AlgorithmRSAPKCS1v15
+}
+
 // EncryptedCardData represents encrypted card information for storage/retrieval
+// The key fields are empty for the records encrypted before the key registry
 type EncryptedCardData struct {
-	EncryptedPan  string `json:"encPan" dynamodbav:"encPan"`
-	EncryptedDate string `json:"encDate" dynamodbav:"encDate"`
+	EncryptedPan   string `json:"encPan" dynamodbav:"encPan"`
+	EncryptedDate  string `json:"encDate" dynamodbav:"encDate"`
+	KeyID          string `json:"keyId,omitempty" dynamodbav:"keyId,omitempty"`
+	Algorithm      string `json:"algorithm,omitempty" dynamodbav:"algorithm,omitempty"`
+	KeyFingerprint string `json:"keyFingerprint,omitempty" dynamodbav:"keyFingerprint,omitempty"`
+}
+
+// WithDefaultAlgorithm the records stored without algorithm were encrypted with PKCS#1 v1.5
+func (e EncryptedCardData) WithDefaultAlgorithm() EncryptedCardData {
+	if e.Algorithm == "" {
+		e.Algorithm = AlgorithmRSAPKCS1v15
+	}
+
+	return e
 }
 
 // IsValid validates the EncryptedCardData
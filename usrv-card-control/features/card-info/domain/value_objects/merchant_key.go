@@ -0,0 +1,111 @@
+package value_objects
+
+import (
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"encoding/pem"
+	"fmt"
+)
+
+// Merchant key statuses
+const (
+	MerchantKeyActive   = "ACTIVE"
+	MerchantKeyInactive = "INACTIVE"
+)
+
+// MinRSAKeyBits is the smallest key size accepted for the card data
+const MinRSAKeyBits = 2048
+
+// MerchantKey represents a public key registered by the PCI entity of a merchant
+type MerchantKey struct {
+	MerchantID  string `json:"merchantId" dynamodbav:"merchantId"`
+	KeyID       string `json:"keyId" dynamodbav:"keyId"`
+	PublicKey   string `json:"publicKey" dynamodbav:"publicKey"`
+	Algorithm   string `json:"algorithm,omitempty" dynamodbav:"algorithm,omitempty"`
+	Status      string `json:"status" dynamodbav:"status"`
+	ActivatedAt int64  `json:"activatedAt" dynamodbav:"activatedAt"`
+	ExpiresAt   int64  `json:"expiresAt,omitempty" dynamodbav:"expiresAt,omitempty"`
+	CreatedAt   int64  `json:"createdAt" dynamodbav:"createdAt"`
+}
+
+// IsUsable checks if the key can encrypt at the given time, a key without expiry never expires
+func (k MerchantKey) IsUsable(currentTime int64) bool {
+	return k.Status == MerchantKeyActive &&
+		k.ActivatedAt <= currentTime &&
+		(k.ExpiresAt == 0 || currentTime < k.ExpiresAt)
+}
+
+// EncryptionAlgorithm the algorithm requested by the key, RSA-OAEP with SHA-256 when it requests none
+func (k MerchantKey) EncryptionAlgorithm() string {
+	if k.Algorithm == "" {
+		return AlgorithmRSAOAEPSHA256
+	}
+
+	return k.Algorithm
+}
+
+// IsPending checks if the key is registered to replace the current one at a later activation
+func (k MerchantKey) IsPending(currentTime int64) bool {
+	return k.Status == MerchantKeyActive &&
+		currentTime < k.ActivatedAt &&
+		(k.ExpiresAt == 0 || k.ActivatedAt < k.ExpiresAt)
+}
+
+// SelectEncryptionKey the most recently activated usable key encrypts the card data
+func SelectEncryptionKey(keys []MerchantKey, currentTime int64) (MerchantKey, bool) {
+	var selected MerchantKey
+	found := false
+	for _, key := range keys {
+		if key.IsUsable(currentTime) && (!found || key.ActivatedAt > selected.ActivatedAt) {
+			selected = key
+			found = true
+		}
+	}
+
+	return selected, found
+}
+
+// SelectNextKey the pending key activated first replaces the encryption key
+func SelectNextKey(keys []MerchantKey, currentTime int64) (MerchantKey, bool) {
+	var selected MerchantKey
+	found := false
+	for _, key := range keys {
+		if key.IsPending(currentTime) && (!found || key.ActivatedAt < selected.ActivatedAt) {
+			selected = key
+			found = true
+		}
+	}
+
+	return selected, found
+}
+
+// ParseRSAPublicKey parses a PEM-encoded (X.509) RSA public key
+func ParseRSAPublicKey(publicKeyPEM string) (*rsa.PublicKey, error) {
+	block, _ := pem.Decode([]byte(publicKeyPEM))
+	if block == nil {
+		return nil, fmt.Errorf("failed to parse PEM block containing public key")
+	}
+
+	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse public key: %w", err)
+	}
+
+	rsaPub, ok := pub.(*rsa.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("public key is not RSA")
+	}
+
+	return rsaPub, nil
+}
+
+// RSAPublicKeyFingerprint hex encoded SHA-256 of the DER (X.509) encoding of the key
+func RSAPublicKeyFingerprint(publicKey *rsa.PublicKey) string {
+	// Marshaling an RSA key cannot fail
+	der, _ := x509.MarshalPKIXPublicKey(publicKey)
+	fingerprint := sha256.Sum256(der)
+
+	return hex.EncodeToString(fingerprint[:])
+}
@@ -0,0 +1,14 @@
+package value_objects
+
+// WebhookConfig represents the webhook registered by the PCI entity of a merchant
+type WebhookConfig struct {
+	MerchantID string `json:"merchantId" dynamodbav:"merchantId"`
+	URL        string `json:"url" dynamodbav:"url"`
+	Secret     string `json:"secret" dynamodbav:"secret"`
+	Enabled    bool   `json:"enabled" dynamodbav:"enabled"`
+}
+
+// IsActive checks if the card info must be delivered to the webhook
+func (w WebhookConfig) IsActive() bool {
+	return w.Enabled && w.URL != "" && w.Secret != ""
+}
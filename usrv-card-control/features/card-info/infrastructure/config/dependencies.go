@@ -14,13 +14,18 @@ import (
 // DependencyContainer holds all the dependencies for the card-info feature
 type DependencyContainer struct {
 	// Use Cases
-	ProcessCardInfoUseCase *use_cases.ProcessCardInfoMessageUseCase
+	ProcessCardInfoUseCase        *use_cases.ProcessCardInfoMessageUseCase
+	GetCardInfoUseCase            *use_cases.GetCardInfoUseCase
+	DeliverCardInfoWebhookUseCase *use_cases.DeliverCardInfoWebhookUseCase
+	FailCardInfoWebhookUseCase    *use_cases.FailCardInfoWebhookUseCase
+	RegisterMerchantKeyUseCase    *use_cases.RegisterMerchantKeyUseCase
+	PromoteCardInfoKeysUseCase    *use_cases.PromoteCardInfoKeysUseCase
 
 	// Infrastructure
 	Logger logger.KushkiLogger
 }
 
-// NewDependencyContainer creates and wires up all dependencies
+// NewDependencyContainer creates and wires up all dependencies, only the processor and the webhook delivery need the SQS client
 func NewDependencyContainer(ctx context.Context) (*DependencyContainer, error) {
 	// Initialize logger
 	kskLogger, err := logger.NewKushkiLogger()
@@ -34,11 +39,19 @@ func NewDependencyContainer(ctx context.Context) (*DependencyContainer, error) {
 		return nil, fmt.Errorf("failed to initialize DynamoDB gateway: %w", err)
 	}
 
-	// Create repository
+	// Initialize SQS client for the webhook delivery queue
+	sqsClient, err := tools.InitializeSQSClient(ctx, kskLogger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize SQS client: %w", err)
+	}
+
+	// Create repositories
 	cardInfoRepo := repositories.NewDynamoCardInfoRepository(dynamoGtw, kskLogger)
+	webhookRepo := repositories.NewDynamoWebhookRepository(dynamoGtw, kskLogger)
+	merchantKeyRepo := repositories.NewDynamoMerchantKeyRepository(dynamoGtw, kskLogger)
 
 	// Create concrete service implementations
-	keyProvider := services.NewMerchantKeyService(kskLogger)
+	keyProvider := services.NewMerchantKeyService(merchantKeyRepo, kskLogger)
 	encryptionService := services.NewRSAEncryptionService(keyProvider, kskLogger)
 
 	merchantAccessProvider := services.NewMerchantAccessService(kskLogger)
@@ -49,16 +62,121 @@ func NewDependencyContainer(ctx context.Context) (*DependencyContainer, error) {
 		kskLogger,
 	)
 
-	// Create use case
+	// Create use cases
+	deliverCardInfoWebhookUseCase := use_cases.NewDeliverCardInfoWebhookUseCase(
+		cardInfoRepo,
+		webhookRepo,
+		services.NewSQSWebhookQueue(sqsClient, kskLogger),
+		services.NewHTTPWebhookSender(kskLogger),
+		kskLogger,
+	)
+
 	processCardInfoUseCase := use_cases.NewProcessCardInfoMessageUseCase(
 		cardInfoRepo,
 		encryptionService,
 		validationService,
 		kskLogger,
+	).WithWebhookDelivery(deliverCardInfoWebhookUseCase)
+
+	getCardInfoUseCase := use_cases.NewGetCardInfoUseCase(
+		cardInfoRepo,
+		credentialProvider,
+		kskLogger,
+	)
+
+	registerMerchantKeyUseCase := use_cases.NewRegisterMerchantKeyUseCase(
+		merchantKeyRepo,
+		credentialProvider,
+		kskLogger,
+	)
+
+	promoteCardInfoKeysUseCase := use_cases.NewPromoteCardInfoKeysUseCase(
+		cardInfoRepo,
+		merchantKeyRepo,
+		kskLogger,
+	)
+
+	return &DependencyContainer{
+		ProcessCardInfoUseCase:        processCardInfoUseCase,
+		GetCardInfoUseCase:            getCardInfoUseCase,
+		DeliverCardInfoWebhookUseCase: deliverCardInfoWebhookUseCase,
+		RegisterMerchantKeyUseCase:    registerMerchantKeyUseCase,
+		PromoteCardInfoKeysUseCase:    promoteCardInfoKeysUseCase,
+		Logger:                        kskLogger,
+	}, nil
+}
+
+// NewRetrievalDependencyContainer wires only the card info retrieval, the API answers without the queues and keys
+func NewRetrievalDependencyContainer(ctx context.Context) (*DependencyContainer, error) {
+	kskLogger, err := logger.NewKushkiLogger()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create logger: %w", err)
+	}
+
+	dynamoGtw, err := tools.InitializeDynamoGtw(ctx, kskLogger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize DynamoDB gateway: %w", err)
+	}
+
+	getCardInfoUseCase := use_cases.NewGetCardInfoUseCase(
+		repositories.NewDynamoCardInfoRepository(dynamoGtw, kskLogger),
+		services.NewCredentialService(kskLogger),
+		kskLogger,
 	)
 
 	return &DependencyContainer{
-		ProcessCardInfoUseCase: processCardInfoUseCase,
-		Logger:                 kskLogger,
+		GetCardInfoUseCase: getCardInfoUseCase,
+		Logger:             kskLogger,
+	}, nil
+}
+
+// NewMerchantKeyDependencyContainer wires the key registration and promotion, neither of them publishes to the webhook queue
+func NewMerchantKeyDependencyContainer(ctx context.Context) (*DependencyContainer, error) {
+	kskLogger, err := logger.NewKushkiLogger()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create logger: %w", err)
+	}
+
+	dynamoGtw, err := tools.InitializeDynamoGtw(ctx, kskLogger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize DynamoDB gateway: %w", err)
+	}
+
+	cardInfoRepo := repositories.NewDynamoCardInfoRepository(dynamoGtw, kskLogger)
+	merchantKeyRepo := repositories.NewDynamoMerchantKeyRepository(dynamoGtw, kskLogger)
+
+	return &DependencyContainer{
+		RegisterMerchantKeyUseCase: use_cases.NewRegisterMerchantKeyUseCase(
+			merchantKeyRepo,
+			services.NewCredentialService(kskLogger),
+			kskLogger,
+		),
+		PromoteCardInfoKeysUseCase: use_cases.NewPromoteCardInfoKeysUseCase(
+			cardInfoRepo,
+			merchantKeyRepo,
+			kskLogger,
+		),
+		Logger: kskLogger,
+	}, nil
+}
+
+// NewWebhookDLQDependencyContainer wires the failure of the deliveries that reached the dead letter queue
+func NewWebhookDLQDependencyContainer(ctx context.Context) (*DependencyContainer, error) {
+	kskLogger, err := logger.NewKushkiLogger()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create logger: %w", err)
+	}
+
+	dynamoGtw, err := tools.InitializeDynamoGtw(ctx, kskLogger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to initialize DynamoDB gateway: %w", err)
+	}
+
+	return &DependencyContainer{
+		FailCardInfoWebhookUseCase: use_cases.NewFailCardInfoWebhookUseCase(
+			repositories.NewDynamoWebhookRepository(dynamoGtw, kskLogger),
+			kskLogger,
+		),
+		Logger: kskLogger,
 	}, nil
 }
@@ -0,0 +1,35 @@
+package handlers
+
+import (
+	"context"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/infrastructure/config"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/interfaces/adapters"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// APICardInfoHandler is the lambda entry point for the card info API
+type APICardInfoHandler struct {
+	adapter *adapters.APIGatewayAdapter
+}
+
+// NewAPICardInfoHandler wires the API Gateway adapter with the feature dependencies
+func NewAPICardInfoHandler(dependencies *config.DependencyContainer) *APICardInfoHandler {
+	return &APICardInfoHandler{
+		adapter: adapters.NewAPIGatewayAdapter(
+			dependencies.GetCardInfoUseCase,
+			dependencies.RegisterMerchantKeyUseCase,
+			dependencies.Logger,
+		),
+	}
+}
+
+// HandleGetCardInfo returns the card info of the requested transaction
+func (h *APICardInfoHandler) HandleGetCardInfo(ctx context.Context, event events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	return h.adapter.HandleGetCardInfo(ctx, event)
+}
+
+// HandleRegisterMerchantKey registers a public key of the merchant
+func (h *APICardInfoHandler) HandleRegisterMerchantKey(ctx context.Context, event events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	return h.adapter.HandleRegisterMerchantKey(ctx, event)
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"context"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/application/use_cases"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/infrastructure/config"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/interfaces/adapters"
+)
+
+// ScheduledKeyPromotionHandler is the lambda entry point for the card info key promotion job
+type ScheduledKeyPromotionHandler struct {
+	adapter *adapters.ScheduledKeyPromotionAdapter
+}
+
+// NewScheduledKeyPromotionHandler wires the key promotion adapter with the feature dependencies
+func NewScheduledKeyPromotionHandler(dependencies *config.DependencyContainer) *ScheduledKeyPromotionHandler {
+	return &ScheduledKeyPromotionHandler{
+		adapter: adapters.NewScheduledKeyPromotionAdapter(
+			dependencies.PromoteCardInfoKeysUseCase,
+			dependencies.Logger,
+		),
+	}
+}
+
+// HandleScheduledEvent promotes the card data of the merchants whose next key activated
+func (h *ScheduledKeyPromotionHandler) HandleScheduledEvent(ctx context.Context) (*use_cases.PromoteCardInfoKeysResponse, error) {
+	return h.adapter.HandleScheduledEvent(ctx)
+}
@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"context"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/infrastructure/config"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/interfaces/adapters"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// SQSCardInfoHandler is the lambda entry point for card info SQS batches
+type SQSCardInfoHandler struct {
+	adapter *adapters.SQSAdapter
+}
+
+// NewSQSCardInfoHandler wires the SQS adapter with the feature dependencies
+func NewSQSCardInfoHandler(dependencies *config.DependencyContainer) *SQSCardInfoHandler {
+	return &SQSCardInfoHandler{
+		adapter: adapters.NewSQSAdapter(
+			dependencies.ProcessCardInfoUseCase,
+			dependencies.Logger,
+		).(*adapters.SQSAdapter),
+	}
+}
+
+// HandleSQSEvent processes the batch and returns the records that must be retried
+func (h *SQSCardInfoHandler) HandleSQSEvent(ctx context.Context, event events.SQSEvent) events.SQSEventResponse {
+	return h.adapter.HandleSQSEvent(ctx, event)
+}
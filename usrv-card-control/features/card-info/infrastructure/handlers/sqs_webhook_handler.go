@@ -0,0 +1,29 @@
+package handlers
+
+import (
+	"context"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/infrastructure/config"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/interfaces/adapters"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// SQSWebhookHandler is the lambda entry point for the webhook delivery queue
+type SQSWebhookHandler struct {
+	adapter *adapters.SQSWebhookAdapter
+}
+
+// NewSQSWebhookHandler wires the SQS webhook adapter with the feature dependencies
+func NewSQSWebhookHandler(dependencies *config.DependencyContainer) *SQSWebhookHandler {
+	return &SQSWebhookHandler{
+		adapter: adapters.NewSQSWebhookAdapter(
+			dependencies.DeliverCardInfoWebhookUseCase,
+			dependencies.Logger,
+		),
+	}
+}
+
+// HandleSQSEvent delivers the batch and returns the records that must be retried
+func (h *SQSWebhookHandler) HandleSQSEvent(ctx context.Context, event events.SQSEvent) events.SQSEventResponse {
+	return h.adapter.HandleSQSEvent(ctx, event)
+}
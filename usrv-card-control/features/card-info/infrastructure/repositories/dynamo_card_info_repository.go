@@ -13,6 +13,7 @@ import (
 	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
 	"bitbucket.org/kushki/usrv-go-core/logger"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
 const (
@@ -21,6 +22,11 @@ const (
 	ExternalReferenceIDField = "externalReferenceId"
 	MerchantIDField          = "merchantId"
 	ExpiresAtField           = "expiresAt"
+	CreatedAtField           = "createdAt"
+	CardField                = "card"
+	NextCardField            = "nextCard"
+
+	MerchantIDIndex = "merchantId-index"
 )
 
 // DynamoCardInfoRepository implements the CardInfoRepository using DynamoDB
@@ -79,7 +85,7 @@ func (r *DynamoCardInfoRepository) FindByExternalReferenceID(
 		if errors.Is(err, dynamoerror.ErrItemNotFound) {
 			r.logger.Info(fmt.Sprintf("%s | NotFound", operation),
 				fmt.Sprintf("ExternalReferenceID: %s", externalReferenceID))
-			return nil, fmt.Errorf("card info not found for externalReferenceID: %s", externalReferenceID)
+			return nil, fmt.Errorf("card info not found for externalReferenceID: %s: %w", externalReferenceID, err)
 		}
 		r.logger.Error(fmt.Sprintf("%s | Error", operation), err)
 		return nil, fmt.Errorf("failed to get card info from DynamoDB: %w", err)
@@ -112,7 +118,8 @@ func (r *DynamoCardInfoRepository) FindByMerchantIDAndExternalReferenceID(
 	if cardInfo.MerchantID != merchantID {
 		r.logger.Info(fmt.Sprintf("%s | MerchantMismatch", operation),
 			fmt.Sprintf("Expected: %s, Got: %s", merchantID, cardInfo.MerchantID))
-		return nil, fmt.Errorf("card info not found for merchant: %s", merchantID)
+		// Another merchant's record is reported as missing so its existence is not disclosed
+		return nil, fmt.Errorf("card info not found for merchant: %s: %w", merchantID, dynamoerror.ErrItemNotFound)
 	}
 
 	return cardInfo, nil
@@ -162,6 +169,84 @@ func (r *DynamoCardInfoRepository) FindExpiredRecords(
 	return expiredRecords, nil
 }
 
+// FindByNextKey retrieves the records of the merchant created between from and to with a copy encrypted with the key
+func (r *DynamoCardInfoRepository) FindByNextKey(
+	ctx context.Context,
+	merchantID,
+	keyID string,
+	from,
+	to int64,
+) ([]*entities.StoredCardInfo, error) {
+	const operation = "DynamoCardInfoRepository.FindByNextKey"
+
+	r.logger.Info(fmt.Sprintf("%s | Starting", operation),
+		fmt.Sprintf("MerchantID: %s, KeyID: %s, From: %d, To: %d", merchantID, keyID, from, to))
+
+	queryBuilder := r.buildQueryNextKeyBuilder(merchantID, keyID, from, to)
+
+	records := make([]*entities.StoredCardInfo, 0)
+	if err := r.dynamoGateway.Query(ctx, queryBuilder, &records); err != nil {
+		r.logger.Error(fmt.Sprintf("%s | Error", operation), err)
+		return nil, fmt.Errorf("failed to query records to promote: %w", err)
+	}
+
+	r.logger.Info(fmt.Sprintf("%s | Success", operation),
+		fmt.Sprintf("Found %d records to promote", len(records)))
+
+	return records, nil
+}
+
+// FindByPreviousKey retrieves the records of the merchant created between from and to encrypted with another key
+func (r *DynamoCardInfoRepository) FindByPreviousKey(
+	ctx context.Context,
+	merchantID,
+	keyID string,
+	from,
+	to int64,
+) ([]*entities.StoredCardInfo, error) {
+	const operation = "DynamoCardInfoRepository.FindByPreviousKey"
+
+	r.logger.Info(fmt.Sprintf("%s | Starting", operation),
+		fmt.Sprintf("MerchantID: %s, KeyID: %s, From: %d, To: %d", merchantID, keyID, from, to))
+
+	queryBuilder := r.buildQueryPreviousKeyBuilder(merchantID, keyID, from, to)
+
+	records := make([]*entities.StoredCardInfo, 0)
+	if err := r.dynamoGateway.Query(ctx, queryBuilder, &records); err != nil {
+		r.logger.Error(fmt.Sprintf("%s | Error", operation), err)
+		return nil, fmt.Errorf("failed to query records under a previous key: %w", err)
+	}
+
+	r.logger.Info(fmt.Sprintf("%s | Success", operation),
+		fmt.Sprintf("Found %d records under a previous key", len(records)))
+
+	return records, nil
+}
+
+// PromoteNextKey replaces the card data with the copy encrypted with the key, in a single conditional update
+func (r *DynamoCardInfoRepository) PromoteNextKey(ctx context.Context, externalReferenceID, keyID string) error {
+	const operation = "DynamoCardInfoRepository.PromoteNextKey"
+
+	updateBuilder := r.buildPromoteNextKeyBuilder(externalReferenceID, keyID)
+
+	if err := r.dynamoGateway.UpdateItem(ctx, updateBuilder); err != nil {
+		// Another run already promoted the record
+		var conditionalErr *dynamoTypes.ConditionalCheckFailedException
+		if errors.As(err, &conditionalErr) {
+			r.logger.Info(fmt.Sprintf("%s | AlreadyPromoted", operation),
+				fmt.Sprintf("ExternalReferenceID: %s", externalReferenceID))
+			return nil
+		}
+		r.logger.Error(fmt.Sprintf("%s | Error", operation), err)
+		return fmt.Errorf("failed to promote card info %s to key %s: %w", externalReferenceID, keyID, err)
+	}
+
+	r.logger.Info(fmt.Sprintf("%s | Success", operation),
+		fmt.Sprintf("ExternalReferenceID: %s, KeyID: %s", externalReferenceID, keyID))
+
+	return nil
+}
+
 // Builder methods following the existing project patterns
 
 // buildPutItemBuilder creates a put item builder for saving card info
@@ -196,3 +281,48 @@ func (r *DynamoCardInfoRepository) buildScanExpiredBuilder(currentTime int64) *b
 		WithTable(r.tableName).
 		WithExpression(&expr)
 }
+
+// buildQueryNextKeyBuilder creates a query builder for the records of the merchant encrypted with the next key
+func (r *DynamoCardInfoRepository) buildQueryNextKeyBuilder(merchantID, keyID string, from, to int64) *builder.QueryBuilder {
+	keyCondition := expression.Key(MerchantIDField).Equal(expression.Value(merchantID)).
+		And(expression.Key(CreatedAtField).Between(expression.Value(from), expression.Value(to)))
+	filter := expression.Name(NextCardField + "." + KeyIDField).Equal(expression.Value(keyID))
+
+	expr := expression.NewBuilder().WithKeyCondition(keyCondition).WithFilter(filter)
+
+	return builder.NewQueryBuilder().
+		WithTable(r.tableName).
+		WithIndexName(MerchantIDIndex).
+		WithExpression(&expr)
+}
+
+// buildQueryPreviousKeyBuilder creates a query builder for the records of the merchant not encrypted with the key
+// The records stored before the key registry carry no key
+func (r *DynamoCardInfoRepository) buildQueryPreviousKeyBuilder(merchantID, keyID string, from, to int64) *builder.QueryBuilder {
+	keyCondition := expression.Key(MerchantIDField).Equal(expression.Value(merchantID)).
+		And(expression.Key(CreatedAtField).Between(expression.Value(from), expression.Value(to)))
+	filter := expression.AttributeNotExists(expression.Name(CardField + "." + KeyIDField)).
+		Or(expression.Name(CardField + "." + KeyIDField).NotEqual(expression.Value(keyID)))
+
+	expr := expression.NewBuilder().WithKeyCondition(keyCondition).WithFilter(filter)
+
+	return builder.NewQueryBuilder().
+		WithTable(r.tableName).
+		WithIndexName(MerchantIDIndex).
+		WithExpression(&expr)
+}
+
+// buildPromoteNextKeyBuilder creates an update builder that moves the next card data to the card data
+// The condition skips the records promoted by a previous run
+func (r *DynamoCardInfoRepository) buildPromoteNextKeyBuilder(externalReferenceID, keyID string) *builder.UpdateItemBuilder {
+	update := expression.Set(expression.Name(CardField), expression.Name(NextCardField)).
+		Remove(expression.Name(NextCardField))
+	condition := expression.Name(NextCardField + "." + KeyIDField).Equal(expression.Value(keyID))
+
+	expr := expression.NewBuilder().WithUpdate(update).WithCondition(condition)
+
+	return builder.NewUpdateItemBuilder().
+		WithTable(r.tableName).
+		WithPartitionKey(ExternalReferenceIDField, externalReferenceID).
+		WithExpression(&expr)
+}
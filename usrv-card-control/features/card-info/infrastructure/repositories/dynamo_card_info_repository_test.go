@@ -3,6 +3,7 @@ package repositories
 import (
 	"context"
 	"errors"
+	"fmt"
 	"os"
 	"strings"
 	"testing"
@@ -13,6 +14,8 @@ import (
 	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo/builder"
 	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
 	coreTypes "bitbucket.org/kushki/usrv-go-core/utils/types"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -304,6 +307,7 @@ func TestDynamoCardInfoRepository_FindByExternalReferenceID_Failures(t *testing.
 		assert.Error(t, err)
 		assert.Nil(t, cardInfo)
 		assert.Contains(t, err.Error(), "card info not found for externalReferenceID: non-existent-ref")
+		assert.ErrorIs(t, err, dynamoerror.ErrItemNotFound)
 		mockDynamo.AssertExpectations(t)
 		mockLogger.AssertExpectations(t)
 	})
@@ -555,6 +559,7 @@ func TestDynamoCardInfoRepository_FindByMerchantIDAndExternalReferenceID(t *test
 		assert.Error(t, err)
 		assert.Nil(t, cardInfo)
 		assert.Contains(t, err.Error(), "card info not found for merchant: merchant-123")
+		assert.ErrorIs(t, err, dynamoerror.ErrItemNotFound)
 		mockDynamo.AssertExpectations(t)
 		mockLogger.AssertExpectations(t)
 	})
@@ -1000,3 +1005,125 @@ func TestDynamoCardInfoRepository_ContextHandling(t *testing.T) {
 		mockLogger.AssertExpectations(t)
 	})
 }
+
+// Test FindByNextKey
+func TestDynamoCardInfoRepository_FindByNextKey(t *testing.T) {
+	t.Run("Queries the merchant index in the promotion window", func(t *testing.T) {
+		repo, mockDynamo, mockLogger := setupRepository(t)
+		mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
+
+		expr := expression.NewBuilder().
+			WithKeyCondition(expression.Key(MerchantIDField).Equal(expression.Value("merchant-123")).
+				And(expression.Key(CreatedAtField).Between(expression.Value(int64(1000)), expression.Value(int64(2000))))).
+			WithFilter(expression.Name("nextCard.keyId").Equal(expression.Value("key-2")))
+		mockDynamo.On("Query", mock.Anything, builder.NewQueryBuilder().
+			WithTable("test-card-info-table").
+			WithIndexName("merchantId-index").
+			WithExpression(&expr), mock.AnythingOfType("*[]*entities.StoredCardInfo")).
+			Run(func(args mock.Arguments) {
+				*args.Get(2).(*[]*entities.StoredCardInfo) = []*entities.StoredCardInfo{{ExternalReferenceID: "ext-ref-1"}}
+			}).Return(nil)
+
+		records, err := repo.FindByNextKey(context.Background(), "merchant-123", "key-2", 1000, 2000)
+
+		assert.NoError(t, err)
+		assert.Len(t, records, 1)
+		mockDynamo.AssertExpectations(t)
+	})
+
+	t.Run("Returns the DynamoDB error", func(t *testing.T) {
+		repo, mockDynamo, mockLogger := setupRepository(t)
+		mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
+		mockLogger.On("Error", "DynamoCardInfoRepository.FindByNextKey | Error", mock.Anything).Return()
+		mockDynamo.On("Query", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("dynamo error"))
+
+		records, err := repo.FindByNextKey(context.Background(), "merchant-123", "key-2", 1000, 2000)
+
+		assert.Nil(t, records)
+		assert.Error(t, err)
+		mockLogger.AssertExpectations(t)
+	})
+}
+
+// Test FindByPreviousKey
+func TestDynamoCardInfoRepository_FindByPreviousKey(t *testing.T) {
+	t.Run("Queries the merchant index for the records under another key", func(t *testing.T) {
+		repo, mockDynamo, mockLogger := setupRepository(t)
+		mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
+
+		expr := expression.NewBuilder().
+			WithKeyCondition(expression.Key(MerchantIDField).Equal(expression.Value("merchant-123")).
+				And(expression.Key(CreatedAtField).Between(expression.Value(int64(1000)), expression.Value(int64(2000))))).
+			WithFilter(expression.AttributeNotExists(expression.Name("card.keyId")).
+				Or(expression.Name("card.keyId").NotEqual(expression.Value("key-2"))))
+		mockDynamo.On("Query", mock.Anything, builder.NewQueryBuilder().
+			WithTable("test-card-info-table").
+			WithIndexName("merchantId-index").
+			WithExpression(&expr), mock.AnythingOfType("*[]*entities.StoredCardInfo")).
+			Run(func(args mock.Arguments) {
+				*args.Get(2).(*[]*entities.StoredCardInfo) = []*entities.StoredCardInfo{{ExternalReferenceID: "ext-ref-1"}}
+			}).Return(nil)
+
+		records, err := repo.FindByPreviousKey(context.Background(), "merchant-123", "key-2", 1000, 2000)
+
+		assert.NoError(t, err)
+		assert.Len(t, records, 1)
+		mockDynamo.AssertExpectations(t)
+	})
+
+	t.Run("Returns the DynamoDB error", func(t *testing.T) {
+		repo, mockDynamo, mockLogger := setupRepository(t)
+		mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
+		mockLogger.On("Error", "DynamoCardInfoRepository.FindByPreviousKey | Error", mock.Anything).Return()
+		mockDynamo.On("Query", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("dynamo error"))
+
+		records, err := repo.FindByPreviousKey(context.Background(), "merchant-123", "key-2", 1000, 2000)
+
+		assert.Nil(t, records)
+		assert.Error(t, err)
+		mockLogger.AssertExpectations(t)
+	})
+}
+
+// Test PromoteNextKey
+func TestDynamoCardInfoRepository_PromoteNextKey(t *testing.T) {
+	tests := []struct {
+		name      string
+		updateErr error
+		expectErr bool
+	}{
+		{
+			name: "Moves the next card data to the card data",
+		},
+		{
+			name:      "Skips a record promoted by another run",
+			updateErr: fmt.Errorf("update item: %w", &dynamoTypes.ConditionalCheckFailedException{}),
+		},
+		{
+			name:      "Returns the DynamoDB error",
+			updateErr: errors.New("dynamo error"),
+			expectErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, mockDynamo, mockLogger := setupRepository(t)
+			mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
+			mockLogger.On("Error", mock.AnythingOfType("string"), mock.Anything).Return()
+
+			expr := expression.NewBuilder().
+				WithUpdate(expression.Set(expression.Name("card"), expression.Name("nextCard")).Remove(expression.Name("nextCard"))).
+				WithCondition(expression.Name("nextCard.keyId").Equal(expression.Value("key-2")))
+			mockDynamo.On("UpdateItem", mock.Anything, builder.NewUpdateItemBuilder().
+				WithTable("test-card-info-table").
+				WithPartitionKey(ExternalReferenceIDField, "ext-ref-1").
+				WithExpression(&expr)).Return(tt.updateErr)
+
+			err := repo.PromoteNextKey(context.Background(), "ext-ref-1", "key-2")
+
+			assert.Equal(t, tt.expectErr, err != nil)
+			mockDynamo.AssertExpectations(t)
+		})
+	}
+}
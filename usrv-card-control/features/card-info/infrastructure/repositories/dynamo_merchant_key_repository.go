@@ -0,0 +1,105 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/repositories"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/value_objects"
+	"bitbucket.org/kushki/usrv-card-control/features/shared/constants"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo/builder"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+)
+
+const KeyIDField = "keyId"
+
+// DynamoMerchantKeyRepository implements the MerchantKeyRepository using DynamoDB
+type DynamoMerchantKeyRepository struct {
+	dynamoGateway dynamo.IDynamoGateway
+	logger        logger.KushkiLogger
+	tableName     string
+}
+
+// NewDynamoMerchantKeyRepository creates a new DynamoDB merchant key repository instance
+func NewDynamoMerchantKeyRepository(
+	dynamoGateway dynamo.IDynamoGateway,
+	logger logger.KushkiLogger,
+) repositories.MerchantKeyRepository {
+	return &DynamoMerchantKeyRepository{
+		dynamoGateway: dynamoGateway,
+		logger:        logger,
+		tableName:     os.Getenv(constants.EnvCardInfoMerchantKeyTable),
+	}
+}
+
+// FindByMerchantID retrieves every key registered by the merchant
+func (r *DynamoMerchantKeyRepository) FindByMerchantID(ctx context.Context, merchantID string) ([]value_objects.MerchantKey, error) {
+	const operation = "DynamoMerchantKeyRepository.FindByMerchantID"
+
+	keyCondition := expression.Key(MerchantIDField).Equal(expression.Value(merchantID))
+	expr := expression.NewBuilder().WithKeyCondition(keyCondition)
+	queryBuilder := builder.NewQueryBuilder().
+		WithTable(r.tableName).
+		WithExpression(&expr)
+
+	keys := make([]value_objects.MerchantKey, 0)
+	if err := r.dynamoGateway.Query(ctx, queryBuilder, &keys); err != nil {
+		r.logger.Error(fmt.Sprintf("%s | Error", operation), err)
+		return nil, fmt.Errorf("failed to query merchant keys: %w", err)
+	}
+
+	r.logger.Info(fmt.Sprintf("%s | Success", operation),
+		fmt.Sprintf("MerchantID: %s, Keys: %d", merchantID, len(keys)))
+
+	return keys, nil
+}
+
+// FindAll retrieves the keys of every merchant, the registry holds a few keys per merchant
+func (r *DynamoMerchantKeyRepository) FindAll(ctx context.Context) ([]value_objects.MerchantKey, error) {
+	const operation = "DynamoMerchantKeyRepository.FindAll"
+
+	scanBuilder := builder.NewScanBuilder().
+		WithTable(r.tableName)
+
+	keys := make([]value_objects.MerchantKey, 0)
+	if err := r.dynamoGateway.ScanAllItems(ctx, scanBuilder, &keys); err != nil {
+		r.logger.Error(fmt.Sprintf("%s | Error", operation), err)
+		return nil, fmt.Errorf("failed to scan merchant keys: %w", err)
+	}
+
+	r.logger.Info(fmt.Sprintf("%s | Success", operation),
+		fmt.Sprintf("Keys: %d", len(keys)))
+
+	return keys, nil
+}
+
+// Create stores a new key, the key ID of a merchant cannot be registered twice
+func (r *DynamoMerchantKeyRepository) Create(ctx context.Context, key value_objects.MerchantKey) error {
+	const operation = "DynamoMerchantKeyRepository.Create"
+
+	expr := expression.NewBuilder().
+		WithCondition(expression.Name(KeyIDField).AttributeNotExists())
+	putBuilder := builder.NewPutItemBuilder().
+		WithItem(key).
+		WithTable(r.tableName).
+		WithExpression(&expr)
+
+	if err := r.dynamoGateway.PutItem(ctx, putBuilder); err != nil {
+		var conditionalErr *dynamoTypes.ConditionalCheckFailedException
+		if errors.As(err, &conditionalErr) {
+			return fmt.Errorf("key %s of merchant %s: %w", key.KeyID, key.MerchantID, repositories.ErrMerchantKeyExists)
+		}
+		r.logger.Error(fmt.Sprintf("%s | Error", operation), err)
+		return fmt.Errorf("failed to save merchant key to DynamoDB: %w", err)
+	}
+
+	r.logger.Info(fmt.Sprintf("%s | Success", operation),
+		fmt.Sprintf("MerchantID: %s, KeyID: %s", key.MerchantID, key.KeyID))
+
+	return nil
+}
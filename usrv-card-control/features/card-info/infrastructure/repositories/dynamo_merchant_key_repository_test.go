@@ -0,0 +1,128 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/repositories"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/value_objects"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo/builder"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupMerchantKeyRepository(t *testing.T) (*DynamoMerchantKeyRepository, *MockDynamoGateway) {
+	t.Helper()
+	t.Setenv("DYNAMO_CARD_INFO_MERCHANT_KEY_TABLE", "test-merchant-key-table")
+	mockDynamo := &MockDynamoGateway{}
+	mockLogger := &MockDynamoLogger{}
+	mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
+	mockLogger.On("Error", mock.AnythingOfType("string"), mock.Anything).Return()
+
+	return NewDynamoMerchantKeyRepository(mockDynamo, mockLogger).(*DynamoMerchantKeyRepository), mockDynamo
+}
+
+func TestDynamoMerchantKeyRepository_FindByMerchantID(t *testing.T) {
+	t.Run("should return the keys of the merchant", func(t *testing.T) {
+		repo, mockDynamo := setupMerchantKeyRepository(t)
+		expr := expression.NewBuilder().
+			WithKeyCondition(expression.Key(MerchantIDField).Equal(expression.Value("merchant-123")))
+		mockDynamo.On("Query", mock.Anything, builder.NewQueryBuilder().
+			WithTable("test-merchant-key-table").
+			WithExpression(&expr), mock.AnythingOfType("*[]value_objects.MerchantKey")).
+			Run(func(args mock.Arguments) {
+				*args.Get(2).(*[]value_objects.MerchantKey) = []value_objects.MerchantKey{{KeyID: "key-1"}, {KeyID: "key-2"}}
+			}).Return(nil)
+
+		keys, err := repo.FindByMerchantID(context.Background(), "merchant-123")
+
+		assert.NoError(t, err)
+		assert.Len(t, keys, 2)
+	})
+
+	t.Run("should return the DynamoDB error", func(t *testing.T) {
+		repo, mockDynamo := setupMerchantKeyRepository(t)
+		mockDynamo.On("Query", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("dynamo error"))
+
+		keys, err := repo.FindByMerchantID(context.Background(), "merchant-123")
+
+		assert.Nil(t, keys)
+		assert.Error(t, err)
+	})
+}
+
+func TestDynamoMerchantKeyRepository_FindAll(t *testing.T) {
+	t.Run("should scan the keys of every merchant", func(t *testing.T) {
+		repo, mockDynamo := setupMerchantKeyRepository(t)
+		mockDynamo.On("ScanAllItems", mock.Anything, builder.NewScanBuilder().WithTable("test-merchant-key-table"),
+			mock.AnythingOfType("*[]value_objects.MerchantKey")).
+			Run(func(args mock.Arguments) {
+				*args.Get(2).(*[]value_objects.MerchantKey) = []value_objects.MerchantKey{{MerchantID: "merchant-1"}, {MerchantID: "merchant-2"}}
+			}).Return(nil)
+
+		keys, err := repo.FindAll(context.Background())
+
+		assert.NoError(t, err)
+		assert.Len(t, keys, 2)
+	})
+
+	t.Run("should return the DynamoDB error", func(t *testing.T) {
+		repo, mockDynamo := setupMerchantKeyRepository(t)
+		mockDynamo.On("ScanAllItems", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("dynamo error"))
+
+		keys, err := repo.FindAll(context.Background())
+
+		assert.Nil(t, keys)
+		assert.Error(t, err)
+	})
+}
+
+func TestDynamoMerchantKeyRepository_Create(t *testing.T) {
+	key := value_objects.MerchantKey{MerchantID: "merchant-123", KeyID: "key-1", Status: value_objects.MerchantKeyActive}
+
+	tests := []struct {
+		name        string
+		putErr      error
+		expectedErr error
+	}{
+		{
+			name: "should store the key",
+		},
+		{
+			name:        "should map the failed condition to an existing key",
+			putErr:      fmt.Errorf("put item: %w", &dynamoTypes.ConditionalCheckFailedException{}),
+			expectedErr: repositories.ErrMerchantKeyExists,
+		},
+		{
+			name:   "should return the DynamoDB error",
+			putErr: errors.New("dynamo error"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			repo, mockDynamo := setupMerchantKeyRepository(t)
+			expr := expression.NewBuilder().WithCondition(expression.Name(KeyIDField).AttributeNotExists())
+			mockDynamo.On("PutItem", mock.Anything, builder.NewPutItemBuilder().
+				WithItem(key).
+				WithTable("test-merchant-key-table").
+				WithExpression(&expr)).Return(tt.putErr)
+
+			err := repo.Create(context.Background(), key)
+
+			switch {
+			case tt.putErr == nil:
+				assert.NoError(t, err)
+			case tt.expectedErr != nil:
+				assert.ErrorIs(t, err, tt.expectedErr)
+			default:
+				assert.ErrorIs(t, err, tt.putErr)
+				assert.NotErrorIs(t, err, repositories.ErrMerchantKeyExists)
+			}
+		})
+	}
+}
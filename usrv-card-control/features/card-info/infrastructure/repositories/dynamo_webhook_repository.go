@@ -0,0 +1,96 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/entities"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/repositories"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/value_objects"
+	"bitbucket.org/kushki/usrv-card-control/features/shared/constants"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo/builder"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+)
+
+// DynamoWebhookRepository implements the WebhookRepository using DynamoDB
+type DynamoWebhookRepository struct {
+	dynamoGateway dynamo.IDynamoGateway
+	logger        logger.KushkiLogger
+	configTable   string
+	deliveryTable string
+}
+
+// NewDynamoWebhookRepository creates a new DynamoDB webhook repository instance
+func NewDynamoWebhookRepository(
+	dynamoGateway dynamo.IDynamoGateway,
+	logger logger.KushkiLogger,
+) repositories.WebhookRepository {
+	return &DynamoWebhookRepository{
+		dynamoGateway: dynamoGateway,
+		logger:        logger,
+		configTable:   os.Getenv(constants.EnvCardInfoWebhookConfigTable),
+		deliveryTable: os.Getenv(constants.EnvCardInfoWebhookDeliveryTable),
+	}
+}
+
+// FindConfig retrieves the webhook registered by the merchant
+func (r *DynamoWebhookRepository) FindConfig(ctx context.Context, merchantID string) (*value_objects.WebhookConfig, error) {
+	const operation = "DynamoWebhookRepository.FindConfig"
+
+	getBuilder := builder.NewGetItemBuilder().
+		WithTable(r.configTable).
+		WithPartitionKey(MerchantIDField, merchantID)
+
+	var webhook value_objects.WebhookConfig
+	if err := r.dynamoGateway.GetItem(ctx, getBuilder, &webhook); err != nil {
+		if !errors.Is(err, dynamoerror.ErrItemNotFound) {
+			r.logger.Error(fmt.Sprintf("%s | Error", operation), err)
+		}
+		return nil, fmt.Errorf("failed to get webhook config for merchant: %s: %w", merchantID, err)
+	}
+
+	return &webhook, nil
+}
+
+// FindDelivery retrieves the delivery status of the external reference ID
+func (r *DynamoWebhookRepository) FindDelivery(ctx context.Context, externalReferenceID string) (*entities.WebhookDelivery, error) {
+	const operation = "DynamoWebhookRepository.FindDelivery"
+
+	getBuilder := builder.NewGetItemBuilder().
+		WithTable(r.deliveryTable).
+		WithPartitionKey(ExternalReferenceIDField, externalReferenceID).
+		WithConsistentRead(true)
+
+	var delivery entities.WebhookDelivery
+	if err := r.dynamoGateway.GetItem(ctx, getBuilder, &delivery); err != nil {
+		if !errors.Is(err, dynamoerror.ErrItemNotFound) {
+			r.logger.Error(fmt.Sprintf("%s | Error", operation), err)
+		}
+		return nil, fmt.Errorf("failed to get webhook delivery for externalReferenceID: %s: %w", externalReferenceID, err)
+	}
+
+	return &delivery, nil
+}
+
+// SaveDelivery stores the delivery status
+func (r *DynamoWebhookRepository) SaveDelivery(ctx context.Context, delivery *entities.WebhookDelivery) error {
+	const operation = "DynamoWebhookRepository.SaveDelivery"
+
+	putBuilder := builder.NewPutItemBuilder().
+		WithItem(delivery).
+		WithTable(r.deliveryTable)
+
+	if err := r.dynamoGateway.PutItem(ctx, putBuilder); err != nil {
+		r.logger.Error(fmt.Sprintf("%s | Error", operation), err)
+		return fmt.Errorf("failed to save webhook delivery to DynamoDB: %w", err)
+	}
+
+	r.logger.Info(fmt.Sprintf("%s | Success", operation),
+		fmt.Sprintf("ExternalReferenceID: %s, Status: %s", delivery.ExternalReferenceID, delivery.Status))
+
+	return nil
+}
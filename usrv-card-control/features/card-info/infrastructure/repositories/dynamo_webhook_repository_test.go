@@ -0,0 +1,107 @@
+package repositories
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/entities"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/value_objects"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo/builder"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func setupWebhookRepository(t *testing.T) (*DynamoWebhookRepository, *MockDynamoGateway, *MockDynamoLogger) {
+	t.Helper()
+	t.Setenv("DYNAMO_CARD_INFO_WEBHOOK_CONFIG_TABLE", "test-webhook-config-table")
+	t.Setenv("DYNAMO_CARD_INFO_WEBHOOK_DELIVERY_TABLE", "test-webhook-delivery-table")
+	mockDynamo := &MockDynamoGateway{}
+	mockLogger := &MockDynamoLogger{}
+	mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
+	mockLogger.On("Error", mock.AnythingOfType("string"), mock.Anything).Return()
+
+	return NewDynamoWebhookRepository(mockDynamo, mockLogger).(*DynamoWebhookRepository), mockDynamo, mockLogger
+}
+
+func TestDynamoWebhookRepository_FindConfig(t *testing.T) {
+	t.Run("should find the webhook of the merchant", func(t *testing.T) {
+		repo, mockDynamo, _ := setupWebhookRepository(t)
+		mockDynamo.On("GetItem", mock.Anything, mock.MatchedBy(func(getBuilder *builder.GetItemBuilder) bool {
+			return assert.ObjectsAreEqual(builder.NewGetItemBuilder().
+				WithTable("test-webhook-config-table").
+				WithPartitionKey(MerchantIDField, "merchant-123"), getBuilder)
+		}), mock.AnythingOfType("*value_objects.WebhookConfig")).
+			Run(func(args mock.Arguments) {
+				*args.Get(2).(*value_objects.WebhookConfig) = value_objects.WebhookConfig{MerchantID: "merchant-123", Enabled: true}
+			}).Return(nil)
+
+		webhook, err := repo.FindConfig(context.Background(), "merchant-123")
+
+		assert.NoError(t, err)
+		assert.True(t, webhook.Enabled)
+	})
+
+	t.Run("should wrap the not found error", func(t *testing.T) {
+		repo, mockDynamo, mockLogger := setupWebhookRepository(t)
+		mockDynamo.On("GetItem", mock.Anything, mock.Anything, mock.Anything).Return(dynamoerror.ErrItemNotFound)
+
+		webhook, err := repo.FindConfig(context.Background(), "merchant-123")
+
+		assert.Nil(t, webhook)
+		assert.ErrorIs(t, err, dynamoerror.ErrItemNotFound)
+		mockLogger.AssertNotCalled(t, "Error", mock.Anything, mock.Anything)
+	})
+}
+
+func TestDynamoWebhookRepository_FindDelivery(t *testing.T) {
+	t.Run("should find the delivery status", func(t *testing.T) {
+		repo, mockDynamo, _ := setupWebhookRepository(t)
+		mockDynamo.On("GetItem", mock.Anything, mock.MatchedBy(func(getBuilder *builder.GetItemBuilder) bool {
+			return assert.ObjectsAreEqual(builder.NewGetItemBuilder().
+				WithTable("test-webhook-delivery-table").
+				WithPartitionKey(ExternalReferenceIDField, "ext-ref-123").
+				WithConsistentRead(true), getBuilder)
+		}), mock.AnythingOfType("*entities.WebhookDelivery")).
+			Run(func(args mock.Arguments) {
+				*args.Get(2).(*entities.WebhookDelivery) = entities.WebhookDelivery{Status: entities.WebhookDeliveryRetrying}
+			}).Return(nil)
+
+		delivery, err := repo.FindDelivery(context.Background(), "ext-ref-123")
+
+		assert.NoError(t, err)
+		assert.Equal(t, entities.WebhookDeliveryRetrying, delivery.Status)
+	})
+
+	t.Run("should return the DynamoDB error", func(t *testing.T) {
+		repo, mockDynamo, mockLogger := setupWebhookRepository(t)
+		mockDynamo.On("GetItem", mock.Anything, mock.Anything, mock.Anything).Return(errors.New("dynamo error"))
+
+		_, err := repo.FindDelivery(context.Background(), "ext-ref-123")
+
+		assert.Error(t, err)
+		mockLogger.AssertCalled(t, "Error", "DynamoWebhookRepository.FindDelivery | Error", mock.Anything)
+	})
+}
+
+func TestDynamoWebhookRepository_SaveDelivery(t *testing.T) {
+	delivery := &entities.WebhookDelivery{ExternalReferenceID: "ext-ref-123", Status: entities.WebhookDeliveryPending}
+
+	t.Run("should save the delivery status", func(t *testing.T) {
+		repo, mockDynamo, _ := setupWebhookRepository(t)
+		mockDynamo.On("PutItem", mock.Anything, builder.NewPutItemBuilder().
+			WithItem(delivery).
+			WithTable("test-webhook-delivery-table")).Return(nil)
+
+		assert.NoError(t, repo.SaveDelivery(context.Background(), delivery))
+		mockDynamo.AssertExpectations(t)
+	})
+
+	t.Run("should return the DynamoDB error", func(t *testing.T) {
+		repo, mockDynamo, _ := setupWebhookRepository(t)
+		mockDynamo.On("PutItem", mock.Anything, mock.Anything).Return(errors.New("dynamo error"))
+
+		assert.Error(t, repo.SaveDelivery(context.Background(), delivery))
+	})
+}
@@ -9,6 +9,9 @@ import (
 	"bitbucket.org/kushki/usrv-go-core/logger"
 )
 
+// merchantCredentialPrefix private credentials issued to a merchant start with PRIV_<merchantID>_
+const merchantCredentialPrefix = "PRIV_"
+
 // CredentialService implements the CredentialService interface
 type CredentialService struct {
 	logger logger.KushkiLogger
@@ -49,15 +52,15 @@ func (s *CredentialService) ValidatePrivateCredential(privateCredentialID, merch
 	}
 
 	// Check merchant-specific credential pattern
-	expectedPattern := fmt.Sprintf("PRIV_%s_", merchantID)
+	expectedPattern := fmt.Sprintf("%s%s_", merchantCredentialPrefix, merchantID)
 	if strings.HasPrefix(privateCredentialID, expectedPattern) {
 		s.logger.Info(fmt.Sprintf("%s | PatternMatched", operation),
 			fmt.Sprintf("MerchantID: %s", merchantID))
 		return true
 	}
 
-	// In test/dev environment, use lenient validation
-	if isTestEnvironment() {
+	// In test/dev environment, use lenient validation, never for the credential of another merchant
+	if isTestEnvironment() && !s.BelongsToAnotherMerchant(privateCredentialID, merchantID) {
 		// Accept any credential that starts with "TEST_" or is longer than 10 characters
 		if strings.HasPrefix(privateCredentialID, "TEST_") || len(privateCredentialID) > 10 {
 			s.logger.Info(fmt.Sprintf("%s | TestEnvironmentValid", operation),
@@ -77,3 +80,17 @@ func (s *CredentialService) ValidatePrivateCredential(privateCredentialID, merch
 		fmt.Sprintf("MerchantID: %s", merchantID))
 	return false
 }
+
+// BelongsToAnotherMerchant checks if the credential follows the pattern of a merchant other than the given one
+func (s *CredentialService) BelongsToAnotherMerchant(privateCredentialID, merchantID string) bool {
+	owner, _, found := strings.Cut(strings.TrimPrefix(privateCredentialID, merchantCredentialPrefix), "_")
+
+	return strings.HasPrefix(privateCredentialID, merchantCredentialPrefix) && found && owner != "" &&
+		!strings.HasPrefix(privateCredentialID, fmt.Sprintf("%s%s_", merchantCredentialPrefix, merchantID))
+}
+
+// isTestEnvironment the stages where the credentials are validated leniently
+func isTestEnvironment() bool {
+	stage := os.Getenv("USRV_STAGE")
+	return stage == "dev" || stage == "test" || stage == "local"
+}
@@ -197,6 +197,16 @@ func TestCredentialService_ValidatePrivateCredential_Failures(t *testing.T) {
 			},
 			expectedResult: false,
 		},
+		{
+			name:                "Credential of another merchant in test environment",
+			privateCredentialID: "PRIV_OTHERMERCHANT_CREDENTIAL",
+			merchantID:          "merchant-456",
+			setupEnv: func(t *testing.T) {
+				setEnvVar(t, "USRV_STAGE", "test")
+				setEnvVar(t, "CARD_INFO_VALID_CREDENTIALS", "")
+			},
+			expectedResult: false,
+		},
 		{
 			name:                "No TEST_ prefix and short in test environment",
 			privateCredentialID: "shortcred",
@@ -371,6 +381,49 @@ func TestCredentialService_MerchantPatternMatching(t *testing.T) {
 	}
 }
 
+// Test BelongsToAnotherMerchant
+func TestCredentialService_BelongsToAnotherMerchant(t *testing.T) {
+	testCases := []struct {
+		name                string
+		privateCredentialID string
+		merchantID          string
+		expected            bool
+	}{
+		{
+			name:                "Credential of another merchant",
+			privateCredentialID: "PRIV_MERCHANT456_CREDENTIAL",
+			merchantID:          "MERCHANT123",
+			expected:            true,
+		},
+		{
+			name:                "Credential of the same merchant",
+			privateCredentialID: "PRIV_MERCHANT123_CREDENTIAL",
+			merchantID:          "MERCHANT123",
+			expected:            false,
+		},
+		{
+			name:                "Credential without the merchant pattern",
+			privateCredentialID: "invalid-credential",
+			merchantID:          "MERCHANT123",
+			expected:            false,
+		},
+		{
+			name:                "Credential without a merchant",
+			privateCredentialID: "PRIV__CREDENTIAL",
+			merchantID:          "MERCHANT123",
+			expected:            false,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			service, _ := setupCredentialService(t)
+
+			assert.Equal(t, tc.expected, service.BelongsToAnotherMerchant(tc.privateCredentialID, tc.merchantID))
+		})
+	}
+}
+
 // Test Logging Behavior
 func TestCredentialService_LoggingBehavior(t *testing.T) {
 	t.Run("Logs start of validation", func(t *testing.T) {
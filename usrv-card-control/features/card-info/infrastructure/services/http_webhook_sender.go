@@ -0,0 +1,74 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"time"
+
+	domainServices "bitbucket.org/kushki/usrv-card-control/features/card-info/domain/services"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/value_objects"
+	"bitbucket.org/kushki/usrv-card-control/features/shared/constants"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+)
+
+// HTTPWebhookSender implements the WebhookSender interface over HTTPS
+type HTTPWebhookSender struct {
+	client *http.Client
+	logger logger.KushkiLogger
+	now    func() time.Time
+}
+
+// NewHTTPWebhookSender creates a new webhook sender
+func NewHTTPWebhookSender(logger logger.KushkiLogger) domainServices.WebhookSender {
+	return &HTTPWebhookSender{
+		client: &http.Client{Timeout: constants.WebhookTimeout},
+		logger: logger,
+		now:    time.Now,
+	}
+}
+
+// Send posts the payload signed with the secret of the webhook
+func (s *HTTPWebhookSender) Send(ctx context.Context, webhook value_objects.WebhookConfig, payload []byte) (int, error) {
+	const operation = "HTTPWebhookSender.Send"
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, webhook.URL, bytes.NewReader(payload))
+	if err != nil {
+		return 0, fmt.Errorf("invalid webhook request: %w", err)
+	}
+
+	timestamp := strconv.FormatInt(s.now().Unix(), 10)
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set(constants.WebhookTimestampHeader, timestamp)
+	request.Header.Set(constants.WebhookSignatureHeader, signWebhookPayload(webhook.Secret, timestamp, payload))
+
+	response, err := s.client.Do(request)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("%s | Error", operation), err)
+		return 0, fmt.Errorf("webhook request failed: %w", err)
+	}
+	defer response.Body.Close()
+
+	// The body is not used, it is drained so the connection can be reused
+	_, _ = io.Copy(io.Discard, response.Body)
+
+	s.logger.Info(fmt.Sprintf("%s | Answered", operation),
+		fmt.Sprintf("MerchantID: %s, StatusCode: %d", webhook.MerchantID, response.StatusCode))
+
+	return response.StatusCode, nil
+}
+
+// signWebhookPayload the timestamp is signed with the body so a captured request cannot be replayed later
+func signWebhookPayload(secret, timestamp string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "."))
+	mac.Write(payload)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,69 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/value_objects"
+	"bitbucket.org/kushki/usrv-card-control/mocks"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestHTTPWebhookSender_Send(t *testing.T) {
+	payload := []byte(`{"externalReferenceId":"ext-ref-123"}`)
+	now := time.Unix(1749661979, 0)
+
+	t.Run("should post the payload signed with the webhook secret", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			body, _ := io.ReadAll(r.Body)
+			assert.Equal(t, http.MethodPost, r.Method)
+			assert.Equal(t, payload, body)
+			assert.Equal(t, "application/json", r.Header.Get("Content-Type"))
+			assert.Equal(t, "1749661979", r.Header.Get("X-Kushki-Timestamp"))
+			assert.Equal(t, signWebhookPayload("secret", "1749661979", payload), r.Header.Get("X-Kushki-Signature"))
+			w.WriteHeader(http.StatusAccepted)
+		}))
+		defer server.Close()
+		sender := NewHTTPWebhookSender(mocks.GetMockLogger(t)).(*HTTPWebhookSender)
+		sender.now = func() time.Time { return now }
+
+		statusCode, err := sender.Send(context.Background(), value_objects.WebhookConfig{URL: server.URL, Secret: "secret"}, payload)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusAccepted, statusCode)
+	})
+
+	t.Run("should return the status code of a rejected delivery", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}))
+		defer server.Close()
+
+		statusCode, err := NewHTTPWebhookSender(mocks.GetMockLogger(t)).
+			Send(context.Background(), value_objects.WebhookConfig{URL: server.URL, Secret: "secret"}, payload)
+
+		assert.NoError(t, err)
+		assert.Equal(t, http.StatusServiceUnavailable, statusCode)
+	})
+
+	t.Run("should return an error when the request fails", func(t *testing.T) {
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+		server.Close()
+
+		statusCode, err := NewHTTPWebhookSender(mocks.GetMockLogger(t)).
+			Send(context.Background(), value_objects.WebhookConfig{URL: server.URL, Secret: "secret"}, payload)
+
+		assert.Error(t, err)
+		assert.Zero(t, statusCode)
+	})
+}
+
+func TestSignWebhookPayload(t *testing.T) {
+	// echo -n '1749661979.{}' | openssl dgst -sha256 -hmac secret
+	assert.Equal(t, "d84aebbf6faaff89077d1f91c3167364eb7563419eae419bc778b037f7d30e0c",
+		signWebhookPayload("secret", "1749661979", []byte("{}")))
+}
@@ -1,98 +1,149 @@
 package services
 
 import (
+	"context"
 	"fmt"
-	"os"
+	"sync"
+	"time"
 
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/repositories"
 	domainServices "bitbucket.org/kushki/usrv-card-control/features/card-info/domain/services"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/value_objects"
+	"bitbucket.org/kushki/usrv-card-control/features/shared/constants"
 	"bitbucket.org/kushki/usrv-go-core/logger"
 )
 
-// MerchantKeyService implements the MerchantKeyProvider interface
+// merchantKeyCache keeps the keys found in the registry for constants.MerchantKeyCacheTTL
+type merchantKeyCache struct {
+	mu      sync.RWMutex
+	entries map[string]cachedMerchantKeys
+}
+
+type cachedMerchantKeys struct {
+	keys     []value_objects.MerchantKey
+	loadedAt time.Time
+}
+
+// merchantKeys is shared by the service instances, the dependencies are created on every invocation
+var merchantKeys = newMerchantKeyCache()
+
+func newMerchantKeyCache() *merchantKeyCache {
+	return &merchantKeyCache{entries: make(map[string]cachedMerchantKeys)}
+}
+
+// MerchantKeyService implements the MerchantKeyService interface with the key registry
 type MerchantKeyService struct {
-	logger logger.KushkiLogger
+	keyRepo repositories.MerchantKeyRepository
+	cache   *merchantKeyCache
+	logger  logger.KushkiLogger
+	now     func() time.Time
 }
 
 // NewMerchantKeyService creates a new merchant key service
-func NewMerchantKeyService(logger logger.KushkiLogger) domainServices.MerchantKeyService {
+func NewMerchantKeyService(
+	keyRepo repositories.MerchantKeyRepository,
+	logger logger.KushkiLogger,
+) domainServices.MerchantKeyService {
 	return &MerchantKeyService{
-		logger: logger,
+		keyRepo: keyRepo,
+		cache:   merchantKeys,
+		logger:  logger,
+		now:     time.Now,
 	}
 }
 
-// GetMerchantPublicKey retrieves the public key for a merchant
+// GetMerchantPublicKey retrieves the PEM of the key the card data of the merchant is encrypted with
 func (s *MerchantKeyService) GetMerchantPublicKey(merchantID string) (string, error) {
-	const operation = "MerchantKeyService.GetMerchantPublicKey"
-
-	s.logger.Info(fmt.Sprintf("%s | Starting", operation),
-		fmt.Sprintf("MerchantID: %s", merchantID))
-
-	// TODO: Implement actual key retrieval logic
-	// This could involve:
-	// 1. Calling an external service
-	// 2. Querying a database
-	// 3. Reading from AWS Parameter Store/Secrets Manager
-	// 4. Reading from environment variables for testing
-
-	// For now, check if there's a test key in environment
-	envKey := fmt.Sprintf("MERCHANT_%s_PUBLIC_KEY", merchantID)
-	if key := os.Getenv(envKey); key != "" {
-		s.logger.Info(fmt.Sprintf("%s | FoundInEnv", operation),
-			fmt.Sprintf("MerchantID: %s", merchantID))
-		return key, nil
+	key, err := s.GetEncryptionKey(merchantID)
+	if err != nil {
+		return "", err
 	}
 
-	// Mock implementation for development/testing
-	if isTestEnvironment() {
-		s.logger.Info(fmt.Sprintf("%s | UsingMockKey", operation),
-			fmt.Sprintf("MerchantID: %s", merchantID))
-		return getMockPublicKey(), nil
+	return key.PublicKey, nil
+}
+
+// GetEncryptionKey retrieves the key the card data of the merchant is encrypted with
+func (s *MerchantKeyService) GetEncryptionKey(merchantID string) (value_objects.MerchantKey, error) {
+	const operation = "MerchantKeyService.GetEncryptionKey"
+
+	key, err := s.getEncryptionKey(merchantID)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("%s | KeyNotFound", operation), err)
+		return value_objects.MerchantKey{}, err
 	}
 
-	s.logger.Error(fmt.Sprintf("%s | KeyNotFound", operation),
-		fmt.Sprintf("MerchantID: %s", merchantID))
-	return "", fmt.Errorf("public key not found for merchant: %s", merchantID)
+	s.logger.Info(fmt.Sprintf("%s | Success", operation),
+		fmt.Sprintf("MerchantID: %s, KeyID: %s", merchantID, key.KeyID))
+
+	return key, nil
 }
 
-// HasMerchantKey checks if a merchant has a registered public key
+// GetNextKey retrieves the key that replaces the encryption key, nil when no rotation is in progress
+func (s *MerchantKeyService) GetNextKey(merchantID string) (*value_objects.MerchantKey, error) {
+	keys, err := s.getKeys(merchantID)
+	if err != nil {
+		return nil, err
+	}
+
+	key, found := value_objects.SelectNextKey(keys, s.now().UnixMilli())
+	if !found {
+		return nil, nil
+	}
+
+	return &key, nil
+}
+
+// HasMerchantKey checks if a merchant has a usable public key
 func (s *MerchantKeyService) HasMerchantKey(merchantID string) bool {
-	const operation = "MerchantKeyService.HasMerchantKey"
+	_, err := s.getEncryptionKey(merchantID)
+
+	return err == nil
+}
 
-	// Check environment variable
-	envKey := fmt.Sprintf("MERCHANT_%s_PUBLIC_KEY", merchantID)
-	if os.Getenv(envKey) != "" {
-		return true
+// getEncryptionKey selects the encryption key among the registered keys
+func (s *MerchantKeyService) getEncryptionKey(merchantID string) (value_objects.MerchantKey, error) {
+	keys, err := s.getKeys(merchantID)
+	if err != nil {
+		return value_objects.MerchantKey{}, err
 	}
 
-	// In test environment, assume all merchants have keys
-	if isTestEnvironment() {
-		s.logger.Info(fmt.Sprintf("%s | MockEnvironment", operation),
-			fmt.Sprintf("MerchantID: %s", merchantID))
-		return true
+	key, found := value_objects.SelectEncryptionKey(keys, s.now().UnixMilli())
+	if !found {
+		return value_objects.MerchantKey{}, fmt.Errorf("public key not found for merchant: %s", merchantID)
 	}
 
-	// TODO: Implement actual key existence check
-	s.logger.Info(fmt.Sprintf("%s | KeyNotFound", operation),
-		fmt.Sprintf("MerchantID: %s", merchantID))
-	return false
+	return key, nil
 }
 
-// Helper functions
+// getKeys serves the cached keys while they are fresh and one of them is usable, otherwise reads the registry
+// The keys are selected on every call, so an activation or expiry applies without waiting for the TTL
+func (s *MerchantKeyService) getKeys(merchantID string) ([]value_objects.MerchantKey, error) {
+	currentTime := s.now()
+
+	s.cache.mu.RLock()
+	cached, found := s.cache.entries[merchantID]
+	s.cache.mu.RUnlock()
+	if found && currentTime.Sub(cached.loadedAt) < constants.MerchantKeyCacheTTL {
+		if _, usable := value_objects.SelectEncryptionKey(cached.keys, currentTime.UnixMilli()); usable {
+			return cached.keys, nil
+		}
+	}
 
-func isTestEnvironment() bool {
-	stage := os.Getenv("USRV_STAGE")
-	return stage == "dev" || stage == "test" || stage == "local"
-}
+	// The interface has no context, the lookup is bounded so the encryption does not hang
+	ctx, cancel := context.WithTimeout(context.Background(), constants.MerchantKeyLookupTimeout)
+	defer cancel()
+
+	keys, err := s.keyRepo.FindByMerchantID(ctx, merchantID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get public keys of merchant %s: %w", merchantID, err)
+	}
+
+	// A merchant without usable keys is not cached, the key it registers next is found right away
+	if _, usable := value_objects.SelectEncryptionKey(keys, currentTime.UnixMilli()); usable {
+		s.cache.mu.Lock()
+		s.cache.entries[merchantID] = cachedMerchantKeys{keys: keys, loadedAt: currentTime}
+		s.cache.mu.Unlock()
+	}
 
-func getMockPublicKey() string {
-	// This is a mock RSA public key for testing purposes
-	return `-----BEGIN PUBLIC KEY-----
-MIIBIjANBgkqhkiG9w0BAQEFAAOCAQ8AMIIBCgKCAQEA2rKz7QrJ3ztC9JPQXR1l
-YNaJy8LU8Q5O1F2J6V3P8V9J1K2L3M4N5O6P7Q8R9S0T1U2V3W4X5Y6Z7A8B9C0D
-1E2F3G4H5I6J7K8L9M0N1O2P3Q4R5S6T7U8V9W0X1Y2Z3A4B5C6D7E8F9G0H1I2J
-3K4L5M6N7O8P9Q0R1S2T3U4V5W6X7Y8Z9A0B1C2D3E4F5G6H7I8J9K0L1M2N3O4P
-5Q6R7S8T9U0V1W2X3Y4Z5A6B7C8D9E0F1G2H3I4J5K6L7M8N9O0P1Q2R3S4T5U6V
-7W8X9Y0Z1A2B3C4D5E6F7G8H9I0J1K2L3M4N5O6P7Q8R9S0T1U2V3W4X5Y6Z7A8B
-9QIDAQAB
------END PUBLIC KEY-----`
+	return keys, nil
 }
@@ -3,9 +3,8 @@ package services
 import (
 	"crypto/rand"
 	"crypto/rsa"
-	"crypto/x509"
+	"crypto/sha256"
 	"encoding/base64"
-	"encoding/pem"
 	"fmt"
 
 	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/services"
@@ -40,40 +39,86 @@ func (s *RSAEncryptionService) EncryptCardData(
 	s.logger.Info(fmt.Sprintf("%s | Starting", operation),
 		fmt.Sprintf("MerchantID: %s", merchantID))
 
-	// Get merchant's public key using the injected key provider
-	publicKeyPEM, err := s.keyProvider.GetMerchantPublicKey(merchantID)
+	// Get the encryption key of the merchant using the injected key provider
+	key, err := s.keyProvider.GetEncryptionKey(merchantID)
 	if err != nil {
 		s.logger.Error(fmt.Sprintf("%s | KeyRetrievalError", operation), err)
 		return value_objects.EncryptedCardData{}, fmt.Errorf("failed to get public key for merchant %s: %w", merchantID, err)
 	}
 
+	encryptedData, err := s.encryptWithKey(operation, cardData, key)
+	if err != nil {
+		return value_objects.EncryptedCardData{}, err
+	}
+
+	s.logger.Info(fmt.Sprintf("%s | Success", operation),
+		fmt.Sprintf("MerchantID: %s", merchantID))
+
+	return encryptedData, nil
+}
+
+// EncryptCardDataForNextKey encrypts the card PAN and date with the key the merchant is rotating to
+func (s *RSAEncryptionService) EncryptCardDataForNextKey(
+	cardData value_objects.CardData,
+	merchantID string,
+) (*value_objects.EncryptedCardData, error) {
+	const operation = "RSAEncryptionService.EncryptCardDataForNextKey"
+
+	key, err := s.keyProvider.GetNextKey(merchantID)
+	if err != nil {
+		s.logger.Error(fmt.Sprintf("%s | KeyRetrievalError", operation), err)
+		return nil, fmt.Errorf("failed to get next public key for merchant %s: %w", merchantID, err)
+	}
+	if key == nil {
+		return nil, nil
+	}
+
+	encryptedData, err := s.encryptWithKey(operation, cardData, *key)
+	if err != nil {
+		return nil, err
+	}
+
+	s.logger.Info(fmt.Sprintf("%s | Success", operation),
+		fmt.Sprintf("MerchantID: %s, KeyID: %s", merchantID, key.KeyID))
+
+	return &encryptedData, nil
+}
+
+// encryptWithKey encrypts the PAN and date with the key and records which key and algorithm were used
+func (s *RSAEncryptionService) encryptWithKey(
+	operation string,
+	cardData value_objects.CardData,
+	key value_objects.MerchantKey,
+) (value_objects.EncryptedCardData, error) {
 	// Parse the public key
-	publicKey, err := s.parsePublicKey(publicKeyPEM)
+	publicKey, err := s.parsePublicKey(key.PublicKey)
 	if err != nil {
 		s.logger.Error(fmt.Sprintf("%s | KeyParsingError", operation), err)
 		return value_objects.EncryptedCardData{}, fmt.Errorf("failed to parse public key: %w", err)
 	}
 
+	algorithm := key.EncryptionAlgorithm()
+
 	// Encrypt PAN
-	encryptedPan, err := s.encryptData(cardData.Pan, publicKey)
+	encryptedPan, err := s.encryptData(cardData.Pan, publicKey, algorithm)
 	if err != nil {
 		s.logger.Error(fmt.Sprintf("%s | PANEncryptionError", operation), err)
 		return value_objects.EncryptedCardData{}, fmt.Errorf("failed to encrypt PAN: %w", err)
 	}
 
 	// Encrypt Date
-	encryptedDate, err := s.encryptData(cardData.Date, publicKey)
+	encryptedDate, err := s.encryptData(cardData.Date, publicKey, algorithm)
 	if err != nil {
 		s.logger.Error(fmt.Sprintf("%s | DateEncryptionError", operation), err)
 		return value_objects.EncryptedCardData{}, fmt.Errorf("failed to encrypt date: %w", err)
 	}
 
-	s.logger.Info(fmt.Sprintf("%s | Success", operation),
-		fmt.Sprintf("MerchantID: %s", merchantID))
-
 	return value_objects.EncryptedCardData{
-		EncryptedPan:  encryptedPan,
-		EncryptedDate: encryptedDate,
+		EncryptedPan:   encryptedPan,
+		EncryptedDate:  encryptedDate,
+		KeyID:          key.KeyID,
+		Algorithm:      algorithm,
+		KeyFingerprint: value_objects.RSAPublicKeyFingerprint(publicKey),
 	}, nil
 }
 
@@ -92,27 +137,21 @@ func (s *RSAEncryptionService) ValidateMerchantKey(merchantID string) error {
 
 // parsePublicKey parses a PEM-encoded RSA public key
 func (s *RSAEncryptionService) parsePublicKey(publicKeyPEM string) (*rsa.PublicKey, error) {
-	block, _ := pem.Decode([]byte(publicKeyPEM))
-	if block == nil {
-		return nil, fmt.Errorf("failed to parse PEM block containing public key")
-	}
-
-	pub, err := x509.ParsePKIXPublicKey(block.Bytes)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse public key: %w", err)
-	}
-
-	rsaPub, ok := pub.(*rsa.PublicKey)
-	if !ok {
-		return nil, fmt.Errorf("public key is not RSA")
-	}
-
-	return rsaPub, nil
+	return value_objects.ParseRSAPublicKey(publicKeyPEM)
 }
 
 // encryptData encrypts data using RSA public key and returns base64 encoded result
-func (s *RSAEncryptionService) encryptData(data string, publicKey *rsa.PublicKey) (string, error) {
-	encryptedBytes, err := rsa.EncryptPKCS1v15(rand.Reader, publicKey, []byte(data))
+func (s *RSAEncryptionService) encryptData(data string, publicKey *rsa.PublicKey, algorithm string) (string, error) {
+	var encryptedBytes []byte
+	var err error
+	switch algorithm {
+	case value_objects.AlgorithmRSAOAEPSHA256:
+		encryptedBytes, err = rsa.EncryptOAEP(sha256.New(), rand.Reader, publicKey, []byte(data), nil)
+	case value_objects.AlgorithmRSAPKCS1v15:
+		encryptedBytes, err = rsa.EncryptPKCS1v15(rand.Reader, publicKey, []byte(data))
+	default:
+		return "", fmt.Errorf("unsupported encryption algorithm: %s", algorithm)
+	}
 	if err != nil {
 		return "", fmt.Errorf("RSA encryption failed: %w", err)
 	}
@@ -3,8 +3,10 @@ package services
 import (
 	"crypto/rand"
 	"crypto/rsa"
+	"crypto/sha256"
 	"crypto/x509"
 	"encoding/base64"
+	"encoding/hex"
 	"encoding/pem"
 	"errors"
 	"strings"
@@ -25,6 +27,16 @@ func (m *MockMerchantKeyProvider) GetMerchantPublicKey(merchantID string) (strin
 	return args.String(0), args.Error(1)
 }
 
+func (m *MockMerchantKeyProvider) GetEncryptionKey(merchantID string) (value_objects.MerchantKey, error) {
+	args := m.Called(merchantID)
+	return args.Get(0).(value_objects.MerchantKey), args.Error(1)
+}
+
+func (m *MockMerchantKeyProvider) GetNextKey(merchantID string) (*value_objects.MerchantKey, error) {
+	args := m.Called(merchantID)
+	return args.Get(0).(*value_objects.MerchantKey), args.Error(1)
+}
+
 func (m *MockMerchantKeyProvider) HasMerchantKey(merchantID string) bool {
 	args := m.Called(merchantID)
 	return args.Bool(0)
@@ -99,7 +111,7 @@ func TestRSAEncryptionService_EncryptCardData_Success(t *testing.T) {
 
 		// Setup mocks
 		mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
-		mockKeyProvider.On("GetMerchantPublicKey", merchantID).Return(publicKeyPEM, nil)
+		mockKeyProvider.On("GetEncryptionKey", merchantID).Return(value_objects.MerchantKey{KeyID: "key-1", PublicKey: publicKeyPEM}, nil)
 
 		// Act
 		encryptedData, err := service.EncryptCardData(cardData, merchantID)
@@ -135,7 +147,7 @@ func TestRSAEncryptionService_EncryptCardData_Success(t *testing.T) {
 
 		// Setup mocks for both calls
 		mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
-		mockKeyProvider.On("GetMerchantPublicKey", merchantID).Return(publicKeyPEM, nil).Times(2)
+		mockKeyProvider.On("GetEncryptionKey", merchantID).Return(value_objects.MerchantKey{KeyID: "key-1", PublicKey: publicKeyPEM}, nil).Times(2)
 
 		// Act
 		encrypted1, err1 := service.EncryptCardData(cardData1, merchantID)
@@ -166,7 +178,7 @@ func TestRSAEncryptionService_EncryptCardData_Failures(t *testing.T) {
 		// Setup mocks
 		mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
 		mockLogger.On("Error", mock.AnythingOfType("string"), mock.Anything).Return()
-		mockKeyProvider.On("GetMerchantPublicKey", merchantID).Return("", keyError)
+		mockKeyProvider.On("GetEncryptionKey", merchantID).Return(value_objects.MerchantKey{KeyID: "key-1", PublicKey: ""}, keyError)
 
 		// Act
 		encryptedData, err := service.EncryptCardData(cardData, merchantID)
@@ -191,7 +203,7 @@ func TestRSAEncryptionService_EncryptCardData_Failures(t *testing.T) {
 		// Setup mocks
 		mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
 		mockLogger.On("Error", mock.AnythingOfType("string"), mock.Anything).Return()
-		mockKeyProvider.On("GetMerchantPublicKey", merchantID).Return(invalidPEM, nil)
+		mockKeyProvider.On("GetEncryptionKey", merchantID).Return(value_objects.MerchantKey{KeyID: "key-1", PublicKey: invalidPEM}, nil)
 
 		// Act
 		encryptedData, err := service.EncryptCardData(cardData, merchantID)
@@ -217,7 +229,7 @@ invalid-base64-content-that-cannot-be-decoded
 		// Setup mocks
 		mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
 		mockLogger.On("Error", mock.AnythingOfType("string"), mock.Anything).Return()
-		mockKeyProvider.On("GetMerchantPublicKey", merchantID).Return(invalidPEMBlock, nil)
+		mockKeyProvider.On("GetEncryptionKey", merchantID).Return(value_objects.MerchantKey{KeyID: "key-1", PublicKey: invalidPEMBlock}, nil)
 
 		// Act
 		encryptedData, err := service.EncryptCardData(cardData, merchantID)
@@ -246,7 +258,7 @@ iTo7Tu6KPAqv7D7gS2XpJFbZiItSs3m9+9Ue6GnvHw/A6egHQ0XWChOJkw==
 		// Setup mocks
 		mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
 		mockLogger.On("Error", mock.AnythingOfType("string"), mock.Anything).Return()
-		mockKeyProvider.On("GetMerchantPublicKey", merchantID).Return(nonRSAKey, nil)
+		mockKeyProvider.On("GetEncryptionKey", merchantID).Return(value_objects.MerchantKey{KeyID: "key-1", PublicKey: nonRSAKey}, nil)
 
 		// Act
 		encryptedData, err := service.EncryptCardData(cardData, merchantID)
@@ -269,7 +281,7 @@ iTo7Tu6KPAqv7D7gS2XpJFbZiItSs3m9+9Ue6GnvHw/A6egHQ0XWChOJkw==
 		// Setup mocks
 		mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
 		mockLogger.On("Error", mock.AnythingOfType("string"), mock.Anything).Return()
-		mockKeyProvider.On("GetMerchantPublicKey", merchantID).Return("", nil)
+		mockKeyProvider.On("GetEncryptionKey", merchantID).Return(value_objects.MerchantKey{KeyID: "key-1", PublicKey: ""}, nil)
 
 		// Act
 		encryptedData, err := service.EncryptCardData(cardData, merchantID)
@@ -358,7 +370,7 @@ func TestRSAEncryptionService_ParsePublicKey(t *testing.T) {
 
 				// Setup mocks
 				mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
-				mockKeyProvider.On("GetMerchantPublicKey", merchantID).Return(publicKeyPEM, nil)
+				mockKeyProvider.On("GetEncryptionKey", merchantID).Return(value_objects.MerchantKey{KeyID: "key-1", PublicKey: publicKeyPEM}, nil)
 
 				// Act
 				encryptedData, err := service.EncryptCardData(cardData, merchantID)
@@ -406,7 +418,7 @@ func TestRSAEncryptionService_EncryptData(t *testing.T) {
 			t.Run(tc.name, func(t *testing.T) {
 				// Setup mocks
 				mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
-				mockKeyProvider.On("GetMerchantPublicKey", merchantID).Return(publicKeyPEM, nil)
+				mockKeyProvider.On("GetEncryptionKey", merchantID).Return(value_objects.MerchantKey{KeyID: "key-1", PublicKey: publicKeyPEM}, nil)
 
 				// Act
 				encryptedData, err := service.EncryptCardData(tc.data, merchantID)
@@ -432,7 +444,7 @@ func TestRSAEncryptionService_EncryptData(t *testing.T) {
 
 		// Setup mocks
 		mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
-		mockKeyProvider.On("GetMerchantPublicKey", merchantID).Return(publicKeyPEM, nil)
+		mockKeyProvider.On("GetEncryptionKey", merchantID).Return(value_objects.MerchantKey{KeyID: "key-1", PublicKey: publicKeyPEM}, nil)
 
 		// Act
 		encryptedData, err := service.EncryptCardData(emptyCardData, merchantID)
@@ -458,7 +470,7 @@ func TestRSAEncryptionService_LoggingBehavior(t *testing.T) {
 		// Setup mocks
 		mockLogger.On("Info", "RSAEncryptionService.EncryptCardData | Starting", "MerchantID: MERCHANT123").Return()
 		mockLogger.On("Info", "RSAEncryptionService.EncryptCardData | Success", "MerchantID: MERCHANT123").Return()
-		mockKeyProvider.On("GetMerchantPublicKey", merchantID).Return(publicKeyPEM, nil)
+		mockKeyProvider.On("GetEncryptionKey", merchantID).Return(value_objects.MerchantKey{KeyID: "key-1", PublicKey: publicKeyPEM}, nil)
 
 		// Act
 		encryptedData, err := service.EncryptCardData(cardData, merchantID)
@@ -480,7 +492,7 @@ func TestRSAEncryptionService_LoggingBehavior(t *testing.T) {
 		// Setup mocks
 		mockLogger.On("Info", "RSAEncryptionService.EncryptCardData | Starting", "MerchantID: ERROR_MERCHANT").Return()
 		mockLogger.On("Error", "RSAEncryptionService.EncryptCardData | KeyRetrievalError", keyError).Return()
-		mockKeyProvider.On("GetMerchantPublicKey", merchantID).Return("", keyError)
+		mockKeyProvider.On("GetEncryptionKey", merchantID).Return(value_objects.MerchantKey{KeyID: "key-1", PublicKey: ""}, keyError)
 
 		// Act
 		encryptedData, err := service.EncryptCardData(cardData, merchantID)
@@ -502,7 +514,7 @@ func TestRSAEncryptionService_LoggingBehavior(t *testing.T) {
 		// Setup mocks
 		mockLogger.On("Info", "RSAEncryptionService.EncryptCardData | Starting", "MerchantID: INVALID_KEY_MERCHANT").Return()
 		mockLogger.On("Error", "RSAEncryptionService.EncryptCardData | KeyParsingError", mock.AnythingOfType("*errors.errorString")).Return()
-		mockKeyProvider.On("GetMerchantPublicKey", merchantID).Return(invalidKey, nil)
+		mockKeyProvider.On("GetEncryptionKey", merchantID).Return(value_objects.MerchantKey{KeyID: "key-1", PublicKey: invalidKey}, nil)
 
 		// Act
 		encryptedData, err := service.EncryptCardData(cardData, merchantID)
@@ -544,7 +556,7 @@ func TestRSAEncryptionService_EdgeCases(t *testing.T) {
 
 		// Setup mocks
 		mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
-		mockKeyProvider.On("GetMerchantPublicKey", longMerchantID).Return(publicKeyPEM, nil)
+		mockKeyProvider.On("GetEncryptionKey", longMerchantID).Return(value_objects.MerchantKey{KeyID: "key-1", PublicKey: publicKeyPEM}, nil)
 
 		// Act
 		encryptedData, err := service.EncryptCardData(cardData, longMerchantID)
@@ -567,7 +579,7 @@ func TestRSAEncryptionService_EdgeCases(t *testing.T) {
 
 		// Setup mocks
 		mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
-		mockKeyProvider.On("GetMerchantPublicKey", merchantID).Return(publicKeyPEM, nil)
+		mockKeyProvider.On("GetEncryptionKey", merchantID).Return(value_objects.MerchantKey{KeyID: "key-1", PublicKey: publicKeyPEM}, nil)
 
 		// Act
 		encryptedData, err := service.EncryptCardData(specialCardData, merchantID)
@@ -590,7 +602,7 @@ func TestRSAEncryptionService_EdgeCases(t *testing.T) {
 
 		// Setup mocks
 		mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
-		mockKeyProvider.On("GetMerchantPublicKey", merchantID).Return(publicKeyPEM, nil)
+		mockKeyProvider.On("GetEncryptionKey", merchantID).Return(value_objects.MerchantKey{KeyID: "key-1", PublicKey: publicKeyPEM}, nil)
 
 		// Act
 		encryptedData, err := service.EncryptCardData(unicodeCardData, merchantID)
@@ -601,3 +613,148 @@ func TestRSAEncryptionService_EdgeCases(t *testing.T) {
 		assert.NotEmpty(t, encryptedData.EncryptedDate)
 	})
 }
+
+// Test the key metadata stored with the ciphertext
+func TestRSAEncryptionService_KeyMetadata(t *testing.T) {
+	privateKey, publicKeyPEM := generateTestKeyPair(t)
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	assert.NoError(t, err)
+	fingerprint := sha256.Sum256(der)
+
+	testCases := []struct {
+		name              string
+		keyAlgorithm      string
+		expectedAlgorithm string
+		decrypt           func(ciphertext []byte) ([]byte, error)
+	}{
+		{
+			name:              "Uses RSA-OAEP with SHA-256 when the key requests no algorithm",
+			expectedAlgorithm: value_objects.AlgorithmRSAOAEPSHA256,
+			decrypt: func(ciphertext []byte) ([]byte, error) {
+				return rsa.DecryptOAEP(sha256.New(), nil, privateKey, ciphertext, nil)
+			},
+		},
+		{
+			name:              "Uses RSA-OAEP with SHA-256 when the key requests it",
+			keyAlgorithm:      value_objects.AlgorithmRSAOAEPSHA256,
+			expectedAlgorithm: value_objects.AlgorithmRSAOAEPSHA256,
+			decrypt: func(ciphertext []byte) ([]byte, error) {
+				return rsa.DecryptOAEP(sha256.New(), nil, privateKey, ciphertext, nil)
+			},
+		},
+		{
+			name:              "Uses PKCS#1 v1.5 only when the key requests it",
+			keyAlgorithm:      value_objects.AlgorithmRSAPKCS1v15,
+			expectedAlgorithm: value_objects.AlgorithmRSAPKCS1v15,
+			decrypt: func(ciphertext []byte) ([]byte, error) {
+				return rsa.DecryptPKCS1v15(nil, privateKey, ciphertext)
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			service, mockKeyProvider, mockLogger := setupRSAEncryptionService(t)
+			mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
+			mockKeyProvider.On("GetEncryptionKey", "MERCHANT123").Return(
+				value_objects.MerchantKey{KeyID: "key-2025", PublicKey: publicKeyPEM, Algorithm: tc.keyAlgorithm}, nil)
+
+			// Act
+			encryptedData, err := service.EncryptCardData(createValidCardData(), "MERCHANT123")
+
+			// Assert
+			assert.NoError(t, err)
+			assert.Equal(t, "key-2025", encryptedData.KeyID)
+			assert.Equal(t, tc.expectedAlgorithm, encryptedData.Algorithm)
+			assert.Equal(t, hex.EncodeToString(fingerprint[:]), encryptedData.KeyFingerprint)
+
+			ciphertext, _ := base64.StdEncoding.DecodeString(encryptedData.EncryptedPan)
+			pan, err := tc.decrypt(ciphertext)
+			assert.NoError(t, err)
+			assert.Equal(t, "4111111111111111", string(pan))
+		})
+	}
+
+	t.Run("Rejects an unsupported algorithm", func(t *testing.T) {
+		// Arrange
+		service, mockKeyProvider, mockLogger := setupRSAEncryptionService(t)
+		mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
+		mockLogger.On("Error", "RSAEncryptionService.EncryptCardData | PANEncryptionError", mock.Anything).Return()
+		mockKeyProvider.On("GetEncryptionKey", "MERCHANT123").Return(
+			value_objects.MerchantKey{KeyID: "key-2025", PublicKey: publicKeyPEM, Algorithm: "RSA-OAEP"}, nil)
+
+		// Act
+		encryptedData, err := service.EncryptCardData(createValidCardData(), "MERCHANT123")
+
+		// Assert
+		assert.ErrorContains(t, err, "unsupported encryption algorithm")
+		assert.Empty(t, encryptedData)
+		mockLogger.AssertExpectations(t)
+	})
+}
+
+// Test EncryptCardDataForNextKey
+func TestRSAEncryptionService_EncryptCardDataForNextKey(t *testing.T) {
+	t.Run("Encrypts with the next key", func(t *testing.T) {
+		// Arrange
+		service, mockKeyProvider, mockLogger := setupRSAEncryptionService(t)
+		privateKey, publicKeyPEM := generateTestKeyPair(t)
+
+		mockLogger.On("Info", mock.AnythingOfType("string"), mock.Anything).Return()
+		mockKeyProvider.On("GetNextKey", "MERCHANT123").Return(&value_objects.MerchantKey{KeyID: "key-next", PublicKey: publicKeyPEM}, nil)
+
+		// Act
+		encryptedData, err := service.EncryptCardDataForNextKey(createValidCardData(), "MERCHANT123")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Equal(t, "key-next", encryptedData.KeyID)
+		ciphertext, _ := base64.StdEncoding.DecodeString(encryptedData.EncryptedDate)
+		date, err := rsa.DecryptOAEP(sha256.New(), nil, privateKey, ciphertext, nil)
+		assert.NoError(t, err)
+		assert.Equal(t, "1225", string(date))
+	})
+
+	t.Run("Returns nil without rotation", func(t *testing.T) {
+		// Arrange
+		service, mockKeyProvider, _ := setupRSAEncryptionService(t)
+		mockKeyProvider.On("GetNextKey", "MERCHANT123").Return((*value_objects.MerchantKey)(nil), nil)
+
+		// Act
+		encryptedData, err := service.EncryptCardDataForNextKey(createValidCardData(), "MERCHANT123")
+
+		// Assert
+		assert.NoError(t, err)
+		assert.Nil(t, encryptedData)
+	})
+
+	t.Run("Fails with an invalid next key", func(t *testing.T) {
+		// Arrange
+		service, mockKeyProvider, mockLogger := setupRSAEncryptionService(t)
+		mockLogger.On("Error", "RSAEncryptionService.EncryptCardDataForNextKey | KeyParsingError", mock.Anything).Return()
+		mockKeyProvider.On("GetNextKey", "MERCHANT123").Return(&value_objects.MerchantKey{KeyID: "key-next", PublicKey: "invalid"}, nil)
+
+		// Act
+		encryptedData, err := service.EncryptCardDataForNextKey(createValidCardData(), "MERCHANT123")
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, encryptedData)
+		mockLogger.AssertExpectations(t)
+	})
+
+	t.Run("Fails when the registry fails", func(t *testing.T) {
+		// Arrange
+		service, mockKeyProvider, mockLogger := setupRSAEncryptionService(t)
+		mockLogger.On("Error", "RSAEncryptionService.EncryptCardDataForNextKey | KeyRetrievalError", mock.Anything).Return()
+		mockKeyProvider.On("GetNextKey", "MERCHANT123").Return((*value_objects.MerchantKey)(nil), errors.New("dynamo error"))
+
+		// Act
+		encryptedData, err := service.EncryptCardDataForNextKey(createValidCardData(), "MERCHANT123")
+
+		// Assert
+		assert.Error(t, err)
+		assert.Nil(t, encryptedData)
+	})
+}
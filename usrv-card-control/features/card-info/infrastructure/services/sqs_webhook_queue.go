@@ -0,0 +1,88 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sync"
+	"time"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/entities"
+	domainServices "bitbucket.org/kushki/usrv-card-control/features/card-info/domain/services"
+	"bitbucket.org/kushki/usrv-card-control/features/shared/constants"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+)
+
+// SQSClient is the part of the SQS client used by the webhook queue
+type SQSClient interface {
+	GetQueueUrl(ctx context.Context, params *sqs.GetQueueUrlInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueUrlOutput, error)
+	SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error)
+}
+
+// SQSWebhookQueue implements the WebhookQueue interface with the webhook delivery queue
+type SQSWebhookQueue struct {
+	client    SQSClient
+	logger    logger.KushkiLogger
+	queueName string
+
+	mu       sync.Mutex
+	queueURL string
+}
+
+// NewSQSWebhookQueue creates a new webhook queue
+func NewSQSWebhookQueue(client SQSClient, logger logger.KushkiLogger) domainServices.WebhookQueue {
+	return &SQSWebhookQueue{
+		client:    client,
+		logger:    logger,
+		queueName: os.Getenv(constants.EnvCardInfoWebhookQueue),
+	}
+}
+
+// Enqueue sends the message, SQS holds it for the delay before the delivery lambda receives it
+func (q *SQSWebhookQueue) Enqueue(ctx context.Context, message entities.WebhookDeliveryMessage, delay time.Duration) error {
+	const operation = "SQSWebhookQueue.Enqueue"
+
+	queueURL, err := q.getQueueURL(ctx)
+	if err != nil {
+		return err
+	}
+
+	// The message is a plain struct, marshaling it cannot fail
+	body, _ := json.Marshal(message)
+
+	if _, err := q.client.SendMessage(ctx, &sqs.SendMessageInput{
+		QueueUrl:     aws.String(queueURL),
+		MessageBody:  aws.String(string(body)),
+		DelaySeconds: int32(min(delay, constants.WebhookRetryMaxDelay).Seconds()),
+	}); err != nil {
+		q.logger.Error(fmt.Sprintf("%s | Error", operation), err)
+		return fmt.Errorf("failed to send webhook delivery message: %w", err)
+	}
+
+	q.logger.Info(fmt.Sprintf("%s | Success", operation),
+		fmt.Sprintf("ExternalReferenceID: %s, Delay: %s", message.ExternalReferenceID, delay))
+
+	return nil
+}
+
+// getQueueURL resolves the URL of the queue once per container
+func (q *SQSWebhookQueue) getQueueURL(ctx context.Context) (string, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if q.queueURL != "" {
+		return q.queueURL, nil
+	}
+
+	output, err := q.client.GetQueueUrl(ctx, &sqs.GetQueueUrlInput{QueueName: aws.String(q.queueName)})
+	if err != nil {
+		return "", fmt.Errorf("failed to get the URL of queue %s: %w", q.queueName, err)
+	}
+
+	q.queueURL = aws.ToString(output.QueueUrl)
+
+	return q.queueURL, nil
+}
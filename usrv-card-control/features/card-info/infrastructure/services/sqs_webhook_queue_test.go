@@ -0,0 +1,75 @@
+package services
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/entities"
+	"bitbucket.org/kushki/usrv-card-control/mocks"
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockSQSClient struct {
+	mock.Mock
+}
+
+func (m *MockSQSClient) GetQueueUrl(ctx context.Context, params *sqs.GetQueueUrlInput, optFns ...func(*sqs.Options)) (*sqs.GetQueueUrlOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*sqs.GetQueueUrlOutput), args.Error(1)
+}
+
+func (m *MockSQSClient) SendMessage(ctx context.Context, params *sqs.SendMessageInput, optFns ...func(*sqs.Options)) (*sqs.SendMessageOutput, error) {
+	args := m.Called(ctx, params)
+	return args.Get(0).(*sqs.SendMessageOutput), args.Error(1)
+}
+
+func TestSQSWebhookQueue_Enqueue(t *testing.T) {
+	const queueURL = "https://sqs.us-east-1.amazonaws.com/123/cardInfoWebhookQueue"
+	message := entities.WebhookDeliveryMessage{ExternalReferenceID: "ext-ref-123"}
+
+	t.Run("should send the message with the delay and resolve the URL once", func(t *testing.T) {
+		t.Setenv("SQS_CARD_INFO_WEBHOOK_QUEUE", "cardInfoWebhookQueue")
+		mockClient := &MockSQSClient{}
+		mockClient.On("GetQueueUrl", mock.Anything, &sqs.GetQueueUrlInput{QueueName: aws.String("cardInfoWebhookQueue")}).
+			Return(&sqs.GetQueueUrlOutput{QueueUrl: aws.String(queueURL)}, nil).Once()
+		mockClient.On("SendMessage", mock.Anything, &sqs.SendMessageInput{
+			QueueUrl:     aws.String(queueURL),
+			MessageBody:  aws.String(`{"externalReferenceId":"ext-ref-123"}`),
+			DelaySeconds: 120,
+		}).Return(&sqs.SendMessageOutput{}, nil).Twice()
+		queue := NewSQSWebhookQueue(mockClient, mocks.GetMockLogger(t))
+
+		assert.NoError(t, queue.Enqueue(context.Background(), message, 2*time.Minute))
+		assert.NoError(t, queue.Enqueue(context.Background(), message, 2*time.Minute))
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("should not exceed the maximum delay of SQS", func(t *testing.T) {
+		mockClient := &MockSQSClient{}
+		mockClient.On("GetQueueUrl", mock.Anything, mock.Anything).Return(&sqs.GetQueueUrlOutput{QueueUrl: aws.String(queueURL)}, nil)
+		mockClient.On("SendMessage", mock.Anything, mock.MatchedBy(func(input *sqs.SendMessageInput) bool {
+			return input.DelaySeconds == 900
+		})).Return(&sqs.SendMessageOutput{}, nil)
+
+		assert.NoError(t, NewSQSWebhookQueue(mockClient, mocks.GetMockLogger(t)).Enqueue(context.Background(), message, time.Hour))
+		mockClient.AssertExpectations(t)
+	})
+
+	t.Run("should return the SQS errors", func(t *testing.T) {
+		mockClient := &MockSQSClient{}
+		mockClient.On("GetQueueUrl", mock.Anything, mock.Anything).
+			Return((*sqs.GetQueueUrlOutput)(nil), errors.New("sqs error")).Once()
+		mockClient.On("GetQueueUrl", mock.Anything, mock.Anything).Return(&sqs.GetQueueUrlOutput{QueueUrl: aws.String(queueURL)}, nil)
+		mockClient.On("SendMessage", mock.Anything, mock.Anything).Return((*sqs.SendMessageOutput)(nil), errors.New("sqs error"))
+		queue := NewSQSWebhookQueue(mockClient, mocks.GetMockLogger(t))
+
+		assert.Error(t, queue.Enqueue(context.Background(), message, 0))
+		assert.Error(t, queue.Enqueue(context.Background(), message, 0))
+		mockClient.AssertNumberOfCalls(t, "SendMessage", 1)
+	})
+}
@@ -0,0 +1,154 @@
+package adapters
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/application/use_cases"
+	"bitbucket.org/kushki/usrv-card-control/features/shared/constants"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// ErrorResponse error body documented in pxp-card-info.yml
+type ErrorResponse struct {
+	Message string `json:"message"`
+	Code    string `json:"code,omitempty"`
+}
+
+// APIGatewayAdapter exposes the card info retrieval and the key registration through API Gateway
+type APIGatewayAdapter struct {
+	getCardInfoUseCase         *use_cases.GetCardInfoUseCase
+	registerMerchantKeyUseCase *use_cases.RegisterMerchantKeyUseCase
+	logger                     logger.KushkiLogger
+}
+
+// NewAPIGatewayAdapter creates a new API Gateway adapter
+func NewAPIGatewayAdapter(
+	getCardInfoUseCase *use_cases.GetCardInfoUseCase,
+	registerMerchantKeyUseCase *use_cases.RegisterMerchantKeyUseCase,
+	logger logger.KushkiLogger,
+) *APIGatewayAdapter {
+	return &APIGatewayAdapter{
+		getCardInfoUseCase:         getCardInfoUseCase,
+		registerMerchantKeyUseCase: registerMerchantKeyUseCase,
+		logger:                     logger,
+	}
+}
+
+// HandleGetCardInfo answers GET /analytics/v1/card-info/{externalReferenceId}
+// The merchant comes from the authorizer, the Private-Merchant-Id header is its private credential
+func (a *APIGatewayAdapter) HandleGetCardInfo(ctx context.Context, event events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	const adapter = "APIGatewayAdapter.HandleGetCardInfo"
+
+	privateCredentialID := getHeader(event.Headers, constants.PrivateMerchantIDHeader)
+	if privateCredentialID == "" {
+		return jsonResponse(http.StatusUnauthorized, ErrorResponse{Message: constants.ErrorMessageUnauthorized})
+	}
+
+	merchantID, _ := event.RequestContext.Authorizer[constants.AuthorizerMerchantIDField].(string)
+	response, err := a.getCardInfoUseCase.Execute(ctx, use_cases.GetCardInfoRequest{
+		ExternalReferenceID: event.PathParameters[constants.ExternalReferenceIDParam],
+		MerchantID:          merchantID,
+		PrivateCredentialID: privateCredentialID,
+	})
+
+	switch {
+	case err == nil:
+		return jsonResponse(http.StatusOK, response)
+	case errors.Is(err, use_cases.ErrInvalidGetCardInfoRequest):
+		return jsonResponse(http.StatusBadRequest, ErrorResponse{
+			Message: constants.ErrorMessageInvalidID,
+			Code:    constants.ErrorCodeInvalidRequest,
+		})
+	case errors.Is(err, use_cases.ErrInvalidCredential):
+		return jsonResponse(http.StatusUnauthorized, ErrorResponse{Message: constants.ErrorMessageUnauthorized})
+	case errors.Is(err, use_cases.ErrForbiddenCredential):
+		return jsonResponse(http.StatusForbidden, ErrorResponse{Message: constants.ErrorMessageForbidden})
+	case errors.Is(err, use_cases.ErrCardInfoNotFound):
+		return jsonResponse(http.StatusNotFound, ErrorResponse{
+			Message: constants.ErrorMessageNotFound,
+			Code:    constants.ErrorCodeNotFound,
+		})
+	default:
+		a.logger.Error(fmt.Sprintf("%s | Error", adapter), err)
+		return jsonResponse(http.StatusInternalServerError, ErrorResponse{
+			Message: constants.ErrorMessageUnexpected,
+			Code:    constants.ErrorCodeUnexpected,
+		})
+	}
+}
+
+// HandleRegisterMerchantKey answers POST /analytics/v1/card-info/keys
+// The merchant comes from the authorizer, the Private-Merchant-Id header is its private credential
+func (a *APIGatewayAdapter) HandleRegisterMerchantKey(ctx context.Context, event events.APIGatewayProxyRequest) events.APIGatewayProxyResponse {
+	const adapter = "APIGatewayAdapter.HandleRegisterMerchantKey"
+
+	privateCredentialID := getHeader(event.Headers, constants.PrivateMerchantIDHeader)
+	if privateCredentialID == "" {
+		return jsonResponse(http.StatusUnauthorized, ErrorResponse{Message: constants.ErrorMessageUnauthorized})
+	}
+
+	var request use_cases.RegisterMerchantKeyRequest
+	if err := json.Unmarshal([]byte(event.Body), &request); err != nil {
+		return jsonResponse(http.StatusBadRequest, ErrorResponse{
+			Message: constants.ErrorMessageInvalidKey,
+			Code:    constants.ErrorCodeInvalidRequest,
+		})
+	}
+	request.MerchantID, _ = event.RequestContext.Authorizer[constants.AuthorizerMerchantIDField].(string)
+	request.PrivateCredentialID = privateCredentialID
+
+	response, err := a.registerMerchantKeyUseCase.Execute(ctx, request)
+
+	switch {
+	case err == nil:
+		return jsonResponse(http.StatusCreated, response)
+	case errors.Is(err, use_cases.ErrInvalidCredential):
+		return jsonResponse(http.StatusUnauthorized, ErrorResponse{Message: constants.ErrorMessageUnauthorized})
+	case errors.Is(err, use_cases.ErrForbiddenCredential):
+		return jsonResponse(http.StatusForbidden, ErrorResponse{Message: constants.ErrorMessageForbidden})
+	case errors.Is(err, use_cases.ErrInvalidMerchantKey):
+		return jsonResponse(http.StatusBadRequest, ErrorResponse{
+			Message: constants.ErrorMessageInvalidKey,
+			Code:    constants.ErrorCodeInvalidRequest,
+		})
+	case errors.Is(err, use_cases.ErrMerchantKeyExists):
+		return jsonResponse(http.StatusConflict, ErrorResponse{
+			Message: constants.ErrorMessageKeyExists,
+			Code:    constants.ErrorCodeInvalidRequest,
+		})
+	default:
+		a.logger.Error(fmt.Sprintf("%s | Error", adapter), err)
+		return jsonResponse(http.StatusInternalServerError, ErrorResponse{
+			Message: constants.ErrorMessageUnexpected,
+			Code:    constants.ErrorCodeUnexpected,
+		})
+	}
+}
+
+func jsonResponse(statusCode int, body interface{}) events.APIGatewayProxyResponse {
+	// The bodies are plain structs, marshaling them cannot fail
+	payload, _ := json.Marshal(body)
+
+	return events.APIGatewayProxyResponse{
+		StatusCode: statusCode,
+		Headers:    map[string]string{"Content-Type": "application/json"},
+		Body:       string(payload),
+	}
+}
+
+// getHeader API Gateway keeps the header case sent by the client
+func getHeader(headers map[string]string, name string) string {
+	for key, value := range headers {
+		if strings.EqualFold(key, name) {
+			return strings.TrimSpace(value)
+		}
+	}
+
+	return ""
+}
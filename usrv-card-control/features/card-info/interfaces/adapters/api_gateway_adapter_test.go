@@ -0,0 +1,278 @@
+package adapters
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"testing"
+	"time"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/application/use_cases"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/entities"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/repositories"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/value_objects"
+	"bitbucket.org/kushki/usrv-card-control/mocks"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockCredentialService struct {
+	mock.Mock
+}
+
+func (m *MockCredentialService) ValidatePrivateCredential(privateCredentialID, merchantID string) bool {
+	args := m.Called(privateCredentialID, merchantID)
+	return args.Bool(0)
+}
+
+func (m *MockCredentialService) BelongsToAnotherMerchant(privateCredentialID, merchantID string) bool {
+	args := m.Called(privateCredentialID, merchantID)
+	return args.Bool(0)
+}
+
+func TestAPIGatewayAdapter_HandleGetCardInfo(t *testing.T) {
+	storedCardInfo := &entities.StoredCardInfo{
+		ExternalReferenceID:  "EXT_REF_123",
+		TransactionReference: "TXN_REF_123",
+		CardBrand:            "VISA",
+		TerminalID:           "TERM_123",
+		MerchantID:           "MERCHANT_123",
+		EncryptedCard:        value_objects.EncryptedCardData{EncryptedPan: "enc-pan", EncryptedDate: "enc-date"},
+		TransactionDate:      1749661979000,
+		ExpiresAt:            time.Now().Add(time.Hour).UnixMilli(),
+	}
+	request := func(headers map[string]string) events.APIGatewayProxyRequest {
+		return events.APIGatewayProxyRequest{
+			Headers:        headers,
+			PathParameters: map[string]string{"externalReferenceId": "EXT_REF_123"},
+			RequestContext: events.APIGatewayProxyRequestContext{
+				Authorizer: map[string]interface{}{"merchantId": "MERCHANT_123"},
+			},
+		}
+	}
+	validHeaders := map[string]string{"private-merchant-id": "PRIV_CRED_123"}
+
+	tests := []struct {
+		name            string
+		event           events.APIGatewayProxyRequest
+		validCredential bool
+		otherMerchant   bool
+		cardInfo        *entities.StoredCardInfo
+		findErr         error
+		expectedStatus  int
+		expectedBody    string
+	}{
+		{
+			name:            "should return the card info",
+			event:           request(validHeaders),
+			validCredential: true,
+			cardInfo:        storedCardInfo,
+			expectedStatus:  http.StatusOK,
+		},
+		{
+			name:           "should return 401 without the Private-Merchant-Id header",
+			event:          request(nil),
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"message":"Unauthorized"}`,
+		},
+		{
+			name:           "should return 400 without the external reference ID",
+			event:          events.APIGatewayProxyRequest{Headers: validHeaders},
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"ID de comercio o credencial no válido","code":"K004"}`,
+		},
+		{
+			name:           "should return 401 for an invalid credential",
+			event:          request(validHeaders),
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"message":"Unauthorized"}`,
+		},
+		{
+			name:           "should return 403 for a credential of another merchant",
+			event:          request(validHeaders),
+			otherMerchant:  true,
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"message":"Forbidden"}`,
+		},
+		{
+			name:            "should return 404 when the card info is not found",
+			event:           request(validHeaders),
+			validCredential: true,
+			findErr:         dynamoerror.ErrItemNotFound,
+			expectedStatus:  http.StatusNotFound,
+			expectedBody:    `{"message":"Resource not found","code":"K004"}`,
+		},
+		{
+			name:            "should return 500 on unexpected errors",
+			event:           request(validHeaders),
+			validCredential: true,
+			findErr:         errors.New("dynamo error"),
+			expectedStatus:  http.StatusInternalServerError,
+			expectedBody:    `{"message":"Ha ocurrido un error inesperado","code":"K002"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockRepo := &MockCardInfoRepository{}
+			mockCredential := &MockCredentialService{}
+			mockLogger := mocks.GetMockLogger(t)
+			mockCredential.On("ValidatePrivateCredential", "PRIV_CRED_123", "MERCHANT_123").Return(tt.validCredential)
+			mockCredential.On("BelongsToAnotherMerchant", "PRIV_CRED_123", "MERCHANT_123").Return(tt.otherMerchant).Maybe()
+			mockRepo.On("FindByMerchantIDAndExternalReferenceID", mock.Anything, "MERCHANT_123", "EXT_REF_123").
+				Return(tt.cardInfo, tt.findErr)
+			adapter := NewAPIGatewayAdapter(use_cases.NewGetCardInfoUseCase(mockRepo, mockCredential, mockLogger), nil, mockLogger)
+
+			response := adapter.HandleGetCardInfo(context.Background(), tt.event)
+
+			assert.Equal(t, tt.expectedStatus, response.StatusCode)
+			assert.Equal(t, "application/json", response.Headers["Content-Type"])
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, response.Body)
+				return
+			}
+			var body use_cases.TransactionResponse
+			assert.NoError(t, json.Unmarshal([]byte(response.Body), &body))
+			assert.Equal(t, "EXT_REF_123", body.ExternalReferenceID)
+			assert.Contains(t, response.Body, `"card":{"encPan":"enc-pan","encDate":"enc-date","algorithm":"RSA1_5"}`)
+		})
+	}
+}
+
+type MockMerchantKeyRepository struct {
+	mock.Mock
+}
+
+func (m *MockMerchantKeyRepository) FindByMerchantID(ctx context.Context, merchantID string) ([]value_objects.MerchantKey, error) {
+	args := m.Called(ctx, merchantID)
+	return args.Get(0).([]value_objects.MerchantKey), args.Error(1)
+}
+
+func (m *MockMerchantKeyRepository) FindAll(ctx context.Context) ([]value_objects.MerchantKey, error) {
+	args := m.Called(ctx)
+	return args.Get(0).([]value_objects.MerchantKey), args.Error(1)
+}
+
+func (m *MockMerchantKeyRepository) Create(ctx context.Context, key value_objects.MerchantKey) error {
+	args := m.Called(ctx, key)
+	return args.Error(0)
+}
+
+func TestAPIGatewayAdapter_HandleRegisterMerchantKey(t *testing.T) {
+	privateKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	assert.NoError(t, err)
+	der, err := x509.MarshalPKIXPublicKey(&privateKey.PublicKey)
+	assert.NoError(t, err)
+	validBody, _ := json.Marshal(map[string]string{
+		"keyId":     "key-2025",
+		"publicKey": string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})),
+	})
+	request := func(headers map[string]string, body string) events.APIGatewayProxyRequest {
+		return events.APIGatewayProxyRequest{
+			Headers: headers,
+			Body:    body,
+			RequestContext: events.APIGatewayProxyRequestContext{
+				Authorizer: map[string]interface{}{"merchantId": "MERCHANT_123"},
+			},
+		}
+	}
+	validHeaders := map[string]string{"Private-Merchant-Id": "PRIV_CRED_123"}
+
+	tests := []struct {
+		name            string
+		event           events.APIGatewayProxyRequest
+		validCredential bool
+		otherMerchant   bool
+		createErr       error
+		expectedStatus  int
+		expectedBody    string
+	}{
+		{
+			name:            "should register the key",
+			event:           request(validHeaders, string(validBody)),
+			validCredential: true,
+			expectedStatus:  http.StatusCreated,
+		},
+		{
+			name:           "should return 401 without the Private-Merchant-Id header",
+			event:          request(nil, string(validBody)),
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"message":"Unauthorized"}`,
+		},
+		{
+			name:           "should return 400 for an invalid body",
+			event:          request(validHeaders, "{"),
+			expectedStatus: http.StatusBadRequest,
+			expectedBody:   `{"message":"Llave pública no válida","code":"K004"}`,
+		},
+		{
+			name:            "should return 400 for an invalid key",
+			event:           request(validHeaders, `{"keyId":"key-2025","publicKey":"not a pem"}`),
+			validCredential: true,
+			expectedStatus:  http.StatusBadRequest,
+			expectedBody:    `{"message":"Llave pública no válida","code":"K004"}`,
+		},
+		{
+			name:           "should return 401 for an invalid credential",
+			event:          request(validHeaders, string(validBody)),
+			expectedStatus: http.StatusUnauthorized,
+			expectedBody:   `{"message":"Unauthorized"}`,
+		},
+		{
+			name:           "should return 403 for a credential of another merchant",
+			event:          request(validHeaders, string(validBody)),
+			otherMerchant:  true,
+			expectedStatus: http.StatusForbidden,
+			expectedBody:   `{"message":"Forbidden"}`,
+		},
+		{
+			name:            "should return 409 for a key already registered",
+			event:           request(validHeaders, string(validBody)),
+			validCredential: true,
+			createErr:       fmt.Errorf("key: %w", repositories.ErrMerchantKeyExists),
+			expectedStatus:  http.StatusConflict,
+			expectedBody:    `{"message":"La llave pública ya está registrada","code":"K004"}`,
+		},
+		{
+			name:            "should return 500 on unexpected errors",
+			event:           request(validHeaders, string(validBody)),
+			validCredential: true,
+			createErr:       errors.New("dynamo error"),
+			expectedStatus:  http.StatusInternalServerError,
+			expectedBody:    `{"message":"Ha ocurrido un error inesperado","code":"K002"}`,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			mockKeyRepo := &MockMerchantKeyRepository{}
+			mockCredential := &MockCredentialService{}
+			mockLogger := mocks.GetMockLogger(t)
+			mockCredential.On("ValidatePrivateCredential", "PRIV_CRED_123", "MERCHANT_123").Return(tt.validCredential)
+			mockCredential.On("BelongsToAnotherMerchant", "PRIV_CRED_123", "MERCHANT_123").Return(tt.otherMerchant).Maybe()
+			mockKeyRepo.On("Create", mock.Anything, mock.AnythingOfType("value_objects.MerchantKey")).Return(tt.createErr)
+			adapter := NewAPIGatewayAdapter(nil, use_cases.NewRegisterMerchantKeyUseCase(mockKeyRepo, mockCredential, mockLogger), mockLogger)
+
+			response := adapter.HandleRegisterMerchantKey(context.Background(), tt.event)
+
+			assert.Equal(t, tt.expectedStatus, response.StatusCode)
+			if tt.expectedBody != "" {
+				assert.JSONEq(t, tt.expectedBody, response.Body)
+				return
+			}
+			var body use_cases.MerchantKeyResponse
+			assert.NoError(t, json.Unmarshal([]byte(response.Body), &body))
+			assert.Equal(t, "key-2025", body.KeyID)
+			assert.Equal(t, value_objects.MerchantKeyActive, body.Status)
+			assert.NotContains(t, response.Body, "publicKey")
+		})
+	}
+}
@@ -0,0 +1,38 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/application/use_cases"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+)
+
+// ScheduledKeyPromotionAdapter promotes the stored card data on the schedule of the promotion job
+type ScheduledKeyPromotionAdapter struct {
+	promoteKeysUseCase *use_cases.PromoteCardInfoKeysUseCase
+	logger             logger.KushkiLogger
+}
+
+// NewScheduledKeyPromotionAdapter creates a new scheduled key promotion adapter
+func NewScheduledKeyPromotionAdapter(
+	promoteKeysUseCase *use_cases.PromoteCardInfoKeysUseCase,
+	logger logger.KushkiLogger,
+) *ScheduledKeyPromotionAdapter {
+	return &ScheduledKeyPromotionAdapter{
+		promoteKeysUseCase: promoteKeysUseCase,
+		logger:             logger,
+	}
+}
+
+// HandleScheduledEvent runs a promotion, the error reports the failed records so the run is flagged
+func (a *ScheduledKeyPromotionAdapter) HandleScheduledEvent(ctx context.Context) (*use_cases.PromoteCardInfoKeysResponse, error) {
+	const adapter = "ScheduledKeyPromotionAdapter.HandleScheduledEvent"
+
+	response, err := a.promoteKeysUseCase.Execute(ctx)
+	if err != nil {
+		a.logger.Error(fmt.Sprintf("%s | Error", adapter), err)
+	}
+
+	return response, err
+}
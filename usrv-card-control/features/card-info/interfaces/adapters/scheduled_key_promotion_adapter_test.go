@@ -0,0 +1,41 @@
+package adapters
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/application/use_cases"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/value_objects"
+	"bitbucket.org/kushki/usrv-card-control/mocks"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestScheduledKeyPromotionAdapter_HandleScheduledEvent(t *testing.T) {
+	t.Run("should return the outcome of the promotion", func(t *testing.T) {
+		mockKeyRepo := &MockMerchantKeyRepository{}
+		mockLogger := mocks.GetMockLogger(t)
+		mockKeyRepo.On("FindAll", mock.Anything).Return([]value_objects.MerchantKey{}, nil)
+		adapter := NewScheduledKeyPromotionAdapter(
+			use_cases.NewPromoteCardInfoKeysUseCase(&MockCardInfoRepository{}, mockKeyRepo, mockLogger), mockLogger)
+
+		response, err := adapter.HandleScheduledEvent(context.Background())
+
+		assert.NoError(t, err)
+		assert.Equal(t, &use_cases.PromoteCardInfoKeysResponse{}, response)
+	})
+
+	t.Run("should return the error so the run is flagged", func(t *testing.T) {
+		mockKeyRepo := &MockMerchantKeyRepository{}
+		mockLogger := mocks.GetMockLogger(t)
+		mockKeyRepo.On("FindAll", mock.Anything).Return([]value_objects.MerchantKey(nil), errors.New("dynamo error"))
+		adapter := NewScheduledKeyPromotionAdapter(
+			use_cases.NewPromoteCardInfoKeysUseCase(&MockCardInfoRepository{}, mockKeyRepo, mockLogger), mockLogger)
+
+		response, err := adapter.HandleScheduledEvent(context.Background())
+
+		assert.Error(t, err)
+		assert.Nil(t, response)
+	})
+}
@@ -47,13 +47,18 @@ func (a *SQSAdapter) ProcessCardInfoMessage(ctx context.Context, messageBody str
 	return nil
 }
 
-// HandleSQSEvent processes SQS events (SQS-specific method)
-func (a *SQSAdapter) HandleSQSEvent(ctx context.Context, event events.SQSEvent) error {
+// HandleSQSEvent processes every record of the SQS event (SQS-specific method)
+// Failed records are reported as batch item failures so only those messages are retried
+func (a *SQSAdapter) HandleSQSEvent(ctx context.Context, event events.SQSEvent) events.SQSEventResponse {
 	const adapter = "SQSAdapter.HandleSQSEvent"
 
 	a.logger.Info(fmt.Sprintf("%s | Starting", adapter),
 		fmt.Sprintf("Processing %d records", len(event.Records)))
 
+	response := events.SQSEventResponse{
+		BatchItemFailures: make([]events.SQSBatchItemFailure, 0),
+	}
+
 	for i, record := range event.Records {
 		a.logger.Info(fmt.Sprintf("%s | ProcessingRecord", adapter),
 			fmt.Sprintf("Record %d/%d, MessageId: %s", i+1, len(event.Records), record.MessageId))
@@ -61,19 +66,22 @@ func (a *SQSAdapter) HandleSQSEvent(ctx context.Context, event events.SQSEvent)
 		// Use the interface method to process the message
 		if err := a.ProcessCardInfoMessage(ctx, record.Body); err != nil {
 			a.logger.Error(fmt.Sprintf("%s | RecordError", adapter),
-				fmt.Sprintf("Failed to process record %d: %v", i+1, err))
+				fmt.Sprintf("Failed to process record %d (MessageId: %s): %v", i+1, record.MessageId, err))
 
-			// In SQS, if any record fails, the entire batch should be retried
-			return fmt.Errorf("failed to process SQS record %d (MessageId: %s): %w",
-				i+1, record.MessageId, err)
+			// Keep going with the remaining records, only this one goes back to the queue
+			response.BatchItemFailures = append(response.BatchItemFailures, events.SQSBatchItemFailure{
+				ItemIdentifier: record.MessageId,
+			})
+
+			continue
 		}
 
 		a.logger.Info(fmt.Sprintf("%s | RecordSuccess", adapter),
 			fmt.Sprintf("Successfully processed record %d/%d", i+1, len(event.Records)))
 	}
 
-	a.logger.Info(fmt.Sprintf("%s | Success", adapter),
-		fmt.Sprintf("Successfully processed all %d records", len(event.Records)))
+	a.logger.Info(fmt.Sprintf("%s | Finished", adapter),
+		fmt.Sprintf("Processed %d records, %d failed", len(event.Records), len(response.BatchItemFailures)))
 
-	return nil
+	return response
 }
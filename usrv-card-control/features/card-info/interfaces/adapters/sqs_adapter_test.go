@@ -35,6 +35,14 @@ func (m *MockCardInfoRepository) FindByExternalReferenceID(ctx context.Context,
 	return args.Get(0).(*entities.StoredCardInfo), args.Error(1)
 }
 
+func (m *MockCardInfoRepository) FindByMerchantIDAndExternalReferenceID(ctx context.Context, merchantID, externalReferenceID string) (*entities.StoredCardInfo, error) {
+	args := m.Called(ctx, merchantID, externalReferenceID)
+	if args.Get(0) == nil {
+		return nil, args.Error(1)
+	}
+	return args.Get(0).(*entities.StoredCardInfo), args.Error(1)
+}
+
 func (m *MockCardInfoRepository) Delete(ctx context.Context, externalReferenceID string) error {
 	args := m.Called(ctx, externalReferenceID)
 	return args.Error(0)
@@ -48,6 +56,21 @@ func (m *MockCardInfoRepository) FindExpiredRecords(ctx context.Context, current
 	return args.Get(0).([]*entities.StoredCardInfo), args.Error(1)
 }
 
+func (m *MockCardInfoRepository) FindByNextKey(ctx context.Context, merchantID, keyID string, from, to int64) ([]*entities.StoredCardInfo, error) {
+	args := m.Called(ctx, merchantID, keyID, from, to)
+	return args.Get(0).([]*entities.StoredCardInfo), args.Error(1)
+}
+
+func (m *MockCardInfoRepository) FindByPreviousKey(ctx context.Context, merchantID, keyID string, from, to int64) ([]*entities.StoredCardInfo, error) {
+	args := m.Called(ctx, merchantID, keyID, from, to)
+	return args.Get(0).([]*entities.StoredCardInfo), args.Error(1)
+}
+
+func (m *MockCardInfoRepository) PromoteNextKey(ctx context.Context, externalReferenceID, keyID string) error {
+	args := m.Called(ctx, externalReferenceID, keyID)
+	return args.Error(0)
+}
+
 type MockEncryptionService struct {
 	mock.Mock
 }
@@ -57,6 +80,11 @@ func (m *MockEncryptionService) EncryptCardData(cardData value_objects.CardData,
 	return args.Get(0).(value_objects.EncryptedCardData), args.Error(1)
 }
 
+func (m *MockEncryptionService) EncryptCardDataForNextKey(cardData value_objects.CardData, merchantID string) (*value_objects.EncryptedCardData, error) {
+	args := m.Called(cardData, merchantID)
+	return args.Get(0).(*value_objects.EncryptedCardData), args.Error(1)
+}
+
 type MockValidationService struct {
 	mock.Mock
 }
@@ -93,9 +121,7 @@ func TestSQSAdapter_ProcessCardInfoMessage(t *testing.T) {
 				validation.On("ValidatePrivateCredential", "PRIV_CRED_123", "MERCHANT_123").Return(nil)
 
 				// Repository should not find existing record (for idempotency check)
-				// Looking at the use case code: if err != nil, return false, nil (meaning "not found, continue")
-				// So we return an error to simulate "not found"
-				repo.On("FindByExternalReferenceID", mock.Anything, "EXT_REF_123").Return((*entities.StoredCardInfo)(nil), errors.New("item not found"))
+				repo.On("FindByExternalReferenceID", mock.Anything, "EXT_REF_123").Return((*entities.StoredCardInfo)(nil), dynamoerror.ErrItemNotFound)
 
 				// Encryption should work
 				encryption.On("EncryptCardData", mock.AnythingOfType("value_objects.CardData"), "MERCHANT_123").Return(
@@ -103,6 +129,8 @@ func TestSQSAdapter_ProcessCardInfoMessage(t *testing.T) {
 						EncryptedPan:  "encrypted_pan_data",
 						EncryptedDate: "encrypted_date_data",
 					}, nil)
+				encryption.On("EncryptCardDataForNextKey", mock.AnythingOfType("value_objects.CardData"), "MERCHANT_123").Return(
+					(*value_objects.EncryptedCardData)(nil), nil)
 
 				// Repository save should work
 				repo.On("Save", mock.Anything, mock.AnythingOfType("*entities.StoredCardInfo")).Return(nil)
@@ -128,7 +156,17 @@ func TestSQSAdapter_ProcessCardInfoMessage(t *testing.T) {
 				// Validation should fail
 				validation.On("ValidateCardInfoMessage", mock.AnythingOfType("*entities.PxpCardInfoMessage")).Return(errors.New("validation failed"))
 			},
-			messageBody:   `{"externalReferenceId":"test-123"}`,
+			messageBody: `{
+				"card": {"pan": "4111111111111111", "date": "1225"},
+				"externalReferenceId": "EXT_REF_123",
+				"transactionReference": "TXN_REF_123",
+				"card_brand": "VISA",
+				"terminalId": "TERM_123",
+				"transactionType": "charge",
+				"transaction_status": "APPROVAL",
+				"merchant_id": "MERCHANT_123",
+				"privateCredentialId": "PRIV_CRED_123"
+			}`,
 			expectedError: true,
 			errorContains: "message validation failed",
 		},
@@ -150,6 +188,11 @@ func TestSQSAdapter_ProcessCardInfoMessage(t *testing.T) {
 			messageBody: `{
 				"card": {"pan": "4111111111111111", "date": "1225"},
 				"externalReferenceId": "EXT_REF_123",
+				"transactionReference": "TXN_REF_123",
+				"card_brand": "VISA",
+				"terminalId": "TERM_123",
+				"transactionType": "charge",
+				"transaction_status": "APPROVAL",
 				"merchant_id": "MERCHANT_123",
 				"privateCredentialId": "PRIV_CRED_123"
 			}`,
@@ -201,12 +244,25 @@ func TestSQSAdapter_ProcessCardInfoMessage(t *testing.T) {
 }
 
 func TestSQSAdapter_HandleSQSEvent(t *testing.T) {
+	validBody := func(externalReferenceID string) string {
+		return `{
+			"card": {"pan": "4111111111111111", "date": "1225"},
+			"externalReferenceId": "` + externalReferenceID + `",
+			"transactionReference": "TXN_REF_123",
+			"card_brand": "VISA",
+			"terminalId": "TERM_123",
+			"transactionType": "charge",
+			"transaction_status": "APPROVAL",
+			"merchant_id": "MERCHANT_123",
+			"privateCredentialId": "PRIV_CRED_123"
+		}`
+	}
+
 	tests := []struct {
-		name          string
-		setupMocks    func(*MockCardInfoRepository, *MockEncryptionService, *MockValidationService)
-		event         events.SQSEvent
-		expectedError bool
-		errorContains string
+		name             string
+		setupMocks       func(*MockCardInfoRepository, *MockEncryptionService, *MockValidationService)
+		event            events.SQSEvent
+		expectedFailures []events.SQSBatchItemFailure
 	}{
 		{
 			name: "should process single SQS record successfully",
@@ -221,39 +277,50 @@ func TestSQSAdapter_HandleSQSEvent(t *testing.T) {
 						EncryptedPan:  "encrypted_pan_data",
 						EncryptedDate: "encrypted_date_data",
 					}, nil)
+				encryption.On("EncryptCardDataForNextKey", mock.AnythingOfType("value_objects.CardData"), "MERCHANT_123").Return(
+					(*value_objects.EncryptedCardData)(nil), nil)
 				repo.On("Save", mock.Anything, mock.AnythingOfType("*entities.StoredCardInfo")).Return(nil)
 			},
 			event: events.SQSEvent{
 				Records: []events.SQSMessage{
-					{
-						MessageId: "message-123",
-						Body: `{
-							"card": {"pan": "4111111111111111", "date": "1225"},
-							"externalReferenceId": "EXT_REF_123",
-							"merchant_id": "MERCHANT_123",
-							"privateCredentialId": "PRIV_CRED_123"
-						}`,
-					},
+					{MessageId: "message-123", Body: validBody("EXT_REF_123")},
 				},
 			},
-			expectedError: false,
+			expectedFailures: []events.SQSBatchItemFailure{},
 		},
 		{
-			name: "should return error when processing fails",
+			name: "should report the failed record",
 			setupMocks: func(repo *MockCardInfoRepository, encryption *MockEncryptionService, validation *MockValidationService) {
 				// Setup failing validation
 				validation.On("ValidateCardInfoMessage", mock.AnythingOfType("*entities.PxpCardInfoMessage")).Return(errors.New("validation failed"))
 			},
 			event: events.SQSEvent{
 				Records: []events.SQSMessage{
-					{
-						MessageId: "message-1",
-						Body:      `{"invalid":"message"}`,
-					},
+					{MessageId: "message-1", Body: validBody("EXT_REF_123")},
 				},
 			},
-			expectedError: true,
-			errorContains: "failed to process SQS record 1",
+			expectedFailures: []events.SQSBatchItemFailure{{ItemIdentifier: "message-1"}},
+		},
+		{
+			name: "should keep processing the batch and report only the failed records",
+			setupMocks: func(repo *MockCardInfoRepository, encryption *MockEncryptionService, validation *MockValidationService) {
+				validation.On("ValidateCardInfoMessage", mock.AnythingOfType("*entities.PxpCardInfoMessage")).Return(nil)
+				validation.On("ValidateMerchantAccess", "MERCHANT_123").Return(nil)
+				validation.On("ValidatePrivateCredential", "PRIV_CRED_123", "MERCHANT_123").Return(nil)
+				repo.On("FindByExternalReferenceID", mock.Anything, "EXT_REF_1").Return((*entities.StoredCardInfo)(nil), errors.New("dynamo unavailable"))
+				repo.On("FindByExternalReferenceID", mock.Anything, "EXT_REF_2").Return(&entities.StoredCardInfo{}, nil)
+			},
+			event: events.SQSEvent{
+				Records: []events.SQSMessage{
+					{MessageId: "message-1", Body: validBody("EXT_REF_1")},
+					{MessageId: "message-2", Body: validBody("EXT_REF_2")},
+					{MessageId: "message-3", Body: `{"invalid":"message"}`},
+				},
+			},
+			expectedFailures: []events.SQSBatchItemFailure{
+				{ItemIdentifier: "message-1"},
+				{ItemIdentifier: "message-3"},
+			},
 		},
 		{
 			name: "should handle empty SQS event successfully",
@@ -263,7 +330,7 @@ func TestSQSAdapter_HandleSQSEvent(t *testing.T) {
 			event: events.SQSEvent{
 				Records: []events.SQSMessage{},
 			},
-			expectedError: false,
+			expectedFailures: []events.SQSBatchItemFailure{},
 		},
 	}
 
@@ -292,17 +359,10 @@ func TestSQSAdapter_HandleSQSEvent(t *testing.T) {
 			sqsAdapter := adapter.(*SQSAdapter)
 
 			// Execute
-			err := sqsAdapter.HandleSQSEvent(context.Background(), tt.event)
+			response := sqsAdapter.HandleSQSEvent(context.Background(), tt.event)
 
 			// Assert
-			if tt.expectedError {
-				assert.Error(t, err)
-				if tt.errorContains != "" {
-					assert.Contains(t, err.Error(), tt.errorContains)
-				}
-			} else {
-				assert.NoError(t, err)
-			}
+			assert.Equal(t, tt.expectedFailures, response.BatchItemFailures)
 
 			// Verify all expectations were met
 			mockRepo.AssertExpectations(t)
@@ -0,0 +1,55 @@
+package adapters
+
+import (
+	"context"
+	"fmt"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/application/use_cases"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+// SQSWebhookAdapter delivers the card info of the webhook delivery queue
+type SQSWebhookAdapter struct {
+	deliverWebhookUseCase *use_cases.DeliverCardInfoWebhookUseCase
+	logger                logger.KushkiLogger
+}
+
+// NewSQSWebhookAdapter creates a new SQS webhook adapter
+func NewSQSWebhookAdapter(
+	deliverWebhookUseCase *use_cases.DeliverCardInfoWebhookUseCase,
+	logger logger.KushkiLogger,
+) *SQSWebhookAdapter {
+	return &SQSWebhookAdapter{
+		deliverWebhookUseCase: deliverWebhookUseCase,
+		logger:                logger,
+	}
+}
+
+// HandleSQSEvent makes a delivery attempt for every record of the SQS event
+// Failed attempts are queued again by the use case, only infrastructure errors are batch item failures
+func (a *SQSWebhookAdapter) HandleSQSEvent(ctx context.Context, event events.SQSEvent) events.SQSEventResponse {
+	const adapter = "SQSWebhookAdapter.HandleSQSEvent"
+
+	response := events.SQSEventResponse{
+		BatchItemFailures: make([]events.SQSBatchItemFailure, 0),
+	}
+
+	for _, record := range event.Records {
+		if err := a.deliverWebhookUseCase.Execute(ctx, use_cases.DeliverCardInfoWebhookRequest{
+			SQSMessageBody: record.Body,
+		}); err != nil {
+			a.logger.Error(fmt.Sprintf("%s | RecordError", adapter),
+				fmt.Sprintf("MessageId: %s, Error: %v", record.MessageId, err))
+
+			response.BatchItemFailures = append(response.BatchItemFailures, events.SQSBatchItemFailure{
+				ItemIdentifier: record.MessageId,
+			})
+		}
+	}
+
+	a.logger.Info(fmt.Sprintf("%s | Finished", adapter),
+		fmt.Sprintf("Processed %d records, %d failed", len(event.Records), len(response.BatchItemFailures)))
+
+	return response
+}
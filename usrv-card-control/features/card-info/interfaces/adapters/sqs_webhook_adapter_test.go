@@ -0,0 +1,55 @@
+package adapters
+
+import (
+	"context"
+	"testing"
+
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/application/use_cases"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/entities"
+	"bitbucket.org/kushki/usrv-card-control/features/card-info/domain/value_objects"
+	"bitbucket.org/kushki/usrv-card-control/mocks"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+type MockWebhookRepository struct {
+	mock.Mock
+}
+
+func (m *MockWebhookRepository) FindConfig(ctx context.Context, merchantID string) (*value_objects.WebhookConfig, error) {
+	args := m.Called(ctx, merchantID)
+	return args.Get(0).(*value_objects.WebhookConfig), args.Error(1)
+}
+
+func (m *MockWebhookRepository) FindDelivery(ctx context.Context, externalReferenceID string) (*entities.WebhookDelivery, error) {
+	args := m.Called(ctx, externalReferenceID)
+	return args.Get(0).(*entities.WebhookDelivery), args.Error(1)
+}
+
+func (m *MockWebhookRepository) SaveDelivery(ctx context.Context, delivery *entities.WebhookDelivery) error {
+	args := m.Called(ctx, delivery)
+	return args.Error(0)
+}
+
+func TestSQSWebhookAdapter_HandleSQSEvent(t *testing.T) {
+	mockWebhookRepo := &MockWebhookRepository{}
+	mockLogger := mocks.GetMockLogger(t)
+	mockWebhookRepo.On("FindDelivery", mock.Anything, "EXT_REF_123").
+		Return(&entities.WebhookDelivery{Status: entities.WebhookDeliveryDelivered}, nil)
+	mockWebhookRepo.On("FindDelivery", mock.Anything, "EXT_REF_404").
+		Return((*entities.WebhookDelivery)(nil), dynamoerror.ErrItemNotFound)
+	adapter := NewSQSWebhookAdapter(
+		use_cases.NewDeliverCardInfoWebhookUseCase(&MockCardInfoRepository{}, mockWebhookRepo, nil, nil, mockLogger),
+		mockLogger,
+	)
+
+	response := adapter.HandleSQSEvent(context.Background(), events.SQSEvent{Records: []events.SQSMessage{
+		{MessageId: "msg-1", Body: `{"externalReferenceId":"EXT_REF_123"}`},
+		{MessageId: "msg-2", Body: "{"},
+		{MessageId: "msg-3", Body: `{"externalReferenceId":"EXT_REF_404"}`},
+	}})
+
+	assert.Equal(t, []events.SQSBatchItemFailure{{ItemIdentifier: "msg-2"}}, response.BatchItemFailures)
+}
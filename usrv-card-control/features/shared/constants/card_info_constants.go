@@ -1,10 +1,20 @@
 package constants
 
+import "time"
+
 // Environment variable names for card info feature
 const (
 	// DynamoDB table
 	EnvCardInfoTable = "DYNAMO_CARD_INFO_TABLE"
 
+	// Webhook delivery resources
+	EnvCardInfoWebhookConfigTable   = "DYNAMO_CARD_INFO_WEBHOOK_CONFIG_TABLE"
+	EnvCardInfoWebhookDeliveryTable = "DYNAMO_CARD_INFO_WEBHOOK_DELIVERY_TABLE"
+	EnvCardInfoWebhookQueue         = "SQS_CARD_INFO_WEBHOOK_QUEUE"
+
+	// Merchant public key registry
+	EnvCardInfoMerchantKeyTable = "DYNAMO_CARD_INFO_MERCHANT_KEY_TABLE"
+
 	// External service endpoints
 	EnvMerchantKeyServiceURL    = "MERCHANT_KEY_SERVICE_URL"
 	EnvMerchantAccessServiceURL = "MERCHANT_ACCESS_SERVICE_URL"
@@ -48,3 +58,52 @@ const (
 	MaxPANLength         = 19
 	ExpirationDateLength = 4
 )
+
+// Card info retrieval API constants
+const (
+	// Request
+	PrivateMerchantIDHeader   = "Private-Merchant-Id"
+	ExternalReferenceIDParam  = "externalReferenceId"
+	AuthorizerMerchantIDField = "merchantId"
+
+	// CardInfoRetrievalTimeout leaves room to answer within the 3 seconds of the API
+	CardInfoRetrievalTimeout = 2500 * time.Millisecond
+
+	// Error responses
+	ErrorCodeInvalidRequest  = "K004"
+	ErrorCodeNotFound        = "K004"
+	ErrorCodeUnexpected      = "K002"
+	ErrorMessageInvalidID    = "ID de comercio o credencial no válido"
+	ErrorMessageUnauthorized = "Unauthorized"
+	ErrorMessageForbidden    = "Forbidden"
+	ErrorMessageNotFound     = "Resource not found"
+	ErrorMessageUnexpected   = "Ha ocurrido un error inesperado"
+	ErrorMessageInvalidKey   = "Llave pública no válida"
+	ErrorMessageKeyExists    = "La llave pública ya está registrada"
+)
+
+// Merchant public key registry constants
+const (
+	// MerchantKeyCacheTTL time a key is served from memory, a new key is used after it at the latest
+	MerchantKeyCacheTTL = 5 * time.Minute
+
+	// MerchantKeyLookupTimeout bounds the registry query of the encryption
+	MerchantKeyLookupTimeout = 2 * time.Second
+)
+
+// Webhook delivery constants
+const (
+	// Signature headers, the signature is the hex HMAC-SHA256 of "<timestamp>.<body>" with the merchant secret
+	WebhookSignatureHeader = "X-Kushki-Signature"
+	WebhookTimestampHeader = "X-Kushki-Timestamp"
+
+	// WebhookTimeout time given to the merchant endpoint to answer each attempt
+	WebhookTimeout = 10 * time.Second
+
+	// WebhookMaxAttempts attempts before the delivery is marked as failed
+	WebhookMaxAttempts = 6
+
+	// Retry backoff, doubled on every failed attempt up to the maximum delay allowed by SQS
+	WebhookRetryBaseDelay = 30 * time.Second
+	WebhookRetryMaxDelay  = 15 * time.Minute
+)
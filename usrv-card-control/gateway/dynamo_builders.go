@@ -2,6 +2,7 @@ package gateway
 
 import (
 	"fmt"
+	"maps"
 	"os"
 	"strings"
 	"time"
@@ -10,23 +11,28 @@ import (
 	"bitbucket.org/kushki/usrv-card-control/types"
 	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo/builder"
 	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
 func PutBlockedCardBuilder(blockedCard types.DynamoBlockedCard) *builder.PutItemBuilder {
+	blockedCard.ExpiresAt = generateBlockedCardTTL(blockedCard.BlockedMerchants, blockedCard.CardBlock)
+
 	return builder.NewPutItemBuilder().
 		WithItem(blockedCard).
 		WithTable(os.Getenv(constants.DynamoBlockedCard))
 }
 
-func UpdateLastRetryBuilder(currentDate int64, merchantID string, blockedCard types.DynamoBlockedCard) *builder.UpdateItemBuilder {
+func UpdateLastRetryBuilder(currentDate int64, retriesEnd int64, merchantID string, blockedCard types.DynamoBlockedCard) *builder.UpdateItemBuilder {
 	newVersion := time.Now().UTC().UnixMilli()
-	blockedMerchant := types.BlockedMerchant{
-		LastRetry: currentDate,
-	}
+	blockedMerchant := withEscalation(types.BlockedMerchant{
+		LastRetry:  currentDate,
+		RetriesEnd: retriesEnd,
+	}, blockedCard.BlockedMerchants[merchantID])
 	update := expression.Set(
 		expression.Name(fmt.Sprintf("%s.%s", constants.BlockedMerchants, merchantID)),
 		expression.Value(blockedMerchant)).
 		Set(expression.Name(constants.TimeStamp), expression.Value(newVersion))
+	update = withBlockedCardTTL(update, withMerchant(blockedCard.BlockedMerchants, merchantID, blockedMerchant), blockedCard.CardBlock)
 	condition := expression.Name(constants.TimeStamp).Equal(expression.Value(blockedCard.TimeStamp)) // optimistic concurrency.
 	expr := expression.NewBuilder().
 		WithUpdate(update).
@@ -38,13 +44,37 @@ func UpdateLastRetryBuilder(currentDate int64, merchantID string, blockedCard ty
 		WithExpression(&expr)
 }
 
-func IncrementRetryBuilder(retries []int64, request types.BlockCardRequest, key string, cardRetry types.CardRetry) *builder.UpdateItemBuilder {
+func ClearLastRetryBuilder(merchantID string, blockedCard types.DynamoBlockedCard) *builder.UpdateItemBuilder {
 	newVersion := time.Now().UTC().UnixMilli()
+	update := expression.Remove(expression.Name(fmt.Sprintf("%s.%s.%s", constants.BlockedMerchants, merchantID, constants.LastRetryField))).
+		Remove(expression.Name(fmt.Sprintf("%s.%s.%s", constants.BlockedMerchants, merchantID, constants.RetriesEndField))).
+		Set(expression.Name(constants.TimeStamp), expression.Value(newVersion))
+	update = withBlockedCardTTL(update, blockedCard.BlockedMerchants, blockedCard.CardBlock)
+	condition := expression.Name(constants.TimeStamp).Equal(expression.Value(blockedCard.TimeStamp)) // optimistic concurrency.
+	expr := expression.NewBuilder().
+		WithUpdate(update).
+		WithCondition(condition)
+
+	return builder.NewUpdateItemBuilder().
+		WithTable(os.Getenv(constants.DynamoBlockedCard)).
+		WithPartitionKey(constants.CardIdField, blockedCard.CardID).
+		WithExpression(&expr)
+}
+
+// IncrementRetryBuilder refreshes the TTL so the retries outlive the longest window of the policy.
+// The brand and processor let the sweeper resolve the same policy later.
+func IncrementRetryBuilder(retries []int64, request types.BlockCardRequest, key string, cardRetry types.CardRetry, ttlHours int) *builder.UpdateItemBuilder {
+	now := time.Now().UTC()
+	newVersion := now.UnixMilli()
+	expiresAt := now.Add(time.Duration(ttlHours) * time.Hour).Unix()
 
 	update := expression.Set(expression.Name(constants.TimeStamp), expression.Value(newVersion)).
 		Set(expression.Name(constants.RetriesField), expression.Value(retries)).
+		Set(expression.Name(constants.ExpiresAtField), expression.Value(expiresAt)).
 		Set(expression.Name(constants.CardIdField), expression.IfNotExists(expression.Name(constants.CardIdField), expression.Value(request.CardID))).
-		Set(expression.Name(constants.MerchantIDField), expression.IfNotExists(expression.Name(constants.MerchantIDField), expression.Value(request.MerchantIdentifier)))
+		Set(expression.Name(constants.MerchantIDField), expression.IfNotExists(expression.Name(constants.MerchantIDField), expression.Value(request.MerchantIdentifier))).
+		Set(expression.Name(constants.BrandField), expression.IfNotExists(expression.Name(constants.BrandField), expression.Value(request.Franchise))).
+		Set(expression.Name(constants.ProcessorField), expression.IfNotExists(expression.Name(constants.ProcessorField), expression.Value(request.Processor)))
 	condition := expression.Name(constants.TimeStamp).Equal(expression.Value(cardRetry.TimeStamp)).
 		Or(expression.Name(constants.TimeStamp).AttributeNotExists()) // optimistic concurrency.
 	expr := expression.NewBuilder().
@@ -71,8 +101,23 @@ func GetBlockedCardBuilder(cardID string) *builder.GetItemBuilder {
 		WithConsistentRead(true)
 }
 
-func UpdateBlockCardBuilder(request types.BlockCardRequest, blockedCard types.DynamoBlockedCard) *builder.UpdateItemBuilder {
-	update := generateBlockUpdate(request.MerchantIdentifier, request.Operation)
+func BatchGetBlockedCardsBuilder(cardIDs []string) *builder.BatchGetItemInputBuilder {
+	keys := make([]map[string]interface{}, 0, len(cardIDs))
+	for _, cardID := range cardIDs {
+		keys = append(keys, map[string]interface{}{constants.CardIdField: cardID})
+	}
+
+	return builder.NewBatchGetItemInputBuilder().
+		WithTable(os.Getenv(constants.DynamoBlockedCard)).
+		WithKeys(keys)
+}
+
+func UpdateBlockCardBuilder(
+	request types.BlockCardRequest,
+	blockedMerchant types.BlockedMerchant,
+	blockedCard types.DynamoBlockedCard,
+) *builder.UpdateItemBuilder {
+	update := generateBlockUpdate(request.MerchantIdentifier, blockedMerchant, blockedCard)
 	condition := expression.Name(constants.TimeStamp).Equal(expression.Value(blockedCard.TimeStamp))
 
 	exprBuilder := expression.NewBuilder().
@@ -85,28 +130,279 @@ func UpdateBlockCardBuilder(request types.BlockCardRequest, blockedCard types.Dy
 		WithExpression(&exprBuilder)
 }
 
-func generateBlockUpdate(merchantID string, operation string) expression.UpdateBuilder {
-	var blockType string
-	if strings.EqualFold(operation, constants.BlockCardOperation) {
-		blockType = constants.PERMANENT
-	} else {
-		blockType = constants.TEMPORARY
-	}
-	newBlockedMerchant := types.BlockedMerchant{
-		ExpirationDate: generateExpirationDate(),
-		BlockType:      blockType,
+// UpdateCardBlockBuilder card-wide block, it applies to every merchant of the card.
+func UpdateCardBlockBuilder(cardBlock types.BlockedMerchant, blockedCard types.DynamoBlockedCard) *builder.UpdateItemBuilder {
+	newVersion := time.Now().UTC().UnixMilli()
+	update := expression.Set(expression.Name(constants.CardBlockField), expression.Value(cardBlock)).
+		Set(expression.Name(constants.TimeStamp), expression.Value(newVersion))
+	update = withBlockedCardTTL(update, blockedCard.BlockedMerchants, &cardBlock)
+	condition := expression.Name(constants.TimeStamp).Equal(expression.Value(blockedCard.TimeStamp)) // optimistic concurrency.
+	expr := expression.NewBuilder().
+		WithUpdate(update).
+		WithCondition(condition)
+
+	return builder.NewUpdateItemBuilder().
+		WithTable(os.Getenv(constants.DynamoBlockedCard)).
+		WithPartitionKey(constants.CardIdField, blockedCard.CardID).
+		WithExpression(&expr)
+}
+
+func RemoveExpiredBlockBuilder(merchantID string, blockedCard types.DynamoBlockedCard) *builder.UpdateItemBuilder {
+	return RemoveExpiredBlocksBuilder([]string{merchantID}, blockedCard)
+}
+
+func RemoveExpiredBlocksBuilder(merchantIDs []string, blockedCard types.DynamoBlockedCard) *builder.UpdateItemBuilder {
+	newVersion := time.Now().UTC().UnixMilli()
+	update := expression.Set(expression.Name(constants.TimeStamp), expression.Value(newVersion))
+	blockedMerchants := maps.Clone(blockedCard.BlockedMerchants)
+	for _, merchantID := range merchantIDs {
+		name := expression.Name(fmt.Sprintf("%s.%s", constants.BlockedMerchants, merchantID))
+		// the escalation outlives the block, only the block is removed.
+		escalation := withEscalation(types.BlockedMerchant{}, blockedCard.BlockedMerchants[merchantID])
+		if escalation.EscalationCount > 0 {
+			update = update.Set(name, expression.Value(escalation))
+			blockedMerchants[merchantID] = escalation
+			continue
+		}
+		update = update.Remove(name)
+		delete(blockedMerchants, merchantID)
 	}
+	update = withBlockedCardTTL(update, blockedMerchants, blockedCard.CardBlock)
+	condition := expression.Name(constants.TimeStamp).Equal(expression.Value(blockedCard.TimeStamp)) // optimistic concurrency.
+	expr := expression.NewBuilder().
+		WithUpdate(update).
+		WithCondition(condition)
+
+	return builder.NewUpdateItemBuilder().
+		WithTable(os.Getenv(constants.DynamoBlockedCard)).
+		WithPartitionKey(constants.CardIdField, blockedCard.CardID).
+		WithExpression(&expr)
+}
+
+func generateBlockUpdate(merchantID string, newBlockedMerchant types.BlockedMerchant, blockedCard types.DynamoBlockedCard) expression.UpdateBuilder {
 	newVersion := time.Now().UTC().UnixMilli()
-	return expression.Set(
+	update := expression.Set(
 		expression.Name(fmt.Sprintf("%s.%s", constants.BlockedMerchants, merchantID)),
 		expression.Value(newBlockedMerchant)).
 		Set(expression.Name(constants.TimeStamp), expression.Value(newVersion))
+
+	return withBlockedCardTTL(update, withMerchant(blockedCard.BlockedMerchants, merchantID, newBlockedMerchant), blockedCard.CardBlock)
+}
+
+// generateBlockedCardTTL epoch seconds when the card has nothing left to enforce, 0 while the card or a merchant is permanently blocked.
+func generateBlockedCardTTL(blockedMerchants map[string]types.BlockedMerchant, cardBlock *types.BlockedMerchant) int64 {
+	expiresAt := time.Now().UTC().Add(time.Duration(constants.BlockedCardRetentionHours) * time.Hour).UnixMilli()
+	if cardBlock != nil {
+		blockedMerchants = withMerchant(blockedMerchants, constants.CardBlockField, *cardBlock)
+	}
+	for _, blockedMerchant := range blockedMerchants {
+		if strings.EqualFold(blockedMerchant.BlockType, constants.PERMANENT) {
+			return 0
+		}
+		expiresAt = max(expiresAt, blockedMerchant.ExpirationDate)
+	}
+
+	return time.UnixMilli(expiresAt).Unix()
+}
+
+// withBlockedCardTTL stamps the TTL for the merchants left after the update, a permanent block removes it.
+func withBlockedCardTTL(
+	update expression.UpdateBuilder,
+	blockedMerchants map[string]types.BlockedMerchant,
+	cardBlock *types.BlockedMerchant,
+) expression.UpdateBuilder {
+	expiresAt := generateBlockedCardTTL(blockedMerchants, cardBlock)
+	if expiresAt == 0 {
+		return update.Remove(expression.Name(constants.ExpiresAtField))
+	}
+
+	return update.Set(expression.Name(constants.ExpiresAtField), expression.Value(expiresAt))
+}
+
+func withMerchant(
+	blockedMerchants map[string]types.BlockedMerchant,
+	merchantID string,
+	blockedMerchant types.BlockedMerchant,
+) map[string]types.BlockedMerchant {
+	merged := maps.Clone(blockedMerchants)
+	if merged == nil {
+		merged = make(map[string]types.BlockedMerchant, 1)
+	}
+	merged[merchantID] = blockedMerchant
+
+	return merged
+}
+
+// withEscalation carries the escalation of the current merchant entry into the one replacing it.
+func withEscalation(blockedMerchant types.BlockedMerchant, current types.BlockedMerchant) types.BlockedMerchant {
+	blockedMerchant.EscalationCount = current.EscalationCount
+	blockedMerchant.EscalationStart = current.EscalationStart
+
+	return blockedMerchant
+}
+
+func UnblockMerchantBuilder(merchantID string, action types.AdminAction, blockedCard types.DynamoBlockedCard) *builder.UpdateItemBuilder {
+	update := expression.Remove(expression.Name(fmt.Sprintf("%s.%s", constants.BlockedMerchants, merchantID)))
+	blockedMerchants := maps.Clone(blockedCard.BlockedMerchants)
+	delete(blockedMerchants, merchantID)
+
+	return adminUpdateBuilder(withBlockedCardTTL(update, blockedMerchants, blockedCard.CardBlock), action, blockedCard)
+}
+
+func UnblockAllMerchantsBuilder(action types.AdminAction, blockedCard types.DynamoBlockedCard) *builder.UpdateItemBuilder {
+	update := expression.Set(
+		expression.Name(constants.BlockedMerchants),
+		expression.Value(map[string]types.BlockedMerchant{})).
+		Remove(expression.Name(constants.CardBlockField))
+
+	return adminUpdateBuilder(withBlockedCardTTL(update, nil, nil), action, blockedCard)
+}
+
+func AdminBlockCardBuilder(
+	merchantID string,
+	newBlockedMerchant types.BlockedMerchant,
+	action types.AdminAction,
+	blockedCard types.DynamoBlockedCard,
+) *builder.UpdateItemBuilder {
+	update := expression.Set(
+		expression.Name(fmt.Sprintf("%s.%s", constants.BlockedMerchants, merchantID)),
+		expression.Value(newBlockedMerchant))
+	update = withBlockedCardTTL(update, withMerchant(blockedCard.BlockedMerchants, merchantID, newBlockedMerchant), blockedCard.CardBlock)
+
+	return adminUpdateBuilder(update, action, blockedCard)
 }
 
-func generateExpirationDate() int64 {
-	timeToAdd := time.Hour * time.Duration(constants.DayHours)
+func ResetLastRetryBuilder(merchantID string, action types.AdminAction, blockedCard types.DynamoBlockedCard) *builder.UpdateItemBuilder {
+	update := expression.UpdateBuilder{}
+	if _, ok := blockedCard.BlockedMerchants[merchantID]; ok {
+		update = update.Remove(expression.Name(fmt.Sprintf("%s.%s.%s", constants.BlockedMerchants, merchantID, constants.LastRetryField))).
+			Remove(expression.Name(fmt.Sprintf("%s.%s.%s", constants.BlockedMerchants, merchantID, constants.RetriesEndField)))
+	}
 
-	return time.Now().UTC().Add(timeToAdd).UnixMilli()
+	return adminUpdateBuilder(withBlockedCardTTL(update, blockedCard.BlockedMerchants, blockedCard.CardBlock), action, blockedCard)
+}
+
+// adminUpdateBuilder records the operator action along with the update.
+func adminUpdateBuilder(update expression.UpdateBuilder, action types.AdminAction, blockedCard types.DynamoBlockedCard) *builder.UpdateItemBuilder {
+	newVersion := time.Now().UTC().UnixMilli()
+	update = update.
+		Set(expression.Name(constants.LastAdminActionField), expression.Value(action)).
+		Set(expression.Name(constants.TimeStamp), expression.Value(newVersion))
+	condition := expression.Name(constants.TimeStamp).Equal(expression.Value(blockedCard.TimeStamp)) // optimistic concurrency.
+	expr := expression.NewBuilder().
+		WithUpdate(update).
+		WithCondition(condition)
+
+	return builder.NewUpdateItemBuilder().
+		WithTable(os.Getenv(constants.DynamoBlockedCard)).
+		WithPartitionKey(constants.CardIdField, blockedCard.CardID).
+		WithExpression(&expr)
+}
+
+func PutBlockHistoryBuilder(entry types.BlockHistoryEntry) *builder.PutItemBuilder {
+	condition := expression.Name(constants.EventKeyField).AttributeNotExists() // append-only.
+	expr := expression.NewBuilder().
+		WithCondition(condition)
+
+	return builder.NewPutItemBuilder().
+		WithItem(entry).
+		WithTable(os.Getenv(constants.DynamoBlockHistory)).
+		WithExpression(&expr)
+}
+
+func QueryBlockHistoryBuilder(request types.BlockHistoryRequest, limit int32) *builder.QueryBuilder {
+	to := fmt.Sprintf(constants.EventKeyFormat, request.To, "~")
+	if request.Cursor != "" {
+		to = request.Cursor
+	} else if request.To == 0 {
+		to = fmt.Sprintf(constants.EventKeyFormat, time.Now().UTC().UnixMilli(), "~")
+	}
+	keyCondition := expression.Key(constants.CardIdField).
+		Equal(expression.Value(request.CardID)).
+		And(expression.Key(constants.EventKeyField).Between(
+			expression.Value(fmt.Sprintf(constants.EventKeyFormat, request.From, "")),
+			expression.Value(to)))
+
+	exprBuilder := expression.NewBuilder().
+		WithKeyCondition(keyCondition)
+
+	return builder.NewQueryBuilder().
+		WithTable(os.Getenv(constants.DynamoBlockHistory)).
+		WithExpression(&exprBuilder).
+		WithScanIndexForward(false).
+		WithLimit(limit)
+}
+
+func GetRetryPolicyBuilder(policyID string) *builder.GetItemBuilder {
+	return builder.NewGetItemBuilder().
+		WithTable(os.Getenv(constants.DynamoRetryPolicy)).
+		WithPartitionKey(constants.PolicyIDField, policyID)
+}
+
+// IncrementShadowReportBuilder adds one evaluation of the shadow policy to the row of its version, brand and merchant.
+func IncrementShadowReportBuilder(
+	policy types.RetryPolicy,
+	request types.BlockCardRequest,
+	activeBlocked bool,
+	shadowBlocked bool,
+) *builder.UpdateItemBuilder {
+	brand := strings.ToUpper(request.Franchise)
+	update := expression.Set(expression.Name(constants.VersionField), expression.Value(policy.Version)).
+		Set(expression.Name(constants.BrandField), expression.Value(brand)).
+		Set(expression.Name(constants.MerchantIDField), expression.Value(request.MerchantIdentifier)).
+		Add(expression.Name(constants.EvaluationsField), expression.Value(1)).
+		Add(expression.Name(constants.ActiveBlocksField), expression.Value(countDecision(activeBlocked))).
+		Add(expression.Name(constants.ShadowBlocksField), expression.Value(countDecision(shadowBlocked))).
+		Add(expression.Name(constants.ExtraBlocksField), expression.Value(countDecision(shadowBlocked && !activeBlocked))).
+		Add(expression.Name(constants.FewerBlocksField), expression.Value(countDecision(activeBlocked && !shadowBlocked)))
+	expr := expression.NewBuilder().
+		WithUpdate(update)
+
+	return builder.NewUpdateItemBuilder().
+		WithTable(os.Getenv(constants.DynamoShadowReport)).
+		WithPartitionKey(constants.PolicyIDField, policy.PolicyID).
+		WithSortKey(constants.ReportKeyField, fmt.Sprintf(constants.ReportKeyFormat, policy.Version, brand, request.MerchantIdentifier)).
+		WithExpression(&expr)
+}
+
+func countDecision(blocked bool) int {
+	if blocked {
+		return 1
+	}
+
+	return 0
+}
+
+// QueryShadowReportBuilder pages the rows of a shadow policy, only the ones of the version when it is set.
+func QueryShadowReportBuilder(request types.ShadowReportRequest, lastRow types.ShadowReportRow, limit int32) *builder.QueryBuilder {
+	keyCondition := expression.Key(constants.PolicyIDField).
+		Equal(expression.Value(request.PolicyID))
+	if request.Version != "" {
+		keyCondition = keyCondition.And(expression.Key(constants.ReportKeyField).
+			BeginsWith(request.Version + "#"))
+	}
+
+	expr := expression.NewBuilder().
+		WithKeyCondition(keyCondition)
+
+	query := builder.NewQueryBuilder().
+		WithTable(os.Getenv(constants.DynamoShadowReport)).
+		WithExpression(&expr).
+		WithLimit(limit)
+	if lastRow.ReportKey != "" {
+		query = query.WithExclusiveStartKey(map[string]dynamoTypes.AttributeValue{
+			constants.PolicyIDField:  &dynamoTypes.AttributeValueMemberS{Value: lastRow.PolicyID},
+			constants.ReportKeyField: &dynamoTypes.AttributeValueMemberS{Value: lastRow.ReportKey},
+		})
+	}
+
+	return query
+}
+
+func GetMerchantSettingsBuilder(merchantID string) *builder.GetItemBuilder {
+	return builder.NewGetItemBuilder().
+		WithTable(os.Getenv(constants.DynamoMerchantSettings)).
+		WithPartitionKey(constants.MerchantIDField, merchantID)
 }
 
 func DeleteCardRetryBuilder(key string) *builder.DeleteItemBuilder {
@@ -115,20 +411,133 @@ func DeleteCardRetryBuilder(key string) *builder.DeleteItemBuilder {
 		WithPartitionKey(constants.RetryKeyField, key)
 }
 
-func QueryRetriesBuilder(cardID string, merchantID string) *builder.QueryBuilder {
+func QueryCardRetriesBuilder(cardID string, merchantID string) *builder.QueryBuilder {
 	keyCondition := expression.Key(constants.CardIdField).
 		Equal(expression.Value(cardID)).
 		And(expression.Key(constants.MerchantIDField).
 			Equal(expression.Value(merchantID)))
 
-	filter := expression.Name(constants.RetryKeyField).Contains(constants.Daily)
-
 	expr := expression.NewBuilder().
-		WithKeyCondition(keyCondition).
-		WithFilter(filter)
+		WithKeyCondition(keyCondition)
 
 	return builder.NewQueryBuilder().
 		WithTable(os.Getenv(constants.DynamoCardRetry)).
 		WithIndexName(constants.CardIdMerchantIndex).
 		WithExpression(&expr)
 }
+
+// QueryRestoreRetriesBuilder pages the retries of a card, only the ones of the merchant when merchantID is set.
+func QueryRestoreRetriesBuilder(cardID string, merchantID string, lastRetry types.CardRetry, limit int32) *builder.QueryBuilder {
+	keyCondition := expression.Key(constants.CardIdField).
+		Equal(expression.Value(cardID))
+	if merchantID != "" {
+		keyCondition = keyCondition.And(expression.Key(constants.MerchantIDField).
+			Equal(expression.Value(merchantID)))
+	}
+
+	expr := expression.NewBuilder().
+		WithKeyCondition(keyCondition)
+
+	query := builder.NewQueryBuilder().
+		WithTable(os.Getenv(constants.DynamoCardRetry)).
+		WithIndexName(constants.CardIdMerchantIndex).
+		WithExpression(&expr).
+		WithLimit(limit)
+	if lastRetry.RetryKey != "" {
+		query = query.WithExclusiveStartKey(retryIndexStartKey(lastRetry))
+	}
+
+	return query
+}
+
+// QueryMerchantRetriesBuilder pages the retries of every card of the merchant.
+func QueryMerchantRetriesBuilder(merchantID string, lastRetry types.CardRetry, limit int32) *builder.QueryBuilder {
+	keyCondition := expression.Key(constants.MerchantIDField).
+		Equal(expression.Value(merchantID))
+
+	expr := expression.NewBuilder().
+		WithKeyCondition(keyCondition)
+
+	query := builder.NewQueryBuilder().
+		WithTable(os.Getenv(constants.DynamoCardRetry)).
+		WithIndexName(constants.MerchantIdCardIndex).
+		WithExpression(&expr).
+		WithLimit(limit)
+	if lastRetry.RetryKey != "" {
+		query = query.WithExclusiveStartKey(retryIndexStartKey(lastRetry))
+	}
+
+	return query
+}
+
+// retryIndexStartKey an index page starts after the table key plus the index keys of the last item.
+func retryIndexStartKey(lastRetry types.CardRetry) map[string]dynamoTypes.AttributeValue {
+	return map[string]dynamoTypes.AttributeValue{
+		constants.RetryKeyField:   &dynamoTypes.AttributeValueMemberS{Value: lastRetry.RetryKey},
+		constants.CardIdField:     &dynamoTypes.AttributeValueMemberS{Value: lastRetry.CardID},
+		constants.MerchantIDField: &dynamoTypes.AttributeValueMemberS{Value: lastRetry.MerchantID},
+	}
+}
+
+func ScanBlockedCardsBuilder(lastCardID string, limit int32) *builder.ScanBuilder {
+	scan := builder.NewScanBuilder().
+		WithTable(os.Getenv(constants.DynamoBlockedCard)).
+		WithLimit(limit)
+	if lastCardID != "" {
+		scan = scan.WithExclusiveStartKey(map[string]dynamoTypes.AttributeValue{
+			constants.CardIdField: &dynamoTypes.AttributeValueMemberS{Value: lastCardID},
+		})
+	}
+
+	return scan
+}
+
+func ScanCardRetriesBuilder(lastRetryKey string, limit int32) *builder.ScanBuilder {
+	scan := builder.NewScanBuilder().
+		WithTable(os.Getenv(constants.DynamoCardRetry)).
+		WithLimit(limit)
+	if lastRetryKey != "" {
+		scan = scan.WithExclusiveStartKey(map[string]dynamoTypes.AttributeValue{
+			constants.RetryKeyField: &dynamoTypes.AttributeValueMemberS{Value: lastRetryKey},
+		})
+	}
+
+	return scan
+}
+
+func DeleteStaleRetryBuilder(cardRetry types.CardRetry) *builder.DeleteItemBuilder {
+	condition := expression.Name(constants.TimeStamp).Equal(expression.Value(cardRetry.TimeStamp)) // optimistic concurrency.
+	expr := expression.NewBuilder().
+		WithCondition(condition)
+
+	return builder.NewDeleteItemBuilder().
+		WithTable(os.Getenv(constants.DynamoCardRetry)).
+		WithPartitionKey(constants.RetryKeyField, cardRetry.RetryKey).
+		WithExpression(&expr)
+}
+
+// PutProcessedMessageBuilder only the first delivery writes the record, an expired one waiting for the TTL is replaced.
+func PutProcessedMessageBuilder(record types.ProcessedMessage, now int64) *builder.PutItemBuilder {
+	condition := expression.Name(constants.IdempotencyKeyField).AttributeNotExists().
+		Or(expression.Name(constants.ExpiresAtField).LessThanEqual(expression.Value(now)))
+	expr := expression.NewBuilder().
+		WithCondition(condition)
+
+	return builder.NewPutItemBuilder().
+		WithItem(record).
+		WithTable(os.Getenv(constants.DynamoProcessedMessage)).
+		WithExpression(&expr)
+}
+
+// CompleteProcessedMessageBuilder replaces the claim in progress with the record of the processed message.
+func CompleteProcessedMessageBuilder(record types.ProcessedMessage) *builder.PutItemBuilder {
+	return builder.NewPutItemBuilder().
+		WithItem(record).
+		WithTable(os.Getenv(constants.DynamoProcessedMessage))
+}
+
+func DeleteProcessedMessageBuilder(key string) *builder.DeleteItemBuilder {
+	return builder.NewDeleteItemBuilder().
+		WithTable(os.Getenv(constants.DynamoProcessedMessage)).
+		WithPartitionKey(constants.IdempotencyKeyField, key)
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"strconv"
 	"testing"
+	"time"
 
 	constants "bitbucket.org/kushki/usrv-card-control"
 	"bitbucket.org/kushki/usrv-card-control/types"
@@ -29,6 +30,7 @@ func TestPutBlockedCardBuilder(t *testing.T) {
 	if err != nil {
 		t.Fatal("error marshal item", err.Error())
 	}
+	item.ExpiresAt = generateBlockedCardTTL(nil, nil)
 	itemMarshal, err := attributevalue.MarshalMap(item)
 	expected := &dynamodb.PutItemInput{
 		Item:      itemMarshal,
@@ -43,7 +45,7 @@ func TestUpdateLastRetryBuilder(t *testing.T) {
 
 	item := types.DynamoBlockedCard{}
 	item.CardID = "cardID123"
-	res := UpdateLastRetryBuilder(mockTimeStamp, mockMerchantID, item)
+	res := UpdateLastRetryBuilder(mockTimeStamp, 0, mockMerchantID, item)
 	build, err := res.BuildInput()
 
 	expected := &dynamodb.UpdateItemInput{
@@ -76,25 +78,25 @@ func TestUpdateBlockCardBuilder(t *testing.T) {
 	t.Setenv(constants.DynamoBlockedCard, mockTableName)
 	itemRequest := types.BlockCardRequest{}
 
-	t.Run("should build UpdateBlockCardBuilder with block operation is RetryCardOperation", func(t *testing.T) {
+	t.Run("should build UpdateBlockCardBuilder with TEMPORARY block type", func(t *testing.T) {
 		itemRequest.CardID = "cardID123"
 		itemRequest.Operation = constants.RetryCardOperation
 		itemRequest.MerchantIdentifier = mockMerchantID
 		itemCardBlocked := types.DynamoBlockedCard{}
-		assertUpdateBlockCardBuilder(t, itemRequest, itemCardBlocked)
+		assertUpdateBlockCardBuilder(t, itemRequest, types.BlockedMerchant{BlockType: constants.TEMPORARY}, itemCardBlocked)
 	})
 
-	t.Run("should build UpdateBlockCardBuilder with block operation is BlockCardOperation", func(t *testing.T) {
+	t.Run("should build UpdateBlockCardBuilder with PERMANENT block type", func(t *testing.T) {
 		itemRequest.CardID = "cardID854"
 		itemRequest.MerchantIdentifier = mockMerchantID
 		itemRequest.Operation = constants.BlockCardOperation
 		itemCardBlocked := types.DynamoBlockedCard{}
-		assertUpdateBlockCardBuilder(t, itemRequest, itemCardBlocked)
+		assertUpdateBlockCardBuilder(t, itemRequest, types.BlockedMerchant{BlockType: constants.PERMANENT}, itemCardBlocked)
 	})
 }
 
-func assertUpdateBlockCardBuilder(t *testing.T, itemRequest types.BlockCardRequest, itemCardBlocked types.DynamoBlockedCard) {
-	res := UpdateBlockCardBuilder(itemRequest, itemCardBlocked)
+func assertUpdateBlockCardBuilder(t *testing.T, itemRequest types.BlockCardRequest, blockedMerchant types.BlockedMerchant, itemCardBlocked types.DynamoBlockedCard) {
+	res := UpdateBlockCardBuilder(itemRequest, blockedMerchant, itemCardBlocked)
 	build, err := res.BuildInput()
 
 	expected := &dynamodb.UpdateItemInput{
@@ -123,6 +125,73 @@ func assertUpdateBlockCardBuilder(t *testing.T, itemRequest types.BlockCardReque
 	assert.NoError(t, err)
 }
 
+func TestRemoveExpiredBlockBuilder(t *testing.T) {
+	t.Setenv(constants.DynamoBlockedCard, mockTableName)
+	blockedCard := types.DynamoBlockedCard{CardID: "cardID123", TimeStamp: mockTimeStamp}
+
+	build, err := RemoveExpiredBlockBuilder(mockMerchantID, blockedCard).BuildInput()
+	assert.NoError(t, err)
+	assert.Equal(t, aws.String(mockTableName), build.TableName)
+	assert.Contains(t, *build.UpdateExpression, "REMOVE")
+	assert.ElementsMatch(t, []string{
+		constants.BlockedMerchants, mockMerchantID, constants.TimeStamp, constants.ExpiresAtField,
+	}, mapValues(build.ExpressionAttributeNames))
+	assert.NotNil(t, build.ConditionExpression)
+}
+
+func TestRemoveExpiredBlocksBuilder(t *testing.T) {
+	t.Setenv(constants.DynamoBlockedCard, mockTableName)
+	blockedCard := types.DynamoBlockedCard{CardID: "cardID123", TimeStamp: mockTimeStamp}
+
+	build, err := RemoveExpiredBlocksBuilder([]string{mockMerchantID, "merchantID2"}, blockedCard).BuildInput()
+	assert.NoError(t, err)
+	assert.Contains(t, *build.UpdateExpression, "REMOVE")
+	assert.ElementsMatch(t, []string{
+		constants.BlockedMerchants, mockMerchantID, "merchantID2", constants.TimeStamp, constants.ExpiresAtField,
+	}, mapValues(build.ExpressionAttributeNames))
+	assert.Equal(t, &typesDynamo.AttributeValueMemberS{Value: "cardID123"}, build.Key[constants.CardIdField])
+	assert.NotNil(t, build.ConditionExpression)
+}
+
+func TestScanBlockedCardsBuilder(t *testing.T) {
+	t.Setenv(constants.DynamoBlockedCard, mockTableName)
+
+	first, err := ScanBlockedCardsBuilder("", 10).BuildInput()
+	assert.NoError(t, err)
+	assert.Equal(t, &dynamodb.ScanInput{TableName: aws.String(mockTableName), Limit: aws.Int32(10)}, first)
+
+	next, err := ScanBlockedCardsBuilder("cardID123", 10).BuildInput()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]typesDynamo.AttributeValue{
+		constants.CardIdField: &typesDynamo.AttributeValueMemberS{Value: "cardID123"},
+	}, next.ExclusiveStartKey)
+}
+
+func TestScanCardRetriesBuilder(t *testing.T) {
+	t.Setenv(constants.DynamoCardRetry, mockTableName)
+
+	first, err := ScanCardRetriesBuilder("", 10).BuildInput()
+	assert.NoError(t, err)
+	assert.Equal(t, &dynamodb.ScanInput{TableName: aws.String(mockTableName), Limit: aws.Int32(10)}, first)
+
+	next, err := ScanCardRetriesBuilder("retryKey1", 10).BuildInput()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]typesDynamo.AttributeValue{
+		constants.RetryKeyField: &typesDynamo.AttributeValueMemberS{Value: "retryKey1"},
+	}, next.ExclusiveStartKey)
+}
+
+func TestDeleteStaleRetryBuilder(t *testing.T) {
+	t.Setenv(constants.DynamoCardRetry, mockTableName)
+
+	build, err := DeleteStaleRetryBuilder(types.CardRetry{RetryKey: "retryKey1", TimeStamp: mockTimeStamp}).BuildInput()
+	assert.NoError(t, err)
+	assert.Equal(t, aws.String(mockTableName), build.TableName)
+	assert.Equal(t, &typesDynamo.AttributeValueMemberS{Value: "retryKey1"}, build.Key[constants.RetryKeyField])
+	assert.Equal(t, &typesDynamo.AttributeValueMemberN{Value: strconv.Itoa(mockTimeStamp)}, build.ExpressionAttributeValues[":0"])
+	assert.NotNil(t, build.ConditionExpression)
+}
+
 func TestIncrementRetryBuilder(t *testing.T) {
 	t.Setenv(constants.DynamoCardRetry, mockTableName)
 
@@ -132,7 +201,7 @@ func TestIncrementRetryBuilder(t *testing.T) {
 	itemRequest.MerchantIdentifier = mockMerchantID
 	const mockKey = "key123"
 	mockRetries := []int64{mockTimeStamp}
-	res := IncrementRetryBuilder(mockRetries, itemRequest, mockKey, itemCardRetry)
+	res := IncrementRetryBuilder(mockRetries, itemRequest, mockKey, itemCardRetry, constants.DayHours)
 	build, err := res.BuildInput()
 
 	expected := &dynamodb.UpdateItemInput{
@@ -169,6 +238,82 @@ func TestIncrementRetryBuilder(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestIncrementRetryBuilder_TTL(t *testing.T) {
+	t.Setenv(constants.DynamoCardRetry, mockTableName)
+	before := time.Now().Add(72 * time.Hour).Unix()
+
+	build, err := IncrementRetryBuilder([]int64{mockTimeStamp}, types.BlockCardRequest{}, "key123", types.CardRetry{}, 72).BuildInput()
+
+	assert.NoError(t, err)
+	assert.Contains(t, mapValues(build.ExpressionAttributeNames), constants.ExpiresAtField)
+	assertTTLValue(t, build.ExpressionAttributeValues, before)
+}
+
+func TestGenerateBlockedCardTTL(t *testing.T) {
+	retention := time.Now().Add(constants.BlockedCardRetentionHours * time.Hour).Unix()
+	longBlock := time.Now().Add(constants.BlockedCardRetentionHours * 2 * time.Hour)
+
+	assert.GreaterOrEqual(t, generateBlockedCardTTL(nil, nil), retention)
+	assert.Equal(t, longBlock.Unix(), generateBlockedCardTTL(map[string]types.BlockedMerchant{
+		"merchantA": {BlockType: constants.TEMPORARY, ExpirationDate: longBlock.UnixMilli()},
+		"merchantB": {LastRetry: mockTimeStamp},
+	}, nil))
+	assert.Zero(t, generateBlockedCardTTL(map[string]types.BlockedMerchant{
+		"merchantA": {BlockType: constants.TEMPORARY, ExpirationDate: longBlock.UnixMilli()},
+		"merchantB": {BlockType: constants.PERMANENT},
+	}, nil))
+	assert.Zero(t, generateBlockedCardTTL(nil, &types.BlockedMerchant{BlockType: constants.PERMANENT}))
+	assert.Equal(t, longBlock.Unix(), generateBlockedCardTTL(nil, &types.BlockedMerchant{
+		BlockType: constants.TEMPORARY, ExpirationDate: longBlock.UnixMilli(),
+	}))
+}
+
+func TestUpdateBlockCardBuilder_TTL(t *testing.T) {
+	t.Setenv(constants.DynamoBlockedCard, mockTableName)
+	request := types.BlockCardRequest{CardID: "cardID123", MerchantIdentifier: mockMerchantID}
+	before := time.Now().Add(constants.BlockedCardRetentionHours * time.Hour).Unix()
+
+	t.Run("should stamp the TTL for a temporary block", func(t *testing.T) {
+		blockedMerchant := types.BlockedMerchant{BlockType: constants.TEMPORARY, ExpirationDate: time.Now().Add(time.Hour).UnixMilli()}
+		build, err := UpdateBlockCardBuilder(request, blockedMerchant, types.DynamoBlockedCard{}).BuildInput()
+		assert.NoError(t, err)
+		assert.NotContains(t, *build.UpdateExpression, "REMOVE")
+		assert.Contains(t, mapValues(build.ExpressionAttributeNames), constants.ExpiresAtField)
+		assertTTLValue(t, build.ExpressionAttributeValues, before)
+	})
+
+	t.Run("should remove the TTL for a permanent block", func(t *testing.T) {
+		build, err := UpdateBlockCardBuilder(request, types.BlockedMerchant{BlockType: constants.PERMANENT}, types.DynamoBlockedCard{}).BuildInput()
+		assert.NoError(t, err)
+		assert.Contains(t, *build.UpdateExpression, "REMOVE")
+		assert.Contains(t, mapValues(build.ExpressionAttributeNames), constants.ExpiresAtField)
+	})
+
+	t.Run("should keep the TTL removed while another merchant is permanently blocked", func(t *testing.T) {
+		blockedCard := types.DynamoBlockedCard{BlockedMerchants: map[string]types.BlockedMerchant{
+			"otherMerchant": {BlockType: constants.PERMANENT},
+		}}
+		build, err := UpdateLastRetryBuilder(mockTimeStamp, 0, mockMerchantID, blockedCard).BuildInput()
+		assert.NoError(t, err)
+		assert.Contains(t, *build.UpdateExpression, "REMOVE")
+	})
+}
+
+// assertTTLValue the only expression value holding epoch seconds at or after min.
+func assertTTLValue(t *testing.T, values map[string]typesDynamo.AttributeValue, min int64) {
+	t.Helper()
+	for _, value := range values {
+		number, ok := value.(*typesDynamo.AttributeValueMemberN)
+		if !ok {
+			continue
+		}
+		if seconds, err := strconv.ParseInt(number.Value, 10, 64); err == nil && seconds >= min && seconds < min+60 {
+			return
+		}
+	}
+	t.Errorf("no TTL value from %d in %v", min, values)
+}
+
 func TestGetRetryBuilder(t *testing.T) {
 	t.Setenv(constants.DynamoCardRetry, mockTableName)
 	assertions := assert.New(t)
@@ -207,6 +352,38 @@ func TestGetBlockedCardBuilder(t *testing.T) {
 	assertions.NoError(err)
 }
 
+func TestGetRetryPolicyBuilder(t *testing.T) {
+	t.Setenv(constants.DynamoRetryPolicy, mockTableName)
+	assertions := assert.New(t)
+	policyID := "VISA#*#*"
+
+	input, err := GetRetryPolicyBuilder(policyID).BuildInput()
+	expected := &dynamodb.GetItemInput{
+		TableName: aws.String(mockTableName),
+		Key: map[string]typesDynamo.AttributeValue{
+			constants.PolicyIDField: &typesDynamo.AttributeValueMemberS{
+				Value: policyID,
+			},
+		},
+	}
+	assertions.Equal(expected, input)
+	assertions.NoError(err)
+}
+
+func TestGetMerchantSettingsBuilder(t *testing.T) {
+	t.Setenv(constants.DynamoMerchantSettings, mockTableName)
+
+	input, err := GetMerchantSettingsBuilder("merchant1").BuildInput()
+
+	assert.NoError(t, err)
+	assert.Equal(t, &dynamodb.GetItemInput{
+		TableName: aws.String(mockTableName),
+		Key: map[string]typesDynamo.AttributeValue{
+			constants.MerchantIDField: &typesDynamo.AttributeValueMemberS{Value: "merchant1"},
+		},
+	}, input)
+}
+
 func TestDeleteCardRetryBuilder(t *testing.T) {
 	t.Setenv(constants.DynamoCardRetry, mockTableName)
 	assertions := assert.New(t)
@@ -225,30 +402,364 @@ func TestDeleteCardRetryBuilder(t *testing.T) {
 	assertions.NoError(err)
 }
 
-func TestQueryRetriesBuilder(t *testing.T) {
+func TestQueryRestoreRetriesBuilder(t *testing.T) {
 	assertions := assert.New(t)
 
 	t.Setenv(constants.DynamoCardRetry, mockTableName)
-	resp := QueryRetriesBuilder("cardID123", mockMerchantID)
+	resp := QueryRestoreRetriesBuilder("cardID123", mockMerchantID, types.CardRetry{}, 10)
 	build, err := resp.BuildInput()
 
+	expected := &dynamodb.QueryInput{
+		TableName: aws.String(mockTableName),
+		IndexName: aws.String(constants.CardIdMerchantIndex),
+		Limit:     aws.Int32(10),
+		ExpressionAttributeValues: map[string]typesDynamo.AttributeValue{
+			":0": &typesDynamo.AttributeValueMemberS{Value: "cardID123"},
+			":1": &typesDynamo.AttributeValueMemberS{Value: mockMerchantID},
+		},
+		ExpressionAttributeNames: map[string]string{
+			"#0": constants.CardIdField,
+			"#1": constants.MerchantIDField,
+		},
+		KeyConditionExpression: aws.String("(#0 = :0) AND (#1 = :1)"),
+	}
+
+	assertions.Equal(expected, build)
+	assertions.NoError(err)
+
+	lastRetry := types.CardRetry{RetryKey: "key", CardID: "cardID123", MerchantID: mockMerchantID}
+	build, err = QueryRestoreRetriesBuilder("cardID123", "", lastRetry, 10).BuildInput()
+
+	assertions.NoError(err)
+	assertions.Equal("#0 = :0", *build.KeyConditionExpression)
+	assertions.Equal(&typesDynamo.AttributeValueMemberS{Value: "key"}, build.ExclusiveStartKey[constants.RetryKeyField])
+	assertions.Len(build.ExclusiveStartKey, 3)
+}
+
+func TestQueryMerchantRetriesBuilder(t *testing.T) {
+	t.Setenv(constants.DynamoCardRetry, mockTableName)
+
+	build, err := QueryMerchantRetriesBuilder(mockMerchantID, types.CardRetry{}, 10).BuildInput()
+
+	assert.NoError(t, err)
+	assert.Equal(t, mockTableName, *build.TableName)
+	assert.Equal(t, constants.MerchantIdCardIndex, *build.IndexName)
+	assert.Equal(t, "#0 = :0", *build.KeyConditionExpression)
+	assert.Equal(t, map[string]string{"#0": constants.MerchantIDField}, build.ExpressionAttributeNames)
+	assert.Equal(t, &typesDynamo.AttributeValueMemberS{Value: mockMerchantID}, build.ExpressionAttributeValues[":0"])
+	assert.Nil(t, build.ExclusiveStartKey)
+
+	lastRetry := types.CardRetry{RetryKey: "key", CardID: "cardID123", MerchantID: mockMerchantID}
+	build, err = QueryMerchantRetriesBuilder(mockMerchantID, lastRetry, 10).BuildInput()
+
+	assert.NoError(t, err)
+	assert.Equal(t, &typesDynamo.AttributeValueMemberS{Value: "cardID123"}, build.ExclusiveStartKey[constants.CardIdField])
+	assert.Len(t, build.ExclusiveStartKey, 3)
+}
+
+func TestQueryCardRetriesBuilder(t *testing.T) {
+	assertions := assert.New(t)
+
+	t.Setenv(constants.DynamoCardRetry, mockTableName)
+	build, err := QueryCardRetriesBuilder("cardID123", mockMerchantID).BuildInput()
+
 	expected := &dynamodb.QueryInput{
 		TableName: aws.String(mockTableName),
 		IndexName: aws.String(constants.CardIdMerchantIndex),
 		ExpressionAttributeValues: map[string]typesDynamo.AttributeValue{
-			":0": &typesDynamo.AttributeValueMemberS{Value: constants.Daily},
-			":1": &typesDynamo.AttributeValueMemberS{Value: "cardID123"},
-			":2": &typesDynamo.AttributeValueMemberS{Value: mockMerchantID},
+			":0": &typesDynamo.AttributeValueMemberS{Value: "cardID123"},
+			":1": &typesDynamo.AttributeValueMemberS{Value: mockMerchantID},
 		},
 		ExpressionAttributeNames: map[string]string{
-			"#0": constants.RetryKeyField,
-			"#1": constants.CardIdField,
-			"#2": constants.MerchantIDField,
+			"#0": constants.CardIdField,
+			"#1": constants.MerchantIDField,
 		},
-		FilterExpression:       aws.String("contains (#0, :0)"),
-		KeyConditionExpression: aws.String("(#1 = :1) AND (#2 = :2)"),
+		KeyConditionExpression: aws.String("(#0 = :0) AND (#1 = :1)"),
 	}
 
 	assertions.Equal(expected, build)
 	assertions.NoError(err)
 }
+
+func TestAdminBuilders(t *testing.T) {
+	t.Setenv(constants.DynamoBlockedCard, mockTableName)
+	action := types.AdminAction{Operation: constants.AdminUnblockOperation, Operator: "operator", Reason: "issuer confirmed"}
+	blockedCard := types.DynamoBlockedCard{
+		CardID:    "cardID123",
+		TimeStamp: mockTimeStamp,
+		BlockedMerchants: map[string]types.BlockedMerchant{
+			mockMerchantID: {BlockType: constants.PERMANENT},
+		},
+	}
+
+	t.Run("should remove the merchant block and record the action", func(t *testing.T) {
+		build, err := UnblockMerchantBuilder(mockMerchantID, action, blockedCard).BuildInput()
+		assert.NoError(t, err)
+		assert.Equal(t, aws.String(mockTableName), build.TableName)
+		assert.Contains(t, *build.UpdateExpression, "REMOVE")
+		assert.Contains(t, build.ExpressionAttributeNames, "#0")
+		assert.ElementsMatch(t, []string{
+			constants.BlockedMerchants, mockMerchantID, constants.LastAdminActionField, constants.TimeStamp, constants.ExpiresAtField,
+		}, mapValues(build.ExpressionAttributeNames))
+		assert.NotNil(t, build.ConditionExpression)
+	})
+
+	t.Run("should replace all merchant blocks and remove the card block", func(t *testing.T) {
+		build, err := UnblockAllMerchantsBuilder(action, blockedCard).BuildInput()
+		assert.NoError(t, err)
+		assert.Contains(t, *build.UpdateExpression, "REMOVE")
+		assert.Contains(t, mapValues(build.ExpressionAttributeNames), constants.BlockedMerchants)
+		assert.Contains(t, mapValues(build.ExpressionAttributeNames), constants.CardBlockField)
+	})
+
+	t.Run("should force the requested block type", func(t *testing.T) {
+		blockedMerchant := types.BlockedMerchant{BlockType: constants.TEMPORARY, ExpirationDate: time.Now().Add(time.Hour).UnixMilli()}
+		build, err := AdminBlockCardBuilder(mockMerchantID, blockedMerchant, action, blockedCard).BuildInput()
+		assert.NoError(t, err)
+		assert.Contains(t, mapValues(build.ExpressionAttributeNames), mockMerchantID)
+	})
+
+	t.Run("should only remove the last retry when the merchant exists", func(t *testing.T) {
+		build, err := ResetLastRetryBuilder(mockMerchantID, action, blockedCard).BuildInput()
+		assert.NoError(t, err)
+		assert.Contains(t, *build.UpdateExpression, "REMOVE")
+		assert.Contains(t, mapValues(build.ExpressionAttributeNames), constants.LastRetryField)
+		assert.Contains(t, mapValues(build.ExpressionAttributeNames), constants.RetriesEndField)
+
+		build, err = ResetLastRetryBuilder("otherMerchant", action, blockedCard).BuildInput()
+		assert.NoError(t, err)
+		assert.NotContains(t, mapValues(build.ExpressionAttributeNames), constants.LastRetryField)
+	})
+}
+
+func mapValues(values map[string]string) []string {
+	out := make([]string, 0, len(values))
+	for _, value := range values {
+		out = append(out, value)
+	}
+
+	return out
+}
+
+func TestPutBlockHistoryBuilder(t *testing.T) {
+	t.Setenv(constants.DynamoBlockHistory, mockTableName)
+	entry := types.BlockHistoryEntry{CardID: "cardID123", EventKey: "0000000000001#id"}
+
+	input, err := PutBlockHistoryBuilder(entry).BuildInput()
+	assert.NoError(t, err)
+	assert.Equal(t, aws.String(mockTableName), input.TableName)
+	assert.Equal(t, aws.String("attribute_not_exists (#0)"), input.ConditionExpression)
+	assert.Equal(t, map[string]string{"#0": constants.EventKeyField}, input.ExpressionAttributeNames)
+}
+
+func TestQueryBlockHistoryBuilder(t *testing.T) {
+	t.Setenv(constants.DynamoBlockHistory, mockTableName)
+
+	t.Run("should query the newest card events between dates", func(t *testing.T) {
+		input, err := QueryBlockHistoryBuilder(types.BlockHistoryRequest{CardID: "cardID123", From: 1, To: 2}, 10).BuildInput()
+		assert.NoError(t, err)
+		assert.Equal(t, aws.String(mockTableName), input.TableName)
+		assert.Nil(t, input.FilterExpression)
+		assert.Equal(t, aws.Int32(10), input.Limit)
+		assert.Equal(t, aws.Bool(false), input.ScanIndexForward)
+		assert.Equal(t, map[string]typesDynamo.AttributeValue{
+			":0": &typesDynamo.AttributeValueMemberS{Value: "cardID123"},
+			":1": &typesDynamo.AttributeValueMemberS{Value: "0000000000001#"},
+			":2": &typesDynamo.AttributeValueMemberS{Value: "0000000000002#~"},
+		}, input.ExpressionAttributeValues)
+	})
+
+	t.Run("should start at the cursor", func(t *testing.T) {
+		request := types.BlockHistoryRequest{CardID: "cardID123", MerchantIdentifier: mockMerchantID, Cursor: "0000000000002#b"}
+		input, err := QueryBlockHistoryBuilder(request, 10).BuildInput()
+		assert.NoError(t, err)
+		assert.Nil(t, input.FilterExpression)
+		assert.Equal(t, &typesDynamo.AttributeValueMemberS{Value: "0000000000002#b"}, input.ExpressionAttributeValues[":2"])
+	})
+}
+
+func TestPutProcessedMessageBuilder(t *testing.T) {
+	t.Setenv(constants.DynamoProcessedMessage, mockTableName)
+	record := types.ProcessedMessage{IdempotencyKey: "msg#1", ProcessedAt: mockTimeStamp, ExpiresAt: 1742594591}
+
+	build, err := PutProcessedMessageBuilder(record, 1742508191).BuildInput()
+
+	assert.NoError(t, err)
+	assert.Equal(t, aws.String(mockTableName), build.TableName)
+	assert.Equal(t, &typesDynamo.AttributeValueMemberS{Value: "msg#1"}, build.Item[constants.IdempotencyKeyField])
+	assert.Contains(t, *build.ConditionExpression, "attribute_not_exists")
+	assert.ElementsMatch(t, []string{constants.IdempotencyKeyField, constants.ExpiresAtField}, mapValues(build.ExpressionAttributeNames))
+}
+
+func TestCompleteProcessedMessageBuilder(t *testing.T) {
+	t.Setenv(constants.DynamoProcessedMessage, mockTableName)
+	record := types.ProcessedMessage{IdempotencyKey: "msg#1", Status: constants.ProcessedMessageCompleted, ExpiresAt: 1742594591}
+
+	build, err := CompleteProcessedMessageBuilder(record).BuildInput()
+
+	assert.NoError(t, err)
+	assert.Equal(t, aws.String(mockTableName), build.TableName)
+	assert.Equal(t, &typesDynamo.AttributeValueMemberS{Value: constants.ProcessedMessageCompleted}, build.Item["status"])
+	assert.Nil(t, build.ConditionExpression)
+}
+
+func TestDeleteProcessedMessageBuilder(t *testing.T) {
+	t.Setenv(constants.DynamoProcessedMessage, mockTableName)
+
+	build, err := DeleteProcessedMessageBuilder("msg#1").BuildInput()
+
+	assert.NoError(t, err)
+	assert.Equal(t, &dynamodb.DeleteItemInput{
+		TableName: aws.String(mockTableName),
+		Key: map[string]typesDynamo.AttributeValue{
+			constants.IdempotencyKeyField: &typesDynamo.AttributeValueMemberS{Value: "msg#1"},
+		},
+	}, build)
+}
+
+func TestClearLastRetryBuilder(t *testing.T) {
+	t.Setenv(constants.DynamoBlockedCard, mockTableName)
+	blockedCard := types.DynamoBlockedCard{
+		CardID:           "cardID123",
+		TimeStamp:        mockTimeStamp,
+		BlockedMerchants: map[string]types.BlockedMerchant{mockMerchantID: {LastRetry: mockTimeStamp}},
+	}
+
+	build, err := ClearLastRetryBuilder(mockMerchantID, blockedCard).BuildInput()
+
+	assert.NoError(t, err)
+	assert.Contains(t, *build.UpdateExpression, "REMOVE")
+	assert.ElementsMatch(t, []string{
+		constants.BlockedMerchants, mockMerchantID, constants.LastRetryField, constants.RetriesEndField, constants.TimeStamp, constants.ExpiresAtField,
+	}, mapValues(build.ExpressionAttributeNames))
+	assert.NotNil(t, build.ConditionExpression)
+}
+
+func TestEscalationOutlivesTheBlock(t *testing.T) {
+	t.Setenv(constants.DynamoBlockedCard, mockTableName)
+	escalated := types.BlockedMerchant{
+		BlockType: constants.TEMPORARY, ExpirationDate: mockTimeStamp, EscalationCount: 2, EscalationStart: mockTimeStamp,
+	}
+	blockedCard := types.DynamoBlockedCard{
+		CardID:           "cardID123",
+		TimeStamp:        mockTimeStamp,
+		BlockedMerchants: map[string]types.BlockedMerchant{mockMerchantID: escalated, "merchantID2": {BlockType: constants.TEMPORARY}},
+	}
+
+	t.Run("should keep the escalation when the block is removed", func(t *testing.T) {
+		build, err := RemoveExpiredBlocksBuilder([]string{mockMerchantID, "merchantID2"}, blockedCard).BuildInput()
+
+		assert.NoError(t, err)
+		assert.Contains(t, *build.UpdateExpression, "REMOVE")
+		assertEscalationValue(t, build.ExpressionAttributeValues)
+	})
+
+	t.Run("should keep the escalation when the last retry is updated", func(t *testing.T) {
+		build, err := UpdateLastRetryBuilder(mockTimeStamp, 0, mockMerchantID, blockedCard).BuildInput()
+
+		assert.NoError(t, err)
+		assertEscalationValue(t, build.ExpressionAttributeValues)
+	})
+}
+
+// assertEscalationValue some expression value is a merchant entry with the escalation of the card.
+func assertEscalationValue(t *testing.T, values map[string]typesDynamo.AttributeValue) {
+	t.Helper()
+	for _, value := range values {
+		entry, ok := value.(*typesDynamo.AttributeValueMemberM)
+		if !ok {
+			continue
+		}
+		count, ok := entry.Value["escalationCount"].(*typesDynamo.AttributeValueMemberN)
+		if ok && count.Value == "2" {
+			assert.NotContains(t, entry.Value, "blockType")
+			return
+		}
+	}
+	t.Fatalf("escalation not found in %v", values)
+}
+
+func TestUpdateCardBlockBuilder(t *testing.T) {
+	t.Setenv(constants.DynamoBlockedCard, mockTableName)
+	blockedCard := types.DynamoBlockedCard{CardID: "cardID123", TimeStamp: mockTimeStamp}
+
+	t.Run("should stamp the TTL for a temporary card block", func(t *testing.T) {
+		cardBlock := types.BlockedMerchant{BlockType: constants.TEMPORARY, ExpirationDate: time.Now().Add(time.Hour).UnixMilli()}
+		build, err := UpdateCardBlockBuilder(cardBlock, blockedCard).BuildInput()
+
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []string{constants.CardBlockField, constants.TimeStamp, constants.ExpiresAtField}, mapValues(build.ExpressionAttributeNames))
+		assert.NotContains(t, *build.UpdateExpression, "REMOVE")
+		assert.NotNil(t, build.ConditionExpression)
+	})
+
+	t.Run("should remove the TTL for a permanent card block", func(t *testing.T) {
+		build, err := UpdateCardBlockBuilder(types.BlockedMerchant{BlockType: constants.PERMANENT}, blockedCard).BuildInput()
+
+		assert.NoError(t, err)
+		assert.Contains(t, *build.UpdateExpression, "REMOVE")
+	})
+
+	t.Run("should keep the TTL removed on merchant updates while the card is permanently blocked", func(t *testing.T) {
+		blocked := blockedCard
+		blocked.CardBlock = &types.BlockedMerchant{BlockType: constants.PERMANENT}
+		build, err := UpdateLastRetryBuilder(mockTimeStamp, 0, mockMerchantID, blocked).BuildInput()
+
+		assert.NoError(t, err)
+		assert.Contains(t, *build.UpdateExpression, "REMOVE")
+	})
+}
+
+func TestIncrementShadowReportBuilder(t *testing.T) {
+	t.Setenv(constants.DynamoShadowReport, mockTableName)
+	policy := types.RetryPolicy{PolicyID: "SHADOW#VISA#*#*", Version: "v2"}
+	request := types.BlockCardRequest{Franchise: "visa", MerchantIdentifier: mockMerchantID}
+
+	build, err := IncrementShadowReportBuilder(policy, request, false, true).BuildInput()
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]typesDynamo.AttributeValue{
+		constants.PolicyIDField:  &typesDynamo.AttributeValueMemberS{Value: "SHADOW#VISA#*#*"},
+		constants.ReportKeyField: &typesDynamo.AttributeValueMemberS{Value: "v2#VISA#" + mockMerchantID},
+	}, build.Key)
+	assert.Contains(t, *build.UpdateExpression, "ADD")
+	assert.Subset(t, mapValues(build.ExpressionAttributeNames), []string{
+		constants.EvaluationsField, constants.ActiveBlocksField, constants.ShadowBlocksField,
+		constants.ExtraBlocksField, constants.FewerBlocksField,
+	})
+	counters := make([]string, 0)
+	for _, value := range build.ExpressionAttributeValues {
+		if number, ok := value.(*typesDynamo.AttributeValueMemberN); ok {
+			counters = append(counters, number.Value)
+		}
+	}
+	assert.ElementsMatch(t, []string{"1", "0", "1", "1", "0"}, counters)
+}
+
+func TestQueryShadowReportBuilder(t *testing.T) {
+	t.Setenv(constants.DynamoShadowReport, mockTableName)
+
+	build, err := QueryShadowReportBuilder(types.ShadowReportRequest{PolicyID: "SHADOW#VISA#*#*", Version: "v2"}, types.ShadowReportRow{}, 10).BuildInput()
+
+	assert.NoError(t, err)
+	assert.Equal(t, &dynamodb.QueryInput{
+		TableName: aws.String(mockTableName),
+		Limit:     aws.Int32(10),
+		ExpressionAttributeValues: map[string]typesDynamo.AttributeValue{
+			":0": &typesDynamo.AttributeValueMemberS{Value: "SHADOW#VISA#*#*"},
+			":1": &typesDynamo.AttributeValueMemberS{Value: "v2#"},
+		},
+		ExpressionAttributeNames: map[string]string{
+			"#0": constants.PolicyIDField,
+			"#1": constants.ReportKeyField,
+		},
+		KeyConditionExpression: aws.String("(#0 = :0) AND (begins_with (#1, :1))"),
+	}, build)
+
+	lastRow := types.ShadowReportRow{PolicyID: "SHADOW#VISA#*#*", ReportKey: "v2#VISA#merchant"}
+	build, err = QueryShadowReportBuilder(types.ShadowReportRequest{PolicyID: "SHADOW#VISA#*#*"}, lastRow, 10).BuildInput()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "#0 = :0", *build.KeyConditionExpression)
+	assert.Equal(t, &typesDynamo.AttributeValueMemberS{Value: "v2#VISA#merchant"}, build.ExclusiveStartKey[constants.ReportKeyField])
+}
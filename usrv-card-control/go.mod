@@ -6,15 +6,17 @@ require (
 	bitbucket.org/kushki/usrv-go-core v1.52.6
 	github.com/Jeffail/gabs/v2 v2.6.1
 	github.com/aws/aws-cdk-go/awscdk/v2 v2.110.1
-	github.com/aws/aws-lambda-go v1.25.0
+	github.com/aws/aws-lambda-go v1.47.0
 	github.com/aws/aws-sdk-go-v2 v1.27.2
 	github.com/aws/aws-sdk-go-v2/config v1.27.16
 	github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue v1.13.14
 	github.com/aws/aws-sdk-go-v2/feature/dynamodb/expression v1.7.14
 	github.com/aws/aws-sdk-go-v2/service/dynamodb v1.32.0
+	github.com/aws/aws-sdk-go-v2/service/sqs v1.32.3
 	github.com/aws/constructs-go/constructs/v10 v10.3.0
 	github.com/aws/jsii-runtime-go v1.91.0
 	github.com/fnproject/fdk-go v0.0.50
+	github.com/google/uuid v1.2.0
 	github.com/mefellows/vesper v0.1.0
 	github.com/rollbar/rollbar-go v1.4.1
 	github.com/stretchr/testify v1.8.4
@@ -38,7 +40,6 @@ require (
 	github.com/aws/aws-sdk-go-v2/service/internal/presigned-url v1.11.9 // indirect
 	github.com/aws/aws-sdk-go-v2/service/lambda v1.54.0 // indirect
 	github.com/aws/aws-sdk-go-v2/service/sns v1.29.8 // indirect
-	github.com/aws/aws-sdk-go-v2/service/sqs v1.32.3 // indirect
 	github.com/aws/aws-sdk-go-v2/service/ssm v1.50.0 // indirect
 	github.com/aws/aws-sdk-go-v2/service/sso v1.20.9 // indirect
 	github.com/aws/aws-sdk-go-v2/service/ssooidc v1.24.3 // indirect
@@ -60,7 +61,6 @@ require (
 	github.com/go-stack/stack v1.8.0 // indirect
 	github.com/golang/protobuf v1.5.2 // indirect
 	github.com/golang/snappy v0.0.1 // indirect
-	github.com/google/uuid v1.2.0 // indirect
 	github.com/hashicorp/go-cleanhttp v0.5.1 // indirect
 	github.com/hashicorp/go-retryablehttp v0.5.1 // indirect
 	github.com/imdario/mergo v0.3.12 // indirect
@@ -15,18 +15,18 @@ type IBlockService struct {
 }
 
 // ProcessBlock provides a mock function with given fields: ctx, event
-func (_m *IBlockService) ProcessBlock(ctx context.Context, event events.SQSEvent) error {
+func (_m *IBlockService) ProcessBlock(ctx context.Context, event events.SQSEvent) events.SQSEventResponse {
 	ret := _m.Called(ctx, event)
 
 	if len(ret) == 0 {
 		panic("no return value specified for ProcessBlock")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, events.SQSEvent) error); ok {
+	var r0 events.SQSEventResponse
+	if rf, ok := ret.Get(0).(func(context.Context, events.SQSEvent) events.SQSEventResponse); ok {
 		r0 = rf(ctx, event)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(events.SQSEventResponse)
 	}
 
 	return r0
@@ -13,8 +13,26 @@ type ICheckCardStatusService struct {
 	mock.Mock
 }
 
+// BatchCheckCardStatus provides a mock function with given fields: request
+func (_m *ICheckCardStatusService) BatchCheckCardStatus(request types.BatchCheckCardStatusRequest) types.BatchCheckCardStatusResponse {
+	ret := _m.Called(request)
+
+	if len(ret) == 0 {
+		panic("no return value specified for BatchCheckCardStatus")
+	}
+
+	var r0 types.BatchCheckCardStatusResponse
+	if rf, ok := ret.Get(0).(func(types.BatchCheckCardStatusRequest) types.BatchCheckCardStatusResponse); ok {
+		r0 = rf(request)
+	} else {
+		r0 = ret.Get(0).(types.BatchCheckCardStatusResponse)
+	}
+
+	return r0
+}
+
 // CheckCardStatus provides a mock function with given fields: checkCardStatusRequest
-func (_m *ICheckCardStatusService) CheckCardStatus(checkCardStatusRequest types.CheckCardStatusRequest) types.CheckCardStatusResponse {
+func (_m *ICheckCardStatusService) CheckCardStatus(checkCardStatusRequest types.CheckCardStatusRequest) (types.CheckCardStatusResponse, error) {
 	ret := _m.Called(checkCardStatusRequest)
 
 	if len(ret) == 0 {
@@ -22,13 +40,23 @@ func (_m *ICheckCardStatusService) CheckCardStatus(checkCardStatusRequest types.
 	}
 
 	var r0 types.CheckCardStatusResponse
+	var r1 error
+	if rf, ok := ret.Get(0).(func(types.CheckCardStatusRequest) (types.CheckCardStatusResponse, error)); ok {
+		return rf(checkCardStatusRequest)
+	}
 	if rf, ok := ret.Get(0).(func(types.CheckCardStatusRequest) types.CheckCardStatusResponse); ok {
 		r0 = rf(checkCardStatusRequest)
 	} else {
 		r0 = ret.Get(0).(types.CheckCardStatusResponse)
 	}
 
-	return r0
+	if rf, ok := ret.Get(1).(func(types.CheckCardStatusRequest) error); ok {
+		r1 = rf(checkCardStatusRequest)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
 }
 
 // NewICheckCardStatusService creates a new instance of ICheckCardStatusService. It also registers a testing interface on the mock and a cleanup function to assert the mocks expectations.
@@ -7,6 +7,8 @@ import (
 
 	events "github.com/aws/aws-lambda-go/events"
 	mock "github.com/stretchr/testify/mock"
+
+	types "bitbucket.org/kushki/usrv-card-control/types"
 )
 
 // IRestoreService is an autogenerated mock type for the IRestoreService type
@@ -14,19 +16,47 @@ type IRestoreService struct {
 	mock.Mock
 }
 
+// Restore provides a mock function with given fields: ctx, request
+func (_m *IRestoreService) Restore(ctx context.Context, request types.RestoreDailyRequest) (types.RestoreResult, error) {
+	ret := _m.Called(ctx, request)
+
+	if len(ret) == 0 {
+		panic("no return value specified for Restore")
+	}
+
+	var r0 types.RestoreResult
+	var r1 error
+	if rf, ok := ret.Get(0).(func(context.Context, types.RestoreDailyRequest) (types.RestoreResult, error)); ok {
+		return rf(ctx, request)
+	}
+	if rf, ok := ret.Get(0).(func(context.Context, types.RestoreDailyRequest) types.RestoreResult); ok {
+		r0 = rf(ctx, request)
+	} else {
+		r0 = ret.Get(0).(types.RestoreResult)
+	}
+
+	if rf, ok := ret.Get(1).(func(context.Context, types.RestoreDailyRequest) error); ok {
+		r1 = rf(ctx, request)
+	} else {
+		r1 = ret.Error(1)
+	}
+
+	return r0, r1
+}
+
 // RestoreDailyRetries provides a mock function with given fields: ctx, event
-func (_m *IRestoreService) RestoreDailyRetries(ctx context.Context, event events.SQSEvent) error {
+func (_m *IRestoreService) RestoreDailyRetries(ctx context.Context, event events.SQSEvent) events.SQSEventResponse {
 	ret := _m.Called(ctx, event)
 
 	if len(ret) == 0 {
 		panic("no return value specified for RestoreDailyRetries")
 	}
 
-	var r0 error
-	if rf, ok := ret.Get(0).(func(context.Context, events.SQSEvent) error); ok {
+	var r0 events.SQSEventResponse
+	if rf, ok := ret.Get(0).(func(context.Context, events.SQSEvent) events.SQSEventResponse); ok {
 		r0 = rf(ctx, event)
 	} else {
-		r0 = ret.Error(0)
+		r0 = ret.Get(0).(events.SQSEventResponse)
 	}
 
 	return r0
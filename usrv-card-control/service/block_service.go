@@ -18,13 +18,18 @@ import (
 )
 
 type IBlockService interface {
-	ProcessBlock(ctx context.Context, event events.SQSEvent) error
+	ProcessBlock(ctx context.Context, event events.SQSEvent) events.SQSEventResponse
 }
 
 // BlockService to manage card blocks and retries.
 type BlockService struct {
-	Logger logger.KushkiLogger
-	Dynamo dynamo.IDynamoGateway
+	Logger      logger.KushkiLogger
+	Dynamo      dynamo.IDynamoGateway
+	Policies    IRetryPolicyService
+	History     IHistoryService
+	Idempotency IIdempotencyService
+	Settings    IMerchantSettingsService
+	Velocity    VelocityConfig
 }
 
 const blockSrvTag = "BlockService | %s"
@@ -32,32 +37,52 @@ const blockSrvTag = "BlockService | %s"
 // RefNewBlockService ref to new service.
 var RefNewBlockService = NewBlockService
 
-// NewBlockService function to instantiate.
-func NewBlockService(kskLogger logger.KushkiLogger, dynamoGtw dynamo.IDynamoGateway) IBlockService {
-	return &BlockService{
-		Logger: kskLogger,
-		Dynamo: dynamoGtw,
+// NewBlockService function to instantiate, an invalid card velocity config fails it.
+func NewBlockService(kskLogger logger.KushkiLogger, dynamoGtw dynamo.IDynamoGateway) (IBlockService, error) {
+	velocity, err := loadVelocityConfig()
+	if err != nil {
+		return nil, err
 	}
+
+	return &BlockService{
+		Logger:      kskLogger,
+		Dynamo:      dynamoGtw,
+		Policies:    NewRetryPolicyService(kskLogger, dynamoGtw),
+		History:     NewHistoryService(kskLogger, dynamoGtw),
+		Idempotency: NewIdempotencyService(kskLogger, dynamoGtw),
+		Settings:    NewMerchantSettingsService(kskLogger, dynamoGtw),
+		Velocity:    velocity,
+	}, nil
 }
 
 // InitBlockService used to initialize dependencies for service.
-func InitBlockService(ctx context.Context, event events.SQSEvent) error {
+func InitBlockService(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
 	kskLogger := newKushkiLogger(ctx)
 	dynamoGtw, err := initializeDynamoGtw(ctx, kskLogger)
 	if err != nil {
 		kskLogger.Error(fmt.Sprintf(blockSrvTag, "Error intializing dynamo"), err)
-		return err
+		return events.SQSEventResponse{}, err
 	}
 
-	service := RefNewBlockService(kskLogger, dynamoGtw)
+	service, err := RefNewBlockService(kskLogger, dynamoGtw)
+	if err != nil {
+		kskLogger.Error(fmt.Sprintf(blockSrvTag, "Error initializing card velocity"), err)
+		return events.SQSEventResponse{}, err
+	}
+
+	return service.ProcessBlock(ctx, event), nil
+}
 
-	return service.ProcessBlock(ctx, event)
+// ProcessBlock block or increment card retries for every record in the batch.
+func (bs *BlockService) ProcessBlock(ctx context.Context, event events.SQSEvent) events.SQSEventResponse {
+	return processRecords(bs.Logger, blockSrvTag, event, func(record events.SQSMessage) error {
+		return bs.processRecord(ctx, record)
+	})
 }
 
-// ProcessBlock block or increment card retries.
-func (bs *BlockService) ProcessBlock(ctx context.Context, event events.SQSEvent) error {
+func (bs *BlockService) processRecord(ctx context.Context, record events.SQSMessage) error {
 	var request types.BlockCardRequest
-	err := jsonUnmarshalCaller([]byte(event.Records[0].Body), &request)
+	err := jsonUnmarshalCaller([]byte(record.Body), &request)
 	if err != nil {
 		return err
 	}
@@ -67,6 +92,40 @@ func (bs *BlockService) ProcessBlock(ctx context.Context, event events.SQSEvent)
 		return nil
 	}
 
+	// redeliveries must not count the retry twice, a failed message is released to be processed again
+	// and a crashed one is processed once its claim expires.
+	key := generateIdempotencyKey(record, request)
+	claimed, err := bs.Idempotency.Claim(ctx, key)
+	if err != nil {
+		return err
+	}
+	if !claimed {
+		bs.info("processRecord", "[DUPLICATE MESSAGE, SKIPPING]")
+		return nil
+	}
+	if err := bs.processRequest(ctx, request); err != nil {
+		bs.Idempotency.Release(ctx, key)
+		return err
+	}
+	bs.Idempotency.Complete(ctx, key)
+
+	return nil
+}
+
+func (bs *BlockService) processRequest(ctx context.Context, request types.BlockCardRequest) error {
+	if strings.EqualFold(request.Operation, constants.ApproveCardOperation) {
+		return bs.approveCard(ctx, request)
+	}
+
+	settings, err := bs.Settings.GetSettings(ctx, request.MerchantIdentifier)
+	if err != nil {
+		return err
+	}
+	if settings.Exempt {
+		bs.info("processRequest", "[EXEMPT MERCHANT, SKIPPING]")
+		return nil
+	}
+
 	blockedCard, err := bs.getBlockedCard(ctx, request.CardID)
 	if errors.Is(err, dynamoerror.ErrItemNotFound) {
 		bs.info("No block found", "[NEW Block]")
@@ -76,41 +135,192 @@ func (bs *BlockService) ProcessBlock(ctx context.Context, event events.SQSEvent)
 		return err
 	}
 
+	currentDate := time.Now().UTC().UnixMilli()
+	expiredBlock := isExpiredBlock(blockedCard, request.MerchantIdentifier, currentDate)
+
 	if strings.EqualFold(request.Operation, constants.BlockCardOperation) {
-		return bs.blockCard(ctx, request, blockedCard)
+		return bs.blockCard(ctx, request, constants.PERMANENT, blockedCard, expiredBlock, blockTrigger{Shadow: settings.Shadow})
+	}
+
+	if blockedCard, err = bs.processVelocity(ctx, request, currentDate, blockedCard, settings.Shadow); err != nil {
+		return err
+	}
+
+	category := classifyDecline(request.Franchise, request.Conditional)
+	if category.NeverRetry {
+		bs.info("ProcessBlock - Never retry decline", category)
+		return bs.blockCard(ctx, request, constants.PERMANENT, blockedCard, expiredBlock, blockTrigger{Shadow: settings.Shadow})
+	}
+
+	policy, err := bs.Policies.GetPolicy(ctx, request.Franchise, request.Processor, request.MerchantIdentifier)
+	if err != nil {
+		return err
 	}
 
-	currentDate := time.Now().UTC().UnixMilli()
 	bs.info("ProcessBlock", "[CHECKING RETRIES...]")
-	frequencies := constants.Frequencies[request.Franchise]
-	blockedMap := make(map[string]bool)
-	for _, frequency := range frequencies {
-		blockedMap[frequency], err = bs.processRetry(ctx, request, frequency, currentDate)
+	rules := applyLimitFactor(getApplicableRules(policy, category), settings.LimitFactor)
+	ttlHours := getLongestWindowHours(policy.Rules, currentDate)
+	blockTypes := make([]string, 0, len(rules))
+	trigger := blockTrigger{Escalation: policy.Escalation, Shadow: settings.Shadow}
+	for _, rule := range rules {
+		blocked, retries, err := bs.processRetry(ctx, request, rule, currentDate, ttlHours)
 		if err != nil {
 			return err
 		}
+		if blocked {
+			blockTypes = append(blockTypes, rule.BlockType)
+			trigger.BlockHours = max(trigger.BlockHours, getBlockHours(rule))
+			if retries.After > trigger.Retries.After {
+				trigger.Retries = retries
+			}
+		}
 	}
 
-	blocked := getBlocked(blockedMap)
-	if blocked {
+	bs.evaluateShadowPolicy(ctx, request, category, settings, currentDate, len(blockTypes) > 0)
+
+	if len(blockTypes) > 0 {
 		bs.info("Process Retry - Retries exceeded limit", "[BLOCKING CARD]")
-		return bs.blockCard(ctx, request, blockedCard)
+		return bs.blockCard(ctx, request, getStrongestBlockType(blockTypes), blockedCard, expiredBlock, trigger)
 	}
 
-	// VISA does not have daily retries.
-	if strings.EqualFold(request.Franchise, core.BrandVisa) {
-		return nil
+	// Policies without daily retries (e.g. VISA) do not track the last retry.
+	dailyRule, ok := getFrequencyRule(rules, constants.DailyFrequency)
+	if !ok {
+		return bs.clearExpiredBlock(ctx, request, currentDate, blockedCard)
+	}
+	retriesEnd := getWindowEnd(dailyRule, currentDate)
+	if err := bs.updateLastRetry(ctx, request.MerchantIdentifier, currentDate, retriesEnd, blockedCard); err != nil {
+		return err
 	}
-	return bs.updateLastRetry(ctx, request.MerchantIdentifier, currentDate, blockedCard)
+	// the last retry overwrites the merchant entry, an expired block ends here.
+	bs.recordExpiredBlock(ctx, request, expiredBlock)
+
+	return nil
 }
 
-func getBlocked(blockedMap map[string]bool) bool {
-	for _, blocked := range blockedMap {
-		if blocked {
-			return true
+// approveCard an approval resets the reattempt counting of every frequency, blocks are kept.
+func (bs *BlockService) approveCard(ctx context.Context, request types.BlockCardRequest) error {
+	bs.info("approveCard", "[CLEANING RETRIES]")
+	deleted, err := deleteCardRetries(ctx, bs.Dynamo, gateway.QueryCardRetriesBuilder(request.CardID, request.MerchantIdentifier))
+	if err != nil {
+		return err
+	}
+	for _, retry := range deleted {
+		bs.History.Record(ctx, types.BlockHistoryEntry{
+			CardID:        request.CardID,
+			MerchantID:    request.MerchantIdentifier,
+			Operation:     constants.HistoryApproved,
+			Brand:         request.Franchise,
+			Frequency:     getRetryFrequency(retry.RetryKey),
+			RetriesBefore: len(retry.Retries),
+		})
+	}
+
+	return retryOnConflict(bs.Logger, blockSrvTag, func(int) error {
+		blockedCard, err := bs.getBlockedCard(ctx, request.CardID)
+		if errors.Is(err, dynamoerror.ErrItemNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if blockedCard.BlockedMerchants[request.MerchantIdentifier].LastRetry == 0 {
+			return nil
+		}
+
+		bs.info("approveCard", "[CLEANING LAST RETRY]")
+		return bs.Dynamo.UpdateItem(ctx, gateway.ClearLastRetryBuilder(request.MerchantIdentifier, blockedCard))
+	})
+}
+
+// getRetryFrequency the frequency closes every retry key.
+func getRetryFrequency(retryKey string) string {
+	return retryKey[strings.LastIndex(retryKey, "-")+1:]
+}
+
+// isExpiredBlock whether the merchant has a temporary block already expired.
+func isExpiredBlock(blockedCard types.DynamoBlockedCard, merchantID string, currentDate int64) bool {
+	blockedMerchant, ok := blockedCard.BlockedMerchants[merchantID]
+
+	return ok &&
+		strings.EqualFold(blockedMerchant.BlockType, constants.TEMPORARY) &&
+		blockedMerchant.ExpirationDate <= currentDate
+}
+
+// isActiveBlock whether the merchant has a permanent block or a temporary one not expired yet.
+func isActiveBlock(blockedCard types.DynamoBlockedCard, merchantID string, currentDate int64) bool {
+	blockedMerchant, ok := blockedCard.BlockedMerchants[merchantID]
+
+	return isPermanentBlock(blockedCard, merchantID) ||
+		ok && strings.EqualFold(blockedMerchant.BlockType, constants.TEMPORARY) && blockedMerchant.ExpirationDate > currentDate
+}
+
+func isPermanentBlock(blockedCard types.DynamoBlockedCard, merchantID string) bool {
+	blockedMerchant, ok := blockedCard.BlockedMerchants[merchantID]
+
+	return ok && strings.EqualFold(blockedMerchant.BlockType, constants.PERMANENT)
+}
+
+// clearExpiredBlock removes the expired temporary block when no last retry overwrites the merchant entry.
+func (bs *BlockService) clearExpiredBlock(
+	ctx context.Context,
+	request types.BlockCardRequest,
+	currentDate int64,
+	blockedCard types.DynamoBlockedCard,
+) error {
+	expiredBlock := false
+	err := retryOnConflict(bs.Logger, blockSrvTag, func(attempt int) error {
+		if attempt > 0 {
+			var err error
+			if blockedCard, err = bs.getBlockedCard(ctx, request.CardID); err != nil {
+				return err
+			}
 		}
+
+		expiredBlock = isExpiredBlock(blockedCard, request.MerchantIdentifier, currentDate)
+		if !expiredBlock {
+			return nil
+		}
+
+		bs.info("clearExpiredBlock", "[removing]")
+		return bs.Dynamo.UpdateItem(ctx, gateway.RemoveExpiredBlockBuilder(request.MerchantIdentifier, blockedCard))
+	})
+	if err != nil {
+		return err
 	}
-	return false
+	bs.recordExpiredBlock(ctx, request, expiredBlock)
+
+	return nil
+}
+
+func (bs *BlockService) recordExpiredBlock(ctx context.Context, request types.BlockCardRequest, expiredBlock bool) {
+	if !expiredBlock {
+		return
+	}
+
+	entry := newHistoryEntry(request, constants.HistoryExpired)
+	entry.BlockType = constants.TEMPORARY
+	bs.History.Record(ctx, entry)
+}
+
+// getStrongestBlockType the strongest block type among the exceeded rules.
+func getStrongestBlockType(blockTypes []string) string {
+	for _, blockType := range blockTypes {
+		if strings.EqualFold(blockType, constants.PERMANENT) {
+			return constants.PERMANENT
+		}
+	}
+	return constants.TEMPORARY
+}
+
+// getLongestWindowHours retries must outlive every window of the policy, including the ones of other categories.
+func getLongestWindowHours(rules []types.FrequencyRule, currentDate int64) int {
+	windowHours := constants.DayHours
+	for _, rule := range rules {
+		windowHours = max(windowHours, getWindowHours(rule, currentDate))
+	}
+
+	return windowHours
 }
 
 func (bs *BlockService) generateNewBlockedCard(ctx context.Context, request types.BlockCardRequest) (types.DynamoBlockedCard, error) {
@@ -124,50 +334,102 @@ func (bs *BlockService) generateNewBlockedCard(ctx context.Context, request type
 	return blockedCard, bs.Dynamo.PutItem(ctx, input)
 }
 
-func (bs *BlockService) processRetry(ctx context.Context, request types.BlockCardRequest, frequency string, currentDate int64) (blocked bool, err error) {
-	key := generateRetryKey(request, frequency)
-
-	retry, err := bs.getRetry(ctx, key)
-	if err != nil && !errors.Is(err, dynamoerror.ErrItemNotFound) {
-		return false, err
-	}
+func (bs *BlockService) processRetry(
+	ctx context.Context,
+	request types.BlockCardRequest,
+	rule types.FrequencyRule,
+	currentDate int64,
+	ttlHours int) (blocked bool, retries retryCount, err error) {
+	key := generateRuleRetryKey(request, rule)
 
-	bs.info("Process Retry", "[Incrementing current retry]")
-	validRetries, err := bs.incrementRetry(ctx, request, frequency, retry, currentDate)
+	validRetries, err := bs.countRetry(ctx, request, key, rule, currentDate, ttlHours)
 	if err != nil {
-		return false, err
+		return false, retryCount{}, err
 	}
 
-	limit := constants.Limits[request.Franchise][frequency]
-	if len(validRetries) >= limit {
+	// both counters cover the same window, the retries already outside it are not counted before.
+	retries = retryCount{
+		Frequency: rule.Frequency,
+		Before:    len(validRetries) - 1,
+		After:     len(validRetries),
+	}
+	entry := newHistoryEntry(request, constants.HistoryRetryIncrement)
+	entry.Frequency = retries.Frequency
+	entry.RetriesBefore = retries.Before
+	entry.RetriesAfter = retries.After
+	entry.TimeStamp = currentDate
+	bs.History.Record(ctx, entry)
+
+	if len(validRetries) >= rule.MaxAttempts {
 		blocked = true
 	}
 
-	return blocked, nil
+	return blocked, retries, nil
 }
 
 func (bs *BlockService) updateLastRetry(
 	ctx context.Context,
 	merchantID string,
 	currentDate int64,
+	retriesEnd int64,
 	blockedCard types.DynamoBlockedCard,
 ) error {
-	bs.info("updateLastRetry", "[updating]")
-	input := gateway.UpdateLastRetryBuilder(currentDate, merchantID, blockedCard)
+	return retryOnConflict(bs.Logger, blockSrvTag, func(attempt int) error {
+		if attempt > 0 {
+			var err error
+			if blockedCard, err = bs.getBlockedCard(ctx, blockedCard.CardID); err != nil {
+				return err
+			}
+		}
+
+		// the last retry replaces the merchant entry, it must not erase a block written meanwhile.
+		if isActiveBlock(blockedCard, merchantID, currentDate) {
+			bs.info("updateLastRetry", "[ACTIVE BLOCK, SKIPPING]")
+			return nil
+		}
+
+		bs.info("updateLastRetry", "[updating]")
+		input := gateway.UpdateLastRetryBuilder(currentDate, retriesEnd, merchantID, blockedCard)
+
+		return bs.Dynamo.UpdateItem(ctx, input)
+	})
+}
 
-	return bs.Dynamo.UpdateItem(ctx, input)
+// countRetry adds the current date to the retries of the key and returns the ones inside the rule window.
+func (bs *BlockService) countRetry(
+	ctx context.Context,
+	request types.BlockCardRequest,
+	key string,
+	rule types.FrequencyRule,
+	currentDate int64,
+	ttlHours int) ([]int64, error) {
+	var validRetries []int64
+	err := retryOnConflict(bs.Logger, blockSrvTag, func(int) error {
+		retry, err := bs.getRetry(ctx, key)
+		if err != nil && !errors.Is(err, dynamoerror.ErrItemNotFound) {
+			return err
+		}
+
+		bs.info("Process Retry", "[Incrementing current retry]")
+		validRetries, err = bs.incrementRetry(ctx, request, key, rule, retry, currentDate, ttlHours)
+
+		return err
+	})
+
+	return validRetries, err
 }
 
 func (bs *BlockService) incrementRetry(
 	ctx context.Context,
 	request types.BlockCardRequest,
-	frequency string,
+	key string,
+	rule types.FrequencyRule,
 	cardRetry types.CardRetry,
-	currentDate int64) ([]int64, error) {
-	retries := getValidRetries(currentDate, cardRetry.Retries, frequency)
+	currentDate int64,
+	ttlHours int) ([]int64, error) {
+	retries := getValidRetries(currentDate, cardRetry.Retries, rule)
 	bs.info("incrementRetry | VALID RETRIES", retries)
-	key := generateRetryKey(request, frequency)
-	input := gateway.IncrementRetryBuilder(retries, request, key, cardRetry)
+	input := gateway.IncrementRetryBuilder(retries, request, key, cardRetry, ttlHours)
 
 	err := bs.Dynamo.UpdateItem(ctx, input)
 
@@ -180,6 +442,10 @@ func (bs *BlockService) getRetry(ctx context.Context, key string) (types.CardRet
 	var out types.CardRetry
 
 	err := bs.Dynamo.GetItem(ctx, getItem, &out)
+	// an expired item keeps its timeStamp, the increment is still conditioned on it.
+	if err == nil && isExpiredItem(out.ExpiresAt) {
+		out.Retries = nil
+	}
 
 	return out, err
 }
@@ -190,6 +456,9 @@ func (bs *BlockService) getBlockedCard(ctx context.Context, cardID string) (type
 	var out types.DynamoBlockedCard
 
 	err := bs.Dynamo.GetItem(ctx, getItem, &out)
+	if err == nil && isExpiredItem(out.ExpiresAt) {
+		return types.DynamoBlockedCard{}, dynamoerror.ErrItemNotFound
+	}
 
 	return out, err
 }
@@ -197,28 +466,142 @@ func (bs *BlockService) getBlockedCard(ctx context.Context, cardID string) (type
 func (bs *BlockService) blockCard(
 	ctx context.Context,
 	request types.BlockCardRequest,
-	blockedCard types.DynamoBlockedCard) error {
-	item := gateway.UpdateBlockCardBuilder(request, blockedCard)
+	blockType string,
+	blockedCard types.DynamoBlockedCard,
+	expiredBlock bool,
+	trigger blockTrigger) error {
+	if trigger.Shadow {
+		bs.recordShadowBlock(ctx, request, blockType, trigger)
+		return nil
+	}
+
+	permanentlyBlocked := false
+	requestedType := blockType
+	err := retryOnConflict(bs.Logger, blockSrvTag, func(attempt int) error {
+		if attempt > 0 {
+			var err error
+			if blockedCard, err = bs.getBlockedCard(ctx, request.CardID); err != nil {
+				return err
+			}
+		}
+
+		// a temporary block never downgrades a permanent one, even if it was written meanwhile.
+		permanentlyBlocked = isPermanentBlock(blockedCard, request.MerchantIdentifier) &&
+			!strings.EqualFold(requestedType, constants.PERMANENT)
+		if permanentlyBlocked {
+			return nil
+		}
+
+		blockedMerchant := generateBlockedMerchant(requestedType, trigger,
+			blockedCard.BlockedMerchants[request.MerchantIdentifier], time.Now().UTC())
+		blockType = blockedMerchant.BlockType
+		item := gateway.UpdateBlockCardBuilder(request, blockedMerchant, blockedCard)
 
-	return bs.Dynamo.UpdateItem(ctx, item)
+		return bs.Dynamo.UpdateItem(ctx, item)
+	})
+	if err != nil {
+		return err
+	}
+	if permanentlyBlocked {
+		bs.info("blockCard", "[PERMANENT BLOCK, SKIPPING]")
+		return nil
+	}
+
+	bs.recordExpiredBlock(ctx, request, expiredBlock)
+	bs.History.Record(ctx, newBlockEntry(request, getBlockOperation(blockType), blockType, trigger))
+
+	return nil
+}
+
+// recordShadowBlock a shadow merchant only records the block it would have received.
+func (bs *BlockService) recordShadowBlock(ctx context.Context, request types.BlockCardRequest, blockType string, trigger blockTrigger) {
+	bs.info("recordShadowBlock", "[SHADOW MERCHANT, NOT BLOCKING]")
+	bs.History.Record(ctx, newBlockEntry(request, constants.HistoryShadowBlock, blockType, trigger))
+}
+
+func newBlockEntry(request types.BlockCardRequest, operation string, blockType string, trigger blockTrigger) types.BlockHistoryEntry {
+	entry := newHistoryEntry(request, operation)
+	entry.BlockType = blockType
+	entry.Frequency = trigger.Retries.Frequency
+	entry.RetriesBefore = trigger.Retries.Before
+	entry.RetriesAfter = trigger.Retries.After
+
+	return entry
+}
+
+// blockTrigger rules that triggered a block, zero for direct blocks.
+// Shadow blocks are recorded in the history but never written.
+type blockTrigger struct {
+	Retries    retryCount
+	BlockHours int
+	Escalation *types.BlockEscalation
+	Shadow     bool
+}
+
+// generateBlockedMerchant with an escalation each temporary block inside the window lasts its step,
+// past the last step the merchant is blocked permanently.
+func generateBlockedMerchant(
+	blockType string,
+	trigger blockTrigger,
+	current types.BlockedMerchant,
+	now time.Time,
+) types.BlockedMerchant {
+	blockedMerchant := types.BlockedMerchant{
+		BlockType:       blockType,
+		EscalationCount: current.EscalationCount,
+		EscalationStart: current.EscalationStart,
+	}
+	blockHours := trigger.BlockHours
+	if blockHours == 0 {
+		blockHours = constants.DayHours
+	}
+
+	if trigger.Escalation != nil && strings.EqualFold(blockType, constants.TEMPORARY) {
+		windowDays := trigger.Escalation.WindowDays
+		if windowDays == 0 {
+			windowDays = constants.MonthDays
+		}
+		if now.After(time.UnixMilli(current.EscalationStart).AddDate(0, 0, windowDays)) {
+			blockedMerchant.EscalationCount = 0
+			blockedMerchant.EscalationStart = now.UnixMilli()
+		}
+		blockedMerchant.EscalationCount++
+
+		if blockedMerchant.EscalationCount > len(trigger.Escalation.BlockHours) {
+			blockedMerchant.BlockType = constants.PERMANENT
+		} else {
+			blockHours = trigger.Escalation.BlockHours[blockedMerchant.EscalationCount-1]
+		}
+	}
+	blockedMerchant.ExpirationDate = now.Add(time.Duration(blockHours) * time.Hour).UnixMilli()
+
+	return blockedMerchant
+}
+
+// getBlockHours temporary block duration of the rule, a day when the policy does not set it.
+func getBlockHours(rule types.FrequencyRule) int {
+	if rule.BlockHours > 0 {
+		return rule.BlockHours
+	}
+
+	return constants.DayHours
+}
+
+// retryCount windowed retries of the rule that triggered a block, zero for direct blocks.
+type retryCount struct {
+	Frequency string
+	Before    int
+	After     int
 }
 
 func (bs *BlockService) info(process string, v interface{}) {
 	bs.Logger.Info(fmt.Sprintf(blockSrvTag, process), v)
 }
 
-func getValidRetries(currentDate int64, oldRetries []int64, frequency string) []int64 {
-	const oneDayMiliSeconds = 24 * 60 * 60 * 1000
-	const oneMonthMiliSeconds = oneDayMiliSeconds * 30
-	dateLimit := currentDate
-	if strings.EqualFold(frequency, constants.DailyFrequency) {
-		dateLimit = dateLimit - oneDayMiliSeconds
-	} else {
-		dateLimit = dateLimit - oneMonthMiliSeconds
-	}
+func getValidRetries(currentDate int64, oldRetries []int64, rule types.FrequencyRule) []int64 {
 	retries := []int64{currentDate}
 	for _, retry := range oldRetries {
-		if retry > dateLimit {
+		if isInWindow(rule, retry, currentDate) {
 			retries = append(retries, retry)
 		}
 	}
@@ -226,6 +609,24 @@ func getValidRetries(currentDate int64, oldRetries []int64, frequency string) []
 	return retries
 }
 
+// getRuleCounter category rules count apart from the generic ones of the same frequency.
+func getRuleCounter(rule types.FrequencyRule) string {
+	if rule.Category == "" {
+		return rule.Frequency
+	}
+
+	return fmt.Sprintf("%s-%s", rule.Category, rule.Frequency)
+}
+
+// generateRuleRetryKey category counters are shared by every code of the category, so they leave the conditional out.
+func generateRuleRetryKey(request types.BlockCardRequest, rule types.FrequencyRule) string {
+	if rule.Category == "" {
+		return generateRetryKey(request, rule.Frequency)
+	}
+
+	return fmt.Sprintf("%s-%s-%s", request.CardID, request.MerchantIdentifier, getRuleCounter(rule))
+}
+
 func generateRetryKey(request types.BlockCardRequest, frequency string) string {
 	customID := generateCustomID(request, frequency)
 
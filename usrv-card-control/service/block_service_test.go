@@ -4,6 +4,8 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
+	"strconv"
 	"testing"
 	"time"
 
@@ -15,10 +17,12 @@ import (
 	"bitbucket.org/kushki/usrv-card-control/types"
 	core "bitbucket.org/kushki/usrv-go-core"
 	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo/builder"
 	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
 	"bitbucket.org/kushki/usrv-go-core/logger"
 	"bitbucket.org/kushki/usrv-go-core/middleware"
 	"github.com/aws/aws-lambda-go/events"
+	typesDynamo "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -33,6 +37,7 @@ type blockCardScenario struct {
 	NewBlockedCard bool
 	FranchiseMC    bool
 	EmptyCardID    bool
+	PolicyError    error
 }
 
 type dynamoErrors struct {
@@ -46,12 +51,12 @@ type dynamoErrors struct {
 var (
 	commonError = errors.New("some error")
 	fakeEvent   = events.SQSEvent{
-		Records: []events.SQSMessage{{Body: ""}},
+		Records: []events.SQSMessage{{MessageId: "fakeMessageId", Body: ""}},
 	}
 	notExpiredRetry   = time.Now().UTC().Add(time.Hour).UnixMilli() + 10000
 	notExpiredRetries = func() []int64 {
 		retries := make([]int64, 0)
-		limit := constants.Limits[core.BrandVisa][constants.MonthlyFrequency]
+		limit := defaultRetryPolicies[core.BrandVisa].Rules[0].MaxAttempts
 		for i := 0; i <= limit; i++ {
 			retries = append(retries, notExpiredRetry)
 		}
@@ -72,8 +77,12 @@ func TestBlockService_ProcessBlock_DirectBlock(t *testing.T) {
 			Return(nil).
 			Once()
 		srv := BlockService{
-			Logger: mocks.GetMockLogger(t),
-			Dynamo: dynamoMock,
+			Logger:      mocks.GetMockLogger(t),
+			Dynamo:      dynamoMock,
+			Policies:    &mockService.IRetryPolicyService{},
+			History:     getHistoryMock(),
+			Idempotency: getIdempotencyMock(),
+			Settings:    getSettingsMock(),
 		}
 
 		jsonUnmarshalCaller = func(_ []byte, v any) error {
@@ -84,7 +93,47 @@ func TestBlockService_ProcessBlock_DirectBlock(t *testing.T) {
 		}
 
 		result := srv.ProcessBlock(context.TODO(), fakeEvent)
-		assert.NoError(t, result)
+		assert.Empty(t, result.BatchItemFailures)
+		dynamoMock.AssertExpectations(t)
+	})
+}
+
+func TestBlockService_ProcessBlock_Batch(t *testing.T) {
+	t.Run("should report only the failed records", func(t *testing.T) {
+		t.Cleanup(clean)
+		event := events.SQSEvent{
+			Records: []events.SQSMessage{
+				{MessageId: "ok", Body: "ok"},
+				{MessageId: "failed", Body: "failed"},
+				{MessageId: "ok2", Body: "ok"},
+			},
+		}
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+			Return(nil)
+		dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).
+			Return(nil).
+			Twice()
+		srv := BlockService{
+			Logger:      mocks.GetMockLogger(t),
+			Dynamo:      dynamoMock,
+			Policies:    &mockService.IRetryPolicyService{},
+			History:     getHistoryMock(),
+			Idempotency: getIdempotencyMock(),
+			Settings:    getSettingsMock(),
+		}
+		jsonUnmarshalCaller = func(data []byte, v any) error {
+			if string(data) == "failed" {
+				return commonError
+			}
+			out := v.(*types.BlockCardRequest)
+			*out = types.BlockCardRequest{Operation: constants.BlockCardOperation, CardID: "foo"}
+
+			return nil
+		}
+
+		result := srv.ProcessBlock(context.TODO(), event)
+		assert.Equal(t, []events.SQSBatchItemFailure{{ItemIdentifier: "failed"}}, result.BatchItemFailures)
 		dynamoMock.AssertExpectations(t)
 	})
 }
@@ -132,6 +181,11 @@ func TestBlockService_ProcessBlock_Retry(t *testing.T) {
 			Name:        "should return an error if blocking by retry return an error",
 			FranchiseMC: true,
 		},
+		{
+			Name:        "should return an error if getting the retry policy fails",
+			PolicyError: commonError,
+			HasError:    true,
+		},
 	}
 
 	for _, scenario := range scenarios {
@@ -148,6 +202,23 @@ func TestGenerateCustomID(t *testing.T) {
 	})
 }
 
+func TestGenerateRuleRetryKey(t *testing.T) {
+	request := types.BlockCardRequest{CardID: "card", MerchantIdentifier: "merchant", Franchise: core.BrandVisa, Conditional: "51"}
+
+	t.Run("should keep the conditional in generic visa counters", func(t *testing.T) {
+		assert.Equal(t, "card-merchant-51-monthly", generateRuleRetryKey(request, types.FrequencyRule{Frequency: constants.MonthlyFrequency}))
+	})
+
+	t.Run("should share the category counter between the codes of the category", func(t *testing.T) {
+		rule := types.FrequencyRule{Frequency: constants.MonthlyFrequency, Category: constants.VisaIssuerCannotApproveNow}
+		other := request
+		other.Conditional = "61"
+
+		assert.Equal(t, "card-merchant-VISA_CATEGORY_2-monthly", generateRuleRetryKey(request, rule))
+		assert.Equal(t, generateRuleRetryKey(request, rule), generateRuleRetryKey(other, rule))
+	})
+}
+
 func TestGetValidRetries(t *testing.T) {
 	t.Run("should return daily time stamps", func(t *testing.T) {
 		currentDate := time.Now().UTC().UnixMilli()
@@ -156,11 +227,57 @@ func TestGetValidRetries(t *testing.T) {
 		oneDayExpired := currentDate - oneDayMiliSeconds - 1000
 
 		retries := []int64{oneDayValid, oneDayExpired}
-		res := getValidRetries(currentDate, retries, constants.DailyFrequency)
+		res := getValidRetries(currentDate, retries, types.FrequencyRule{WindowHours: constants.DayHours})
 		assert.Equal(t, 2, len(res))
 	})
 }
 
+func TestGetLongestWindowHours(t *testing.T) {
+	currentDate := time.Now().UnixMilli()
+	assert.Equal(t, constants.DayHours, getLongestWindowHours(nil, currentDate))
+	assert.Equal(t, constants.MonthDays*constants.DayHours, getLongestWindowHours([]types.FrequencyRule{
+		{WindowHours: constants.DayHours},
+		{WindowHours: constants.MonthDays * constants.DayHours},
+		{WindowHours: 72},
+	}, currentDate))
+}
+
+func TestBlockService_IgnoresExpiredItems(t *testing.T) {
+	expired := time.Now().Add(-time.Minute).Unix()
+	dynamoGtw := &coreMock.IDynamoGateway{}
+	dynamoGtw.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.DynamoBlockedCard")).
+		Run(func(args mock.Arguments) {
+			*args.Get(2).(*types.DynamoBlockedCard) = types.DynamoBlockedCard{CardID: "card1", ExpiresAt: expired}
+		}).
+		Return(nil)
+	dynamoGtw.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.CardRetry")).
+		Run(func(args mock.Arguments) {
+			*args.Get(2).(*types.CardRetry) = types.CardRetry{TimeStamp: 10, Retries: []int64{1}, ExpiresAt: expired}
+		}).
+		Return(nil)
+	bs := &BlockService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoGtw}
+
+	_, err := bs.getBlockedCard(context.Background(), "card1")
+	assert.ErrorIs(t, err, dynamoerror.ErrItemNotFound)
+
+	retry, err := bs.getRetry(context.Background(), "key")
+	assert.NoError(t, err)
+	assert.Empty(t, retry.Retries)
+	assert.Equal(t, int64(10), retry.TimeStamp)
+}
+
+func TestGetStrongestBlockType(t *testing.T) {
+	t.Run("should prefer PERMANENT over TEMPORARY", func(t *testing.T) {
+		res := getStrongestBlockType([]string{constants.TEMPORARY, constants.PERMANENT})
+		assert.Equal(t, constants.PERMANENT, res)
+	})
+
+	t.Run("should default to TEMPORARY", func(t *testing.T) {
+		res := getStrongestBlockType([]string{""})
+		assert.Equal(t, constants.TEMPORARY, res)
+	})
+}
+
 func testProcessBlock(t *testing.T, scenario blockCardScenario) {
 	t.Helper()
 	scenario.Request.CardID = "someCardId"
@@ -206,21 +323,39 @@ func testProcessBlock(t *testing.T, scenario blockCardScenario) {
 	dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).
 		Return(scenario.DynamoErrors.UpdateCard)
 
+	policiesMock := &mockService.IRetryPolicyService{}
+	policiesMock.On("GetShadowPolicy", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(types.RetryPolicy{}, false, nil)
+	policiesMock.On("GetPolicy", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(defaultRetryPolicies[scenario.Request.Franchise], scenario.PolicyError)
+
 	srv := BlockService{
-		Logger: mocks.GetMockLogger(t),
-		Dynamo: &dynamoMock,
+		Logger:      mocks.GetMockLogger(t),
+		Dynamo:      &dynamoMock,
+		Policies:    policiesMock,
+		History:     getHistoryMock(),
+		Idempotency: getIdempotencyMock(),
+		Settings:    getSettingsMock(),
 	}
-	err := srv.ProcessBlock(context.TODO(), fakeEvent)
+	result := srv.ProcessBlock(context.TODO(), fakeEvent)
 	if scenario.HasError {
-		assert.Error(t, err)
+		assert.Len(t, result.BatchItemFailures, 1)
 	} else {
-		assert.NoError(t, err)
+		assert.Empty(t, result.BatchItemFailures)
 	}
 }
 
 func TestNewBlockService(t *testing.T) {
 	t.Run("should no be empty", func(t *testing.T) {
-		assert.NotEmpty(t, NewBlockService(mocks.GetMockLogger(t), &coreMock.IDynamoGateway{}))
+		srv, err := NewBlockService(mocks.GetMockLogger(t), &coreMock.IDynamoGateway{})
+		assert.NoError(t, err)
+		assert.NotEmpty(t, srv)
+	})
+
+	t.Run("should fail with an invalid card velocity config", func(t *testing.T) {
+		t.Setenv(constants.EnvCardVelocityRules, "not json")
+		_, err := NewBlockService(mocks.GetMockLogger(t), &coreMock.IDynamoGateway{})
+		assert.ErrorIs(t, err, errInvalidPolicy)
 	})
 }
 
@@ -233,30 +368,863 @@ func TestInitBlockService(t *testing.T) {
 		initializeDynamoGtw = func(context.Context, logger.KushkiLogger) (dynamo.IDynamoGateway, error) {
 			return &coreMock.IDynamoGateway{}, commonError
 		}
-		err := InitBlockService(context.TODO(), fakeEvent)
+		_, err := InitBlockService(context.TODO(), fakeEvent)
 		assert.Error(t, err)
 	})
 
+	t.Run("should return an error if the card velocity config is invalid", func(t *testing.T) {
+		t.Cleanup(clean)
+		t.Setenv(constants.EnvCardVelocityRules, "not json")
+		newKushkiLogger = func(context.Context) logger.KushkiLogger {
+			return mocks.GetMockLogger(t)
+		}
+		initializeDynamoGtw = func(context.Context, logger.KushkiLogger) (dynamo.IDynamoGateway, error) {
+			return &coreMock.IDynamoGateway{}, nil
+		}
+		_, err := InitBlockService(context.TODO(), fakeEvent)
+		assert.ErrorIs(t, err, errInvalidPolicy)
+	})
+
 	t.Run("should be successfully if not error on init dependencies", func(t *testing.T) {
 		t.Cleanup(clean)
 		newKushkiLogger = func(context.Context) logger.KushkiLogger {
 			return mocks.GetMockLogger(t)
 		}
-		RefNewBlockService = func(logger.KushkiLogger, dynamo.IDynamoGateway) IBlockService {
+		RefNewBlockService = func(logger.KushkiLogger, dynamo.IDynamoGateway) (IBlockService, error) {
 			srv := &mockService.IBlockService{}
-			srv.On("ProcessBlock", mock.Anything, mock.Anything).Return(nil)
-			return srv
+			srv.On("ProcessBlock", mock.Anything, mock.Anything).Return(events.SQSEventResponse{})
+			return srv, nil
 		}
-		err := InitBlockService(context.TODO(), fakeEvent)
+		_, err := InitBlockService(context.TODO(), fakeEvent)
 		assert.NoError(t, err)
 	})
 }
 
+func TestBlockService_ProcessBlock_Conflict(t *testing.T) {
+	t.Run("should re-read the blocked card and replay the block on conflict", func(t *testing.T) {
+		t.Cleanup(clean)
+		sleepCaller = func(time.Duration) {}
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+			Return(nil).
+			Twice()
+		dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).
+			Return(&typesDynamo.ConditionalCheckFailedException{}).
+			Once()
+		dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).
+			Return(nil).
+			Once()
+		srv := BlockService{
+			Logger:      mocks.GetMockLogger(t),
+			Dynamo:      dynamoMock,
+			Policies:    &mockService.IRetryPolicyService{},
+			History:     getHistoryMock(),
+			Idempotency: getIdempotencyMock(),
+			Settings:    getSettingsMock(),
+		}
+		jsonUnmarshalCaller = func(_ []byte, v any) error {
+			out := v.(*types.BlockCardRequest)
+			*out = types.BlockCardRequest{Operation: constants.BlockCardOperation, CardID: "foo"}
+
+			return nil
+		}
+
+		result := srv.ProcessBlock(context.TODO(), fakeEvent)
+		assert.Empty(t, result.BatchItemFailures)
+		dynamoMock.AssertExpectations(t)
+	})
+
+	t.Run("should re-read the card retry and replay the increment on conflict", func(t *testing.T) {
+		t.Cleanup(clean)
+		sleepCaller = func(time.Duration) {}
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.DynamoBlockedCard")).
+			Return(nil).
+			Once()
+		// the generic counter conflicts once, the category counter of the decline goes through.
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.CardRetry")).
+			Return(nil).
+			Times(3)
+		dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).
+			Return(fmt.Errorf("update item: %w", &typesDynamo.ConditionalCheckFailedException{})).
+			Once()
+		dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).
+			Return(nil).
+			Twice()
+		policiesMock := &mockService.IRetryPolicyService{}
+		policiesMock.On("GetShadowPolicy", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(types.RetryPolicy{}, false, nil)
+		policiesMock.On("GetPolicy", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(defaultRetryPolicies[core.BrandVisa], nil)
+		srv := BlockService{
+			Logger:      mocks.GetMockLogger(t),
+			Dynamo:      dynamoMock,
+			Policies:    policiesMock,
+			History:     getHistoryMock(),
+			Idempotency: getIdempotencyMock(),
+			Settings:    getSettingsMock(),
+		}
+		jsonUnmarshalCaller = func(_ []byte, v any) error {
+			out := v.(*types.BlockCardRequest)
+			*out = types.BlockCardRequest{Operation: constants.RetryCardOperation, CardID: "foo", Franchise: core.BrandVisa}
+
+			return nil
+		}
+
+		result := srv.ProcessBlock(context.TODO(), fakeEvent)
+		assert.Empty(t, result.BatchItemFailures)
+		dynamoMock.AssertExpectations(t)
+	})
+}
+
+func TestBlockService_ProcessBlock_ConcurrentBlock(t *testing.T) {
+	t.Run("should not overwrite a block written meanwhile when updating the last retry", func(t *testing.T) {
+		t.Cleanup(clean)
+		sleepCaller = func(time.Duration) {}
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.DynamoBlockedCard")).
+			Return(nil).
+			Once()
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.CardRetry")).
+			Return(nil)
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.DynamoBlockedCard")).
+			Run(func(args mock.Arguments) {
+				*args[2].(*types.DynamoBlockedCard) = types.DynamoBlockedCard{
+					CardID: "foo",
+					BlockedMerchants: map[string]types.BlockedMerchant{
+						"merchant": {BlockType: constants.TEMPORARY, ExpirationDate: notExpiredRetry},
+					},
+				}
+			}).
+			Return(nil).
+			Once()
+		dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).
+			Return(nil).
+			Twice()
+		dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).
+			Return(&typesDynamo.ConditionalCheckFailedException{}).
+			Once()
+		policiesMock := &mockService.IRetryPolicyService{}
+		policiesMock.On("GetShadowPolicy", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(types.RetryPolicy{}, false, nil)
+		policiesMock.On("GetPolicy", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(defaultRetryPolicies[core.BrandMasterCard], nil)
+		srv := BlockService{
+			Logger:      mocks.GetMockLogger(t),
+			Dynamo:      dynamoMock,
+			Policies:    policiesMock,
+			History:     getHistoryMock(),
+			Idempotency: getIdempotencyMock(),
+			Settings:    getSettingsMock(),
+		}
+		jsonUnmarshalCaller = func(_ []byte, v any) error {
+			*v.(*types.BlockCardRequest) = types.BlockCardRequest{
+				Operation: constants.RetryCardOperation, CardID: "foo", Franchise: core.BrandMasterCard, MerchantIdentifier: "merchant",
+			}
+			return nil
+		}
+
+		result := srv.ProcessBlock(context.TODO(), fakeEvent)
+		assert.Empty(t, result.BatchItemFailures)
+		dynamoMock.AssertExpectations(t)
+	})
+
+	t.Run("should not downgrade a permanent block written meanwhile", func(t *testing.T) {
+		t.Cleanup(clean)
+		sleepCaller = func(time.Duration) {}
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.DynamoBlockedCard")).
+			Return(nil).
+			Once()
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.CardRetry")).
+			Run(func(args mock.Arguments) {
+				*args[2].(*types.CardRetry) = types.CardRetry{Retries: notExpiredRetries}
+			}).
+			Return(nil)
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.DynamoBlockedCard")).
+			Run(func(args mock.Arguments) {
+				*args[2].(*types.DynamoBlockedCard) = types.DynamoBlockedCard{
+					CardID: "foo",
+					BlockedMerchants: map[string]types.BlockedMerchant{
+						"merchant": {BlockType: constants.PERMANENT},
+					},
+				}
+			}).
+			Return(nil).
+			Once()
+		// generic and category counters.
+		dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).
+			Return(nil).
+			Twice()
+		dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).
+			Return(&typesDynamo.ConditionalCheckFailedException{}).
+			Once()
+		policiesMock := &mockService.IRetryPolicyService{}
+		policiesMock.On("GetShadowPolicy", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(types.RetryPolicy{}, false, nil)
+		policiesMock.On("GetPolicy", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(defaultRetryPolicies[core.BrandVisa], nil)
+		historyMock := &mockService.IHistoryService{}
+		historyMock.On("Record", mock.Anything, mock.MatchedBy(func(entry types.BlockHistoryEntry) bool {
+			return entry.Operation == constants.HistoryRetryIncrement
+		})).Return().Twice()
+		srv := BlockService{
+			Logger:      mocks.GetMockLogger(t),
+			Dynamo:      dynamoMock,
+			Policies:    policiesMock,
+			History:     historyMock,
+			Idempotency: getIdempotencyMock(),
+			Settings:    getSettingsMock(),
+		}
+		jsonUnmarshalCaller = func(_ []byte, v any) error {
+			*v.(*types.BlockCardRequest) = types.BlockCardRequest{
+				Operation: constants.RetryCardOperation, CardID: "foo", Franchise: core.BrandVisa, MerchantIdentifier: "merchant",
+			}
+			return nil
+		}
+
+		result := srv.ProcessBlock(context.TODO(), fakeEvent)
+		assert.Empty(t, result.BatchItemFailures)
+		dynamoMock.AssertExpectations(t)
+		historyMock.AssertExpectations(t)
+	})
+}
+
+func TestBlockService_ProcessBlock_NeverRetry(t *testing.T) {
+	t.Run("should block permanently a do not try again decline without checking retries", func(t *testing.T) {
+		t.Cleanup(clean)
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.DynamoBlockedCard")).
+			Return(nil).
+			Once()
+		dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).Return(nil).Once()
+		historyMock := &mockService.IHistoryService{}
+		historyMock.On("Record", mock.Anything, mock.MatchedBy(func(entry types.BlockHistoryEntry) bool {
+			return entry.Operation == constants.HistoryPermanentBlock && entry.Category == constants.MasterCardDoNotTryAgain
+		})).Return().Once()
+		srv := BlockService{
+			Logger:      mocks.GetMockLogger(t),
+			Dynamo:      dynamoMock,
+			Policies:    &mockService.IRetryPolicyService{},
+			History:     historyMock,
+			Idempotency: getIdempotencyMock(),
+			Settings:    getSettingsMock(),
+		}
+		jsonUnmarshalCaller = func(_ []byte, v any) error {
+			*v.(*types.BlockCardRequest) = types.BlockCardRequest{
+				Operation: constants.RetryCardOperation, CardID: "foo", Franchise: core.BrandMasterCard, Conditional: "21",
+			}
+			return nil
+		}
+
+		result := srv.ProcessBlock(context.TODO(), fakeEvent)
+		assert.Empty(t, result.BatchItemFailures)
+		dynamoMock.AssertExpectations(t)
+		historyMock.AssertExpectations(t)
+	})
+}
+
+func TestBlockService_ProcessBlock_MerchantAdviceCodes(t *testing.T) {
+	scenarios := []struct {
+		conditional string
+		blockHours  int
+	}{
+		{conditional: "01", blockHours: 24},
+		{conditional: "02", blockHours: 72},
+		{conditional: "24", blockHours: 1},
+		{conditional: "25", blockHours: 24},
+		{conditional: "26", blockHours: 2 * 24},
+		{conditional: "27", blockHours: 4 * 24},
+		{conditional: "28", blockHours: 6 * 24},
+		{conditional: "29", blockHours: 8 * 24},
+		{conditional: "30", blockHours: 10 * 24},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(fmt.Sprintf("should block the MAC %s until its wait ends", scenario.conditional), func(t *testing.T) {
+			t.Cleanup(clean)
+			jsonUnmarshalCaller = func(_ []byte, v any) error {
+				*v.(*types.BlockCardRequest) = types.BlockCardRequest{
+					Operation: constants.RetryCardOperation, CardID: "foo", MerchantIdentifier: "merchant",
+					Franchise: core.BrandMasterCard, Conditional: scenario.conditional,
+				}
+				return nil
+			}
+			dynamoMock := &coreMock.IDynamoGateway{}
+			dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.DynamoBlockedCard")).
+				Run(func(args mock.Arguments) {
+					*args[2].(*types.DynamoBlockedCard) = types.DynamoBlockedCard{CardID: "foo"}
+				}).
+				Return(nil)
+			dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.CardRetry")).
+				Return(dynamoerror.ErrItemNotFound)
+			dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).Return(nil)
+			policiesMock := &mockService.IRetryPolicyService{}
+			policiesMock.On("GetShadowPolicy", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(types.RetryPolicy{}, false, nil)
+			policiesMock.On("GetPolicy", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(defaultRetryPolicies[core.BrandMasterCard], nil)
+			srv := BlockService{
+				Logger:      mocks.GetMockLogger(t),
+				Dynamo:      dynamoMock,
+				Policies:    policiesMock,
+				History:     getHistoryMock(),
+				Idempotency: getIdempotencyMock(),
+				Settings:    getSettingsMock(),
+			}
+			blockHours := time.Duration(scenario.blockHours) * time.Hour
+			from := time.Now().Add(blockHours).UnixMilli()
+
+			result := srv.ProcessBlock(context.TODO(), fakeEvent)
+
+			to := time.Now().Add(blockHours).UnixMilli()
+			assert.Empty(t, result.BatchItemFailures)
+			dynamoMock.AssertCalled(t, "UpdateItem", mock.Anything, mock.MatchedBy(func(b *builder.UpdateItemBuilder) bool {
+				expirationDate, ok := getBlockExpirationDate(t, b)
+				return ok && expirationDate >= from && expirationDate <= to
+			}))
+		})
+	}
+}
+
+// getBlockExpirationDate expiration date of the temporary merchant block written by the update.
+func getBlockExpirationDate(t *testing.T, update *builder.UpdateItemBuilder) (int64, bool) {
+	t.Helper()
+	input, err := update.BuildInput()
+	if err != nil {
+		return 0, false
+	}
+	for _, value := range input.ExpressionAttributeValues {
+		entry, ok := value.(*typesDynamo.AttributeValueMemberM)
+		if !ok {
+			continue
+		}
+		blockType, ok := entry.Value["blockType"].(*typesDynamo.AttributeValueMemberS)
+		if !ok || blockType.Value != constants.TEMPORARY {
+			continue
+		}
+		expirationDate, ok := entry.Value["expirationDate"].(*typesDynamo.AttributeValueMemberN)
+		if !ok {
+			continue
+		}
+		parsed, err := strconv.ParseInt(expirationDate.Value, 10, 64)
+
+		return parsed, err == nil
+	}
+
+	return 0, false
+}
+
+func TestBlockService_ProcessBlock_History(t *testing.T) {
+	t.Run("should record the expired block and the new permanent block", func(t *testing.T) {
+		t.Cleanup(clean)
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				*args[2].(*types.DynamoBlockedCard) = types.DynamoBlockedCard{
+					CardID: "foo",
+					BlockedMerchants: map[string]types.BlockedMerchant{
+						"merchant": {BlockType: constants.TEMPORARY, ExpirationDate: 1},
+					},
+				}
+			}).
+			Return(nil)
+		dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).Return(nil).Once()
+		historyMock := &mockService.IHistoryService{}
+		historyMock.On("Record", mock.Anything, mock.MatchedBy(func(entry types.BlockHistoryEntry) bool {
+			return entry.Operation == constants.HistoryExpired && entry.MerchantID == "merchant"
+		})).Return().Once()
+		historyMock.On("Record", mock.Anything, mock.MatchedBy(func(entry types.BlockHistoryEntry) bool {
+			return entry.Operation == constants.HistoryPermanentBlock && entry.BlockType == constants.PERMANENT
+		})).Return().Once()
+		srv := BlockService{
+			Logger:      mocks.GetMockLogger(t),
+			Dynamo:      dynamoMock,
+			Policies:    &mockService.IRetryPolicyService{},
+			History:     historyMock,
+			Idempotency: getIdempotencyMock(),
+			Settings:    getSettingsMock(),
+		}
+		jsonUnmarshalCaller = func(_ []byte, v any) error {
+			*v.(*types.BlockCardRequest) = types.BlockCardRequest{
+				Operation: constants.BlockCardOperation, CardID: "foo", MerchantIdentifier: "merchant",
+			}
+			return nil
+		}
+
+		result := srv.ProcessBlock(context.TODO(), fakeEvent)
+		assert.Empty(t, result.BatchItemFailures)
+		historyMock.AssertExpectations(t)
+	})
+
+	t.Run("should remove and record the expired block of a policy without daily retries", func(t *testing.T) {
+		t.Cleanup(clean)
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.DynamoBlockedCard")).
+			Run(func(args mock.Arguments) {
+				*args[2].(*types.DynamoBlockedCard) = types.DynamoBlockedCard{
+					CardID: "foo",
+					BlockedMerchants: map[string]types.BlockedMerchant{
+						"merchant": {BlockType: constants.TEMPORARY, ExpirationDate: 1},
+					},
+				}
+			}).
+			Return(nil).
+			Once()
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.CardRetry")).
+			Return(dynamoerror.ErrItemNotFound)
+		dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).Return(nil).Times(3)
+		historyMock := &mockService.IHistoryService{}
+		historyMock.On("Record", mock.Anything, mock.MatchedBy(func(entry types.BlockHistoryEntry) bool {
+			return entry.Operation == constants.HistoryRetryIncrement && entry.RetriesBefore == 0 && entry.RetriesAfter == 1
+		})).Return().Twice()
+		historyMock.On("Record", mock.Anything, mock.MatchedBy(func(entry types.BlockHistoryEntry) bool {
+			return entry.Operation == constants.HistoryExpired && entry.MerchantID == "merchant"
+		})).Return().Once()
+		policiesMock := &mockService.IRetryPolicyService{}
+		policiesMock.On("GetShadowPolicy", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(types.RetryPolicy{}, false, nil)
+		policiesMock.On("GetPolicy", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(defaultRetryPolicies[core.BrandVisa], nil)
+		srv := BlockService{
+			Logger:      mocks.GetMockLogger(t),
+			Dynamo:      dynamoMock,
+			Policies:    policiesMock,
+			History:     historyMock,
+			Idempotency: getIdempotencyMock(),
+			Settings:    getSettingsMock(),
+		}
+		jsonUnmarshalCaller = func(_ []byte, v any) error {
+			*v.(*types.BlockCardRequest) = types.BlockCardRequest{
+				Operation: constants.RetryCardOperation, CardID: "foo", Franchise: core.BrandVisa, MerchantIdentifier: "merchant",
+			}
+			return nil
+		}
+
+		result := srv.ProcessBlock(context.TODO(), fakeEvent)
+		assert.Empty(t, result.BatchItemFailures)
+		dynamoMock.AssertExpectations(t)
+		historyMock.AssertExpectations(t)
+	})
+
+	t.Run("should record the windowed counters of the rule that blocked the card", func(t *testing.T) {
+		t.Cleanup(clean)
+		rule := defaultRetryPolicies[core.BrandVisa].Rules[0]
+		outsideWindow := time.Now().UTC().Add(-time.Duration(rule.WindowHours+1) * time.Hour).UnixMilli()
+		retries := []int64{outsideWindow}
+		for i := 1; i < rule.MaxAttempts; i++ {
+			retries = append(retries, notExpiredRetry)
+		}
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.DynamoBlockedCard")).
+			Return(nil)
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.CardRetry")).
+			Run(func(args mock.Arguments) {
+				*args[2].(*types.CardRetry) = types.CardRetry{Retries: retries}
+			}).
+			Return(nil)
+		dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).Return(nil).Times(3)
+		historyMock := &mockService.IHistoryService{}
+		historyMock.On("Record", mock.Anything, mock.MatchedBy(func(entry types.BlockHistoryEntry) bool {
+			return entry.Operation == constants.HistoryRetryIncrement &&
+				entry.RetriesBefore == rule.MaxAttempts-1 && entry.RetriesAfter == rule.MaxAttempts
+		})).Return().Twice()
+		historyMock.On("Record", mock.Anything, mock.MatchedBy(func(entry types.BlockHistoryEntry) bool {
+			return entry.Operation == constants.HistoryTemporaryBlock && entry.Frequency == rule.Frequency &&
+				entry.RetriesBefore == rule.MaxAttempts-1 && entry.RetriesAfter == rule.MaxAttempts
+		})).Return().Once()
+		policiesMock := &mockService.IRetryPolicyService{}
+		policiesMock.On("GetShadowPolicy", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(types.RetryPolicy{}, false, nil)
+		policiesMock.On("GetPolicy", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(defaultRetryPolicies[core.BrandVisa], nil)
+		srv := BlockService{
+			Logger:      mocks.GetMockLogger(t),
+			Dynamo:      dynamoMock,
+			Policies:    policiesMock,
+			History:     historyMock,
+			Idempotency: getIdempotencyMock(),
+			Settings:    getSettingsMock(),
+		}
+		jsonUnmarshalCaller = func(_ []byte, v any) error {
+			*v.(*types.BlockCardRequest) = types.BlockCardRequest{
+				Operation: constants.RetryCardOperation, CardID: "foo", Franchise: core.BrandVisa, MerchantIdentifier: "merchant",
+			}
+			return nil
+		}
+
+		result := srv.ProcessBlock(context.TODO(), fakeEvent)
+		assert.Empty(t, result.BatchItemFailures)
+		dynamoMock.AssertExpectations(t)
+		historyMock.AssertExpectations(t)
+	})
+}
+
+func TestRetryOnConflict(t *testing.T) {
+	t.Run("should give up after the max retries", func(t *testing.T) {
+		t.Cleanup(clean)
+		sleeps := 0
+		sleepCaller = func(time.Duration) { sleeps++ }
+		calls := 0
+		err := retryOnConflict(mocks.GetMockLogger(t), blockSrvTag, func(int) error {
+			calls++
+			return &typesDynamo.ConditionalCheckFailedException{}
+		})
+		assert.Error(t, err)
+		assert.Equal(t, constants.ConflictMaxRetries+1, calls)
+		assert.Equal(t, constants.ConflictMaxRetries, sleeps)
+	})
+
+	t.Run("should not retry other errors", func(t *testing.T) {
+		t.Cleanup(clean)
+		calls := 0
+		err := retryOnConflict(mocks.GetMockLogger(t), blockSrvTag, func(int) error {
+			calls++
+			return commonError
+		})
+		assert.Equal(t, commonError, err)
+		assert.Equal(t, 1, calls)
+	})
+}
+
+func TestConflictBackoff(t *testing.T) {
+	t.Run("should stay between 1ms and the max delay", func(t *testing.T) {
+		for attempt := 1; attempt <= 10; attempt++ {
+			delay := conflictBackoff(attempt)
+			assert.GreaterOrEqual(t, delay, time.Millisecond)
+			assert.LessOrEqual(t, delay, time.Duration(constants.ConflictMaxDelayMs)*time.Millisecond)
+		}
+	})
+}
+
 func clean() {
 	RefNewRestoreService = NewRestoreService
 	RefNewBlockService = NewBlockService
+	RefNewSweeperService = NewSweeperService
 	refNewCheckCardStatusService = NewCheckCardStatusService
+	refNewCardAdminService = NewCardAdminService
+	refNewHistoryService = NewHistoryService
+	refNewShadowReportService = NewShadowReportService
 	newKushkiLogger = middleware.GetLoggerFromContext
 	initializeDynamoGtw = tools.InitializeDynamoGtw
 	jsonUnmarshalCaller = json.Unmarshal
+	sleepCaller = time.Sleep
+	nowCaller = time.Now
+	cleanSettingsCache()
+}
+
+func TestBlockService_ProcessBlock_Approve(t *testing.T) {
+	request := types.BlockCardRequest{CardID: "card1", MerchantIdentifier: "merchant1", Operation: constants.ApproveCardOperation}
+	scenarios := []struct {
+		name          string
+		blockedCard   types.DynamoBlockedCard
+		getErr        error
+		deleteErr     error
+		updateErrs    []error
+		expectUpdates int
+		hasError      bool
+	}{
+		{
+			name:          "should delete every retry and clear the last retry",
+			blockedCard:   types.DynamoBlockedCard{CardID: "card1", BlockedMerchants: map[string]types.BlockedMerchant{"merchant1": {LastRetry: 10}}},
+			expectUpdates: 1,
+		},
+		{
+			name:          "should retry the last retry clean on conflict",
+			blockedCard:   types.DynamoBlockedCard{CardID: "card1", BlockedMerchants: map[string]types.BlockedMerchant{"merchant1": {LastRetry: 10}}},
+			updateErrs:    []error{&typesDynamo.ConditionalCheckFailedException{}},
+			expectUpdates: 2,
+		},
+		{
+			name:        "should keep the block of the merchant untouched",
+			blockedCard: types.DynamoBlockedCard{CardID: "card1", BlockedMerchants: map[string]types.BlockedMerchant{"merchant1": {BlockType: constants.TEMPORARY}}},
+		},
+		{
+			name:   "should only delete the retries when the card has no record",
+			getErr: dynamoerror.ErrItemNotFound,
+		},
+		{
+			name:      "should fail when a retry cannot be deleted",
+			deleteErr: commonError,
+			hasError:  true,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Cleanup(clean)
+			sleepCaller = func(time.Duration) {}
+			jsonUnmarshalCaller = func(_ []byte, v any) error {
+				*v.(*types.BlockCardRequest) = request
+				return nil
+			}
+			dynamoMock := &coreMock.IDynamoGateway{}
+			dynamoMock.On("Query", mock.Anything, mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) {
+					*args.Get(2).(*[]types.CardRetry) = []types.CardRetry{
+						{RetryKey: "card1-merchant1-daily", Retries: []int64{1, 2}},
+						{RetryKey: "card1-merchant1-51-monthly", Retries: []int64{1}},
+					}
+				}).
+				Return(nil)
+			dynamoMock.On("DeleteItem", mock.Anything, mock.Anything).Return(scenario.deleteErr)
+			dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) {
+					*args.Get(2).(*types.DynamoBlockedCard) = scenario.blockedCard
+				}).
+				Return(scenario.getErr)
+			for _, err := range scenario.updateErrs {
+				dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).Return(err).Once()
+			}
+			dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).Return(nil)
+			historyMock := getHistoryMock()
+			srv := BlockService{
+				Logger:      mocks.GetMockLogger(t),
+				Dynamo:      dynamoMock,
+				History:     historyMock,
+				Idempotency: getIdempotencyMock(),
+				Settings:    getSettingsMock(),
+			}
+
+			result := srv.ProcessBlock(context.TODO(), fakeEvent)
+
+			if scenario.hasError {
+				assert.Len(t, result.BatchItemFailures, 1)
+				return
+			}
+			assert.Empty(t, result.BatchItemFailures)
+			dynamoMock.AssertNumberOfCalls(t, "DeleteItem", 2)
+			dynamoMock.AssertNumberOfCalls(t, "UpdateItem", scenario.expectUpdates)
+			historyMock.AssertCalled(t, "Record", mock.Anything, types.BlockHistoryEntry{
+				CardID: "card1", MerchantID: "merchant1", Operation: constants.HistoryApproved, Frequency: constants.MonthlyFrequency, RetriesBefore: 1,
+			})
+		})
+	}
+}
+
+func TestGetRetryFrequency(t *testing.T) {
+	assert.Equal(t, constants.DailyFrequency, getRetryFrequency("card-merchant-daily"))
+	assert.Equal(t, constants.RetryAfterFrequency, getRetryFrequency("card-merchant-MC_MAC_RETRY_AFTER_1H-retryAfter"))
+}
+
+func TestGenerateBlockedMerchant(t *testing.T) {
+	now := time.Date(2025, 3, 20, 12, 0, 0, 0, time.UTC)
+	escalation := &types.BlockEscalation{BlockHours: []int{24, 72}}
+	inWindow := now.AddDate(0, 0, -10).UnixMilli()
+	scenarios := []struct {
+		name      string
+		blockType string
+		trigger   blockTrigger
+		current   types.BlockedMerchant
+		expected  types.BlockedMerchant
+	}{
+		{
+			name:      "should block a day by default",
+			blockType: constants.TEMPORARY,
+			expected:  types.BlockedMerchant{BlockType: constants.TEMPORARY, ExpirationDate: now.Add(24 * time.Hour).UnixMilli()},
+		},
+		{
+			name:      "should block for the hours of the rule",
+			blockType: constants.TEMPORARY,
+			trigger:   blockTrigger{BlockHours: 6},
+			expected:  types.BlockedMerchant{BlockType: constants.TEMPORARY, ExpirationDate: now.Add(6 * time.Hour).UnixMilli()},
+		},
+		{
+			name:      "should start the escalation with the first step",
+			blockType: constants.TEMPORARY,
+			trigger:   blockTrigger{BlockHours: 6, Escalation: escalation},
+			expected: types.BlockedMerchant{
+				BlockType: constants.TEMPORARY, ExpirationDate: now.Add(24 * time.Hour).UnixMilli(),
+				EscalationCount: 1, EscalationStart: now.UnixMilli(),
+			},
+		},
+		{
+			name:      "should escalate the second block inside the window",
+			blockType: constants.TEMPORARY,
+			trigger:   blockTrigger{Escalation: escalation},
+			current:   types.BlockedMerchant{LastRetry: 1, EscalationCount: 1, EscalationStart: inWindow},
+			expected: types.BlockedMerchant{
+				BlockType: constants.TEMPORARY, ExpirationDate: now.Add(72 * time.Hour).UnixMilli(),
+				EscalationCount: 2, EscalationStart: inWindow,
+			},
+		},
+		{
+			name:      "should block permanently past the last step",
+			blockType: constants.TEMPORARY,
+			trigger:   blockTrigger{Escalation: escalation},
+			current:   types.BlockedMerchant{EscalationCount: 2, EscalationStart: inWindow},
+			expected: types.BlockedMerchant{
+				BlockType: constants.PERMANENT, ExpirationDate: now.Add(24 * time.Hour).UnixMilli(),
+				EscalationCount: 3, EscalationStart: inWindow,
+			},
+		},
+		{
+			name:      "should restart the escalation after the window",
+			blockType: constants.TEMPORARY,
+			trigger:   blockTrigger{Escalation: &types.BlockEscalation{BlockHours: []int{24, 72}, WindowDays: 7}},
+			current:   types.BlockedMerchant{EscalationCount: 2, EscalationStart: inWindow},
+			expected: types.BlockedMerchant{
+				BlockType: constants.TEMPORARY, ExpirationDate: now.Add(24 * time.Hour).UnixMilli(),
+				EscalationCount: 1, EscalationStart: now.UnixMilli(),
+			},
+		},
+		{
+			name:      "should keep the escalation on a permanent block",
+			blockType: constants.PERMANENT,
+			trigger:   blockTrigger{Escalation: escalation},
+			current:   types.BlockedMerchant{EscalationCount: 1, EscalationStart: inWindow},
+			expected: types.BlockedMerchant{
+				BlockType: constants.PERMANENT, ExpirationDate: now.Add(24 * time.Hour).UnixMilli(),
+				EscalationCount: 1, EscalationStart: inWindow,
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			assert.Equal(t, scenario.expected, generateBlockedMerchant(scenario.blockType, scenario.trigger, scenario.current, now))
+		})
+	}
+}
+
+func TestBlockService_ProcessBlock_Escalation(t *testing.T) {
+	t.Cleanup(clean)
+	jsonUnmarshalCaller = func(_ []byte, v any) error {
+		*v.(*types.BlockCardRequest) = types.BlockCardRequest{
+			Operation: constants.RetryCardOperation, CardID: "foo", MerchantIdentifier: "merchant", Franchise: core.BrandMasterCard,
+		}
+		return nil
+	}
+	dynamoMock := &coreMock.IDynamoGateway{}
+	dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.DynamoBlockedCard")).
+		Run(func(args mock.Arguments) {
+			*args[2].(*types.DynamoBlockedCard) = types.DynamoBlockedCard{
+				CardID: "foo",
+				BlockedMerchants: map[string]types.BlockedMerchant{
+					"merchant": {LastRetry: 1, EscalationCount: 2, EscalationStart: time.Now().Add(-time.Hour).UnixMilli()},
+				},
+			}
+		}).
+		Return(nil)
+	dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.CardRetry")).
+		Return(dynamoerror.ErrItemNotFound)
+	dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).Return(nil)
+	policiesMock := &mockService.IRetryPolicyService{}
+	policiesMock.On("GetShadowPolicy", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(types.RetryPolicy{}, false, nil)
+	policiesMock.On("GetPolicy", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+		Return(types.RetryPolicy{
+			Rules:      []types.FrequencyRule{{Frequency: constants.DailyFrequency, WindowHours: 24, MaxAttempts: 1, BlockType: constants.TEMPORARY}},
+			Escalation: &types.BlockEscalation{BlockHours: []int{24, 72}},
+		}, nil)
+	historyMock := getHistoryMock()
+	srv := BlockService{
+		Logger:      mocks.GetMockLogger(t),
+		Dynamo:      dynamoMock,
+		Policies:    policiesMock,
+		History:     historyMock,
+		Idempotency: getIdempotencyMock(),
+		Settings:    getSettingsMock(),
+	}
+
+	result := srv.ProcessBlock(context.TODO(), fakeEvent)
+
+	assert.Empty(t, result.BatchItemFailures)
+	historyMock.AssertCalled(t, "Record", mock.Anything, mock.MatchedBy(func(entry types.BlockHistoryEntry) bool {
+		return entry.Operation == constants.HistoryPermanentBlock && entry.BlockType == constants.PERMANENT
+	}))
+}
+
+func TestBlockService_ProcessBlock_MerchantSettings(t *testing.T) {
+	scenarios := []struct {
+		name           string
+		settings       types.MerchantSettings
+		settingsErr    error
+		expectFailures int
+		expectReads    int
+		expectUpdates  int
+		expectRecord   string
+	}{
+		{
+			name:     "should skip an exempt merchant",
+			settings: types.MerchantSettings{Exempt: true},
+		},
+		{
+			name:          "should only record the block of a shadow merchant",
+			settings:      types.MerchantSettings{Shadow: true},
+			expectReads:   1,
+			expectUpdates: 1,
+			expectRecord:  constants.HistoryShadowBlock,
+		},
+		{
+			name:          "should scale the limits with the merchant factor",
+			settings:      types.MerchantSettings{LimitFactor: 2},
+			expectReads:   1,
+			expectUpdates: 2,
+		},
+		{
+			name:          "should block with the policy limits by default",
+			expectReads:   1,
+			expectUpdates: 2,
+			expectRecord:  constants.HistoryTemporaryBlock,
+		},
+		{
+			name:           "should fail the record if the settings cannot be read",
+			settingsErr:    commonError,
+			expectFailures: 1,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			t.Cleanup(clean)
+			jsonUnmarshalCaller = func(_ []byte, v any) error {
+				*v.(*types.BlockCardRequest) = types.BlockCardRequest{
+					Operation: constants.RetryCardOperation, CardID: "foo", MerchantIdentifier: "merchant", Franchise: core.BrandMasterCard,
+				}
+				return nil
+			}
+			dynamoMock := &coreMock.IDynamoGateway{}
+			dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.DynamoBlockedCard")).
+				Run(func(args mock.Arguments) {
+					*args[2].(*types.DynamoBlockedCard) = types.DynamoBlockedCard{CardID: "foo"}
+				}).
+				Return(nil)
+			dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.CardRetry")).
+				Return(dynamoerror.ErrItemNotFound)
+			dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).Return(nil)
+			policiesMock := &mockService.IRetryPolicyService{}
+			policiesMock.On("GetShadowPolicy", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(types.RetryPolicy{}, false, nil)
+			policiesMock.On("GetPolicy", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(types.RetryPolicy{
+					Rules: []types.FrequencyRule{{Frequency: constants.DailyFrequency, WindowHours: 24, MaxAttempts: 1, BlockType: constants.TEMPORARY}},
+				}, nil)
+			settingsMock := &mockService.IMerchantSettingsService{}
+			settingsMock.On("GetSettings", mock.Anything, "merchant").Return(scenario.settings, scenario.settingsErr)
+			historyMock := getHistoryMock()
+			srv := BlockService{
+				Logger:      mocks.GetMockLogger(t),
+				Dynamo:      dynamoMock,
+				Policies:    policiesMock,
+				History:     historyMock,
+				Idempotency: getIdempotencyMock(),
+				Settings:    settingsMock,
+			}
+
+			result := srv.ProcessBlock(context.TODO(), fakeEvent)
+
+			assert.Len(t, result.BatchItemFailures, scenario.expectFailures)
+			dynamoMock.AssertNumberOfCalls(t, "GetItem", 2*scenario.expectReads)
+			dynamoMock.AssertNumberOfCalls(t, "UpdateItem", scenario.expectUpdates)
+			historyMock.AssertNotCalled(t, "Record", mock.Anything, mock.MatchedBy(func(entry types.BlockHistoryEntry) bool {
+				return entry.Operation != scenario.expectRecord && entry.Operation != constants.HistoryRetryIncrement
+			}))
+			if scenario.expectRecord != "" {
+				historyMock.AssertCalled(t, "Record", mock.Anything, mock.MatchedBy(func(entry types.BlockHistoryEntry) bool {
+					return entry.Operation == scenario.expectRecord && entry.Frequency == constants.DailyFrequency
+				}))
+			}
+		})
+	}
 }
@@ -0,0 +1,278 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	constants "bitbucket.org/kushki/usrv-card-control"
+	"bitbucket.org/kushki/usrv-card-control/gateway"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	errorsCore "bitbucket.org/kushki/usrv-go-core/errors"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo/builder"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const cardAdminSrvTag = "CardAdminService | %s"
+
+var (
+	refNewCardAdminService = NewCardAdminService
+
+	errMissingOperator   = errors.New("missing authenticated operator")
+	errMissingReason     = errors.New("reason is required")
+	errMissingMerchant   = errors.New("merchantIdentifier is required for this operation")
+	errInvalidBlockType  = errors.New("blockType must be TEMPORARY or PERMANENT")
+	errInvalidBlockHours = errors.New("blockHours must not be negative")
+	errInvalidOperation  = errors.New("unsupported operation")
+	errCardNotFound      = errors.New("card has no blocks or retries registered")
+)
+
+// ICardAdminService manual administration of card blocks and retries.
+type ICardAdminService interface {
+	ExecuteAction(ctx context.Context, request types.CardAdminRequest, operator string) (types.CardAdminResponse, error)
+}
+
+// CardAdminService lets support teams lift or force blocks and reset retry counters.
+type CardAdminService struct {
+	Logger  logger.KushkiLogger
+	Dynamo  dynamo.IDynamoGateway
+	History IHistoryService
+}
+
+// NewCardAdminService function to instantiate.
+func NewCardAdminService(kskLogger logger.KushkiLogger, dynamoGtw dynamo.IDynamoGateway) ICardAdminService {
+	return &CardAdminService{
+		Logger:  kskLogger,
+		Dynamo:  dynamoGtw,
+		History: NewHistoryService(kskLogger, dynamoGtw),
+	}
+}
+
+// InitializeCardAdmin init dependencies and run the requested action with the authenticated operator.
+func InitializeCardAdmin(ctx context.Context, event events.APIGatewayProxyRequest) (types.CardAdminResponse, error) {
+	tag := fmt.Sprintf(cardAdminSrvTag, "InitializeCardAdmin")
+
+	kskLogger := newKushkiLogger(ctx)
+
+	var request types.CardAdminRequest
+	if err := jsonUnmarshalCaller([]byte(event.Body), &request); err != nil {
+		kskLogger.Error(tag, err)
+		return types.CardAdminResponse{}, newServiceError(errorsCore.E001, tag, err)
+	}
+
+	operator := getOperator(event)
+	if err := validateCardAdminRequest(request, operator); err != nil {
+		kskLogger.Error(tag, err)
+		return types.CardAdminResponse{}, newServiceError(errorsCore.E001, tag, err)
+	}
+
+	dynamoGtw, err := initializeDynamoGtw(ctx, kskLogger)
+	if err != nil {
+		kskLogger.Error(tag, err)
+		return types.CardAdminResponse{}, newServiceError(errorsCore.E002, tag, err)
+	}
+
+	service := refNewCardAdminService(kskLogger, dynamoGtw)
+
+	response, err := service.ExecuteAction(ctx, request, operator)
+	if errors.Is(err, errCardNotFound) {
+		kskLogger.Error(tag, err)
+		return types.CardAdminResponse{}, newServiceError(errorsCore.E001, tag, err)
+	}
+	if err != nil {
+		kskLogger.Error(tag, err)
+		return types.CardAdminResponse{}, newServiceError(errorsCore.E002, tag, err)
+	}
+
+	return response, nil
+}
+
+// ExecuteAction applies the operation and records the operator and reason on the blocked card.
+func (as *CardAdminService) ExecuteAction(ctx context.Context, request types.CardAdminRequest, operator string) (types.CardAdminResponse, error) {
+	action := types.AdminAction{
+		Operation:  request.Operation,
+		MerchantID: request.MerchantIdentifier,
+		Operator:   operator,
+		Reason:     request.Reason,
+		Date:       time.Now().UTC().UnixMilli(),
+	}
+	as.Logger.Info(fmt.Sprintf(cardAdminSrvTag, "ExecuteAction"), action)
+
+	blockedCard, err := as.getBlockedCard(ctx, request.CardID)
+	if errors.Is(err, dynamoerror.ErrItemNotFound) {
+		if !strings.EqualFold(request.Operation, constants.AdminBlockOperation) {
+			return types.CardAdminResponse{}, errCardNotFound
+		}
+		blockedCard, err = as.generateNewBlockedCard(ctx, request.CardID)
+	}
+	if err != nil {
+		return types.CardAdminResponse{}, err
+	}
+
+	if err := as.cleanRetries(ctx, request, blockedCard); err != nil {
+		return types.CardAdminResponse{}, err
+	}
+
+	err = retryOnConflict(as.Logger, cardAdminSrvTag, func(attempt int) error {
+		if attempt > 0 {
+			if blockedCard, err = as.getBlockedCard(ctx, request.CardID); err != nil {
+				return err
+			}
+		}
+
+		return as.Dynamo.UpdateItem(ctx, generateAdminUpdate(request, action, blockedCard))
+	})
+	if err != nil {
+		return types.CardAdminResponse{}, err
+	}
+
+	as.History.Record(ctx, types.BlockHistoryEntry{
+		CardID:     request.CardID,
+		MerchantID: request.MerchantIdentifier,
+		Operation:  constants.HistoryAdminPrefix + strings.ToUpper(request.Operation),
+		BlockType:  strings.ToUpper(request.BlockType),
+		Operator:   operator,
+		Reason:     request.Reason,
+		TimeStamp:  action.Date,
+	})
+
+	return types.CardAdminResponse{
+		Operation:          request.Operation,
+		CardID:             request.CardID,
+		MerchantIdentifier: request.MerchantIdentifier,
+		BlockType:          strings.ToUpper(request.BlockType),
+		Operator:           operator,
+		Reason:             request.Reason,
+		ProcessedAt:        action.Date,
+	}, nil
+}
+
+// cleanRetries resets the retry counters of the merchants released by the operation.
+func (as *CardAdminService) cleanRetries(
+	ctx context.Context,
+	request types.CardAdminRequest,
+	blockedCard types.DynamoBlockedCard) error {
+	switch {
+	case strings.EqualFold(request.Operation, constants.AdminUnblockAllOperation):
+		for merchantID := range blockedCard.BlockedMerchants {
+			if err := as.deleteRetries(ctx, request.CardID, merchantID); err != nil {
+				return err
+			}
+		}
+		// the velocity counters of the card would block it again for every merchant on the next decline.
+		return as.deleteRetries(ctx, request.CardID, constants.VelocityMerchantID)
+	case strings.EqualFold(request.Operation, constants.AdminUnblockOperation),
+		strings.EqualFold(request.Operation, constants.AdminResetRetriesOperation):
+		return as.deleteRetries(ctx, request.CardID, request.MerchantIdentifier)
+	default:
+		return nil
+	}
+}
+
+// deleteRetries removes the retry counters of every frequency for the card and merchant.
+func (as *CardAdminService) deleteRetries(ctx context.Context, cardID string, merchantID string) error {
+	as.Logger.Info(fmt.Sprintf(cardAdminSrvTag, "deleteRetries"), "[CLEANING]")
+	_, err := deleteCardRetries(ctx, as.Dynamo, gateway.QueryCardRetriesBuilder(cardID, merchantID))
+
+	return err
+}
+
+func (as *CardAdminService) getBlockedCard(ctx context.Context, cardID string) (types.DynamoBlockedCard, error) {
+	var out types.DynamoBlockedCard
+
+	err := as.Dynamo.GetItem(ctx, gateway.GetBlockedCardBuilder(cardID), &out)
+	if err == nil && isExpiredItem(out.ExpiresAt) {
+		return types.DynamoBlockedCard{}, dynamoerror.ErrItemNotFound
+	}
+
+	return out, err
+}
+
+func (as *CardAdminService) generateNewBlockedCard(ctx context.Context, cardID string) (types.DynamoBlockedCard, error) {
+	blockedCard := types.DynamoBlockedCard{
+		BlockedMerchants: make(map[string]types.BlockedMerchant),
+		CardID:           cardID,
+		TimeStamp:        time.Now().UTC().UnixMilli(),
+	}
+	input := gateway.PutBlockedCardBuilder(blockedCard)
+
+	return blockedCard, as.Dynamo.PutItem(ctx, input)
+}
+
+func generateAdminUpdate(
+	request types.CardAdminRequest,
+	action types.AdminAction,
+	blockedCard types.DynamoBlockedCard,
+) *builder.UpdateItemBuilder {
+	switch {
+	case strings.EqualFold(request.Operation, constants.AdminUnblockOperation):
+		return gateway.UnblockMerchantBuilder(request.MerchantIdentifier, action, blockedCard)
+	case strings.EqualFold(request.Operation, constants.AdminUnblockAllOperation):
+		return gateway.UnblockAllMerchantsBuilder(action, blockedCard)
+	case strings.EqualFold(request.Operation, constants.AdminBlockOperation):
+		blockedMerchant := generateAdminBlockedMerchant(request, blockedCard.BlockedMerchants[request.MerchantIdentifier], time.Now().UTC())
+		return gateway.AdminBlockCardBuilder(request.MerchantIdentifier, blockedMerchant, action, blockedCard)
+	default:
+		return gateway.ResetLastRetryBuilder(request.MerchantIdentifier, action, blockedCard)
+	}
+}
+
+// generateAdminBlockedMerchant a forced temporary block lasts the requested hours, a day like the policies by default.
+// The operator sets the block type, so the escalation of the merchant is kept but not applied.
+func generateAdminBlockedMerchant(request types.CardAdminRequest, current types.BlockedMerchant, now time.Time) types.BlockedMerchant {
+	return generateBlockedMerchant(strings.ToUpper(request.BlockType), blockTrigger{BlockHours: request.BlockHours}, current, now)
+}
+
+func validateCardAdminRequest(request types.CardAdminRequest, operator string) error {
+	if operator == "" {
+		return errMissingOperator
+	}
+	if strings.TrimSpace(request.Reason) == "" {
+		return errMissingReason
+	}
+
+	switch {
+	case strings.EqualFold(request.Operation, constants.AdminUnblockAllOperation):
+		return nil
+	case strings.EqualFold(request.Operation, constants.AdminUnblockOperation),
+		strings.EqualFold(request.Operation, constants.AdminResetRetriesOperation):
+		if request.MerchantIdentifier == "" {
+			return errMissingMerchant
+		}
+		return nil
+	case strings.EqualFold(request.Operation, constants.AdminBlockOperation):
+		if request.MerchantIdentifier == "" {
+			return errMissingMerchant
+		}
+		if !strings.EqualFold(request.BlockType, constants.TEMPORARY) && !strings.EqualFold(request.BlockType, constants.PERMANENT) {
+			return errInvalidBlockType
+		}
+		if request.BlockHours < 0 {
+			return errInvalidBlockHours
+		}
+		return nil
+	default:
+		return errInvalidOperation
+	}
+}
+
+// getOperator identity set by the API Gateway authorizer.
+func getOperator(event events.APIGatewayProxyRequest) string {
+	authorizer := event.RequestContext.Authorizer
+	if principalID, ok := authorizer[constants.PrincipalIDField].(string); ok && principalID != "" {
+		return principalID
+	}
+
+	claims, ok := authorizer[constants.ClaimsField].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	email, _ := claims[constants.EmailClaim].(string)
+
+	return email
+}
@@ -0,0 +1,367 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	constants "bitbucket.org/kushki/usrv-card-control"
+	"bitbucket.org/kushki/usrv-card-control/mocks"
+	coreMock "bitbucket.org/kushki/usrv-card-control/mocks/core"
+	mockService "bitbucket.org/kushki/usrv-card-control/mocks/service"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo/builder"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+	"github.com/aws/aws-lambda-go/events"
+	typesDynamo "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const mockOperator = "support@kushki.com"
+
+var mockAdminEvent = events.APIGatewayProxyRequest{
+	RequestContext: events.APIGatewayProxyRequestContext{
+		Authorizer: map[string]interface{}{constants.PrincipalIDField: mockOperator},
+	},
+}
+
+func TestNewCardAdminService(t *testing.T) {
+	t.Run("should no be empty", func(t *testing.T) {
+		assert.NotEmpty(t, NewCardAdminService(mocks.GetMockLogger(t), &coreMock.IDynamoGateway{}))
+	})
+}
+
+func TestInitializeCardAdmin(t *testing.T) {
+	tests := []struct {
+		name               string
+		request            types.CardAdminRequest
+		event              events.APIGatewayProxyRequest
+		jsonUnmarshalError error
+		dynamoError        error
+		actionError        error
+		expectedCode       string
+	}{
+		{
+			name:    "should execute the action with the authenticated operator",
+			request: types.CardAdminRequest{Operation: constants.AdminUnblockAllOperation, CardID: "card", Reason: "issuer"},
+			event:   mockAdminEvent,
+		},
+		{
+			name:               "should return E001 if unmarshal fails",
+			event:              mockAdminEvent,
+			jsonUnmarshalError: commonError,
+			expectedCode:       "E001",
+		},
+		{
+			name:         "should return E001 if there is no operator",
+			request:      types.CardAdminRequest{Operation: constants.AdminUnblockAllOperation, CardID: "card", Reason: "issuer"},
+			expectedCode: "E001",
+		},
+		{
+			name:         "should return E002 if dynamo init fails",
+			request:      types.CardAdminRequest{Operation: constants.AdminUnblockAllOperation, CardID: "card", Reason: "issuer"},
+			event:        mockAdminEvent,
+			dynamoError:  commonError,
+			expectedCode: "E002",
+		},
+		{
+			name:         "should return E001 if the card does not exist",
+			request:      types.CardAdminRequest{Operation: constants.AdminUnblockAllOperation, CardID: "card", Reason: "issuer"},
+			event:        mockAdminEvent,
+			actionError:  errCardNotFound,
+			expectedCode: "E001",
+		},
+		{
+			name:         "should return E002 if the action fails",
+			request:      types.CardAdminRequest{Operation: constants.AdminUnblockAllOperation, CardID: "card", Reason: "issuer"},
+			event:        mockAdminEvent,
+			actionError:  commonError,
+			expectedCode: "E002",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Cleanup(clean)
+			newKushkiLogger = func(context.Context) logger.KushkiLogger {
+				return mocks.GetMockLogger(t)
+			}
+			jsonUnmarshalCaller = func(_ []byte, v any) error {
+				out := v.(*types.CardAdminRequest)
+				*out = test.request
+
+				return test.jsonUnmarshalError
+			}
+			initializeDynamoGtw = func(context.Context, logger.KushkiLogger) (dynamo.IDynamoGateway, error) {
+				return &coreMock.IDynamoGateway{}, test.dynamoError
+			}
+			adminMock := &mockService.ICardAdminService{}
+			adminMock.On("ExecuteAction", mock.Anything, test.request, mockOperator).
+				Return(types.CardAdminResponse{Operator: mockOperator}, test.actionError)
+			refNewCardAdminService = func(logger.KushkiLogger, dynamo.IDynamoGateway) ICardAdminService {
+				return adminMock
+			}
+
+			response, err := InitializeCardAdmin(context.TODO(), test.event)
+			if test.expectedCode != "" {
+				assert.ErrorContains(t, err, "Code: "+test.expectedCode)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, mockOperator, response.Operator)
+		})
+	}
+}
+
+func TestCardAdminService_ExecuteAction(t *testing.T) {
+	blockedCard := types.DynamoBlockedCard{
+		CardID:           "card",
+		TimeStamp:        1,
+		BlockedMerchants: map[string]types.BlockedMerchant{"merchant": {BlockType: constants.PERMANENT}},
+	}
+	operations := []types.CardAdminRequest{
+		{Operation: constants.AdminUnblockOperation, CardID: "card", MerchantIdentifier: "merchant", Reason: "issuer"},
+		{Operation: constants.AdminUnblockAllOperation, CardID: "card", Reason: "issuer"},
+		{Operation: constants.AdminBlockOperation, CardID: "card", MerchantIdentifier: "merchant", BlockType: "temporary", Reason: "fraud"},
+	}
+	tests := []struct {
+		request         types.CardAdminRequest
+		expectedQueries int
+	}{
+		{request: operations[0], expectedQueries: 1},
+		{request: operations[1], expectedQueries: 2},
+		{request: operations[2], expectedQueries: 0},
+	}
+
+	for _, test := range tests {
+		t.Run("should execute "+test.request.Operation, func(t *testing.T) {
+			dynamoMock := &coreMock.IDynamoGateway{}
+			dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) {
+					*args[2].(*types.DynamoBlockedCard) = blockedCard
+				}).
+				Return(nil)
+			if test.expectedQueries > 0 {
+				dynamoMock.On("Query", mock.Anything, mock.Anything, mock.Anything).
+					Run(func(args mock.Arguments) {
+						*args[2].(*[]types.CardRetry) = []types.CardRetry{{RetryKey: "daily"}}
+					}).
+					Return(nil).
+					Times(test.expectedQueries)
+				dynamoMock.On("DeleteItem", mock.Anything, mock.Anything).Return(nil).Times(test.expectedQueries)
+			}
+			dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).Return(nil).Once()
+			srv := CardAdminService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock, History: getHistoryMock()}
+
+			response, err := srv.ExecuteAction(context.TODO(), test.request, mockOperator)
+			assert.NoError(t, err)
+			assert.Equal(t, mockOperator, response.Operator)
+			assert.Equal(t, test.request.Reason, response.Reason)
+			dynamoMock.AssertExpectations(t)
+		})
+	}
+
+	t.Run("should delete every retry counter when resetting retries", func(t *testing.T) {
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("Query", mock.Anything, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				*args[2].(*[]types.CardRetry) = []types.CardRetry{{RetryKey: "daily"}, {RetryKey: "monthly"}}
+			}).
+			Return(nil)
+		dynamoMock.On("DeleteItem", mock.Anything, mock.Anything).Return(nil).Twice()
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).Return(nil).Once()
+		srv := CardAdminService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock, History: getHistoryMock()}
+
+		_, err := srv.ExecuteAction(context.TODO(), types.CardAdminRequest{
+			Operation: constants.AdminResetRetriesOperation, CardID: "card", MerchantIdentifier: "merchant", Reason: "issuer",
+		}, mockOperator)
+		assert.NoError(t, err)
+		dynamoMock.AssertExpectations(t)
+	})
+
+	t.Run("should delete the velocity counters and the card block when unblocking every merchant", func(t *testing.T) {
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				*args[2].(*types.DynamoBlockedCard) = types.DynamoBlockedCard{
+					CardID:    "card",
+					TimeStamp: 1,
+					CardBlock: &types.BlockedMerchant{BlockType: constants.PERMANENT},
+				}
+			}).
+			Return(nil)
+		dynamoMock.On("Query", mock.Anything, mock.MatchedBy(func(input *builder.QueryBuilder) bool {
+			query, err := input.BuildInput()
+			if err != nil {
+				return false
+			}
+			for _, value := range query.ExpressionAttributeValues {
+				if assert.ObjectsAreEqual(&typesDynamo.AttributeValueMemberS{Value: constants.VelocityMerchantID}, value) {
+					return true
+				}
+			}
+			return false
+		}), mock.Anything).
+			Run(func(args mock.Arguments) {
+				*args[2].(*[]types.CardRetry) = []types.CardRetry{{RetryKey: "card-*-daily"}}
+			}).
+			Return(nil).
+			Once()
+		dynamoMock.On("DeleteItem", mock.Anything, mock.Anything).Return(nil).Once()
+		dynamoMock.On("UpdateItem", mock.Anything, mock.MatchedBy(func(input *builder.UpdateItemBuilder) bool {
+			update, err := input.BuildInput()
+			return err == nil && strings.Contains(*update.UpdateExpression, "REMOVE")
+		})).Return(nil).Once()
+		srv := CardAdminService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock, History: getHistoryMock()}
+
+		_, err := srv.ExecuteAction(context.TODO(), operations[1], mockOperator)
+		assert.NoError(t, err)
+		dynamoMock.AssertExpectations(t)
+	})
+
+	t.Run("should return an error if query retries fails", func(t *testing.T) {
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		dynamoMock.On("Query", mock.Anything, mock.Anything, mock.Anything).Return(commonError)
+		srv := CardAdminService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock, History: getHistoryMock()}
+
+		_, err := srv.ExecuteAction(context.TODO(), types.CardAdminRequest{
+			Operation: constants.AdminResetRetriesOperation, CardID: "card", MerchantIdentifier: "merchant", Reason: "issuer",
+		}, mockOperator)
+		assert.Error(t, err)
+	})
+
+	t.Run("should replay the update on conflict", func(t *testing.T) {
+		t.Cleanup(clean)
+		sleepCaller = func(time.Duration) {}
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).Return(nil).Twice()
+		dynamoMock.On("Query", mock.Anything, mock.Anything, mock.Anything).Return(nil).Once()
+		dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).
+			Return(&typesDynamo.ConditionalCheckFailedException{}).
+			Once()
+		dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).Return(nil).Once()
+		srv := CardAdminService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock, History: getHistoryMock()}
+
+		_, err := srv.ExecuteAction(context.TODO(), operations[1], mockOperator)
+		assert.NoError(t, err)
+		dynamoMock.AssertExpectations(t)
+	})
+
+	t.Run("should return not found without touching the counters if the card does not exist", func(t *testing.T) {
+		for _, request := range append(operations[:2:2], types.CardAdminRequest{
+			Operation: constants.AdminResetRetriesOperation, CardID: "card", MerchantIdentifier: "merchant", Reason: "issuer",
+		}) {
+			dynamoMock := &coreMock.IDynamoGateway{}
+			dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).Return(dynamoerror.ErrItemNotFound)
+			srv := CardAdminService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock, History: getHistoryMock()}
+
+			_, err := srv.ExecuteAction(context.TODO(), request, mockOperator)
+			assert.ErrorIs(t, err, errCardNotFound)
+			dynamoMock.AssertNotCalled(t, "Query", mock.Anything, mock.Anything, mock.Anything)
+			dynamoMock.AssertNotCalled(t, "UpdateItem", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("should create the blocked card when blocking a card that does not exist", func(t *testing.T) {
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).Return(dynamoerror.ErrItemNotFound)
+		dynamoMock.On("PutItem", mock.Anything, mock.Anything).Return(nil).Once()
+		dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).Return(nil).Once()
+		srv := CardAdminService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock, History: getHistoryMock()}
+
+		_, err := srv.ExecuteAction(context.TODO(), operations[2], mockOperator)
+		assert.NoError(t, err)
+		dynamoMock.AssertExpectations(t)
+	})
+
+	t.Run("should return an error if get blocked card fails", func(t *testing.T) {
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).Return(commonError)
+		srv := CardAdminService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock, History: getHistoryMock()}
+
+		_, err := srv.ExecuteAction(context.TODO(), operations[1], mockOperator)
+		assert.ErrorIs(t, err, commonError)
+	})
+}
+
+func TestValidateCardAdminRequest(t *testing.T) {
+	tests := []struct {
+		name     string
+		request  types.CardAdminRequest
+		operator string
+		expected error
+	}{
+		{name: "missing operator", request: types.CardAdminRequest{Operation: constants.AdminUnblockAllOperation, Reason: "r"}, expected: errMissingOperator},
+		{name: "missing reason", request: types.CardAdminRequest{Operation: constants.AdminUnblockAllOperation}, operator: mockOperator, expected: errMissingReason},
+		{name: "unblock without merchant", request: types.CardAdminRequest{Operation: constants.AdminUnblockOperation, Reason: "r"}, operator: mockOperator, expected: errMissingMerchant},
+		{name: "block without merchant", request: types.CardAdminRequest{Operation: constants.AdminBlockOperation, Reason: "r"}, operator: mockOperator, expected: errMissingMerchant},
+		{name: "block with invalid type", request: types.CardAdminRequest{Operation: constants.AdminBlockOperation, MerchantIdentifier: "m", BlockType: "forever", Reason: "r"}, operator: mockOperator, expected: errInvalidBlockType},
+		{name: "block with negative hours", request: types.CardAdminRequest{Operation: constants.AdminBlockOperation, MerchantIdentifier: "m", BlockType: "temporary", BlockHours: -1, Reason: "r"}, operator: mockOperator, expected: errInvalidBlockHours},
+		{name: "unknown operation", request: types.CardAdminRequest{Operation: "delete", Reason: "r"}, operator: mockOperator, expected: errInvalidOperation},
+		{name: "valid reset", request: types.CardAdminRequest{Operation: constants.AdminResetRetriesOperation, MerchantIdentifier: "m", Reason: "r"}, operator: mockOperator},
+		{name: "valid block", request: types.CardAdminRequest{Operation: constants.AdminBlockOperation, MerchantIdentifier: "m", BlockType: "permanent", Reason: "r"}, operator: mockOperator},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.True(t, errors.Is(validateCardAdminRequest(test.request, test.operator), test.expected))
+		})
+	}
+}
+
+func TestGenerateAdminBlockedMerchant(t *testing.T) {
+	now := time.Date(2025, 3, 20, 12, 0, 0, 0, time.UTC)
+	current := types.BlockedMerchant{EscalationCount: 2, EscalationStart: now.AddDate(0, 0, -1).UnixMilli()}
+	tests := []struct {
+		name     string
+		request  types.CardAdminRequest
+		expected types.BlockedMerchant
+	}{
+		{
+			name:    "should block for a day by default",
+			request: types.CardAdminRequest{BlockType: "temporary"},
+			expected: types.BlockedMerchant{
+				BlockType: constants.TEMPORARY, ExpirationDate: now.Add(constants.DayHours * time.Hour).UnixMilli(),
+				EscalationCount: current.EscalationCount, EscalationStart: current.EscalationStart,
+			},
+		},
+		{
+			name:    "should block for the requested hours",
+			request: types.CardAdminRequest{BlockType: "temporary", BlockHours: 72},
+			expected: types.BlockedMerchant{
+				BlockType: constants.TEMPORARY, ExpirationDate: now.Add(72 * time.Hour).UnixMilli(),
+				EscalationCount: current.EscalationCount, EscalationStart: current.EscalationStart,
+			},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			assert.Equal(t, test.expected, generateAdminBlockedMerchant(test.request, current, now))
+		})
+	}
+}
+
+func TestGetOperator(t *testing.T) {
+	t.Run("should use the cognito email when there is no principal", func(t *testing.T) {
+		event := events.APIGatewayProxyRequest{
+			RequestContext: events.APIGatewayProxyRequestContext{
+				Authorizer: map[string]interface{}{
+					constants.ClaimsField: map[string]interface{}{constants.EmailClaim: mockOperator},
+				},
+			},
+		}
+		assert.Equal(t, mockOperator, getOperator(event))
+	})
+
+	t.Run("should be empty without authorizer", func(t *testing.T) {
+		assert.Empty(t, getOperator(events.APIGatewayProxyRequest{}))
+	})
+}
@@ -0,0 +1,196 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	constants "bitbucket.org/kushki/usrv-card-control"
+	"bitbucket.org/kushki/usrv-card-control/gateway"
+	"bitbucket.org/kushki/usrv-card-control/types"
+)
+
+// processVelocity counts the declines of the card in every merchant, crossing a rule blocks the card for all of them.
+// The blocked card is read again after a card block so the merchant updates that follow are not in conflict.
+// A shadow merchant counts as usual but its card block is only recorded.
+func (bs *BlockService) processVelocity(
+	ctx context.Context,
+	request types.BlockCardRequest,
+	currentDate int64,
+	blockedCard types.DynamoBlockedCard,
+	shadow bool,
+) (types.DynamoBlockedCard, error) {
+	rules := bs.Velocity.Rules
+	if len(rules) == 0 || isVelocityExempt(bs.Velocity.Allowlist, request.MerchantIdentifier) {
+		return blockedCard, nil
+	}
+
+	// the counters belong to the card, not to the merchant that declined it.
+	velocityRequest := request
+	velocityRequest.MerchantIdentifier = constants.VelocityMerchantID
+	ttlHours := getLongestWindowHours(rules, currentDate)
+	blockTypes := make([]string, 0, len(rules))
+	trigger := blockTrigger{Shadow: shadow}
+	for _, rule := range rules {
+		retries, err := bs.countRetry(ctx, velocityRequest, generateVelocityKey(request.CardID, rule), rule, currentDate, ttlHours)
+		if err != nil {
+			return blockedCard, err
+		}
+		if len(retries) >= rule.MaxAttempts {
+			blockTypes = append(blockTypes, rule.BlockType)
+			trigger.BlockHours = max(trigger.BlockHours, getBlockHours(rule))
+			if len(retries) > trigger.Retries.After {
+				trigger.Retries = retryCount{Frequency: rule.Frequency, Before: len(retries) - 1, After: len(retries)}
+			}
+		}
+	}
+	if len(blockTypes) == 0 {
+		return blockedCard, nil
+	}
+
+	bs.info("processVelocity - Card velocity exceeded", "[BLOCKING CARD FOR EVERY MERCHANT]")
+	if err := bs.blockWholeCard(ctx, request, getStrongestBlockType(blockTypes), blockedCard, trigger); err != nil || shadow {
+		return blockedCard, err
+	}
+
+	return bs.getBlockedCard(ctx, request.CardID)
+}
+
+func (bs *BlockService) blockWholeCard(
+	ctx context.Context,
+	request types.BlockCardRequest,
+	blockType string,
+	blockedCard types.DynamoBlockedCard,
+	trigger blockTrigger) error {
+	if trigger.Shadow {
+		bs.recordShadowBlock(ctx, request, blockType, trigger)
+		return nil
+	}
+
+	permanentlyBlocked := false
+	err := retryOnConflict(bs.Logger, blockSrvTag, func(attempt int) error {
+		if attempt > 0 {
+			var err error
+			if blockedCard, err = bs.getBlockedCard(ctx, request.CardID); err != nil {
+				return err
+			}
+		}
+
+		permanentlyBlocked = blockedCard.CardBlock != nil &&
+			strings.EqualFold(blockedCard.CardBlock.BlockType, constants.PERMANENT) &&
+			!strings.EqualFold(blockType, constants.PERMANENT)
+		if permanentlyBlocked {
+			return nil
+		}
+
+		cardBlock := generateBlockedMerchant(blockType, trigger, types.BlockedMerchant{}, time.Now().UTC())
+
+		return bs.Dynamo.UpdateItem(ctx, gateway.UpdateCardBlockBuilder(cardBlock, blockedCard))
+	})
+	if err != nil || permanentlyBlocked {
+		return err
+	}
+
+	bs.History.Record(ctx, newBlockEntry(request, constants.HistoryCardBlock, blockType, trigger))
+
+	return nil
+}
+
+// VelocityConfig card velocity rules and the merchants exempt from them, read from the environment when the service is built.
+type VelocityConfig struct {
+	Rules     []types.FrequencyRule
+	Allowlist []string
+}
+
+// loadVelocityConfig a malformed or invalid rule set fails the service build instead of every message.
+func loadVelocityConfig() (VelocityConfig, error) {
+	velocity := VelocityConfig{Allowlist: loadVelocityAllowlist()}
+	config := os.Getenv(constants.EnvCardVelocityRules)
+	if config == "" {
+		return velocity, nil
+	}
+
+	if err := json.Unmarshal([]byte(config), &velocity.Rules); err != nil {
+		return VelocityConfig{}, fmt.Errorf("%w: %s is not a rule list: %w", errInvalidPolicy, constants.EnvCardVelocityRules, err)
+	}
+	if err := validateVelocityRules(velocity.Rules); err != nil {
+		return VelocityConfig{}, err
+	}
+
+	return velocity, nil
+}
+
+// loadVelocityAllowlist comma separated merchants of the environment.
+func loadVelocityAllowlist() []string {
+	allowlist := make([]string, 0)
+	for _, merchantID := range strings.Split(os.Getenv(constants.EnvCardVelocityAllowlist), ",") {
+		if merchantID = strings.TrimSpace(merchantID); merchantID != "" {
+			allowlist = append(allowlist, merchantID)
+		}
+	}
+
+	return allowlist
+}
+
+// validateVelocityRules the velocity key of a rule only has its frequency, two rules of one frequency would share the counter.
+func validateVelocityRules(rules []types.FrequencyRule) error {
+	frequencies := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		frequency := strings.ToUpper(rule.Frequency)
+		if frequencies[frequency] {
+			return fmt.Errorf("%w: duplicated %s velocity rule", errInvalidPolicy, rule.Frequency)
+		}
+		frequencies[frequency] = true
+	}
+
+	return validatePolicy(types.RetryPolicy{Rules: rules})
+}
+
+// isVelocityExempt platform merchants neither count for the velocity nor honor the card block.
+func isVelocityExempt(allowlist []string, merchantID string) bool {
+	return merchantID != "" && slices.Contains(allowlist, merchantID)
+}
+
+func generateVelocityKey(cardID string, rule types.FrequencyRule) string {
+	return fmt.Sprintf("%s-%s-%s", cardID, constants.VelocityMerchantID, rule.Frequency)
+}
+
+// isActiveCardBlock a temporary card block ends at its expiration, a permanent one never does.
+func isActiveCardBlock(cardBlock *types.BlockedMerchant, currentDate int64) bool {
+	if cardBlock == nil {
+		return false
+	}
+	if strings.EqualFold(cardBlock.BlockType, constants.PERMANENT) {
+		return true
+	}
+
+	return strings.EqualFold(cardBlock.BlockType, constants.TEMPORARY) && cardBlock.ExpirationDate > currentDate
+}
+
+// getCardStatus the card block answers for every merchant but the exempt ones, the merchant block otherwise.
+func getCardStatus(
+	blockedCard types.DynamoBlockedCard,
+	merchantID string,
+	currentDate int64,
+	velocityAllowlist []string,
+) types.CheckCardStatusResponse {
+	if !isActiveCardBlock(blockedCard.CardBlock, currentDate) || isVelocityExempt(velocityAllowlist, merchantID) {
+		return getBlockStatus(blockedCard.BlockedMerchants, merchantID, currentDate)
+	}
+
+	if strings.EqualFold(blockedCard.CardBlock.BlockType, constants.PERMANENT) {
+		return types.CheckCardStatusResponse{Blocked: true, CardBlocked: true, BlockType: constants.PERMANENT}
+	}
+
+	return types.CheckCardStatusResponse{
+		Blocked:         true,
+		CardBlocked:     true,
+		BlockType:       constants.TEMPORARY,
+		ExpirationDate:  blockedCard.CardBlock.ExpirationDate,
+		NextAllowedDate: blockedCard.CardBlock.ExpirationDate,
+	}
+}
@@ -0,0 +1,203 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	constants "bitbucket.org/kushki/usrv-card-control"
+	"bitbucket.org/kushki/usrv-card-control/mocks"
+	coreMock "bitbucket.org/kushki/usrv-card-control/mocks/core"
+	mockService "bitbucket.org/kushki/usrv-card-control/mocks/service"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo/builder"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const velocityRules = `[{"frequency":"hourly","windowHours":1,"maxAttempts":3,"blockType":"TEMPORARY","blockHours":48}]`
+
+func newVelocityConfig(t *testing.T, rules string, allowlist string) VelocityConfig {
+	t.Helper()
+	t.Setenv(constants.EnvCardVelocityRules, rules)
+	t.Setenv(constants.EnvCardVelocityAllowlist, allowlist)
+	velocity, err := loadVelocityConfig()
+	assert.NoError(t, err)
+
+	return velocity
+}
+
+func newVelocityScenario(t *testing.T, retries []int64, velocity VelocityConfig) (*BlockService, *coreMock.IDynamoGateway, *mockService.IHistoryService) {
+	t.Helper()
+	t.Cleanup(clean)
+	dynamoMock := &coreMock.IDynamoGateway{}
+	dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.CardRetry")).
+		Run(func(args mock.Arguments) {
+			*args[2].(*types.CardRetry) = types.CardRetry{Retries: retries}
+		}).
+		Return(nil)
+	dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.DynamoBlockedCard")).
+		Return(nil)
+	dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).Return(nil)
+	historyMock := getHistoryMock()
+
+	return &BlockService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock, History: historyMock, Velocity: velocity}, dynamoMock, historyMock
+}
+
+func TestBlockService_ProcessVelocity(t *testing.T) {
+	request := types.BlockCardRequest{CardID: "card1", MerchantIdentifier: "merchant1", Operation: constants.RetryCardOperation}
+	currentDate := time.Now().UTC().UnixMilli()
+	recent := []int64{currentDate - 1000, currentDate - 2000}
+
+	t.Run("should block the whole card when the velocity is exceeded", func(t *testing.T) {
+		srv, dynamoMock, historyMock := newVelocityScenario(t, recent, newVelocityConfig(t, velocityRules, ""))
+
+		_, err := srv.processVelocity(context.TODO(), request, currentDate, types.DynamoBlockedCard{CardID: "card1"}, false)
+
+		assert.NoError(t, err)
+		dynamoMock.AssertCalled(t, "UpdateItem", mock.Anything, mock.MatchedBy(func(b *builder.UpdateItemBuilder) bool {
+			return b.Key[constants.RetryKeyField] == "card1-*-hourly"
+		}))
+		dynamoMock.AssertCalled(t, "UpdateItem", mock.Anything, mock.MatchedBy(func(b *builder.UpdateItemBuilder) bool {
+			return b.Key[constants.CardIdField] == "card1"
+		}))
+		historyMock.AssertCalled(t, "Record", mock.Anything, mock.MatchedBy(func(entry types.BlockHistoryEntry) bool {
+			return entry.Operation == constants.HistoryCardBlock && entry.MerchantID == "merchant1" && entry.RetriesAfter == 3
+		}))
+	})
+
+	t.Run("should only count while the velocity is not exceeded", func(t *testing.T) {
+		srv, dynamoMock, historyMock := newVelocityScenario(t, recent[:1], newVelocityConfig(t, velocityRules, ""))
+
+		_, err := srv.processVelocity(context.TODO(), request, currentDate, types.DynamoBlockedCard{CardID: "card1"}, false)
+
+		assert.NoError(t, err)
+		dynamoMock.AssertNumberOfCalls(t, "UpdateItem", 1)
+		historyMock.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should not downgrade a permanent card block", func(t *testing.T) {
+		srv, dynamoMock, historyMock := newVelocityScenario(t, recent, newVelocityConfig(t, velocityRules, ""))
+		blockedCard := types.DynamoBlockedCard{CardID: "card1", CardBlock: &types.BlockedMerchant{BlockType: constants.PERMANENT}}
+
+		_, err := srv.processVelocity(context.TODO(), request, currentDate, blockedCard, false)
+
+		assert.NoError(t, err)
+		dynamoMock.AssertNumberOfCalls(t, "UpdateItem", 1)
+		historyMock.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should skip the velocity when it is not configured or the merchant is exempt", func(t *testing.T) {
+		configs := []struct {
+			rules     string
+			allowlist string
+		}{
+			{},
+			{rules: velocityRules, allowlist: "platform, merchant1"},
+		}
+		for _, config := range configs {
+			srv, dynamoMock, _ := newVelocityScenario(t, recent, newVelocityConfig(t, config.rules, config.allowlist))
+
+			_, err := srv.processVelocity(context.TODO(), request, currentDate, types.DynamoBlockedCard{CardID: "card1"}, false)
+
+			assert.NoError(t, err)
+			dynamoMock.AssertNotCalled(t, "UpdateItem", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("should return an error if the counter cannot be read", func(t *testing.T) {
+		t.Cleanup(clean)
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).Return(commonError)
+		srv := BlockService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock, History: getHistoryMock(), Velocity: newVelocityConfig(t, velocityRules, "")}
+
+		_, err := srv.processVelocity(context.TODO(), request, currentDate, types.DynamoBlockedCard{}, false)
+
+		assert.ErrorIs(t, err, commonError)
+	})
+
+	t.Run("should return an error if the card cannot be read again", func(t *testing.T) {
+		t.Cleanup(clean)
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.CardRetry")).
+			Run(func(args mock.Arguments) {
+				*args[2].(*types.CardRetry) = types.CardRetry{Retries: recent}
+			}).
+			Return(nil)
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.DynamoBlockedCard")).
+			Return(dynamoerror.ErrItemNotFound)
+		dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).Return(nil)
+		srv := BlockService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock, History: getHistoryMock(), Velocity: newVelocityConfig(t, velocityRules, "")}
+
+		_, err := srv.processVelocity(context.TODO(), request, currentDate, types.DynamoBlockedCard{}, false)
+
+		assert.ErrorIs(t, err, dynamoerror.ErrItemNotFound)
+	})
+}
+
+func TestLoadVelocityConfig(t *testing.T) {
+	t.Run("should read the rules and the allowlist", func(t *testing.T) {
+		velocity := newVelocityConfig(t, velocityRules, "platform, ,merchant1")
+
+		assert.Len(t, velocity.Rules, 1)
+		assert.Equal(t, []string{"platform", "merchant1"}, velocity.Allowlist)
+	})
+
+	t.Run("should fail on malformed or invalid rules", func(t *testing.T) {
+		for _, rules := range []string{
+			"not json",
+			`[{"frequency":"hourly","windowHours":1,"maxAttempts":0,"blockType":"TEMPORARY"}]`,
+			`[{"frequency":"hourly","windowHours":1,"maxAttempts":3,"blockType":"TEMPORARY"},` +
+				`{"frequency":"hourly","category":"fraud","windowHours":1,"maxAttempts":5,"blockType":"TEMPORARY"}]`,
+		} {
+			t.Setenv(constants.EnvCardVelocityRules, rules)
+
+			_, err := loadVelocityConfig()
+
+			assert.ErrorIs(t, err, errInvalidPolicy)
+		}
+	})
+}
+
+func TestGetCardStatus(t *testing.T) {
+	currentDate := time.Now().UTC().UnixMilli()
+	active := currentDate + 1000
+	blockedMerchants := map[string]types.BlockedMerchant{
+		"merchant1": {BlockType: constants.PERMANENT},
+	}
+
+	t.Run("should answer the temporary card block for every merchant", func(t *testing.T) {
+		blockedCard := types.DynamoBlockedCard{CardBlock: &types.BlockedMerchant{BlockType: constants.TEMPORARY, ExpirationDate: active}}
+
+		assert.Equal(t, types.CheckCardStatusResponse{
+			Blocked: true, CardBlocked: true, BlockType: constants.TEMPORARY, ExpirationDate: active, NextAllowedDate: active,
+		}, getCardStatus(blockedCard, "merchant2", currentDate, nil))
+	})
+
+	t.Run("should answer the permanent card block without expiration", func(t *testing.T) {
+		blockedCard := types.DynamoBlockedCard{CardBlock: &types.BlockedMerchant{BlockType: constants.PERMANENT, ExpirationDate: active}}
+
+		assert.Equal(t, types.CheckCardStatusResponse{
+			Blocked: true, CardBlocked: true, BlockType: constants.PERMANENT,
+		}, getCardStatus(blockedCard, "merchant2", currentDate, nil))
+	})
+
+	t.Run("should answer the merchant block once the card block expired", func(t *testing.T) {
+		blockedCard := types.DynamoBlockedCard{
+			BlockedMerchants: blockedMerchants,
+			CardBlock:        &types.BlockedMerchant{BlockType: constants.TEMPORARY, ExpirationDate: currentDate - 1000},
+		}
+
+		assert.Equal(t, getBlockStatus(blockedMerchants, "merchant1", currentDate), getCardStatus(blockedCard, "merchant1", currentDate, nil))
+		assert.False(t, getCardStatus(blockedCard, "merchant2", currentDate, nil).Blocked)
+	})
+
+	t.Run("should exempt the merchants of the allowlist", func(t *testing.T) {
+		blockedCard := types.DynamoBlockedCard{CardBlock: &types.BlockedMerchant{BlockType: constants.PERMANENT}}
+		allowlist := []string{"platform"}
+
+		assert.False(t, getCardStatus(blockedCard, "platform", currentDate, allowlist).Blocked)
+		assert.True(t, getCardStatus(blockedCard, "merchant2", currentDate, allowlist).Blocked)
+	})
+}
@@ -2,18 +2,27 @@ package service
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"os"
+	"sort"
 	"strings"
 	"time"
+	"unicode"
 
 	constants "bitbucket.org/kushki/usrv-card-control"
 	dynamoBuilders "bitbucket.org/kushki/usrv-card-control/gateway"
 	"bitbucket.org/kushki/usrv-card-control/types"
 	errorsCore "bitbucket.org/kushki/usrv-go-core/errors"
 	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
 	"bitbucket.org/kushki/usrv-go-core/logger"
+	coreTypes "bitbucket.org/kushki/usrv-go-core/utils/types"
 	"github.com/Jeffail/gabs/v2"
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
 const (
@@ -22,26 +31,41 @@ const (
 
 var (
 	refNewCheckCardStatusService = NewCheckCardStatusService
+
+	errInvalidConditional = errors.New("conditional must be an alphanumeric response code")
+	errInvalidBatchSize   = fmt.Errorf("cards must have between 1 and %d items", constants.BatchCheckMaxCards)
+	errUnprocessedCard    = errors.New("card not processed by dynamo, retry later")
 )
 
 type CheckCardStatusService struct {
-	Context context.Context
-	Logger  logger.KushkiLogger
-	Dynamo  dynamo.IDynamoGateway
+	Context           context.Context
+	Logger            logger.KushkiLogger
+	Dynamo            dynamo.IDynamoGateway
+	Policies          IRetryPolicyService
+	Settings          IMerchantSettingsService
+	FailMode          string
+	MerchantFailModes map[string]string
+	VelocityAllowlist []string
 }
 
 // ICheckCardStatusService Interface to get SyncMerchant.
 type ICheckCardStatusService interface {
-	CheckCardStatus(checkCardStatusRequest types.CheckCardStatusRequest) types.CheckCardStatusResponse
+	CheckCardStatus(checkCardStatusRequest types.CheckCardStatusRequest) (types.CheckCardStatusResponse, error)
+	BatchCheckCardStatus(request types.BatchCheckCardStatusRequest) types.BatchCheckCardStatusResponse
 }
 
 // NewCheckCardStatusService service initiator.
 // Should initialize the dependencies for this service.
 func NewCheckCardStatusService(ctx context.Context, dynamo dynamo.IDynamoGateway, lg logger.KushkiLogger) ICheckCardStatusService {
 	return &CheckCardStatusService{
-		Context: ctx,
-		Dynamo:  dynamo,
-		Logger:  lg,
+		Context:           ctx,
+		Dynamo:            dynamo,
+		Logger:            lg,
+		Policies:          NewRetryPolicyService(lg, dynamo),
+		Settings:          NewMerchantSettingsService(lg, dynamo),
+		FailMode:          strings.ToUpper(os.Getenv(constants.EnvCardStatusFailMode)),
+		MerchantFailModes: getMerchantFailModes(lg),
+		VelocityAllowlist: loadVelocityAllowlist(),
 	}
 }
 
@@ -57,6 +81,11 @@ func InitializeCheckCardStatus(ctx context.Context, event events.APIGatewayProxy
 		return types.CheckCardStatusResponse{}, errorsCore.NewKushkiError(errorsCore.Errors[errorsCore.E001], gabs.Wrap(errorsCore.GenericErrorMetadata{
 			Message: err.Error(), Origin: tag}))
 	}
+	if err := validateCheckCardStatusRequest(checkCardStatusRequest); err != nil {
+		kskLogger.Error(tag, err)
+		return types.CheckCardStatusResponse{}, errorsCore.NewKushkiError(errorsCore.Errors[errorsCore.E001], gabs.Wrap(errorsCore.GenericErrorMetadata{
+			Message: err.Error(), Origin: tag}))
+	}
 	dynamoGtw, err := initializeDynamoGtw(ctx, kskLogger)
 	if err != nil {
 		kskLogger.Error(fmt.Sprintf(tag, "Error initializing dynamo: "), err)
@@ -66,23 +95,278 @@ func InitializeCheckCardStatus(ctx context.Context, event events.APIGatewayProxy
 
 	checkCardStatusService := refNewCheckCardStatusService(ctx, dynamoGtw, kskLogger)
 
-	return checkCardStatusService.CheckCardStatus(checkCardStatusRequest), nil
+	response, err := checkCardStatusService.CheckCardStatus(checkCardStatusRequest)
+	if err != nil {
+		return types.CheckCardStatusResponse{}, errorsCore.NewKushkiError(errorsCore.Errors[errorsCore.E002], gabs.Wrap(errorsCore.GenericErrorMetadata{
+			Message: err.Error(), Origin: tag}))
+	}
+
+	return response, nil
+}
+
+// InitializeBatchCheckCardStatus init dependencies and check every card of the batch.
+func InitializeBatchCheckCardStatus(ctx context.Context, event events.APIGatewayProxyRequest) (types.BatchCheckCardStatusResponse, error) {
+	tag := fmt.Sprint(checkCardStatusServiceTag, "InitializeBatchCheckCardStatus")
+
+	kskLogger := newKushkiLogger(ctx)
+
+	var request types.BatchCheckCardStatusRequest
+	if err := jsonUnmarshalCaller([]byte(event.Body), &request); err != nil {
+		kskLogger.Error(fmt.Sprintf(tag, "Error while unmarshal batch check card status request: "), err)
+		return types.BatchCheckCardStatusResponse{}, errorsCore.NewKushkiError(errorsCore.Errors[errorsCore.E001], gabs.Wrap(errorsCore.GenericErrorMetadata{
+			Message: err.Error(), Origin: tag}))
+	}
+	if len(request.Cards) == 0 || len(request.Cards) > constants.BatchCheckMaxCards {
+		kskLogger.Error(tag, errInvalidBatchSize)
+		return types.BatchCheckCardStatusResponse{}, errorsCore.NewKushkiError(errorsCore.Errors[errorsCore.E001], gabs.Wrap(errorsCore.GenericErrorMetadata{
+			Message: errInvalidBatchSize.Error(), Origin: tag}))
+	}
+
+	dynamoGtw, err := initializeDynamoGtw(ctx, kskLogger)
+	if err != nil {
+		kskLogger.Error(fmt.Sprintf(tag, "Error initializing dynamo: "), err)
+		return types.BatchCheckCardStatusResponse{}, errorsCore.NewKushkiError(errorsCore.Errors[errorsCore.E002], gabs.Wrap(errorsCore.GenericErrorMetadata{
+			Message: err.Error(), Origin: tag}))
+	}
+
+	checkCardStatusService := refNewCheckCardStatusService(ctx, dynamoGtw, kskLogger)
+
+	return checkCardStatusService.BatchCheckCardStatus(request), nil
 }
 
-func (s *CheckCardStatusService) CheckCardStatus(checkCardStatusRequest types.CheckCardStatusRequest) types.CheckCardStatusResponse {
+func (s *CheckCardStatusService) CheckCardStatus(checkCardStatusRequest types.CheckCardStatusRequest) (types.CheckCardStatusResponse, error) {
 	tag := fmt.Sprintf("%s | %s", checkCardStatusServiceTag, "checkCardStatus")
 	if checkCardStatusRequest.CardID == "" {
 		s.Logger.Info(fmt.Sprintf(tag, "omit logic"), "EMPTY CARD_ID")
-		return types.CheckCardStatusResponse{}
+		return types.CheckCardStatusResponse{}, nil
 	}
 
-	blockedCardInfo, err := s.getBlockedCardInfo(checkCardStatusRequest.CardID)
+	settings, err := s.Settings.GetSettings(s.Context, checkCardStatusRequest.MerchantIdentifier)
 	if err != nil {
+		return s.getFailModeResponse(checkCardStatusRequest.MerchantIdentifier, err)
+	}
+	if settings.Exempt {
+		return types.CheckCardStatusResponse{Exempt: true}, nil
+	}
+
+	blockedCardInfo, err := s.getBlockedCardInfo(checkCardStatusRequest.CardID)
+	if err != nil && !errors.Is(err, dynamoerror.ErrItemNotFound) {
 		s.Logger.Error(fmt.Sprintf(tag, "error getting blocked card info: "), err)
-		return types.CheckCardStatusResponse{}
+		return s.getFailModeResponse(checkCardStatusRequest.MerchantIdentifier, err)
+	}
+
+	currentDate := nowCaller().UnixMilli()
+	response := getCardStatus(blockedCardInfo, checkCardStatusRequest.MerchantIdentifier, currentDate, s.VelocityAllowlist)
+	if checkCardStatusRequest.Franchise != "" {
+		s.addRetryDetails(&response, checkCardStatusRequest, currentDate, settings.LimitFactor)
+	}
+
+	return applyMerchantSettings(response, settings), nil
+}
+
+// BatchCheckCardStatus block status of every pair, the cards are read with BatchGetItem.
+// Retry details are not resolved in batch, failed cards and merchants follow the merchant fail mode.
+func (s *CheckCardStatusService) BatchCheckCardStatus(request types.BatchCheckCardStatusRequest) types.BatchCheckCardStatusResponse {
+	blockedCards, failedCards := s.getBlockedCards(getUniqueCardIDs(request.Cards))
+	settings, failedMerchants := s.getMerchantsSettings(request.Cards)
+
+	currentDate := nowCaller().UnixMilli()
+	response := types.BatchCheckCardStatusResponse{
+		Results: make([]types.BatchCheckCardStatusItem, 0, len(request.Cards)),
+	}
+	for _, card := range request.Cards {
+		item := types.BatchCheckCardStatusItem{
+			CardID:             card.CardID,
+			MerchantIdentifier: card.MerchantIdentifier,
+		}
+		status, err := types.CheckCardStatusResponse{}, error(nil)
+		merchantSettings := settings[card.MerchantIdentifier]
+		if failedErr, failed := failedMerchants[card.MerchantIdentifier]; failed {
+			status, err = s.getFailModeResponse(card.MerchantIdentifier, failedErr)
+		} else if merchantSettings.Exempt {
+			status = types.CheckCardStatusResponse{Exempt: true}
+		} else if failedErr, failed := failedCards[card.CardID]; failed {
+			status, err = s.getFailModeResponse(card.MerchantIdentifier, failedErr)
+		} else {
+			status = applyMerchantSettings(
+				getCardStatus(blockedCards[card.CardID], card.MerchantIdentifier, currentDate, s.VelocityAllowlist),
+				merchantSettings,
+			)
+		}
+		if err != nil {
+			item.Error = err.Error()
+		} else {
+			item.Status = &status
+		}
+		response.Results = append(response.Results, item)
+	}
+
+	return response
+}
+
+// getBlockedCards reads the cards in chunks of the BatchGetItem limit, a card without record is not returned nor failed.
+func (s *CheckCardStatusService) getBlockedCards(cardIDs []string) (map[string]types.DynamoBlockedCard, map[string]error) {
+	blockedCards := make(map[string]types.DynamoBlockedCard, len(cardIDs))
+	failedCards := make(map[string]error)
+	for start := 0; start < len(cardIDs); start += constants.BatchGetMaxKeys {
+		chunk := cardIDs[start:min(start+constants.BatchGetMaxKeys, len(cardIDs))]
+		s.getBlockedCardsChunk(chunk, blockedCards, failedCards)
+	}
+
+	return blockedCards, failedCards
+}
+
+// getBlockedCardsChunk retries the unprocessed keys with backoff, the ones left are failed.
+func (s *CheckCardStatusService) getBlockedCardsChunk(
+	pending []string,
+	blockedCards map[string]types.DynamoBlockedCard,
+	failedCards map[string]error,
+) {
+	tag := fmt.Sprintf("%s | %s", checkCardStatusServiceTag, "getBlockedCardsChunk")
+	table := os.Getenv(constants.DynamoBlockedCard)
+	for attempt := 0; len(pending) > 0 && attempt < constants.BatchGetMaxAttempts; attempt++ {
+		if attempt > 0 {
+			sleepCaller(conflictBackoff(attempt))
+		}
+
+		var out coreTypes.BatchGetItemResponse
+		err := s.Dynamo.BatchGetItem(s.Context, dynamoBuilders.BatchGetBlockedCardsBuilder(pending), &out)
+		var items []types.DynamoBlockedCard
+		if err == nil {
+			err = attributevalue.UnmarshalListOfMaps(out.Responses[table], &items)
+		}
+		if err != nil {
+			s.Logger.Error(tag, err)
+			setFailedCards(failedCards, pending, err)
+			return
+		}
+
+		for _, item := range items {
+			if !isExpiredItem(item.ExpiresAt) {
+				blockedCards[item.CardID] = item
+			}
+		}
+		pending = getUnprocessedCardIDs(out.UnprocessedKeys[table])
+	}
+
+	setFailedCards(failedCards, pending, errUnprocessedCard)
+}
+
+// getMerchantsSettings settings of every merchant of the batch, read once each.
+func (s *CheckCardStatusService) getMerchantsSettings(
+	cards []types.CheckCardStatusRequest,
+) (map[string]types.MerchantSettings, map[string]error) {
+	settings := make(map[string]types.MerchantSettings)
+	failedMerchants := make(map[string]error)
+	for _, card := range cards {
+		if _, ok := settings[card.MerchantIdentifier]; ok || failedMerchants[card.MerchantIdentifier] != nil {
+			continue
+		}
+
+		merchantSettings, err := s.Settings.GetSettings(s.Context, card.MerchantIdentifier)
+		if err != nil {
+			failedMerchants[card.MerchantIdentifier] = err
+			continue
+		}
+		settings[card.MerchantIdentifier] = merchantSettings
 	}
 
-	lockInfo, isBlocked := blockedCardInfo.BlockedMerchants[checkCardStatusRequest.MerchantIdentifier]
+	return settings, failedMerchants
+}
+
+// applyMerchantSettings a shadow merchant is never answered as blocked, the block it would get is kept as detail.
+func applyMerchantSettings(response types.CheckCardStatusResponse, settings types.MerchantSettings) types.CheckCardStatusResponse {
+	if settings.Shadow {
+		response.Shadow = true
+		response.Blocked = false
+	}
+
+	return response
+}
+
+func getUniqueCardIDs(cards []types.CheckCardStatusRequest) []string {
+	seen := make(map[string]bool, len(cards))
+	cardIDs := make([]string, 0, len(cards))
+	for _, card := range cards {
+		if card.CardID == "" || seen[card.CardID] {
+			continue
+		}
+		seen[card.CardID] = true
+		cardIDs = append(cardIDs, card.CardID)
+	}
+
+	return cardIDs
+}
+
+func getUnprocessedCardIDs(unprocessed dynamoTypes.KeysAndAttributes) []string {
+	cardIDs := make([]string, 0, len(unprocessed.Keys))
+	for _, key := range unprocessed.Keys {
+		if cardID, ok := key[constants.CardIdField].(*dynamoTypes.AttributeValueMemberS); ok {
+			cardIDs = append(cardIDs, cardID.Value)
+		}
+	}
+
+	return cardIDs
+}
+
+func setFailedCards(failedCards map[string]error, cardIDs []string, err error) {
+	for _, cardID := range cardIDs {
+		failedCards[cardID] = err
+	}
+}
+
+// getFailModeResponse answers with the merchant fail mode, OPEN unless configured otherwise.
+func (s *CheckCardStatusService) getFailModeResponse(merchantID string, err error) (types.CheckCardStatusResponse, error) {
+	failMode := s.FailMode
+	if merchantFailMode, ok := s.MerchantFailModes[merchantID]; ok {
+		failMode = merchantFailMode
+	}
+	s.Logger.Info(fmt.Sprintf("%s | %s", checkCardStatusServiceTag, "getFailModeResponse"), failMode)
+
+	switch failMode {
+	case constants.FailClosed:
+		return types.CheckCardStatusResponse{Blocked: true, Degraded: true}, nil
+	case constants.FailError:
+		return types.CheckCardStatusResponse{}, err
+	default:
+		return types.CheckCardStatusResponse{Degraded: true}, nil
+	}
+}
+
+// getMerchantFailModes merchant overrides of the default fail mode, read from the environment so they work with Dynamo down.
+// A malformed value is logged and every merchant keeps the default.
+func getMerchantFailModes(lg logger.KushkiLogger) map[string]string {
+	overrides := os.Getenv(constants.EnvCardStatusMerchantFailModes)
+	if overrides == "" {
+		return map[string]string{}
+	}
+
+	var failModes map[string]string
+	if err := json.Unmarshal([]byte(overrides), &failModes); err != nil {
+		lg.Error(fmt.Sprintf("%s | %s", checkCardStatusServiceTag, "getMerchantFailModes"), err)
+		return map[string]string{}
+	}
+	for merchantID, failMode := range failModes {
+		failModes[merchantID] = strings.ToUpper(failMode)
+	}
+
+	return failModes
+}
+
+// validateCheckCardStatusRequest the conditional is part of the retry key, a value with separators would read another counter.
+// Without conditional the retry details are read from the counter of the messages blocked without it.
+func validateCheckCardStatusRequest(request types.CheckCardStatusRequest) error {
+	for _, char := range request.Conditional {
+		if !unicode.IsLetter(char) && !unicode.IsDigit(char) {
+			return errInvalidConditional
+		}
+	}
+
+	return nil
+}
+
+func getBlockStatus(blockedMerchants map[string]types.BlockedMerchant, merchantID string, currentDate int64) types.CheckCardStatusResponse {
+	lockInfo, isBlocked := blockedMerchants[merchantID]
 	if !isBlocked {
 		return types.CheckCardStatusResponse{}
 	}
@@ -91,15 +375,106 @@ func (s *CheckCardStatusService) CheckCardStatus(checkCardStatusRequest types.Ch
 		return types.CheckCardStatusResponse{Blocked: true, BlockType: constants.PERMANENT, HasRetries: false}
 	}
 
-	currentDate := time.Now().UnixMilli()
 	isTemporarilyBlocked := lockInfo.ExpirationDate > currentDate
-	lastRetry := time.UnixMilli(lockInfo.LastRetry).Add(24 * time.Hour).UnixMilli()
-	hasRetries := lastRetry > currentDate
-	return types.CheckCardStatusResponse{
+	hasRetries := lockInfo.LastRetry > 0 && getRetriesEnd(lockInfo) > currentDate
+	response := types.CheckCardStatusResponse{
 		Blocked:    isTemporarilyBlocked,
 		BlockType:  getBlockType(isTemporarilyBlocked),
 		HasRetries: hasRetries,
 	}
+	if isTemporarilyBlocked {
+		response.ExpirationDate = lockInfo.ExpirationDate
+		response.NextAllowedDate = lockInfo.ExpirationDate
+	}
+
+	return response
+}
+
+// getRetriesEnd the daily window of the last retry, the records written before the calendar windows keep 24 hours.
+func getRetriesEnd(lockInfo types.BlockedMerchant) int64 {
+	if lockInfo.RetriesEnd > 0 {
+		return lockInfo.RetriesEnd
+	}
+
+	return time.UnixMilli(lockInfo.LastRetry).Add(constants.DayHours * time.Hour).UnixMilli()
+}
+
+// addRetryDetails remaining attempts per rule and the policy that produced them.
+// A permanent block keeps NextAllowedDate empty since no retry will ever be accepted.
+func (s *CheckCardStatusService) addRetryDetails(
+	response *types.CheckCardStatusResponse,
+	request types.CheckCardStatusRequest,
+	currentDate int64,
+	limitFactor float64,
+) {
+	tag := fmt.Sprintf("%s | %s", checkCardStatusServiceTag, "addRetryDetails")
+	policy, err := s.Policies.GetPolicy(s.Context, request.Franchise, request.Processor, request.MerchantIdentifier)
+	if err != nil {
+		s.Logger.Error(tag, err)
+		return
+	}
+
+	category := classifyDecline(request.Franchise, request.Conditional)
+	response.Policy = &types.PolicyDecision{
+		PolicyID: policy.PolicyID,
+		Version:  policy.Version,
+		Category: category.Category,
+	}
+
+	blockRequest := types.BlockCardRequest{
+		MerchantIdentifier: request.MerchantIdentifier,
+		Franchise:          request.Franchise,
+		CardID:             request.CardID,
+		Processor:          request.Processor,
+		Conditional:        request.Conditional,
+	}
+	nextAllowedDate := max(response.NextAllowedDate, currentDate)
+	for _, rule := range applyLimitFactor(getApplicableRules(policy, category), limitFactor) {
+		var cardRetry types.CardRetry
+		err := s.Dynamo.GetItem(s.Context, dynamoBuilders.GetRetryBuilder(generateRuleRetryKey(blockRequest, rule)), &cardRetry)
+		if err != nil && !errors.Is(err, dynamoerror.ErrItemNotFound) {
+			s.Logger.Error(tag, err)
+			return
+		}
+		if isExpiredItem(cardRetry.ExpiresAt) {
+			cardRetry.Retries = nil
+		}
+
+		retries := getWindowRetries(currentDate, cardRetry.Retries, rule)
+		response.RemainingAttempts = append(response.RemainingAttempts, types.RemainingAttempts{
+			Frequency:   rule.Frequency,
+			Category:    rule.Category,
+			WindowHours: rule.WindowHours,
+			Calendar:    rule.Calendar,
+			TimeZone:    rule.TimeZone,
+			MaxAttempts: rule.MaxAttempts,
+			Remaining:   max(rule.MaxAttempts-len(retries), 0),
+		})
+		if len(retries) >= rule.MaxAttempts && rule.MaxAttempts > 0 {
+			// an attempt is freed once only MaxAttempts-1 retries remain inside the window.
+			leaving := retries[rule.MaxAttempts-1]
+			nextAllowedDate = max(nextAllowedDate, getWindowEnd(rule, leaving))
+		}
+	}
+
+	if !strings.EqualFold(response.BlockType, constants.PERMANENT) && !category.NeverRetry {
+		response.NextAllowedDate = nextAllowedDate
+	}
+}
+
+// getWindowRetries retries inside the rule window ordered from the newest.
+func getWindowRetries(currentDate int64, retries []int64, rule types.FrequencyRule) []int64 {
+	windowRetries := make([]int64, 0, len(retries))
+	for _, retry := range retries {
+		if isInWindow(rule, retry, currentDate) {
+			windowRetries = append(windowRetries, retry)
+		}
+	}
+	sort.Slice(windowRetries, func(i, j int) bool {
+		return windowRetries[i] > windowRetries[j]
+	})
+
+	return windowRetries
 }
 
 // getBlockedCardInfo get block card info from dynamodb.
@@ -109,6 +484,9 @@ func (s *CheckCardStatusService) getBlockedCardInfo(cardID string) (types.Dynamo
 	if err := s.Dynamo.GetItem(s.Context, builder, &blockedCardInfo); err != nil {
 		return blockedCardInfo, err
 	}
+	if isExpiredItem(blockedCardInfo.ExpiresAt) {
+		return types.DynamoBlockedCard{}, dynamoerror.ErrItemNotFound
+	}
 	return blockedCardInfo, nil
 }
 
@@ -4,6 +4,7 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -13,9 +14,14 @@ import (
 	mockService "bitbucket.org/kushki/usrv-card-control/mocks/service"
 	"bitbucket.org/kushki/usrv-card-control/types"
 	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo/builder"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
 	"bitbucket.org/kushki/usrv-go-core/logger"
+	coreTypes "bitbucket.org/kushki/usrv-go-core/utils/types"
 	"github.com/Jeffail/gabs/v2"
 	"github.com/aws/aws-lambda-go/events"
+	"github.com/aws/aws-sdk-go-v2/feature/dynamodb/attributevalue"
+	typesDynamo "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -28,11 +34,14 @@ const (
 )
 
 var (
-	checkCardStatusRequest = types.CheckCardStatusRequest{}
+	checkCardStatusRequest  = types.CheckCardStatusRequest{}
+	mockTemporaryExpiration = time.Now().Add(2 * time.Hour).UnixMilli()
 )
 
 type initializeCheckCardStatusTests struct {
 	name                  string
+	request               types.CheckCardStatusRequest
+	serviceError          error
 	jsonUnmarshalError    error
 	initializeDynamoError error
 	expectedError         string
@@ -54,6 +63,20 @@ func TestInitializeCheckCardStatus(t *testing.T) {
 			jsonUnmarshalError: errors.New("unmarshal Json Error"),
 			expectedError:      "Code: E001 | Status Code: 400 | Message: Cuerpo de la petición inválido. | Metadata: {\"Origin\":\"CheckCardStatusServiceInitializeCheckCardStatus\",\"Message\":\"unmarshal Json Error\"}",
 		},
+		{
+			name:          "Should error when the conditional is not a response code",
+			request:       types.CheckCardStatusRequest{CardID: "CardTest123", Franchise: "VISA", Conditional: "51-daily"},
+			expectedError: "Code: E001 | Status Code: 400 | Message: Cuerpo de la petición inválido. | Metadata: {\"Origin\":\"CheckCardStatusServiceInitializeCheckCardStatus\",\"Message\":\"conditional must be an alphanumeric response code\"}",
+		},
+		{
+			name:    "Should accept visa without conditional",
+			request: types.CheckCardStatusRequest{CardID: "CardTest123", Franchise: "VISA"},
+		},
+		{
+			name:          "Should error when the merchant fails with error",
+			serviceError:  errors.New("dynamo unavailable"),
+			expectedError: "Code: E002 | Status Code: 500 | Message: Ha ocurrido un error inesperado. | Metadata: {\"Origin\":\"CheckCardStatusServiceInitializeCheckCardStatus\",\"Message\":\"dynamo unavailable\"}",
+		},
 	}
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
@@ -63,13 +86,14 @@ func TestInitializeCheckCardStatus(t *testing.T) {
 				return mocks.GetMockLogger(t)
 			}
 			mockCheckService := mockService.ICheckCardStatusService{}
-			mockCheckService.On("CheckCardStatus", mock.Anything).Return(types.CheckCardStatusResponse{}, nil)
+			mockCheckService.On("CheckCardStatus", mock.Anything).Return(types.CheckCardStatusResponse{}, test.serviceError)
 
 			refNewCheckCardStatusService = func(context.Context, dynamo.IDynamoGateway, logger.KushkiLogger) ICheckCardStatusService {
 				return &mockCheckService
 			}
 
 			jsonUnmarshalCaller = func(data []byte, v any) error {
+				*v.(*types.CheckCardStatusRequest) = test.request
 				return test.jsonUnmarshalError
 			}
 			initializeDynamoGtw = func(context.Context, logger.KushkiLogger) (dynamo.IDynamoGateway, error) {
@@ -98,6 +122,8 @@ type checkCardStatusServiceTest struct {
 	expectedError              string
 	emptyCardId                bool
 	getItemErr                 error
+	failMode                   string
+	merchantFailModes          string
 	expectedCardStatusResponse types.CheckCardStatusResponse
 }
 
@@ -126,8 +152,10 @@ func TestCheckCardStatus(t *testing.T) {
 			name:           "Should run successfully when card is temporary blocked",
 			merchantIDTest: mockIDMerchantTemporarily,
 			expectedCardStatusResponse: types.CheckCardStatusResponse{
-				BlockType: constants.TEMPORARY,
-				Blocked:   true,
+				BlockType:       constants.TEMPORARY,
+				Blocked:         true,
+				ExpirationDate:  mockTemporaryExpiration,
+				NextAllowedDate: mockTemporaryExpiration,
 			},
 		},
 		{
@@ -138,14 +166,54 @@ func TestCheckCardStatus(t *testing.T) {
 			},
 		},
 		{
-			name:                       "Should return error when get card blocked info from dynamo ",
+			name:                       "Should return not blocked when the card has no record",
+			merchantIDTest:             mockIDMerchantEnable,
+			getItemErr:                 dynamoerror.ErrItemNotFound,
+			expectedCardStatusResponse: types.CheckCardStatusResponse{},
+		},
+		{
+			name:                       "Should fail open and flag the response as degraded by default",
+			merchantIDTest:             mockIDMerchantEnable,
 			getItemErr:                 errors.New("error getting blocked card info"),
+			expectedCardStatusResponse: types.CheckCardStatusResponse{Degraded: true},
+		},
+		{
+			name:                       "Should fail closed when it is the default fail mode",
+			merchantIDTest:             mockIDMerchantEnable,
+			getItemErr:                 errors.New("error getting blocked card info"),
+			failMode:                   "closed",
+			expectedCardStatusResponse: types.CheckCardStatusResponse{Blocked: true, Degraded: true},
+		},
+		{
+			name:                       "Should use the fail mode of the merchant over the default",
+			merchantIDTest:             mockIDMerchantEnable,
+			getItemErr:                 errors.New("error getting blocked card info"),
+			failMode:                   constants.FailClosed,
+			merchantFailModes:          `{"cardEnabled":"OPEN"}`,
+			expectedCardStatusResponse: types.CheckCardStatusResponse{Degraded: true},
+		},
+		{
+			name:                       "Should ignore invalid merchant fail modes",
+			merchantIDTest:             mockIDMerchantEnable,
+			getItemErr:                 errors.New("error getting blocked card info"),
+			failMode:                   constants.FailClosed,
+			merchantFailModes:          `{`,
+			expectedCardStatusResponse: types.CheckCardStatusResponse{Blocked: true, Degraded: true},
+		},
+		{
+			name:                       "Should return the error when the merchant fails with error",
+			merchantIDTest:             mockIDMerchantEnable,
+			getItemErr:                 errors.New("error getting blocked card info"),
+			merchantFailModes:          `{"cardEnabled":"ERROR"}`,
+			expectedError:              "error getting blocked card info",
 			expectedCardStatusResponse: types.CheckCardStatusResponse{},
 		},
 	}
 
 	for _, test := range tests {
 		t.Run(test.name, func(t *testing.T) {
+			t.Setenv(constants.EnvCardStatusFailMode, test.failMode)
+			t.Setenv(constants.EnvCardStatusMerchantFailModes, test.merchantFailModes)
 			mockCheckCardStatusService := makeMockCheckCardStatusService(t, test)
 			mockRequest := types.CheckCardStatusRequest{
 				CardID:             "CardTest123",
@@ -155,7 +223,12 @@ func TestCheckCardStatus(t *testing.T) {
 				mockRequest.CardID = ""
 			}
 
-			cardStatusResponse := mockCheckCardStatusService.CheckCardStatus(mockRequest)
+			cardStatusResponse, err := mockCheckCardStatusService.CheckCardStatus(mockRequest)
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+			} else {
+				assert.NoError(t, err)
+			}
 			assert.Equal(t, test.expectedCardStatusResponse, cardStatusResponse)
 
 			t.Cleanup(clean)
@@ -170,6 +243,7 @@ func makeMockCheckCardStatusService(t *testing.T, test checkCardStatusServiceTes
 	ctx := context.Background()
 	mockLogger := mocks.GetMockLogger(t)
 	mockDynamo := &mocksCore.IDynamoGateway{}
+	mockSettingsNotFound(mockDynamo)
 	mockDynamo.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
 		Return(test.getItemErr).
 		Run(func(args mock.Arguments) {
@@ -183,7 +257,7 @@ func makeMockCheckCardStatusService(t *testing.T, test checkCardStatusServiceTes
 					},
 					mockIDMerchantTemporarily: {
 						BlockType:      constants.TEMPORARY,
-						ExpirationDate: currentDate.Add(2 * time.Hour).UnixMilli(),
+						ExpirationDate: mockTemporaryExpiration,
 					},
 					mockIDMerchantRetries: {
 						ExpirationDate: currentDate.Add(-2 * time.Hour).UnixMilli(),
@@ -199,3 +273,367 @@ func makeMockCheckCardStatusService(t *testing.T, test checkCardStatusServiceTes
 		})
 	return refNewCheckCardStatusService(ctx, mockDynamo, mockLogger)
 }
+
+func TestCheckCardStatus_RetryDetails(t *testing.T) {
+	t.Cleanup(clean)
+	now := time.Now().UnixMilli()
+	nowCaller = func() time.Time { return time.UnixMilli(now) }
+	hour := time.Hour.Milliseconds()
+	visaPolicy := types.RetryPolicy{
+		PolicyID: "VISA#*#*",
+		Version:  "v3",
+		Rules:    []types.FrequencyRule{{Frequency: constants.DailyFrequency, WindowHours: 24, MaxAttempts: 2}},
+	}
+	decision := func(category string) *types.PolicyDecision {
+		return &types.PolicyDecision{PolicyID: visaPolicy.PolicyID, Version: visaPolicy.Version, Category: category}
+	}
+
+	tests := []struct {
+		name              string
+		conditional       string
+		retries           []int64
+		getRetryErr       error
+		policyErr         error
+		expectedRetryKey  string
+		expectedRemaining []types.RemainingAttempts
+		expectedNext      int64
+		expectedPolicy    *types.PolicyDecision
+	}{
+		{
+			name:              "should report the remaining attempts and allow retries now",
+			conditional:       "51",
+			retries:           []int64{now - hour, now - 30*hour},
+			expectedRetryKey:  "CardTest123-cardEnabled-51-daily",
+			expectedRemaining: []types.RemainingAttempts{{Frequency: constants.DailyFrequency, WindowHours: 24, MaxAttempts: 2, Remaining: 1}},
+			expectedNext:      now,
+			expectedPolicy:    decision(constants.VisaIssuerCannotApproveNow),
+		},
+		{
+			name:              "should compute when the window frees an attempt",
+			conditional:       "51",
+			retries:           []int64{now - hour, now - 2*hour, now - 3*hour},
+			expectedRetryKey:  "CardTest123-cardEnabled-51-daily",
+			expectedRemaining: []types.RemainingAttempts{{Frequency: constants.DailyFrequency, WindowHours: 24, MaxAttempts: 2, Remaining: 0}},
+			expectedNext:      now - 2*hour + 24*hour,
+			expectedPolicy:    decision(constants.VisaIssuerCannotApproveNow),
+		},
+		{
+			name:              "should read the counter of the visa conditional",
+			conditional:       "05",
+			retries:           []int64{now - hour},
+			expectedRetryKey:  "CardTest123-cardEnabled-05-daily",
+			expectedRemaining: []types.RemainingAttempts{{Frequency: constants.DailyFrequency, WindowHours: 24, MaxAttempts: 2, Remaining: 1}},
+			expectedNext:      now,
+			expectedPolicy:    decision(constants.VisaGenericDecline),
+		},
+		{
+			name:              "should read the counter of the visa messages without conditional",
+			retries:           []int64{now - hour},
+			expectedRetryKey:  "CardTest123-cardEnabled--daily",
+			expectedRemaining: []types.RemainingAttempts{{Frequency: constants.DailyFrequency, WindowHours: 24, MaxAttempts: 2, Remaining: 1}},
+			expectedNext:      now,
+			expectedPolicy:    decision(constants.VisaGenericDecline),
+		},
+		{
+			name:              "should not allow retries for never retry declines",
+			conditional:       "04",
+			expectedRetryKey:  "CardTest123-cardEnabled-04-daily",
+			expectedRemaining: []types.RemainingAttempts{{Frequency: constants.DailyFrequency, WindowHours: 24, MaxAttempts: 2, Remaining: 2}},
+			expectedPolicy:    decision(constants.VisaIssuerWillNeverApprove),
+		},
+		{
+			name:             "should keep the policy without counters if getting retries fails",
+			conditional:      "51",
+			getRetryErr:      errors.New("dynamo error"),
+			expectedRetryKey: "CardTest123-cardEnabled-51-daily",
+			expectedPolicy:   decision(constants.VisaIssuerCannotApproveNow),
+		},
+		{
+			name:        "should omit retry details if the policy fails",
+			conditional: "51",
+			policyErr:   errors.New("policy error"),
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			mockDynamo := &mocksCore.IDynamoGateway{}
+			mockDynamo.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.DynamoBlockedCard")).
+				Return(nil)
+			mockDynamo.On("GetItem", mock.Anything, mock.MatchedBy(func(input *builder.GetItemBuilder) bool {
+				item, err := input.BuildInput()
+				return err == nil && assert.ObjectsAreEqual(
+					&typesDynamo.AttributeValueMemberS{Value: test.expectedRetryKey}, item.Key[constants.RetryKeyField])
+			}), mock.AnythingOfType("*types.CardRetry")).
+				Run(func(args mock.Arguments) {
+					*args.Get(2).(*types.CardRetry) = types.CardRetry{Retries: test.retries}
+				}).
+				Return(test.getRetryErr)
+			policiesMock := &mockService.IRetryPolicyService{}
+			policiesMock.On("GetPolicy", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+				Return(visaPolicy, test.policyErr)
+			srv := CheckCardStatusService{
+				Context:  context.Background(),
+				Logger:   mocks.GetMockLogger(t),
+				Dynamo:   mockDynamo,
+				Policies: policiesMock,
+				Settings: getSettingsMock(),
+			}
+
+			response, err := srv.CheckCardStatus(types.CheckCardStatusRequest{
+				CardID:             "CardTest123",
+				MerchantIdentifier: mockIDMerchantEnable,
+				Franchise:          "VISA",
+				Conditional:        test.conditional,
+			})
+
+			assert.NoError(t, err)
+			assert.Equal(t, test.expectedRemaining, response.RemainingAttempts)
+			assert.Equal(t, test.expectedNext, response.NextAllowedDate)
+			assert.Equal(t, test.expectedPolicy, response.Policy)
+		})
+	}
+}
+
+func TestInitializeBatchCheckCardStatus(t *testing.T) {
+	tests := []struct {
+		name                  string
+		request               types.BatchCheckCardStatusRequest
+		jsonUnmarshalError    error
+		initializeDynamoError error
+		expectedError         string
+	}{
+		{
+			name:    "Should run successfully when initialize service",
+			request: types.BatchCheckCardStatusRequest{Cards: []types.CheckCardStatusRequest{{CardID: "CardTest123"}}},
+		},
+		{
+			name:               "Should error when unmarshal json request",
+			jsonUnmarshalError: errors.New("unmarshal Json Error"),
+			expectedError:      "Code: E001 | Status Code: 400 | Message: Cuerpo de la petición inválido. | Metadata: {\"Origin\":\"CheckCardStatusServiceInitializeBatchCheckCardStatus\",\"Message\":\"unmarshal Json Error\"}",
+		},
+		{
+			name:          "Should error when the batch is empty",
+			expectedError: "Code: E001 | Status Code: 400 | Message: Cuerpo de la petición inválido. | Metadata: {\"Origin\":\"CheckCardStatusServiceInitializeBatchCheckCardStatus\",\"Message\":\"cards must have between 1 and 500 items\"}",
+		},
+		{
+			name:          "Should error when the batch exceeds the max cards",
+			request:       types.BatchCheckCardStatusRequest{Cards: make([]types.CheckCardStatusRequest, constants.BatchCheckMaxCards+1)},
+			expectedError: "Code: E001 | Status Code: 400 | Message: Cuerpo de la petición inválido. | Metadata: {\"Origin\":\"CheckCardStatusServiceInitializeBatchCheckCardStatus\",\"Message\":\"cards must have between 1 and 500 items\"}",
+		},
+		{
+			name:                  "Should error when initialize dynamo service",
+			request:               types.BatchCheckCardStatusRequest{Cards: []types.CheckCardStatusRequest{{CardID: "CardTest123"}}},
+			initializeDynamoError: errors.New("dynamo service error"),
+			expectedError:         "Code: E002 | Status Code: 500 | Message: Ha ocurrido un error inesperado. | Metadata: {\"Origin\":\"CheckCardStatusServiceInitializeBatchCheckCardStatus\",\"Message\":\"dynamo service error\"}",
+		},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Cleanup(clean)
+			newKushkiLogger = func(context.Context) logger.KushkiLogger {
+				return mocks.GetMockLogger(t)
+			}
+			mockCheckService := mockService.ICheckCardStatusService{}
+			mockCheckService.On("BatchCheckCardStatus", test.request).Return(types.BatchCheckCardStatusResponse{})
+			refNewCheckCardStatusService = func(context.Context, dynamo.IDynamoGateway, logger.KushkiLogger) ICheckCardStatusService {
+				return &mockCheckService
+			}
+			jsonUnmarshalCaller = func(data []byte, v any) error {
+				*v.(*types.BatchCheckCardStatusRequest) = test.request
+				return test.jsonUnmarshalError
+			}
+			initializeDynamoGtw = func(context.Context, logger.KushkiLogger) (dynamo.IDynamoGateway, error) {
+				return &mocksCore.IDynamoGateway{}, test.initializeDynamoError
+			}
+
+			_, err := InitializeBatchCheckCardStatus(context.Background(), events.APIGatewayProxyRequest{Body: "{}"})
+			if test.expectedError != "" {
+				assert.EqualError(t, err, test.expectedError)
+				return
+			}
+			assert.NoError(t, err)
+			mockCheckService.AssertExpectations(t)
+		})
+	}
+}
+
+func TestGetMerchantFailModes(t *testing.T) {
+	t.Setenv(constants.EnvCardStatusMerchantFailModes, `{"merchant1":"closed","merchant2":"ERROR"}`)
+	assert.Equal(t, map[string]string{"merchant1": constants.FailClosed, "merchant2": constants.FailError}, getMerchantFailModes(mocks.GetMockLogger(t)))
+
+	t.Setenv(constants.EnvCardStatusMerchantFailModes, `{`)
+	assert.Empty(t, getMerchantFailModes(mocks.GetMockLogger(t)))
+
+	t.Setenv(constants.EnvCardStatusMerchantFailModes, "")
+	assert.Empty(t, getMerchantFailModes(mocks.GetMockLogger(t)))
+}
+
+func TestBatchCheckCardStatus(t *testing.T) {
+	t.Cleanup(clean)
+	t.Setenv(constants.DynamoBlockedCard, "blocked-card")
+	t.Setenv(constants.EnvCardStatusMerchantFailModes, `{"merchantError":"ERROR"}`)
+	now := time.Date(2025, 3, 20, 12, 0, 0, 0, time.UTC)
+	nowCaller = func() time.Time { return now }
+	sleepCaller = func(time.Duration) {}
+
+	blockedCard := func(cardID string) map[string]typesDynamo.AttributeValue {
+		item, _ := attributevalue.MarshalMap(types.DynamoBlockedCard{
+			CardID: cardID,
+			BlockedMerchants: map[string]types.BlockedMerchant{
+				mockIDMerchantPermanently: {BlockType: constants.PERMANENT},
+			},
+		})
+		return item
+	}
+	unprocessedKey := func(cardID string) map[string]typesDynamo.AttributeValue {
+		return map[string]typesDynamo.AttributeValue{
+			constants.CardIdField: &typesDynamo.AttributeValueMemberS{Value: cardID},
+		}
+	}
+
+	mockDynamo := &mocksCore.IDynamoGateway{}
+	mockSettingsNotFound(mockDynamo)
+	mockDynamo.On("BatchGetItem", mock.Anything, mock.MatchedBy(func(b *builder.BatchGetItemInputBuilder) bool {
+		return len(b.Keys) == 3
+	}), mock.Anything).Return(nil).Once().Run(func(args mock.Arguments) {
+		out := args.Get(2).(*coreTypes.BatchGetItemResponse)
+		out.Responses = map[string][]map[string]typesDynamo.AttributeValue{"blocked-card": {blockedCard("blocked")}}
+		out.UnprocessedKeys = map[string]typesDynamo.KeysAndAttributes{
+			"blocked-card": {Keys: []map[string]typesDynamo.AttributeValue{unprocessedKey("throttled")}},
+		}
+	})
+	mockDynamo.On("BatchGetItem", mock.Anything, mock.Anything, mock.Anything).Return(nil).
+		Times(constants.BatchGetMaxAttempts - 1).Run(func(args mock.Arguments) {
+		out := args.Get(2).(*coreTypes.BatchGetItemResponse)
+		out.UnprocessedKeys = map[string]typesDynamo.KeysAndAttributes{
+			"blocked-card": {Keys: []map[string]typesDynamo.AttributeValue{unprocessedKey("throttled")}},
+		}
+	})
+	service := NewCheckCardStatusService(context.Background(), mockDynamo, mocks.GetMockLogger(t))
+
+	response := service.BatchCheckCardStatus(types.BatchCheckCardStatusRequest{Cards: []types.CheckCardStatusRequest{
+		{CardID: "blocked", MerchantIdentifier: mockIDMerchantPermanently},
+		{CardID: "blocked", MerchantIdentifier: mockIDMerchantEnable},
+		{CardID: "notBlocked", MerchantIdentifier: mockIDMerchantPermanently},
+		{CardID: "throttled", MerchantIdentifier: mockIDMerchantEnable},
+		{CardID: "throttled", MerchantIdentifier: "merchantError"},
+		{MerchantIdentifier: mockIDMerchantEnable},
+	}})
+
+	assert.Equal(t, []types.BatchCheckCardStatusItem{
+		{CardID: "blocked", MerchantIdentifier: mockIDMerchantPermanently, Status: &types.CheckCardStatusResponse{Blocked: true, BlockType: constants.PERMANENT}},
+		{CardID: "blocked", MerchantIdentifier: mockIDMerchantEnable, Status: &types.CheckCardStatusResponse{}},
+		{CardID: "notBlocked", MerchantIdentifier: mockIDMerchantPermanently, Status: &types.CheckCardStatusResponse{}},
+		{CardID: "throttled", MerchantIdentifier: mockIDMerchantEnable, Status: &types.CheckCardStatusResponse{Degraded: true}},
+		{CardID: "throttled", MerchantIdentifier: "merchantError", Error: errUnprocessedCard.Error()},
+		{MerchantIdentifier: mockIDMerchantEnable, Status: &types.CheckCardStatusResponse{}},
+	}, response.Results)
+	mockDynamo.AssertNumberOfCalls(t, "BatchGetItem", constants.BatchGetMaxAttempts)
+}
+
+func TestBatchCheckCardStatus_Chunks(t *testing.T) {
+	t.Cleanup(clean)
+	t.Setenv(constants.EnvCardStatusFailMode, constants.FailClosed)
+	cards := make([]types.CheckCardStatusRequest, 0, constants.BatchGetMaxKeys+1)
+	for i := 0; i <= constants.BatchGetMaxKeys; i++ {
+		cards = append(cards, types.CheckCardStatusRequest{CardID: fmt.Sprint("card", i), MerchantIdentifier: mockIDMerchantEnable})
+	}
+
+	mockDynamo := &mocksCore.IDynamoGateway{}
+	mockSettingsNotFound(mockDynamo)
+	mockDynamo.On("BatchGetItem", mock.Anything, mock.MatchedBy(func(b *builder.BatchGetItemInputBuilder) bool {
+		return len(b.Keys) == constants.BatchGetMaxKeys
+	}), mock.Anything).Return(nil).Once()
+	mockDynamo.On("BatchGetItem", mock.Anything, mock.MatchedBy(func(b *builder.BatchGetItemInputBuilder) bool {
+		return len(b.Keys) == 1
+	}), mock.Anything).Return(errors.New("dynamo unavailable")).Once()
+	service := NewCheckCardStatusService(context.Background(), mockDynamo, mocks.GetMockLogger(t))
+
+	response := service.BatchCheckCardStatus(types.BatchCheckCardStatusRequest{Cards: cards})
+
+	assert.Len(t, response.Results, constants.BatchGetMaxKeys+1)
+	assert.Equal(t, &types.CheckCardStatusResponse{}, response.Results[0].Status)
+	assert.Equal(t, &types.CheckCardStatusResponse{Blocked: true, Degraded: true}, response.Results[constants.BatchGetMaxKeys].Status)
+	mockDynamo.AssertExpectations(t)
+}
+
+func TestCheckCardStatus_ExpiredCard(t *testing.T) {
+	t.Cleanup(clean)
+	mockDynamo := &mocksCore.IDynamoGateway{}
+	mockSettingsNotFound(mockDynamo)
+	mockDynamo.On("GetItem", mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(2).(*types.DynamoBlockedCard) = types.DynamoBlockedCard{
+			CardID:    "CardTest123",
+			ExpiresAt: time.Now().Add(-time.Minute).Unix(),
+			BlockedMerchants: map[string]types.BlockedMerchant{
+				mockIDMerchantTemporarily: {BlockType: constants.TEMPORARY, ExpirationDate: mockTemporaryExpiration},
+			},
+		}
+	})
+	service := NewCheckCardStatusService(context.Background(), mockDynamo, mocks.GetMockLogger(t))
+
+	response, err := service.CheckCardStatus(types.CheckCardStatusRequest{CardID: "CardTest123", MerchantIdentifier: mockIDMerchantTemporarily})
+
+	assert.NoError(t, err)
+	assert.Equal(t, types.CheckCardStatusResponse{}, response)
+}
+
+func TestCheckCardStatus_MerchantSettings(t *testing.T) {
+	t.Cleanup(clean)
+	t.Setenv(constants.DynamoBlockedCard, "blocked-card")
+	mockDynamo := &mocksCore.IDynamoGateway{}
+	mockDynamo.On("GetItem", mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(2).(*types.DynamoBlockedCard) = types.DynamoBlockedCard{
+			CardID: "CardTest123",
+			BlockedMerchants: map[string]types.BlockedMerchant{
+				"exempt": {BlockType: constants.PERMANENT},
+				"shadow": {BlockType: constants.PERMANENT},
+			},
+		}
+	})
+	mockDynamo.On("BatchGetItem", mock.Anything, mock.Anything, mock.Anything).Return(nil).Run(func(args mock.Arguments) {
+		item, _ := attributevalue.MarshalMap(types.DynamoBlockedCard{
+			CardID:           "CardTest123",
+			BlockedMerchants: map[string]types.BlockedMerchant{"shadow": {BlockType: constants.PERMANENT}},
+		})
+		args.Get(2).(*coreTypes.BatchGetItemResponse).Responses = map[string][]map[string]typesDynamo.AttributeValue{"blocked-card": {item}}
+	})
+	settingsMock := &mockService.IMerchantSettingsService{}
+	settingsMock.On("GetSettings", mock.Anything, "exempt").Return(types.MerchantSettings{Exempt: true}, nil)
+	settingsMock.On("GetSettings", mock.Anything, "shadow").Return(types.MerchantSettings{Shadow: true}, nil)
+	settingsMock.On("GetSettings", mock.Anything, "failed").Return(types.MerchantSettings{}, commonError)
+	srv := CheckCardStatusService{
+		Context:  context.Background(),
+		Logger:   mocks.GetMockLogger(t),
+		Dynamo:   mockDynamo,
+		Settings: settingsMock,
+		FailMode: constants.FailClosed,
+	}
+
+	expected := map[string]types.CheckCardStatusResponse{
+		"exempt": {Exempt: true},
+		"shadow": {Shadow: true, BlockType: constants.PERMANENT},
+		"failed": {Blocked: true, Degraded: true},
+	}
+	for merchantID, status := range expected {
+		response, err := srv.CheckCardStatus(types.CheckCardStatusRequest{CardID: "CardTest123", MerchantIdentifier: merchantID})
+		assert.NoError(t, err)
+		assert.Equal(t, status, response)
+	}
+
+	response := srv.BatchCheckCardStatus(types.BatchCheckCardStatusRequest{Cards: []types.CheckCardStatusRequest{
+		{CardID: "CardTest123", MerchantIdentifier: "exempt"},
+		{CardID: "CardTest123", MerchantIdentifier: "shadow"},
+		{CardID: "CardTest123", MerchantIdentifier: "failed"},
+		{CardID: "other", MerchantIdentifier: "shadow"},
+	}})
+
+	assert.Equal(t, []types.BatchCheckCardStatusItem{
+		{CardID: "CardTest123", MerchantIdentifier: "exempt", Status: &types.CheckCardStatusResponse{Exempt: true}},
+		{CardID: "CardTest123", MerchantIdentifier: "shadow", Status: &types.CheckCardStatusResponse{Shadow: true, BlockType: constants.PERMANENT}},
+		{CardID: "CardTest123", MerchantIdentifier: "failed", Status: &types.CheckCardStatusResponse{Blocked: true, Degraded: true}},
+		{CardID: "other", MerchantIdentifier: "shadow", Status: &types.CheckCardStatusResponse{Shadow: true}},
+	}, response.Results)
+	settingsMock.AssertNumberOfCalls(t, "GetSettings", 6)
+}
@@ -0,0 +1,90 @@
+package service
+
+import (
+	"strings"
+
+	constants "bitbucket.org/kushki/usrv-card-control"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	core "bitbucket.org/kushki/usrv-go-core"
+)
+
+var (
+	// visaDeclineCategories Visa response codes by reattempt category, unlisted codes are category 4.
+	visaDeclineCategories = map[string][]string{
+		constants.VisaIssuerWillNeverApprove: {"04", "07", "12", "14", "15", "41", "43", "46", "57", "R0", "R1", "R3"},
+		constants.VisaIssuerCannotApproveNow: {"03", "19", "39", "51", "52", "53", "59", "61", "62", "65", "75", "78", "86", "91", "93", "96", "N3", "N4", "Z5"},
+		constants.VisaDataQuality:            {"54", "55", "6P", "70", "82", "1A", "N7"},
+	}
+
+	// masterCardDeclineCategories Mastercard Merchant Advice Codes by category, 24 to 30 carry their own wait.
+	masterCardDeclineCategories = map[string][]string{
+		constants.MasterCardUpdateAccount:     {"01"},
+		constants.MasterCardTryAgainLater:     {"02"},
+		constants.MasterCardDoNotTryAgain:     {"03", "21"},
+		constants.MasterCardRetryAfter1Hour:   {"24"},
+		constants.MasterCardRetryAfter24Hours: {"25"},
+		constants.MasterCardRetryAfter2Days:   {"26"},
+		constants.MasterCardRetryAfter4Days:   {"27"},
+		constants.MasterCardRetryAfter6Days:   {"28"},
+		constants.MasterCardRetryAfter8Days:   {"29"},
+		constants.MasterCardRetryAfter10Days:  {"30"},
+	}
+
+	// neverRetryCategories categories the networks fine when reattempted.
+	neverRetryCategories = map[string]bool{
+		constants.VisaIssuerWillNeverApprove: true,
+		constants.MasterCardDoNotTryAgain:    true,
+	}
+
+	declineCategoriesByCode = map[string]map[string]string{
+		core.BrandVisa:       indexDeclineCategories(visaDeclineCategories),
+		core.BrandMasterCard: indexDeclineCategories(masterCardDeclineCategories),
+	}
+
+	defaultDeclineCategories = map[string]string{
+		core.BrandVisa: constants.VisaGenericDecline,
+	}
+)
+
+// classifyDecline maps the conditional code of the brand to its network category.
+func classifyDecline(brand string, conditional string) types.DeclineCategory {
+	brand = strings.ToUpper(brand)
+	code := strings.ToUpper(strings.TrimSpace(conditional))
+
+	category, ok := declineCategoriesByCode[brand][code]
+	if !ok {
+		category, ok = defaultDeclineCategories[brand]
+	}
+	if !ok {
+		category = constants.UnclassifiedDeclineCategory
+	}
+
+	return types.DeclineCategory{
+		Network:    brand,
+		Category:   category,
+		NeverRetry: neverRetryCategories[category],
+	}
+}
+
+// getApplicableRules rules without category plus the ones of the decline category.
+func getApplicableRules(policy types.RetryPolicy, category types.DeclineCategory) []types.FrequencyRule {
+	rules := make([]types.FrequencyRule, 0, len(policy.Rules))
+	for _, rule := range policy.Rules {
+		if rule.Category == "" || strings.EqualFold(rule.Category, category.Category) {
+			rules = append(rules, rule)
+		}
+	}
+
+	return rules
+}
+
+func indexDeclineCategories(categories map[string][]string) map[string]string {
+	index := make(map[string]string)
+	for category, codes := range categories {
+		for _, code := range codes {
+			index[code] = category
+		}
+	}
+
+	return index
+}
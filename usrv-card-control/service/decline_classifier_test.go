@@ -0,0 +1,95 @@
+package service
+
+import (
+	"slices"
+	"testing"
+
+	constants "bitbucket.org/kushki/usrv-card-control"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	core "bitbucket.org/kushki/usrv-go-core"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestClassifyDecline(t *testing.T) {
+	tests := []struct {
+		name        string
+		brand       string
+		conditional string
+		category    string
+		neverRetry  bool
+	}{
+		{name: "visa pick up card", brand: core.BrandVisa, conditional: "04", category: constants.VisaIssuerWillNeverApprove, neverRetry: true},
+		{name: "visa insufficient funds", brand: "visa", conditional: "51", category: constants.VisaIssuerCannotApproveNow},
+		{name: "visa invalid card number", brand: core.BrandVisa, conditional: " 14 ", category: constants.VisaIssuerWillNeverApprove, neverRetry: true},
+		{name: "visa expired card", brand: core.BrandVisa, conditional: "54", category: constants.VisaDataQuality},
+		{name: "visa unknown code", brand: core.BrandVisa, conditional: "05", category: constants.VisaGenericDecline},
+		{name: "mastercard do not try again", brand: core.BrandMasterCard, conditional: "03", category: constants.MasterCardDoNotTryAgain, neverRetry: true},
+		{name: "mastercard payment cancellation", brand: core.BrandMasterCard, conditional: "21", category: constants.MasterCardDoNotTryAgain, neverRetry: true},
+		{name: "mastercard try again later", brand: core.BrandMasterCard, conditional: "02", category: constants.MasterCardTryAgainLater},
+		{name: "mastercard retry after 1 hour", brand: core.BrandMasterCard, conditional: "24", category: constants.MasterCardRetryAfter1Hour},
+		{name: "mastercard retry after 10 days", brand: core.BrandMasterCard, conditional: "30", category: constants.MasterCardRetryAfter10Days},
+		{name: "mastercard without advice code", brand: core.BrandMasterCard, category: constants.UnclassifiedDeclineCategory},
+		{name: "unknown brand", brand: "OTHER", conditional: "04", category: constants.UnclassifiedDeclineCategory},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			category := classifyDecline(test.brand, test.conditional)
+			assert.Equal(t, test.category, category.Category)
+			assert.Equal(t, test.neverRetry, category.NeverRetry)
+		})
+	}
+}
+
+func TestGetApplicableRules(t *testing.T) {
+	t.Run("should keep generic rules and the ones of the category", func(t *testing.T) {
+		policy := types.RetryPolicy{Rules: []types.FrequencyRule{
+			{Frequency: constants.MonthlyFrequency},
+			{Frequency: constants.DailyFrequency, Category: constants.VisaDataQuality},
+			{Frequency: constants.DailyFrequency, Category: constants.VisaIssuerCannotApproveNow},
+		}}
+
+		rules := getApplicableRules(policy, types.DeclineCategory{Category: constants.VisaDataQuality})
+		assert.Equal(t, []types.FrequencyRule{policy.Rules[0], policy.Rules[1]}, rules)
+	})
+}
+
+func TestGetRuleCounter(t *testing.T) {
+	t.Run("should separate category counters", func(t *testing.T) {
+		assert.Equal(t, constants.DailyFrequency, getRuleCounter(types.FrequencyRule{Frequency: constants.DailyFrequency}))
+		assert.Equal(t, constants.VisaDataQuality+"-"+constants.DailyFrequency,
+			getRuleCounter(types.FrequencyRule{Frequency: constants.DailyFrequency, Category: constants.VisaDataQuality}))
+	})
+}
+
+func TestDefaultCategoryRules(t *testing.T) {
+	categories := map[string]map[string][]string{
+		core.BrandVisa:       visaDeclineCategories,
+		core.BrandMasterCard: masterCardDeclineCategories,
+	}
+
+	for brand, brandCategories := range categories {
+		for category := range brandCategories {
+			if neverRetryCategories[category] {
+				continue
+			}
+			t.Run(category, func(t *testing.T) {
+				rules := getApplicableRules(defaultRetryPolicies[brand], types.DeclineCategory{Category: category})
+				assert.True(t, slices.ContainsFunc(rules, func(rule types.FrequencyRule) bool {
+					return rule.Category == category
+				}))
+			})
+		}
+	}
+
+	t.Run("should allow a single attempt per mastercard retry after window", func(t *testing.T) {
+		rules := getApplicableRules(defaultRetryPolicies[core.BrandMasterCard], classifyDecline(core.BrandMasterCard, "26"))
+		assert.Contains(t, rules, types.FrequencyRule{
+			Frequency:   constants.RetryAfterFrequency,
+			Category:    constants.MasterCardRetryAfter2Days,
+			WindowHours: 2 * constants.DayHours,
+			MaxAttempts: 1,
+			BlockType:   constants.TEMPORARY,
+		})
+	})
+}
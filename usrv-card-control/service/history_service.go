@@ -0,0 +1,151 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	constants "bitbucket.org/kushki/usrv-card-control"
+	"bitbucket.org/kushki/usrv-card-control/gateway"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	errorsCore "bitbucket.org/kushki/usrv-go-core/errors"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/google/uuid"
+)
+
+const historySrvTag = "HistoryService | %s"
+
+var (
+	refNewHistoryService = NewHistoryService
+
+	errMissingCardID = errors.New("cardId is required")
+)
+
+type IHistoryService interface {
+	Record(ctx context.Context, entry types.BlockHistoryEntry)
+	GetTimeline(ctx context.Context, request types.BlockHistoryRequest) (types.BlockHistoryResponse, error)
+}
+
+// HistoryService append-only trail of card state transitions.
+type HistoryService struct {
+	Logger logger.KushkiLogger
+	Dynamo dynamo.IDynamoGateway
+}
+
+// NewHistoryService function to instantiate.
+func NewHistoryService(kskLogger logger.KushkiLogger, dynamoGtw dynamo.IDynamoGateway) IHistoryService {
+	return &HistoryService{
+		Logger: kskLogger,
+		Dynamo: dynamoGtw,
+	}
+}
+
+// InitializeBlockHistory init dependencies and fetch the card timeline.
+func InitializeBlockHistory(ctx context.Context, event events.APIGatewayProxyRequest) (types.BlockHistoryResponse, error) {
+	tag := fmt.Sprintf(historySrvTag, "InitializeBlockHistory")
+
+	kskLogger := newKushkiLogger(ctx)
+
+	var request types.BlockHistoryRequest
+	if err := jsonUnmarshalCaller([]byte(event.Body), &request); err != nil {
+		kskLogger.Error(tag, err)
+		return types.BlockHistoryResponse{}, newServiceError(errorsCore.E001, tag, err)
+	}
+	if request.CardID == "" {
+		return types.BlockHistoryResponse{}, newServiceError(errorsCore.E001, tag, errMissingCardID)
+	}
+
+	dynamoGtw, err := initializeDynamoGtw(ctx, kskLogger)
+	if err != nil {
+		kskLogger.Error(tag, err)
+		return types.BlockHistoryResponse{}, newServiceError(errorsCore.E002, tag, err)
+	}
+
+	service := refNewHistoryService(kskLogger, dynamoGtw)
+
+	response, err := service.GetTimeline(ctx, request)
+	if err != nil {
+		kskLogger.Error(tag, err)
+		return types.BlockHistoryResponse{}, newServiceError(errorsCore.E002, tag, err)
+	}
+
+	return response, nil
+}
+
+// Record appends the entry to the history table.
+// It is best effort: losing an audit entry must not make the caller retry an already applied transition.
+func (hs *HistoryService) Record(ctx context.Context, entry types.BlockHistoryEntry) {
+	if entry.TimeStamp == 0 {
+		entry.TimeStamp = time.Now().UTC().UnixMilli()
+	}
+	entry.EventKey = fmt.Sprintf(constants.EventKeyFormat, entry.TimeStamp, uuid.NewString())
+
+	if err := hs.Dynamo.PutItem(ctx, gateway.PutBlockHistoryBuilder(entry)); err != nil {
+		hs.Logger.Error(fmt.Sprintf(historySrvTag, "Record"), err)
+	}
+}
+
+// GetTimeline page of card events ordered from the newest.
+// The merchant filter is applied after the page is read, so the cursor never skips events.
+func (hs *HistoryService) GetTimeline(ctx context.Context, request types.BlockHistoryRequest) (types.BlockHistoryResponse, error) {
+	pageSize := request.Limit
+	if pageSize <= 0 || pageSize > constants.HistoryPageSize {
+		pageSize = constants.HistoryPageSize
+	}
+	limit := pageSize
+	if request.Cursor != "" {
+		limit++ // the key condition is inclusive, the cursor event comes back first.
+	}
+
+	var out []types.BlockHistoryEntry
+	if err := hs.Dynamo.Query(ctx, gateway.QueryBlockHistoryBuilder(request, int32(limit)), &out); err != nil {
+		return types.BlockHistoryResponse{}, err
+	}
+
+	page := make([]types.BlockHistoryEntry, 0, len(out))
+	for _, entry := range out {
+		if request.Cursor != "" && entry.EventKey >= request.Cursor {
+			continue
+		}
+		page = append(page, entry)
+	}
+	if len(page) > pageSize {
+		page = page[:pageSize]
+	}
+
+	response := types.BlockHistoryResponse{
+		CardID: request.CardID,
+		Events: make([]types.BlockHistoryEntry, 0, len(page)),
+	}
+	if len(page) == pageSize {
+		response.NextCursor = page[len(page)-1].EventKey
+	}
+	for _, entry := range page {
+		if request.MerchantIdentifier == "" || entry.MerchantID == request.MerchantIdentifier {
+			response.Events = append(response.Events, entry)
+		}
+	}
+
+	return response, nil
+}
+
+func newHistoryEntry(request types.BlockCardRequest, operation string) types.BlockHistoryEntry {
+	return types.BlockHistoryEntry{
+		CardID:      request.CardID,
+		MerchantID:  request.MerchantIdentifier,
+		Operation:   operation,
+		Brand:       request.Franchise,
+		Conditional: request.Conditional,
+		Category:    classifyDecline(request.Franchise, request.Conditional).Category,
+	}
+}
+
+func getBlockOperation(blockType string) string {
+	if blockType == constants.PERMANENT {
+		return constants.HistoryPermanentBlock
+	}
+	return constants.HistoryTemporaryBlock
+}
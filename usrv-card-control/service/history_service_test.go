@@ -0,0 +1,177 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	constants "bitbucket.org/kushki/usrv-card-control"
+	"bitbucket.org/kushki/usrv-card-control/mocks"
+	coreMock "bitbucket.org/kushki/usrv-card-control/mocks/core"
+	mockService "bitbucket.org/kushki/usrv-card-control/mocks/service"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo/builder"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+	"github.com/aws/aws-lambda-go/events"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNewHistoryService(t *testing.T) {
+	t.Run("should no be empty", func(t *testing.T) {
+		assert.NotEmpty(t, NewHistoryService(mocks.GetMockLogger(t), &coreMock.IDynamoGateway{}))
+	})
+}
+
+func TestHistoryService_Record(t *testing.T) {
+	t.Run("should append the entry with a time ordered event key", func(t *testing.T) {
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("PutItem", mock.Anything, mock.MatchedBy(func(input *builder.PutItemBuilder) bool {
+			item, err := input.BuildInput()
+			return err == nil && item.ConditionExpression != nil
+		})).Return(nil).Once()
+		srv := HistoryService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock}
+
+		srv.Record(context.TODO(), types.BlockHistoryEntry{CardID: "card", Operation: constants.HistoryRetryIncrement})
+		dynamoMock.AssertExpectations(t)
+	})
+
+	t.Run("should not fail if the put fails", func(t *testing.T) {
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("PutItem", mock.Anything, mock.Anything).Return(commonError).Once()
+		srv := HistoryService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock}
+
+		assert.NotPanics(t, func() {
+			srv.Record(context.TODO(), types.BlockHistoryEntry{CardID: "card", TimeStamp: 1})
+		})
+		dynamoMock.AssertExpectations(t)
+	})
+}
+
+func TestHistoryService_GetTimeline(t *testing.T) {
+	t.Run("should return a full page with the cursor of the last event", func(t *testing.T) {
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("Query", mock.Anything, mock.MatchedBy(func(input *builder.QueryBuilder) bool {
+			query, err := input.BuildInput()
+			return err == nil && *query.Limit == 2 && !*query.ScanIndexForward
+		}), mock.Anything).
+			Run(func(args mock.Arguments) {
+				*args[2].(*[]types.BlockHistoryEntry) = []types.BlockHistoryEntry{
+					{EventKey: "0000000000003#c"},
+					{EventKey: "0000000000002#b"},
+				}
+			}).
+			Return(nil)
+		srv := HistoryService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock}
+
+		response, err := srv.GetTimeline(context.TODO(), types.BlockHistoryRequest{CardID: "card", Limit: 2})
+		assert.NoError(t, err)
+		assert.Equal(t, "card", response.CardID)
+		assert.Len(t, response.Events, 2)
+		assert.True(t, strings.HasSuffix(response.Events[0].EventKey, "c"))
+		assert.Equal(t, "0000000000002#b", response.NextCursor)
+	})
+
+	t.Run("should continue after the cursor and stop on the last page", func(t *testing.T) {
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("Query", mock.Anything, mock.MatchedBy(func(input *builder.QueryBuilder) bool {
+			query, err := input.BuildInput()
+			return err == nil && *query.Limit == 3
+		}), mock.Anything).
+			Run(func(args mock.Arguments) {
+				*args[2].(*[]types.BlockHistoryEntry) = []types.BlockHistoryEntry{
+					{EventKey: "0000000000002#b"},
+					{EventKey: "0000000000001#a"},
+				}
+			}).
+			Return(nil)
+		srv := HistoryService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock}
+
+		response, err := srv.GetTimeline(context.TODO(), types.BlockHistoryRequest{CardID: "card", Limit: 2, Cursor: "0000000000002#b"})
+		assert.NoError(t, err)
+		assert.Equal(t, []types.BlockHistoryEntry{{EventKey: "0000000000001#a"}}, response.Events)
+		assert.Empty(t, response.NextCursor)
+	})
+
+	t.Run("should filter the merchant without losing the cursor", func(t *testing.T) {
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("Query", mock.Anything, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				*args[2].(*[]types.BlockHistoryEntry) = []types.BlockHistoryEntry{
+					{EventKey: "0000000000003#c", MerchantID: "other"},
+					{EventKey: "0000000000002#b", MerchantID: "merchant"},
+				}
+			}).
+			Return(nil)
+		srv := HistoryService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock}
+
+		response, err := srv.GetTimeline(context.TODO(), types.BlockHistoryRequest{CardID: "card", MerchantIdentifier: "merchant", Limit: 2})
+		assert.NoError(t, err)
+		assert.Len(t, response.Events, 1)
+		assert.Equal(t, "0000000000002#b", response.NextCursor)
+	})
+
+	t.Run("should return an error if query fails", func(t *testing.T) {
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("Query", mock.Anything, mock.Anything, mock.Anything).Return(commonError)
+		srv := HistoryService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock}
+
+		_, err := srv.GetTimeline(context.TODO(), types.BlockHistoryRequest{CardID: "card"})
+		assert.Error(t, err)
+	})
+}
+
+func TestInitializeBlockHistory(t *testing.T) {
+	tests := []struct {
+		name               string
+		request            types.BlockHistoryRequest
+		jsonUnmarshalError error
+		dynamoError        error
+		timelineError      error
+		expectedCode       string
+	}{
+		{name: "should return the timeline", request: types.BlockHistoryRequest{CardID: "card"}},
+		{name: "should return E001 if unmarshal fails", jsonUnmarshalError: commonError, expectedCode: "E001"},
+		{name: "should return E001 without cardId", expectedCode: "E001"},
+		{name: "should return E002 if dynamo init fails", request: types.BlockHistoryRequest{CardID: "card"}, dynamoError: commonError, expectedCode: "E002"},
+		{name: "should return E002 if query fails", request: types.BlockHistoryRequest{CardID: "card"}, timelineError: commonError, expectedCode: "E002"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Cleanup(clean)
+			newKushkiLogger = func(context.Context) logger.KushkiLogger {
+				return mocks.GetMockLogger(t)
+			}
+			jsonUnmarshalCaller = func(_ []byte, v any) error {
+				*v.(*types.BlockHistoryRequest) = test.request
+				return test.jsonUnmarshalError
+			}
+			initializeDynamoGtw = func(context.Context, logger.KushkiLogger) (dynamo.IDynamoGateway, error) {
+				return &coreMock.IDynamoGateway{}, test.dynamoError
+			}
+			historyMock := &mockService.IHistoryService{}
+			historyMock.On("GetTimeline", mock.Anything, test.request).
+				Return(types.BlockHistoryResponse{CardID: test.request.CardID}, test.timelineError)
+			refNewHistoryService = func(logger.KushkiLogger, dynamo.IDynamoGateway) IHistoryService {
+				return historyMock
+			}
+
+			response, err := InitializeBlockHistory(context.TODO(), events.APIGatewayProxyRequest{})
+			if test.expectedCode != "" {
+				assert.ErrorContains(t, err, "Code: "+test.expectedCode)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.request.CardID, response.CardID)
+		})
+	}
+}
+
+func getHistoryMock() *mockService.IHistoryService {
+	historyMock := &mockService.IHistoryService{}
+	historyMock.On("Record", mock.Anything, mock.Anything).Return()
+
+	return historyMock
+}
@@ -0,0 +1,87 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	constants "bitbucket.org/kushki/usrv-card-control"
+	"bitbucket.org/kushki/usrv-card-control/gateway"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+type IIdempotencyService interface {
+	Claim(ctx context.Context, key string) (bool, error)
+	Complete(ctx context.Context, key string)
+	Release(ctx context.Context, key string)
+}
+
+// IdempotencyService dedup records so a message is counted once.
+type IdempotencyService struct {
+	Logger logger.KushkiLogger
+	Dynamo dynamo.IDynamoGateway
+}
+
+const idempotencySrvTag = "IdempotencyService | %s"
+
+// NewIdempotencyService function to instantiate.
+func NewIdempotencyService(kskLogger logger.KushkiLogger, dynamoGtw dynamo.IDynamoGateway) IIdempotencyService {
+	return &IdempotencyService{
+		Logger: kskLogger,
+		Dynamo: dynamoGtw,
+	}
+}
+
+// Claim records the key in progress, false when it was already processed or another delivery is processing it.
+func (is *IdempotencyService) Claim(ctx context.Context, key string) (bool, error) {
+	now := nowCaller().UTC()
+	record := types.ProcessedMessage{
+		IdempotencyKey: key,
+		Status:         constants.ProcessedMessageInProgress,
+		ProcessedAt:    now.UnixMilli(),
+		ExpiresAt:      now.Add(constants.IdempotencyInProgressMinutes * time.Minute).Unix(),
+	}
+
+	err := is.Dynamo.PutItem(ctx, gateway.PutProcessedMessageBuilder(record, now.Unix()))
+	if isConditionalCheckFailed(err) {
+		is.Logger.Info(fmt.Sprintf(idempotencySrvTag, "Claim"), "[DUPLICATE] "+key)
+		return false, nil
+	}
+
+	return err == nil, err
+}
+
+// Complete keeps the key for the whole dedup window once the message was processed.
+func (is *IdempotencyService) Complete(ctx context.Context, key string) {
+	now := nowCaller().UTC()
+	record := types.ProcessedMessage{
+		IdempotencyKey: key,
+		Status:         constants.ProcessedMessageCompleted,
+		ProcessedAt:    now.UnixMilli(),
+		ExpiresAt:      now.Add(constants.IdempotencyTTLHours * time.Hour).Unix(),
+	}
+
+	// the message was already counted, failing it would count it again on redelivery.
+	if err := is.Dynamo.PutItem(ctx, gateway.CompleteProcessedMessageBuilder(record)); err != nil {
+		is.Logger.Error(fmt.Sprintf(idempotencySrvTag, "Complete | "+key), err)
+	}
+}
+
+// Release deletes the key so a message that failed is processed again on redelivery.
+func (is *IdempotencyService) Release(ctx context.Context, key string) {
+	if err := is.Dynamo.DeleteItem(ctx, gateway.DeleteProcessedMessageBuilder(key)); err != nil {
+		is.Logger.Error(fmt.Sprintf(idempotencySrvTag, "Release | "+key), err)
+	}
+}
+
+// generateIdempotencyKey the client transaction survives DLQ redrives, the message id only SQS redeliveries.
+func generateIdempotencyKey(record events.SQSMessage, request types.BlockCardRequest) string {
+	if request.TransactionID != "" {
+		return fmt.Sprintf("txn#%s#%s", request.MerchantIdentifier, request.TransactionID)
+	}
+
+	return fmt.Sprintf("msg#%s", record.MessageId)
+}
@@ -0,0 +1,167 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	constants "bitbucket.org/kushki/usrv-card-control"
+	"bitbucket.org/kushki/usrv-card-control/mocks"
+	coreMock "bitbucket.org/kushki/usrv-card-control/mocks/core"
+	mockService "bitbucket.org/kushki/usrv-card-control/mocks/service"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo/builder"
+	"github.com/aws/aws-lambda-go/events"
+	typesDynamo "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestIdempotencyService_Claim(t *testing.T) {
+	scenarios := []struct {
+		name        string
+		putErr      error
+		wantClaimed bool
+		wantErr     bool
+	}{
+		{name: "should claim the first delivery", wantClaimed: true},
+		{name: "should skip a key already processed", putErr: &typesDynamo.ConditionalCheckFailedException{}},
+		{name: "should return the error when the put fails", putErr: commonError, wantErr: true},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			dynamoGtw := &coreMock.IDynamoGateway{}
+			dynamoGtw.On("PutItem", mock.Anything, mock.Anything).Return(scenario.putErr).Once()
+			srv := NewIdempotencyService(mocks.GetMockLogger(t), dynamoGtw)
+
+			claimed, err := srv.Claim(context.TODO(), "msg#1")
+
+			assert.Equal(t, scenario.wantClaimed, claimed)
+			assert.Equal(t, scenario.wantErr, err != nil)
+			dynamoGtw.AssertExpectations(t)
+		})
+	}
+}
+
+func TestIdempotencyService_Claim_InProgress(t *testing.T) {
+	t.Cleanup(clean)
+	nowCaller = func() time.Time { return time.Unix(1742508191, 0) }
+	dynamoGtw := &coreMock.IDynamoGateway{}
+	dynamoGtw.On("PutItem", mock.Anything, mock.MatchedBy(func(input *builder.PutItemBuilder) bool {
+		build, err := input.BuildInput()
+		return err == nil && assert.ObjectsAreEqual(&typesDynamo.AttributeValueMemberS{Value: constants.ProcessedMessageInProgress}, build.Item["status"]) &&
+			assert.ObjectsAreEqual(&typesDynamo.AttributeValueMemberN{Value: "1742508491"}, build.Item[constants.ExpiresAtField])
+	})).Return(nil).Once()
+	srv := NewIdempotencyService(mocks.GetMockLogger(t), dynamoGtw)
+
+	claimed, err := srv.Claim(context.TODO(), "msg#1")
+
+	assert.True(t, claimed)
+	assert.NoError(t, err)
+	dynamoGtw.AssertExpectations(t)
+}
+
+func TestIdempotencyService_Complete(t *testing.T) {
+	t.Cleanup(clean)
+	nowCaller = func() time.Time { return time.Unix(1742508191, 0) }
+	dynamoGtw := &coreMock.IDynamoGateway{}
+	dynamoGtw.On("PutItem", mock.Anything, mock.MatchedBy(func(input *builder.PutItemBuilder) bool {
+		build, err := input.BuildInput()
+		return err == nil && build.ConditionExpression == nil &&
+			assert.ObjectsAreEqual(&typesDynamo.AttributeValueMemberS{Value: constants.ProcessedMessageCompleted}, build.Item["status"]) &&
+			assert.ObjectsAreEqual(&typesDynamo.AttributeValueMemberN{Value: "1742594591"}, build.Item[constants.ExpiresAtField])
+	})).Return(commonError).Once()
+	srv := NewIdempotencyService(mocks.GetMockLogger(t), dynamoGtw)
+
+	srv.Complete(context.TODO(), "msg#1")
+
+	dynamoGtw.AssertExpectations(t)
+}
+
+func TestIdempotencyService_Release(t *testing.T) {
+	dynamoGtw := &coreMock.IDynamoGateway{}
+	dynamoGtw.On("DeleteItem", mock.Anything, mock.Anything).Return(commonError).Once()
+	srv := NewIdempotencyService(mocks.GetMockLogger(t), dynamoGtw)
+
+	srv.Release(context.TODO(), "msg#1")
+
+	dynamoGtw.AssertExpectations(t)
+}
+
+func TestGenerateIdempotencyKey(t *testing.T) {
+	record := events.SQSMessage{MessageId: "message1"}
+
+	assert.Equal(t, "msg#message1", generateIdempotencyKey(record, types.BlockCardRequest{MerchantIdentifier: "merchant1"}))
+	assert.Equal(t, "txn#merchant1#txn1", generateIdempotencyKey(record, types.BlockCardRequest{
+		MerchantIdentifier: "merchant1",
+		TransactionID:      "txn1",
+	}))
+}
+
+func TestBlockService_ProcessBlock_Idempotency(t *testing.T) {
+	request := types.BlockCardRequest{CardID: "foo", MerchantIdentifier: "merchant1", TransactionID: "txn1", Operation: constants.BlockCardOperation}
+
+	t.Run("should skip a duplicated message without counting it", func(t *testing.T) {
+		t.Cleanup(clean)
+		dynamoMock := &coreMock.IDynamoGateway{}
+		idempotency := &mockService.IIdempotencyService{}
+		idempotency.On("Claim", mock.Anything, "txn#merchant1#txn1").Return(false, nil).Once()
+		srv := BlockService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock, Idempotency: idempotency, Settings: getSettingsMock()}
+		jsonUnmarshalCaller = func(_ []byte, v any) error {
+			*v.(*types.BlockCardRequest) = request
+			return nil
+		}
+
+		result := srv.ProcessBlock(context.TODO(), fakeEvent)
+
+		assert.Empty(t, result.BatchItemFailures)
+		dynamoMock.AssertNotCalled(t, "GetItem", mock.Anything, mock.Anything, mock.Anything)
+		idempotency.AssertExpectations(t)
+	})
+
+	t.Run("should release the key when processing fails", func(t *testing.T) {
+		t.Cleanup(clean)
+		sleepCaller = func(time.Duration) {}
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).Return(commonError).Once()
+		idempotency := &mockService.IIdempotencyService{}
+		idempotency.On("Claim", mock.Anything, "txn#merchant1#txn1").Return(true, nil).Once()
+		idempotency.On("Release", mock.Anything, "txn#merchant1#txn1").Return().Once()
+		srv := BlockService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock, Idempotency: idempotency, Settings: getSettingsMock()}
+		jsonUnmarshalCaller = func(_ []byte, v any) error {
+			*v.(*types.BlockCardRequest) = request
+			return nil
+		}
+
+		result := srv.ProcessBlock(context.TODO(), fakeEvent)
+
+		assert.Len(t, result.BatchItemFailures, 1)
+		idempotency.AssertExpectations(t)
+		idempotency.AssertNotCalled(t, "Complete", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should fail the record when the claim fails", func(t *testing.T) {
+		t.Cleanup(clean)
+		idempotency := &mockService.IIdempotencyService{}
+		idempotency.On("Claim", mock.Anything, mock.Anything).Return(false, commonError).Once()
+		srv := BlockService{Logger: mocks.GetMockLogger(t), Dynamo: &coreMock.IDynamoGateway{}, Idempotency: idempotency, Settings: getSettingsMock()}
+		jsonUnmarshalCaller = func(_ []byte, v any) error {
+			*v.(*types.BlockCardRequest) = request
+			return nil
+		}
+
+		result := srv.ProcessBlock(context.TODO(), fakeEvent)
+
+		assert.Len(t, result.BatchItemFailures, 1)
+	})
+}
+
+func getIdempotencyMock() *mockService.IIdempotencyService {
+	idempotencyMock := &mockService.IIdempotencyService{}
+	idempotencyMock.On("Claim", mock.Anything, mock.Anything).Return(true, nil)
+	idempotencyMock.On("Complete", mock.Anything, mock.Anything).Return()
+	idempotencyMock.On("Release", mock.Anything, mock.Anything).Return()
+
+	return idempotencyMock
+}
@@ -3,9 +3,19 @@ package service
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
+	"math/rand"
+	"time"
 
+	constants "bitbucket.org/kushki/usrv-card-control"
 	"bitbucket.org/kushki/usrv-card-control/tools"
+	errorsCore "bitbucket.org/kushki/usrv-go-core/errors"
+	"bitbucket.org/kushki/usrv-go-core/logger"
 	"bitbucket.org/kushki/usrv-go-core/middleware"
+	"github.com/Jeffail/gabs/v2"
+	"github.com/aws/aws-lambda-go/events"
+	dynamoTypes "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 )
 
 // Definition of functions methods.
@@ -13,4 +23,78 @@ var (
 	newKushkiLogger     = middleware.GetLoggerFromContext
 	initializeDynamoGtw = tools.InitializeDynamoGtw
 	jsonUnmarshalCaller = json.Unmarshal
+	sleepCaller         = time.Sleep
+	nowCaller           = time.Now
 )
+
+// processRecords runs process for every record and reports the failed ones so only those are retried.
+func processRecords(
+	kskLogger logger.KushkiLogger,
+	tag string,
+	event events.SQSEvent,
+	process func(record events.SQSMessage) error,
+) events.SQSEventResponse {
+	response := events.SQSEventResponse{
+		BatchItemFailures: make([]events.SQSBatchItemFailure, 0),
+	}
+	for _, record := range event.Records {
+		if err := process(record); err != nil {
+			kskLogger.Error(fmt.Sprintf(tag, "processRecords | "+record.MessageId), err)
+			response.BatchItemFailures = append(response.BatchItemFailures, events.SQSBatchItemFailure{
+				ItemIdentifier: record.MessageId,
+			})
+		}
+	}
+
+	return response
+}
+
+// retryOnConflict replays operation while the optimistic concurrency condition fails.
+// attempt is 0 on the first run, operations must re-read the item on later attempts.
+func retryOnConflict(kskLogger logger.KushkiLogger, tag string, operation func(attempt int) error) error {
+	var err error
+	for attempt := 0; attempt <= constants.ConflictMaxRetries; attempt++ {
+		if attempt > 0 {
+			sleepCaller(conflictBackoff(attempt))
+		}
+
+		err = operation(attempt)
+		if !isConditionalCheckFailed(err) {
+			return err
+		}
+		kskLogger.Info(fmt.Sprintf(tag, "retryOnConflict"), fmt.Sprintf("[CONFLICT] attempt %d", attempt+1))
+	}
+
+	return err
+}
+
+// conflictBackoff exponential backoff capped to ConflictMaxDelayMs with full jitter.
+func conflictBackoff(attempt int) time.Duration {
+	delay := int64(constants.ConflictBaseDelayMs) << attempt
+	if delay > constants.ConflictMaxDelayMs {
+		delay = constants.ConflictMaxDelayMs
+	}
+
+	return time.Duration(rand.Int63n(delay)+1) * time.Millisecond
+}
+
+// isExpiredItem whether the item TTL already passed, Dynamo keeps returning it until the deletion runs.
+func isExpiredItem(expiresAt int64) bool {
+	return expiresAt > 0 && expiresAt <= nowCaller().Unix()
+}
+
+func isConditionalCheckFailed(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var conditionalErr *dynamoTypes.ConditionalCheckFailedException
+
+	return errors.As(err, &conditionalErr)
+}
+
+// newServiceError kushki error with the origin tag as metadata.
+func newServiceError(code string, tag string, err error) error {
+	return errorsCore.NewKushkiError(errorsCore.Errors[code], gabs.Wrap(errorsCore.GenericErrorMetadata{
+		Message: err.Error(), Origin: tag}))
+}
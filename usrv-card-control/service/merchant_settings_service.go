@@ -0,0 +1,88 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"sync"
+
+	"bitbucket.org/kushki/usrv-card-control/gateway"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+)
+
+type IMerchantSettingsService interface {
+	GetSettings(ctx context.Context, merchantID string) (types.MerchantSettings, error)
+}
+
+// MerchantSettingsService resolves the exemption, limits and shadow mode of a merchant.
+type MerchantSettingsService struct {
+	Logger logger.KushkiLogger
+	Dynamo dynamo.IDynamoGateway
+}
+
+const merchantSettingsSrvTag = "MerchantSettingsService | %s"
+
+var (
+	// settingsCache keeps the settings for the lambda lifetime, merchants without settings included.
+	settingsCache      = make(map[string]types.MerchantSettings)
+	settingsCacheMutex sync.RWMutex
+)
+
+// NewMerchantSettingsService function to instantiate.
+func NewMerchantSettingsService(kskLogger logger.KushkiLogger, dynamoGtw dynamo.IDynamoGateway) IMerchantSettingsService {
+	return &MerchantSettingsService{
+		Logger: kskLogger,
+		Dynamo: dynamoGtw,
+	}
+}
+
+// GetSettings a merchant without settings gets the zero value, the engine applies to it as usual.
+func (ms *MerchantSettingsService) GetSettings(ctx context.Context, merchantID string) (types.MerchantSettings, error) {
+	if merchantID == "" {
+		return types.MerchantSettings{}, nil
+	}
+
+	settingsCacheMutex.RLock()
+	settings, ok := settingsCache[merchantID]
+	settingsCacheMutex.RUnlock()
+	if ok {
+		return settings, nil
+	}
+
+	err := ms.Dynamo.GetItem(ctx, gateway.GetMerchantSettingsBuilder(merchantID), &settings)
+	if err != nil && !errors.Is(err, dynamoerror.ErrItemNotFound) {
+		ms.Logger.Error(fmt.Sprintf(merchantSettingsSrvTag, "GetSettings | "+merchantID), err)
+		return types.MerchantSettings{}, err
+	}
+	if settings.LimitFactor < 0 {
+		// a negative factor would block on the first decline, the policy limits apply instead.
+		ms.Logger.Error(fmt.Sprintf(merchantSettingsSrvTag, "GetSettings | "+merchantID), "[INVALID LIMIT FACTOR, IGNORED]")
+		settings.LimitFactor = 0
+	}
+	ms.Logger.Info(fmt.Sprintf(merchantSettingsSrvTag, "GetSettings"), settings)
+
+	settingsCacheMutex.Lock()
+	settingsCache[merchantID] = settings
+	settingsCacheMutex.Unlock()
+
+	return settings, nil
+}
+
+// applyLimitFactor scales the max attempts of the rules, every rule keeps at least one attempt.
+func applyLimitFactor(rules []types.FrequencyRule, factor float64) []types.FrequencyRule {
+	if factor <= 0 || factor == 1 {
+		return rules
+	}
+
+	scaled := make([]types.FrequencyRule, 0, len(rules))
+	for _, rule := range rules {
+		rule.MaxAttempts = max(int(math.Round(float64(rule.MaxAttempts)*factor)), 1)
+		scaled = append(scaled, rule)
+	}
+
+	return scaled
+}
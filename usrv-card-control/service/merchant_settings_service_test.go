@@ -0,0 +1,121 @@
+package service
+
+import (
+	"context"
+	"testing"
+
+	constants "bitbucket.org/kushki/usrv-card-control"
+	"bitbucket.org/kushki/usrv-card-control/mocks"
+	coreMock "bitbucket.org/kushki/usrv-card-control/mocks/core"
+	mockService "bitbucket.org/kushki/usrv-card-control/mocks/service"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestNewMerchantSettingsService(t *testing.T) {
+	t.Run("should no be empty", func(t *testing.T) {
+		assert.NotEmpty(t, NewMerchantSettingsService(mocks.GetMockLogger(t), &coreMock.IDynamoGateway{}))
+	})
+}
+
+func TestMerchantSettingsService_GetSettings(t *testing.T) {
+	t.Run("should read the settings once and cache them", func(t *testing.T) {
+		t.Cleanup(cleanSettingsCache)
+		stored := types.MerchantSettings{MerchantID: "merchant1", LimitFactor: 0.5, Shadow: true}
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				*args[2].(*types.MerchantSettings) = stored
+			}).
+			Return(nil).
+			Once()
+		srv := MerchantSettingsService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock}
+
+		for range 2 {
+			settings, err := srv.GetSettings(context.TODO(), "merchant1")
+			assert.NoError(t, err)
+			assert.Equal(t, stored, settings)
+		}
+		dynamoMock.AssertExpectations(t)
+	})
+
+	t.Run("should cache the merchants without settings and ignore a negative factor", func(t *testing.T) {
+		t.Cleanup(cleanSettingsCache)
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+			Return(dynamoerror.ErrItemNotFound).
+			Once()
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				*args[2].(*types.MerchantSettings) = types.MerchantSettings{MerchantID: "merchant2", LimitFactor: -1}
+			}).
+			Return(nil).
+			Once()
+		srv := MerchantSettingsService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock}
+
+		for range 2 {
+			settings, err := srv.GetSettings(context.TODO(), "merchant1")
+			assert.NoError(t, err)
+			assert.Equal(t, types.MerchantSettings{}, settings)
+		}
+		settings, err := srv.GetSettings(context.TODO(), "merchant2")
+		assert.NoError(t, err)
+		assert.Equal(t, types.MerchantSettings{MerchantID: "merchant2"}, settings)
+		dynamoMock.AssertExpectations(t)
+	})
+
+	t.Run("should not read nor cache the settings on error or without merchant", func(t *testing.T) {
+		t.Cleanup(cleanSettingsCache)
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).Return(commonError)
+		srv := MerchantSettingsService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock}
+
+		_, err := srv.GetSettings(context.TODO(), "merchant1")
+		assert.ErrorIs(t, err, commonError)
+		_, err = srv.GetSettings(context.TODO(), "merchant1")
+		assert.ErrorIs(t, err, commonError)
+		_, err = srv.GetSettings(context.TODO(), "")
+		assert.NoError(t, err)
+		dynamoMock.AssertNumberOfCalls(t, "GetItem", 2)
+	})
+}
+
+func TestApplyLimitFactor(t *testing.T) {
+	rules := []types.FrequencyRule{
+		{Frequency: constants.DailyFrequency, MaxAttempts: 7},
+		{Frequency: constants.RetryAfterFrequency, MaxAttempts: 1},
+	}
+
+	assert.Equal(t, rules, applyLimitFactor(rules, 0))
+	assert.Equal(t, rules, applyLimitFactor(rules, 1))
+	assert.Equal(t, []types.FrequencyRule{
+		{Frequency: constants.DailyFrequency, MaxAttempts: 4},
+		{Frequency: constants.RetryAfterFrequency, MaxAttempts: 1},
+	}, applyLimitFactor(rules, 0.5))
+	assert.Equal(t, []types.FrequencyRule{
+		{Frequency: constants.DailyFrequency, MaxAttempts: 14},
+		{Frequency: constants.RetryAfterFrequency, MaxAttempts: 2},
+	}, applyLimitFactor(rules, 2))
+	assert.Equal(t, 7, rules[0].MaxAttempts)
+}
+
+func cleanSettingsCache() {
+	settingsCacheMutex.Lock()
+	settingsCache = make(map[string]types.MerchantSettings)
+	settingsCacheMutex.Unlock()
+}
+
+func getSettingsMock() *mockService.IMerchantSettingsService {
+	settingsMock := &mockService.IMerchantSettingsService{}
+	settingsMock.On("GetSettings", mock.Anything, mock.Anything).Return(types.MerchantSettings{}, nil)
+
+	return settingsMock
+}
+
+// mockSettingsNotFound for the services built with their real settings service.
+func mockSettingsNotFound(dynamoMock *coreMock.IDynamoGateway) {
+	dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.AnythingOfType("*types.MerchantSettings")).
+		Return(dynamoerror.ErrItemNotFound)
+}
@@ -2,92 +2,290 @@ package service
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"sort"
+	"strings"
 
+	constants "bitbucket.org/kushki/usrv-card-control"
 	"bitbucket.org/kushki/usrv-card-control/gateway"
 	"bitbucket.org/kushki/usrv-card-control/types"
 	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo/builder"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
 	"bitbucket.org/kushki/usrv-go-core/logger"
 	"github.com/aws/aws-lambda-go/events"
 )
 
 type IRestoreService interface {
-	RestoreDailyRetries(ctx context.Context, event events.SQSEvent) error
+	RestoreDailyRetries(ctx context.Context, event events.SQSEvent) events.SQSEventResponse
+	Restore(ctx context.Context, request types.RestoreDailyRequest) (types.RestoreResult, error)
 }
 
 // RestoreService to clean retries.
 type RestoreService struct {
-	Logger logger.KushkiLogger
-	Dynamo dynamo.IDynamoGateway
+	Logger  logger.KushkiLogger
+	Dynamo  dynamo.IDynamoGateway
+	History IHistoryService
 }
 
 const restoreSrvTag = "RestoreService | %s"
 
+var (
+	errMissingMerchantID   = errors.New("merchantId is required")
+	errInvalidRestoreScope = errors.New("invalid restore scope")
+)
+
 // RefNewRestoreService ref to new service.
 var RefNewRestoreService = NewRestoreService
 
 // NewRestoreService function to instantiate.
 func NewRestoreService(kskLogger logger.KushkiLogger, dynamoGtw dynamo.IDynamoGateway) IRestoreService {
 	return &RestoreService{
-		Logger: kskLogger,
-		Dynamo: dynamoGtw,
+		Logger:  kskLogger,
+		Dynamo:  dynamoGtw,
+		History: NewHistoryService(kskLogger, dynamoGtw),
 	}
 }
 
 // InitRestoreService used to initialize dependencies for service.
-func InitRestoreService(ctx context.Context, event events.SQSEvent) error {
+func InitRestoreService(ctx context.Context, event events.SQSEvent) (events.SQSEventResponse, error) {
 	kskLogger := newKushkiLogger(ctx)
 	dynamoGtw, err := initializeDynamoGtw(ctx, kskLogger)
 	if err != nil {
 		kskLogger.Error(fmt.Sprintf(blockSrvTag, "Error intializing dynamo"), err)
-		return err
+		return events.SQSEventResponse{}, err
 	}
 
 	service := RefNewRestoreService(kskLogger, dynamoGtw)
 
-	return service.RestoreDailyRetries(ctx, event)
+	return service.RestoreDailyRetries(ctx, event), nil
+}
+
+// RestoreDailyRetries clean retries for every record in the batch.
+func (rs *RestoreService) RestoreDailyRetries(ctx context.Context, event events.SQSEvent) events.SQSEventResponse {
+	return processRecords(rs.Logger, restoreSrvTag, event, func(record events.SQSMessage) error {
+		return rs.restoreRecord(ctx, record)
+	})
 }
 
-func (rs *RestoreService) RestoreDailyRetries(ctx context.Context, event events.SQSEvent) error {
+func (rs *RestoreService) restoreRecord(ctx context.Context, record events.SQSMessage) error {
 	var request types.RestoreDailyRequest
-	if err := jsonUnmarshalCaller([]byte(event.Records[0].Body), &request); err != nil {
+	if err := jsonUnmarshalCaller([]byte(record.Body), &request); err != nil {
 		return err
 	}
 
-	if err := rs.cleanDailyRetries(ctx, request); err != nil {
+	result, err := rs.Restore(ctx, request)
+	rs.Logger.Info(fmt.Sprintf(restoreSrvTag, "restoreRecord"), result)
+
+	return err
+}
+
+// Restore deletes the retries selected by the request and cleans the lastRetry of every card and merchant restored.
+func (rs *RestoreService) Restore(ctx context.Context, request types.RestoreDailyRequest) (types.RestoreResult, error) {
+	request = withRestoreDefaults(request)
+	result := types.RestoreResult{
+		Scope:       request.Scope,
+		Frequency:   request.Frequency,
+		CardID:      request.CardID,
+		MerchantID:  request.MerchantID,
+		DeletedKeys: make([]string, 0),
+	}
+	if err := validateRestoreRequest(request); err != nil {
+		return result, err
+	}
+
+	restored := make(map[string]map[string]bool)
+	if request.Scope == constants.RestoreCardMerchantScope {
+		restored[request.CardID] = map[string]bool{request.MerchantID: true}
+	}
+
+	err := rs.pageRetries(ctx, request, func(page []types.CardRetry) error {
+		deleted, err := deleteRetryItems(ctx, rs.Dynamo, filterRestoreRetries(page, request))
+		for _, retry := range deleted {
+			result.DeletedKeys = append(result.DeletedKeys, retry.RetryKey)
+			if restored[retry.CardID] == nil {
+				restored[retry.CardID] = make(map[string]bool)
+			}
+			restored[retry.CardID][retry.MerchantID] = true
+			rs.History.Record(ctx, types.BlockHistoryEntry{
+				CardID:        retry.CardID,
+				MerchantID:    retry.MerchantID,
+				Operation:     constants.HistoryRestore,
+				Brand:         retry.Brand,
+				Frequency:     getRetryFrequency(retry.RetryKey),
+				RetriesBefore: len(retry.Retries),
+			})
+		}
+
 		return err
+	})
+	if err != nil {
+		return result, err
 	}
 
-	return rs.cleanLastRetry(ctx, request)
+	for _, cardID := range sortedKeys(restored) {
+		for _, merchantID := range sortedKeys(restored[cardID]) {
+			if err := rs.cleanLastRetry(ctx, cardID, merchantID); err != nil {
+				return result, err
+			}
+		}
+	}
+
+	return result, nil
 }
 
-func (rs *RestoreService) cleanDailyRetries(ctx context.Context, request types.RestoreDailyRequest) error {
-	rs.Logger.Info(fmt.Sprintf(restoreSrvTag, "cleanDailyRetries"), "[CLEANING]")
-	queryInput := gateway.QueryRetriesBuilder(request.CardID, request.MerchantID)
-	var out []types.CardRetry
-	if err := rs.Dynamo.Query(ctx, queryInput, &out); err != nil {
-		return err
+func withRestoreDefaults(request types.RestoreDailyRequest) types.RestoreDailyRequest {
+	if request.Scope == "" {
+		request.Scope = constants.RestoreCardMerchantScope
+	}
+	if request.Frequency == "" {
+		request.Frequency = constants.DailyFrequency
 	}
 
-	for _, retry := range out {
-		deleteItemBuilder := gateway.DeleteCardRetryBuilder(retry.RetryKey)
-		if err := rs.Dynamo.DeleteItem(ctx, deleteItemBuilder); err != nil {
-			return err
+	return request
+}
+
+func validateRestoreRequest(request types.RestoreDailyRequest) error {
+	switch request.Scope {
+	case constants.RestoreCardMerchantScope:
+		if request.CardID == "" {
+			return errMissingCardID
 		}
+		if request.MerchantID == "" {
+			return errMissingMerchantID
+		}
+	case constants.RestoreCardScope:
+		if request.CardID == "" {
+			return errMissingCardID
+		}
+	case constants.RestoreMerchantScope:
+		if request.MerchantID == "" {
+			return errMissingMerchantID
+		}
+	default:
+		return errInvalidRestoreScope
 	}
 
 	return nil
 }
 
-func (rs *RestoreService) cleanLastRetry(ctx context.Context, request types.RestoreDailyRequest) error {
+// pageRetries the gateway does not return the last evaluated key, so a short page is the last one.
+func (rs *RestoreService) pageRetries(
+	ctx context.Context,
+	request types.RestoreDailyRequest,
+	process func(page []types.CardRetry) error,
+) error {
+	var lastRetry types.CardRetry
+	for {
+		var page []types.CardRetry
+		if err := rs.queryRetries(ctx, request, lastRetry, &page); err != nil {
+			rs.Logger.Error(fmt.Sprintf(restoreSrvTag, "pageRetries"), err)
+			return err
+		}
+		if err := process(page); err != nil {
+			return err
+		}
+		if len(page) < constants.RestorePageSize {
+			return nil
+		}
+		lastRetry = page[len(page)-1]
+	}
+}
+
+func (rs *RestoreService) queryRetries(
+	ctx context.Context,
+	request types.RestoreDailyRequest,
+	lastRetry types.CardRetry,
+	out *[]types.CardRetry,
+) error {
+	switch request.Scope {
+	case constants.RestoreMerchantScope:
+		return rs.Dynamo.Query(ctx, gateway.QueryMerchantRetriesBuilder(request.MerchantID, lastRetry, constants.RestorePageSize), out)
+	case constants.RestoreCardScope:
+		return rs.Dynamo.Query(ctx, gateway.QueryRestoreRetriesBuilder(request.CardID, "", lastRetry, constants.RestorePageSize), out)
+	default:
+		return rs.Dynamo.Query(ctx, gateway.QueryRestoreRetriesBuilder(request.CardID, request.MerchantID, lastRetry, constants.RestorePageSize), out)
+	}
+}
+
+// filterRestoreRetries the selectors are applied here so every page keeps its size, the retries stored before the brand never match one.
+func filterRestoreRetries(retries []types.CardRetry, request types.RestoreDailyRequest) []types.CardRetry {
+	filtered := make([]types.CardRetry, 0, len(retries))
+	for _, retry := range retries {
+		if request.MerchantID != "" && retry.MerchantID != request.MerchantID {
+			continue
+		}
+		if request.Frequency != constants.RestoreAllFrequencies &&
+			!strings.EqualFold(getRetryFrequency(retry.RetryKey), request.Frequency) {
+			continue
+		}
+		if request.Brand != "" && !strings.EqualFold(retry.Brand, request.Brand) {
+			continue
+		}
+		filtered = append(filtered, retry)
+	}
+
+	return filtered
+}
+
+// deleteCardRetries deletes the retries matched by the query.
+func deleteCardRetries(ctx context.Context, dynamoGtw dynamo.IDynamoGateway, query *builder.QueryBuilder) ([]types.CardRetry, error) {
+	var out []types.CardRetry
+	if err := dynamoGtw.Query(ctx, query, &out); err != nil {
+		return nil, err
+	}
+
+	return deleteRetryItems(ctx, dynamoGtw, out)
+}
+
+// deleteRetryItems deletes the retries, the expired ones are left to the TTL.
+func deleteRetryItems(ctx context.Context, dynamoGtw dynamo.IDynamoGateway, retries []types.CardRetry) ([]types.CardRetry, error) {
+	deleted := make([]types.CardRetry, 0, len(retries))
+	for _, retry := range retries {
+		if isExpiredItem(retry.ExpiresAt) {
+			continue
+		}
+		if err := dynamoGtw.DeleteItem(ctx, gateway.DeleteCardRetryBuilder(retry.RetryKey)); err != nil {
+			return deleted, err
+		}
+		deleted = append(deleted, retry)
+	}
+
+	return deleted, nil
+}
+
+func (rs *RestoreService) cleanLastRetry(ctx context.Context, cardID string, merchantID string) error {
 	rs.Logger.Info(fmt.Sprintf(restoreSrvTag, "cleanLastRetry"), "[CLEANING]")
-	input := gateway.GetBlockedCardBuilder(request.CardID)
-	var blockedCard types.DynamoBlockedCard
 
-	if err := rs.Dynamo.GetItem(ctx, input, &blockedCard); err != nil {
-		return err
+	return retryOnConflict(rs.Logger, restoreSrvTag, func(int) error {
+		var blockedCard types.DynamoBlockedCard
+		err := rs.Dynamo.GetItem(ctx, gateway.GetBlockedCardBuilder(cardID), &blockedCard)
+		if errors.Is(err, dynamoerror.ErrItemNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if isExpiredItem(blockedCard.ExpiresAt) {
+			rs.Logger.Info(fmt.Sprintf(restoreSrvTag, "cleanLastRetry"), "[EXPIRED CARD, SKIPPING]")
+			return nil
+		}
+		if blockedCard.BlockedMerchants[merchantID].LastRetry == 0 {
+			return nil
+		}
+
+		// only the last retry is removed, the block and the escalation of the merchant are kept.
+		return rs.Dynamo.UpdateItem(ctx, gateway.ClearLastRetryBuilder(merchantID, blockedCard))
+	})
+}
+
+func sortedKeys[V any](values map[string]V) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
 	}
+	sort.Strings(keys)
 
-	cleanInput := gateway.UpdateLastRetryBuilder(0, request.MerchantID, blockedCard)
-	return rs.Dynamo.UpdateItem(ctx, cleanInput)
+	return keys
 }
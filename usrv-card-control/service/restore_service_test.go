@@ -2,14 +2,23 @@ package service
 
 import (
 	"context"
+	"fmt"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 
+	constants "bitbucket.org/kushki/usrv-card-control"
 	"bitbucket.org/kushki/usrv-card-control/mocks"
 	coreMock "bitbucket.org/kushki/usrv-card-control/mocks/core"
 	mockService "bitbucket.org/kushki/usrv-card-control/mocks/service"
 	"bitbucket.org/kushki/usrv-card-control/types"
 	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo/builder"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
 	"bitbucket.org/kushki/usrv-go-core/logger"
+	"github.com/aws/aws-lambda-go/events"
+	typesDynamo "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 )
@@ -29,7 +38,7 @@ func TestInitRestoreService(t *testing.T) {
 		initializeDynamoGtw = func(context.Context, logger.KushkiLogger) (dynamo.IDynamoGateway, error) {
 			return &coreMock.IDynamoGateway{}, commonError
 		}
-		err := InitRestoreService(context.TODO(), fakeEvent)
+		_, err := InitRestoreService(context.TODO(), fakeEvent)
 		assert.Error(t, err)
 	})
 
@@ -40,10 +49,10 @@ func TestInitRestoreService(t *testing.T) {
 		}
 		RefNewRestoreService = func(logger.KushkiLogger, dynamo.IDynamoGateway) IRestoreService {
 			srv := &mockService.IRestoreService{}
-			srv.On("RestoreDailyRetries", mock.Anything, mock.Anything).Return(nil)
+			srv.On("RestoreDailyRetries", mock.Anything, mock.Anything).Return(events.SQSEventResponse{})
 			return srv
 		}
-		err := InitRestoreService(context.TODO(), fakeEvent)
+		_, err := InitRestoreService(context.TODO(), fakeEvent)
 		assert.NoError(t, err)
 	})
 }
@@ -57,34 +66,56 @@ type restoreDynamoErrors struct {
 
 type restoreScenario struct {
 	Name           string
+	Request        types.RestoreDailyRequest
 	DynamoErrors   restoreDynamoErrors
 	HasError       bool
 	UnmarshalError error
 }
 
 func TestRestoreService_RestoreDailyRetries(t *testing.T) {
+	request := types.RestoreDailyRequest{CardID: "card1", MerchantID: "merchant1"}
 	scenarios := []restoreScenario{
 		{
-			Name: "should be successfully",
+			Name:    "should be successfully",
+			Request: request,
+		},
+		{
+			Name:         "should be successfully if the card has no record",
+			Request:      request,
+			DynamoErrors: restoreDynamoErrors{Get: dynamoerror.ErrItemNotFound},
+		},
+		{
+			Name:     "should return an error if the merchant is missing",
+			HasError: true,
+			Request:  types.RestoreDailyRequest{CardID: "card1"},
+		},
+		{
+			Name:     "should return an error if the scope is invalid",
+			HasError: true,
+			Request:  types.RestoreDailyRequest{CardID: "card1", MerchantID: "merchant1", Scope: "other"},
 		},
 		{
 			Name:         "should return an error if query fails",
 			HasError:     true,
+			Request:      request,
 			DynamoErrors: restoreDynamoErrors{Query: commonError},
 		},
 		{
 			Name:         "should return an error if get fails",
 			HasError:     true,
+			Request:      request,
 			DynamoErrors: restoreDynamoErrors{Get: commonError},
 		},
 		{
 			Name:         "should return an error if Delete fails",
 			HasError:     true,
+			Request:      request,
 			DynamoErrors: restoreDynamoErrors{Delete: commonError},
 		},
 		{
 			Name:           "should return an error if unmarshal fails",
 			HasError:       true,
+			Request:        request,
 			UnmarshalError: commonError,
 		},
 	}
@@ -95,7 +126,7 @@ func TestRestoreService_RestoreDailyRetries(t *testing.T) {
 			dynamoGtw.On("Query", mock.Anything, mock.Anything, mock.Anything).
 				Run(func(args mock.Arguments) {
 					out := args[2].(*[]types.CardRetry)
-					*out = make([]types.CardRetry, 1)
+					*out = []types.CardRetry{{RetryKey: "card1-merchant1-daily", CardID: "card1", MerchantID: "merchant1"}}
 				}).
 				Return(scenario.DynamoErrors.Query)
 			dynamoGtw.On("DeleteItem", mock.Anything, mock.Anything).
@@ -110,21 +141,267 @@ func TestRestoreService_RestoreDailyRetries(t *testing.T) {
 			dynamoGtw.On("UpdateItem", mock.Anything, mock.Anything).
 				Return(scenario.DynamoErrors.Update)
 			srv := RestoreService{
-				Logger: mocks.GetMockLogger(t),
-				Dynamo: &dynamoGtw,
+				Logger:  mocks.GetMockLogger(t),
+				Dynamo:  &dynamoGtw,
+				History: getHistoryMock(),
 			}
 			jsonUnmarshalCaller = func(_ []byte, v any) error {
 				out := v.(*types.RestoreDailyRequest)
-				*out = types.RestoreDailyRequest{}
+				*out = scenario.Request
 
 				return scenario.UnmarshalError
 			}
-			err := srv.RestoreDailyRetries(context.TODO(), fakeEvent)
+			result := srv.RestoreDailyRetries(context.TODO(), fakeEvent)
 			if scenario.HasError {
-				assert.Error(t, err)
+				assert.Equal(t, []events.SQSBatchItemFailure{{ItemIdentifier: fakeEvent.Records[0].MessageId}}, result.BatchItemFailures)
 			} else {
-				assert.NoError(t, err)
+				assert.Empty(t, result.BatchItemFailures)
 			}
 		})
 	}
 }
+
+func TestRestoreService_IgnoresExpiredItems(t *testing.T) {
+	t.Cleanup(clean)
+	expired := time.Now().Add(-time.Minute).Unix()
+	dynamoGtw := coreMock.IDynamoGateway{}
+	dynamoGtw.On("Query", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			out := args[2].(*[]types.CardRetry)
+			*out = []types.CardRetry{{RetryKey: "expired", ExpiresAt: expired}}
+		}).
+		Return(nil)
+	dynamoGtw.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			out := args[2].(*types.DynamoBlockedCard)
+			*out = types.DynamoBlockedCard{ExpiresAt: expired}
+		}).
+		Return(nil)
+	history := getHistoryMock()
+	srv := RestoreService{
+		Logger:  mocks.GetMockLogger(t),
+		Dynamo:  &dynamoGtw,
+		History: history,
+	}
+	jsonUnmarshalCaller = func(_ []byte, v any) error {
+		*v.(*types.RestoreDailyRequest) = types.RestoreDailyRequest{CardID: "card1", MerchantID: "merchant1"}
+		return nil
+	}
+
+	result := srv.RestoreDailyRetries(context.TODO(), fakeEvent)
+
+	assert.Empty(t, result.BatchItemFailures)
+	dynamoGtw.AssertNotCalled(t, "DeleteItem", mock.Anything, mock.Anything)
+	dynamoGtw.AssertNotCalled(t, "UpdateItem", mock.Anything, mock.Anything)
+}
+
+func newRestoreScenario(t *testing.T) (*RestoreService, *coreMock.IDynamoGateway, *mockService.IHistoryService) {
+	t.Helper()
+	t.Cleanup(clean)
+	dynamoGtw := &coreMock.IDynamoGateway{}
+	dynamoGtw.On("DeleteItem", mock.Anything, mock.Anything).Return(nil)
+	dynamoGtw.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			*args.Get(2).(*types.DynamoBlockedCard) = newRestoredCard()
+		}).
+		Return(nil)
+	dynamoGtw.On("UpdateItem", mock.Anything, mock.Anything).Return(nil)
+	history := getHistoryMock()
+
+	return &RestoreService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoGtw, History: history}, dynamoGtw, history
+}
+
+// newRestoredCard card with the last retry of every merchant restored by the scenarios.
+func newRestoredCard() types.DynamoBlockedCard {
+	return types.DynamoBlockedCard{
+		CardID: "card1",
+		BlockedMerchants: map[string]types.BlockedMerchant{
+			"merchant1": {LastRetry: 1},
+			"merchant2": {LastRetry: 1},
+		},
+	}
+}
+
+func TestRestoreService_Restore(t *testing.T) {
+	retries := []types.CardRetry{
+		{RetryKey: "card1-merchant1-daily", CardID: "card1", MerchantID: "merchant1", Brand: "Visa", Retries: []int64{1}},
+		{RetryKey: "card1-merchant1-monthly", CardID: "card1", MerchantID: "merchant1", Brand: "Visa"},
+		{RetryKey: "card1-merchant2-51-daily", CardID: "card1", MerchantID: "merchant2", Brand: "Mastercard"},
+		{RetryKey: "card2-merchant2-daily", CardID: "card2", MerchantID: "merchant2"},
+	}
+	scenarios := []struct {
+		name        string
+		request     types.RestoreDailyRequest
+		merchant    bool
+		deletedKeys []string
+		cleaned     int
+	}{
+		{
+			name:        "should restore every frequency of a card and merchant",
+			request:     types.RestoreDailyRequest{CardID: "card1", MerchantID: "merchant1", Frequency: constants.RestoreAllFrequencies},
+			deletedKeys: []string{"card1-merchant1-daily", "card1-merchant1-monthly"},
+			cleaned:     1,
+		},
+		{
+			name:        "should restore the daily retries of every merchant of a card",
+			request:     types.RestoreDailyRequest{CardID: "card1", Scope: constants.RestoreCardScope},
+			deletedKeys: []string{"card1-merchant1-daily", "card1-merchant2-51-daily"},
+			cleaned:     2,
+		},
+		{
+			name:        "should restore the monthly retries of a brand",
+			request:     types.RestoreDailyRequest{CardID: "card1", Scope: constants.RestoreCardScope, Frequency: constants.MonthlyFrequency, Brand: "VISA"},
+			deletedKeys: []string{"card1-merchant1-monthly"},
+			cleaned:     1,
+		},
+		{
+			name:        "should restore every card of a merchant",
+			request:     types.RestoreDailyRequest{MerchantID: "merchant2", Scope: constants.RestoreMerchantScope},
+			merchant:    true,
+			deletedKeys: []string{"card1-merchant2-51-daily", "card2-merchant2-daily"},
+			cleaned:     2,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			srv, dynamoGtw, history := newRestoreScenario(t)
+			page := retries[:3]
+			if scenario.merchant {
+				page = retries
+			}
+			dynamoGtw.On("Query", mock.Anything, mock.Anything, mock.Anything).
+				Run(func(args mock.Arguments) {
+					*args.Get(2).(*[]types.CardRetry) = page
+				}).
+				Return(nil)
+
+			result, err := srv.Restore(context.TODO(), scenario.request)
+
+			assert.NoError(t, err)
+			assert.Equal(t, scenario.deletedKeys, result.DeletedKeys)
+			dynamoGtw.AssertNumberOfCalls(t, "DeleteItem", len(scenario.deletedKeys))
+			dynamoGtw.AssertNumberOfCalls(t, "UpdateItem", scenario.cleaned)
+			history.AssertNumberOfCalls(t, "Record", len(scenario.deletedKeys))
+		})
+	}
+
+	t.Run("should clean the merchant when it has no retries", func(t *testing.T) {
+		srv, dynamoGtw, history := newRestoreScenario(t)
+		dynamoGtw.On("Query", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+
+		result, err := srv.Restore(context.TODO(), types.RestoreDailyRequest{CardID: "card1", MerchantID: "merchant1"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, types.RestoreResult{
+			Scope: constants.RestoreCardMerchantScope, Frequency: constants.DailyFrequency,
+			CardID: "card1", MerchantID: "merchant1", DeletedKeys: []string{},
+		}, result)
+		dynamoGtw.AssertNumberOfCalls(t, "UpdateItem", 1)
+		history.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should retry the clean on conflict", func(t *testing.T) {
+		t.Cleanup(clean)
+		sleepCaller = func(time.Duration) {}
+		dynamoGtw := &coreMock.IDynamoGateway{}
+		dynamoGtw.On("Query", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+		dynamoGtw.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				*args.Get(2).(*types.DynamoBlockedCard) = newRestoredCard()
+			}).
+			Return(nil)
+		dynamoGtw.On("UpdateItem", mock.Anything, mock.Anything).
+			Return(&typesDynamo.ConditionalCheckFailedException{}).Once()
+		dynamoGtw.On("UpdateItem", mock.Anything, mock.Anything).Return(nil)
+		srv := RestoreService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoGtw, History: getHistoryMock()}
+
+		_, err := srv.Restore(context.TODO(), types.RestoreDailyRequest{CardID: "card1", MerchantID: "merchant1"})
+
+		assert.NoError(t, err)
+		dynamoGtw.AssertNumberOfCalls(t, "GetItem", 2)
+	})
+}
+
+func TestRestoreService_KeepsTheBlock(t *testing.T) {
+	t.Cleanup(clean)
+	blockedCard := types.DynamoBlockedCard{
+		CardID:    "card1",
+		TimeStamp: 1,
+		BlockedMerchants: map[string]types.BlockedMerchant{
+			"merchant1": {
+				BlockType: constants.TEMPORARY, ExpirationDate: time.Now().Add(time.Hour).UnixMilli(),
+				LastRetry: 1, RetriesEnd: 2, EscalationCount: 1, EscalationStart: 1,
+			},
+		},
+	}
+	dynamoGtw := &coreMock.IDynamoGateway{}
+	dynamoGtw.On("Query", mock.Anything, mock.Anything, mock.Anything).Return(nil)
+	dynamoGtw.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) {
+			*args.Get(2).(*types.DynamoBlockedCard) = blockedCard
+		}).
+		Return(nil)
+	dynamoGtw.On("UpdateItem", mock.Anything, mock.Anything).Return(nil)
+	srv := RestoreService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoGtw, History: getHistoryMock()}
+
+	_, err := srv.Restore(context.TODO(), types.RestoreDailyRequest{CardID: "card1", MerchantID: "merchant1"})
+
+	assert.NoError(t, err)
+	dynamoGtw.AssertNumberOfCalls(t, "UpdateItem", 1)
+	dynamoGtw.AssertCalled(t, "UpdateItem", mock.Anything, mock.MatchedBy(func(b *builder.UpdateItemBuilder) bool {
+		input, err := b.BuildInput()
+		if err != nil || !strings.Contains(*input.UpdateExpression, "REMOVE") {
+			return false
+		}
+		// the merchant entry is not replaced, its block and escalation stay.
+		for _, value := range input.ExpressionAttributeValues {
+			if _, ok := value.(*typesDynamo.AttributeValueMemberM); ok {
+				return false
+			}
+		}
+		names := make([]string, 0, len(input.ExpressionAttributeNames))
+		for _, name := range input.ExpressionAttributeNames {
+			names = append(names, name)
+		}
+
+		return slices.Contains(names, constants.LastRetryField) && slices.Contains(names, constants.RetriesEndField)
+	}))
+}
+
+func TestRestoreService_RestorePages(t *testing.T) {
+	srv, dynamoGtw, _ := newRestoreScenario(t)
+	fullPage := make([]types.CardRetry, constants.RestorePageSize)
+	for i := range fullPage {
+		fullPage[i] = types.CardRetry{RetryKey: fmt.Sprint("card1-merchant1-", i), CardID: "card1", MerchantID: "merchant1"}
+	}
+	dynamoGtw.On("Query", mock.Anything, mock.MatchedBy(func(b *builder.QueryBuilder) bool {
+		return b.StartKey == nil
+	}), mock.Anything).Return(nil).Once().Run(func(args mock.Arguments) {
+		*args.Get(2).(*[]types.CardRetry) = fullPage
+	})
+	dynamoGtw.On("Query", mock.Anything, mock.MatchedBy(func(b *builder.QueryBuilder) bool {
+		key, ok := b.StartKey[constants.RetryKeyField].(*typesDynamo.AttributeValueMemberS)
+		return ok && key.Value == fullPage[constants.RestorePageSize-1].RetryKey
+	}), mock.Anything).Return(nil).Once().Run(func(args mock.Arguments) {
+		*args.Get(2).(*[]types.CardRetry) = []types.CardRetry{{RetryKey: "card1-merchant1-daily", CardID: "card1", MerchantID: "merchant1"}}
+	})
+
+	result, err := srv.Restore(context.TODO(), types.RestoreDailyRequest{CardID: "card1", MerchantID: "merchant1"})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"card1-merchant1-daily"}, result.DeletedKeys)
+	dynamoGtw.AssertExpectations(t)
+}
+
+func TestFilterRestoreRetries(t *testing.T) {
+	retries := []types.CardRetry{
+		{RetryKey: "card1-merchant1-daily", MerchantID: "merchant1"},
+		{RetryKey: "card1-merchant1-MC_MAC_RETRY_AFTER_1H-retryAfter", MerchantID: "merchant1", Brand: "Mastercard"},
+	}
+
+	assert.Len(t, filterRestoreRetries(retries, types.RestoreDailyRequest{Frequency: constants.RestoreAllFrequencies}), 2)
+	assert.Len(t, filterRestoreRetries(retries, types.RestoreDailyRequest{Frequency: constants.RestoreAllFrequencies, MerchantID: "other"}), 0)
+	assert.Equal(t, retries[1:], filterRestoreRetries(retries, types.RestoreDailyRequest{Frequency: constants.RetryAfterFrequency}))
+	assert.Equal(t, retries[1:], filterRestoreRetries(retries, types.RestoreDailyRequest{Frequency: constants.RestoreAllFrequencies, Brand: "mastercard"}))
+}
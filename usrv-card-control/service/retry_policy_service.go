@@ -0,0 +1,313 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	constants "bitbucket.org/kushki/usrv-card-control"
+	sharedConstants "bitbucket.org/kushki/usrv-card-control/features/shared/constants"
+	"bitbucket.org/kushki/usrv-card-control/gateway"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	core "bitbucket.org/kushki/usrv-go-core"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+)
+
+type IRetryPolicyService interface {
+	GetPolicy(ctx context.Context, brand string, processor string, merchantID string) (types.RetryPolicy, error)
+	GetShadowPolicy(ctx context.Context, brand string, processor string, merchantID string) (types.RetryPolicy, bool, error)
+}
+
+// RetryPolicyService resolves the retry policy for a card brand, processor and merchant.
+type RetryPolicyService struct {
+	Logger logger.KushkiLogger
+	Dynamo dynamo.IDynamoGateway
+}
+
+const retryPolicySrvTag = "RetryPolicyService | %s"
+
+type cachedPolicy struct {
+	policy    types.RetryPolicy
+	found     bool
+	expiresAt time.Time
+}
+
+var (
+	errInvalidPolicy = errors.New("invalid retry policy")
+
+	// policyCache keeps resolved policies for PolicyCacheMinutes, misses included.
+	policyCache      = make(map[string]cachedPolicy)
+	policyCacheMutex sync.RWMutex
+
+	// defaultRetryPolicies fallback used when the policy table has no match for the brand.
+	defaultRetryPolicies = map[string]types.RetryPolicy{
+		core.BrandVisa: {
+			PolicyID: generatePolicyID(core.BrandVisa, "", ""),
+			Brand:    core.BrandVisa,
+			Version:  constants.DefaultPolicyVersion,
+			Rules: []types.FrequencyRule{
+				{Frequency: constants.MonthlyFrequency, WindowHours: constants.MonthDays * constants.DayHours, MaxAttempts: 15, BlockType: constants.TEMPORARY},
+				// Visa caps the reattempts of each category, whatever the code inside it.
+				{Frequency: constants.MonthlyFrequency, Category: constants.VisaIssuerCannotApproveNow, WindowHours: constants.MonthDays * constants.DayHours, MaxAttempts: 15, BlockType: constants.TEMPORARY},
+				{Frequency: constants.MonthlyFrequency, Category: constants.VisaDataQuality, WindowHours: constants.MonthDays * constants.DayHours, MaxAttempts: 15, BlockType: constants.TEMPORARY},
+				{Frequency: constants.MonthlyFrequency, Category: constants.VisaGenericDecline, WindowHours: constants.MonthDays * constants.DayHours, MaxAttempts: 15, BlockType: constants.TEMPORARY},
+			},
+		},
+		core.BrandMasterCard: {
+			PolicyID: generatePolicyID(core.BrandMasterCard, "", ""),
+			Brand:    core.BrandMasterCard,
+			Version:  constants.DefaultPolicyVersion,
+			Rules: []types.FrequencyRule{
+				{Frequency: constants.DailyFrequency, WindowHours: constants.DayHours, MaxAttempts: 7, BlockType: constants.TEMPORARY},
+				{Frequency: constants.MonthlyFrequency, WindowHours: constants.MonthDays * constants.DayHours, MaxAttempts: 35, BlockType: constants.TEMPORARY},
+				// Merchant Advice Codes that ask to wait before the next attempt: a single attempt blocks until the wait ends.
+				{Frequency: constants.RetryAfterFrequency, Category: constants.MasterCardUpdateAccount, WindowHours: constants.DayHours, MaxAttempts: 1, BlockType: constants.TEMPORARY, BlockHours: constants.DayHours},
+				{Frequency: constants.RetryAfterFrequency, Category: constants.MasterCardTryAgainLater, WindowHours: 3 * constants.DayHours, MaxAttempts: 1, BlockType: constants.TEMPORARY, BlockHours: 3 * constants.DayHours},
+				{Frequency: constants.RetryAfterFrequency, Category: constants.MasterCardRetryAfter1Hour, WindowHours: 1, MaxAttempts: 1, BlockType: constants.TEMPORARY, BlockHours: 1},
+				{Frequency: constants.RetryAfterFrequency, Category: constants.MasterCardRetryAfter24Hours, WindowHours: constants.DayHours, MaxAttempts: 1, BlockType: constants.TEMPORARY, BlockHours: constants.DayHours},
+				{Frequency: constants.RetryAfterFrequency, Category: constants.MasterCardRetryAfter2Days, WindowHours: 2 * constants.DayHours, MaxAttempts: 1, BlockType: constants.TEMPORARY, BlockHours: 2 * constants.DayHours},
+				{Frequency: constants.RetryAfterFrequency, Category: constants.MasterCardRetryAfter4Days, WindowHours: 4 * constants.DayHours, MaxAttempts: 1, BlockType: constants.TEMPORARY, BlockHours: 4 * constants.DayHours},
+				{Frequency: constants.RetryAfterFrequency, Category: constants.MasterCardRetryAfter6Days, WindowHours: 6 * constants.DayHours, MaxAttempts: 1, BlockType: constants.TEMPORARY, BlockHours: 6 * constants.DayHours},
+				{Frequency: constants.RetryAfterFrequency, Category: constants.MasterCardRetryAfter8Days, WindowHours: 8 * constants.DayHours, MaxAttempts: 1, BlockType: constants.TEMPORARY, BlockHours: 8 * constants.DayHours},
+				{Frequency: constants.RetryAfterFrequency, Category: constants.MasterCardRetryAfter10Days, WindowHours: 10 * constants.DayHours, MaxAttempts: 1, BlockType: constants.TEMPORARY, BlockHours: 10 * constants.DayHours},
+			},
+		},
+		sharedConstants.CardBrandAmex: {
+			PolicyID: generatePolicyID(sharedConstants.CardBrandAmex, "", ""),
+			Brand:    sharedConstants.CardBrandAmex,
+			Version:  constants.DefaultPolicyVersion,
+			Rules: []types.FrequencyRule{
+				{Frequency: constants.DailyFrequency, WindowHours: constants.DayHours, MaxAttempts: 7, BlockType: constants.TEMPORARY},
+				{Frequency: constants.MonthlyFrequency, WindowHours: constants.MonthDays * constants.DayHours, MaxAttempts: 15, BlockType: constants.TEMPORARY},
+			},
+		},
+		// Discover and Diners share the Discover Global Network reattempt limits.
+		sharedConstants.CardBrandDiscover: {
+			PolicyID: generatePolicyID(sharedConstants.CardBrandDiscover, "", ""),
+			Brand:    sharedConstants.CardBrandDiscover,
+			Version:  constants.DefaultPolicyVersion,
+			Rules: []types.FrequencyRule{
+				{Frequency: constants.DailyFrequency, WindowHours: constants.DayHours, MaxAttempts: 7, BlockType: constants.TEMPORARY},
+				{Frequency: constants.MonthlyFrequency, WindowHours: constants.MonthDays * constants.DayHours, MaxAttempts: 15, BlockType: constants.TEMPORARY},
+			},
+		},
+		sharedConstants.CardBrandDiners: {
+			PolicyID: generatePolicyID(sharedConstants.CardBrandDiners, "", ""),
+			Brand:    sharedConstants.CardBrandDiners,
+			Version:  constants.DefaultPolicyVersion,
+			Rules: []types.FrequencyRule{
+				{Frequency: constants.DailyFrequency, WindowHours: constants.DayHours, MaxAttempts: 7, BlockType: constants.TEMPORARY},
+				{Frequency: constants.MonthlyFrequency, WindowHours: constants.MonthDays * constants.DayHours, MaxAttempts: 15, BlockType: constants.TEMPORARY},
+			},
+		},
+		sharedConstants.CardBrandJCB: {
+			PolicyID: generatePolicyID(sharedConstants.CardBrandJCB, "", ""),
+			Brand:    sharedConstants.CardBrandJCB,
+			Version:  constants.DefaultPolicyVersion,
+			Rules: []types.FrequencyRule{
+				{Frequency: constants.DailyFrequency, WindowHours: constants.DayHours, MaxAttempts: 5, BlockType: constants.TEMPORARY},
+				{Frequency: constants.MonthlyFrequency, WindowHours: constants.MonthDays * constants.DayHours, MaxAttempts: 15, BlockType: constants.TEMPORARY},
+			},
+		},
+	}
+
+	// fallbackRetryPolicy applies to the brands without a default when the table has no fallback policy,
+	// so an unknown brand is still limited instead of passing.
+	fallbackRetryPolicy = types.RetryPolicy{
+		PolicyID: generatePolicyID(constants.PolicyWildcard, "", ""),
+		Brand:    constants.PolicyWildcard,
+		Version:  constants.FallbackPolicyVersion,
+		Rules: []types.FrequencyRule{
+			{Frequency: constants.DailyFrequency, WindowHours: constants.DayHours, MaxAttempts: 7, BlockType: constants.TEMPORARY},
+			{Frequency: constants.MonthlyFrequency, WindowHours: constants.MonthDays * constants.DayHours, MaxAttempts: 15, BlockType: constants.TEMPORARY},
+		},
+	}
+)
+
+// NewRetryPolicyService function to instantiate.
+func NewRetryPolicyService(kskLogger logger.KushkiLogger, dynamoGtw dynamo.IDynamoGateway) IRetryPolicyService {
+	return &RetryPolicyService{
+		Logger: kskLogger,
+		Dynamo: dynamoGtw,
+	}
+}
+
+// GetPolicy returns the most specific stored policy, falling back to the versioned default of the brand.
+// Brands without a default get the fallback policy.
+func (ps *RetryPolicyService) GetPolicy(ctx context.Context, brand string, processor string, merchantID string) (types.RetryPolicy, error) {
+	brand = strings.ToUpper(brand)
+	for _, policyID := range policyCandidates(brand, processor, merchantID) {
+		policy, found, err := ps.getCachedPolicy(ctx, policyID)
+		if err != nil {
+			return types.RetryPolicy{}, err
+		}
+		if found {
+			ps.Logger.Info(fmt.Sprintf(retryPolicySrvTag, "GetPolicy"), policy)
+			return policy, nil
+		}
+	}
+
+	if policy, ok := defaultRetryPolicies[brand]; ok {
+		ps.Logger.Info(fmt.Sprintf(retryPolicySrvTag, "GetPolicy"), "[USING DEFAULT POLICY]")
+		return policy, nil
+	}
+
+	return ps.getFallbackPolicy(ctx, brand)
+}
+
+// getFallbackPolicy the stored PolicyWildcard#*#* policy configures the fallback, the built-in one applies without it.
+func (ps *RetryPolicyService) getFallbackPolicy(ctx context.Context, brand string) (types.RetryPolicy, error) {
+	policy, found, err := ps.getCachedPolicy(ctx, fallbackRetryPolicy.PolicyID)
+	if err != nil {
+		return types.RetryPolicy{}, err
+	}
+	if !found {
+		policy = fallbackRetryPolicy
+	}
+	ps.Logger.Info(fmt.Sprintf(retryPolicySrvTag, "GetPolicy | "+brand), "[USING FALLBACK POLICY]")
+
+	return policy, nil
+}
+
+// GetShadowPolicy the most specific shadow policy, stored with the prefix next to the active ones.
+// Unlike the active policy there is no default, without a stored one nothing is evaluated in shadow.
+func (ps *RetryPolicyService) GetShadowPolicy(ctx context.Context, brand string, processor string, merchantID string) (types.RetryPolicy, bool, error) {
+	for _, policyID := range policyCandidates(strings.ToUpper(brand), processor, merchantID) {
+		policy, found, err := ps.getCachedPolicy(ctx, constants.ShadowPolicyPrefix+policyID)
+		if err != nil || found {
+			return policy, found, err
+		}
+	}
+
+	return types.RetryPolicy{}, false, nil
+}
+
+func (ps *RetryPolicyService) getCachedPolicy(ctx context.Context, policyID string) (types.RetryPolicy, bool, error) {
+	policyCacheMutex.RLock()
+	cached, ok := policyCache[policyID]
+	policyCacheMutex.RUnlock()
+	if ok && time.Now().Before(cached.expiresAt) {
+		return cached.policy, cached.found, nil
+	}
+
+	var policy types.RetryPolicy
+	err := ps.Dynamo.GetItem(ctx, gateway.GetRetryPolicyBuilder(policyID), &policy)
+	if err != nil && !errors.Is(err, dynamoerror.ErrItemNotFound) {
+		return types.RetryPolicy{}, false, err
+	}
+
+	found := err == nil
+	if found {
+		if err := validatePolicy(policy); err != nil {
+			// an invalid stored policy is ignored so the next candidate or the default applies.
+			ps.Logger.Error(fmt.Sprintf(retryPolicySrvTag, "getCachedPolicy | "+policyID), err)
+			found = false
+		}
+	}
+	policyCacheMutex.Lock()
+	policyCache[policyID] = cachedPolicy{
+		policy:    policy,
+		found:     found,
+		expiresAt: time.Now().Add(time.Minute * time.Duration(constants.PolicyCacheMinutes)),
+	}
+	policyCacheMutex.Unlock()
+
+	return policy, found, nil
+}
+
+// validatePolicy rejects rules that would block on the first decline or never count retries.
+func validatePolicy(policy types.RetryPolicy) error {
+	// two rules of the same frequency and category would share the counter of their retry key.
+	ruleKeys := make(map[string]bool, len(policy.Rules))
+	for _, rule := range policy.Rules {
+		ruleKey := strings.ToUpper(rule.Category + "#" + rule.Frequency)
+		if ruleKeys[ruleKey] {
+			return fmt.Errorf("%w: duplicated %s rule for category %q", errInvalidPolicy, rule.Frequency, rule.Category)
+		}
+		ruleKeys[ruleKey] = true
+		if rule.MaxAttempts <= 0 {
+			return fmt.Errorf("%w: maxAttempts must be greater than 0 in %s rule", errInvalidPolicy, rule.Frequency)
+		}
+		if err := validateWindow(rule); err != nil {
+			return err
+		}
+		if !strings.EqualFold(rule.BlockType, constants.TEMPORARY) && !strings.EqualFold(rule.BlockType, constants.PERMANENT) {
+			return fmt.Errorf("%w: blockType must be TEMPORARY or PERMANENT in %s rule", errInvalidPolicy, rule.Frequency)
+		}
+		if rule.BlockHours < 0 {
+			return fmt.Errorf("%w: blockHours must not be negative in %s rule", errInvalidPolicy, rule.Frequency)
+		}
+	}
+
+	return validateEscalation(policy.Escalation)
+}
+
+// validateWindow a rolling rule needs its hours, a calendar one its day or month and a known IANA time zone.
+func validateWindow(rule types.FrequencyRule) error {
+	if rule.Calendar == "" {
+		if rule.TimeZone != "" {
+			return fmt.Errorf("%w: timeZone requires a calendar in %s rule", errInvalidPolicy, rule.Frequency)
+		}
+		if rule.WindowHours <= 0 {
+			return fmt.Errorf("%w: windowHours must be greater than 0 in %s rule", errInvalidPolicy, rule.Frequency)
+		}
+
+		return nil
+	}
+	if !strings.EqualFold(rule.Calendar, constants.CalendarDay) && !strings.EqualFold(rule.Calendar, constants.CalendarMonth) {
+		return fmt.Errorf("%w: calendar must be day or month in %s rule", errInvalidPolicy, rule.Frequency)
+	}
+	if _, err := loadRuleLocation(rule.TimeZone); err != nil {
+		return fmt.Errorf("%w: unknown timeZone %s in %s rule", errInvalidPolicy, rule.TimeZone, rule.Frequency)
+	}
+
+	return nil
+}
+
+func validateEscalation(escalation *types.BlockEscalation) error {
+	if escalation == nil {
+		return nil
+	}
+	if escalation.WindowDays < 0 {
+		return fmt.Errorf("%w: escalation windowDays must not be negative", errInvalidPolicy)
+	}
+	for _, blockHours := range escalation.BlockHours {
+		if blockHours <= 0 {
+			return fmt.Errorf("%w: escalation blockHours must be greater than 0", errInvalidPolicy)
+		}
+	}
+
+	return nil
+}
+
+// policyCandidates policy ids ordered from the most to the least specific.
+func policyCandidates(brand string, processor string, merchantID string) []string {
+	candidates := make([]string, 0, 4)
+	if merchantID != "" && processor != "" {
+		candidates = append(candidates, generatePolicyID(brand, processor, merchantID))
+	}
+	if merchantID != "" {
+		candidates = append(candidates, generatePolicyID(brand, "", merchantID))
+	}
+	if processor != "" {
+		candidates = append(candidates, generatePolicyID(brand, processor, ""))
+	}
+
+	return append(candidates, generatePolicyID(brand, "", ""))
+}
+
+func generatePolicyID(brand string, processor string, merchantID string) string {
+	if processor == "" {
+		processor = constants.PolicyWildcard
+	}
+	if merchantID == "" {
+		merchantID = constants.PolicyWildcard
+	}
+
+	return fmt.Sprintf("%s#%s#%s", brand, processor, merchantID)
+}
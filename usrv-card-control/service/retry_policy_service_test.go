@@ -0,0 +1,300 @@
+package service
+
+import (
+	"context"
+	"strings"
+	"testing"
+
+	constants "bitbucket.org/kushki/usrv-card-control"
+	sharedConstants "bitbucket.org/kushki/usrv-card-control/features/shared/constants"
+	"bitbucket.org/kushki/usrv-card-control/mocks"
+	coreMock "bitbucket.org/kushki/usrv-card-control/mocks/core"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	core "bitbucket.org/kushki/usrv-go-core"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo/builder"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const (
+	mockPolicyMerchant  = "merchant123"
+	mockPolicyProcessor = "processor123"
+)
+
+func TestNewRetryPolicyService(t *testing.T) {
+	t.Run("should no be empty", func(t *testing.T) {
+		assert.NotEmpty(t, NewRetryPolicyService(mocks.GetMockLogger(t), &coreMock.IDynamoGateway{}))
+	})
+}
+
+func TestRetryPolicyService_GetPolicy(t *testing.T) {
+	t.Run("should return the merchant policy when it is stored", func(t *testing.T) {
+		t.Cleanup(cleanPolicyCache)
+		merchantPolicy := types.RetryPolicy{
+			PolicyID: generatePolicyID(core.BrandVisa, "", mockPolicyMerchant),
+			Version:  "v2",
+			Rules:    []types.FrequencyRule{{Frequency: constants.DailyFrequency, WindowHours: 24, MaxAttempts: 3, BlockType: constants.TEMPORARY}},
+		}
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+			Return(dynamoerror.ErrItemNotFound).
+			Once()
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				out := args[2].(*types.RetryPolicy)
+				*out = merchantPolicy
+			}).
+			Return(nil).
+			Once()
+		srv := RetryPolicyService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock}
+
+		policy, err := srv.GetPolicy(context.TODO(), core.BrandVisa, mockPolicyProcessor, mockPolicyMerchant)
+		assert.NoError(t, err)
+		assert.Equal(t, merchantPolicy, policy)
+		dynamoMock.AssertExpectations(t)
+	})
+
+	t.Run("should fallback to the default brand policy and cache the misses", func(t *testing.T) {
+		t.Cleanup(cleanPolicyCache)
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+			Return(dynamoerror.ErrItemNotFound).
+			Times(4)
+		srv := RetryPolicyService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock}
+
+		for i := 0; i < 2; i++ {
+			policy, err := srv.GetPolicy(context.TODO(), "mastercard", mockPolicyProcessor, mockPolicyMerchant)
+			assert.NoError(t, err)
+			assert.Equal(t, defaultRetryPolicies[core.BrandMasterCard], policy)
+			assert.Equal(t, constants.DefaultPolicyVersion, policy.Version)
+		}
+		dynamoMock.AssertExpectations(t)
+	})
+
+	t.Run("should skip invalid stored policies and fallback to the default", func(t *testing.T) {
+		invalidRules := map[string]types.FrequencyRule{
+			"missing max attempts": {Frequency: constants.DailyFrequency, WindowHours: 24, BlockType: constants.TEMPORARY},
+			"missing window":       {Frequency: constants.DailyFrequency, MaxAttempts: 3, BlockType: constants.TEMPORARY},
+			"unknown block type":   {Frequency: constants.DailyFrequency, WindowHours: 24, MaxAttempts: 3, BlockType: "SOFT"},
+			"negative block hours": {Frequency: constants.DailyFrequency, WindowHours: 24, MaxAttempts: 3, BlockType: constants.TEMPORARY, BlockHours: -1},
+		}
+		for name, rule := range invalidRules {
+			t.Run(name, func(t *testing.T) {
+				t.Cleanup(cleanPolicyCache)
+				dynamoMock := &coreMock.IDynamoGateway{}
+				dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+					Run(func(args mock.Arguments) {
+						out := args[2].(*types.RetryPolicy)
+						*out = types.RetryPolicy{PolicyID: "MASTERCARD#*#*", Version: "v2", Rules: []types.FrequencyRule{rule}}
+					}).
+					Return(nil)
+				srv := RetryPolicyService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock}
+
+				policy, err := srv.GetPolicy(context.TODO(), core.BrandMasterCard, "", "")
+				assert.NoError(t, err)
+				assert.Equal(t, defaultRetryPolicies[core.BrandMasterCard], policy)
+			})
+		}
+	})
+
+	t.Run("should return the default policy of every known brand", func(t *testing.T) {
+		for _, brand := range []string{sharedConstants.CardBrandAmex, sharedConstants.CardBrandDiscover, sharedConstants.CardBrandDiners, sharedConstants.CardBrandJCB} {
+			t.Cleanup(cleanPolicyCache)
+			dynamoMock := &coreMock.IDynamoGateway{}
+			dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+				Return(dynamoerror.ErrItemNotFound)
+			srv := RetryPolicyService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock}
+
+			policy, err := srv.GetPolicy(context.TODO(), strings.ToLower(brand), "", "")
+			assert.NoError(t, err)
+			assert.Equal(t, brand, policy.Brand)
+			assert.NotEmpty(t, policy.Rules)
+			assert.NoError(t, validatePolicy(policy))
+		}
+	})
+
+	t.Run("should return the built-in fallback policy for unknown brands", func(t *testing.T) {
+		t.Cleanup(cleanPolicyCache)
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+			Return(dynamoerror.ErrItemNotFound)
+		srv := RetryPolicyService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock}
+
+		policy, err := srv.GetPolicy(context.TODO(), "UNKNOWN", "", "")
+		assert.NoError(t, err)
+		assert.Equal(t, fallbackRetryPolicy, policy)
+		assert.Equal(t, constants.FallbackPolicyVersion, policy.Version)
+		dynamoMock.AssertCalled(t, "GetItem", mock.Anything, mock.MatchedBy(func(b *builder.GetItemBuilder) bool {
+			return b.Key[constants.PolicyIDField] == "*#*#*"
+		}), mock.Anything)
+	})
+
+	t.Run("should return the stored fallback policy for unknown brands", func(t *testing.T) {
+		t.Cleanup(cleanPolicyCache)
+		storedFallback := types.RetryPolicy{
+			PolicyID: "*#*#*",
+			Version:  "v2",
+			Rules:    []types.FrequencyRule{{Frequency: constants.DailyFrequency, WindowHours: 24, MaxAttempts: 3, BlockType: constants.TEMPORARY}},
+		}
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.MatchedBy(func(b *builder.GetItemBuilder) bool {
+			return b.Key[constants.PolicyIDField] == "*#*#*"
+		}), mock.Anything).
+			Run(func(args mock.Arguments) {
+				*args[2].(*types.RetryPolicy) = storedFallback
+			}).
+			Return(nil)
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+			Return(dynamoerror.ErrItemNotFound)
+		srv := RetryPolicyService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock}
+
+		policy, err := srv.GetPolicy(context.TODO(), "ELO", "processor", "merchant")
+		assert.NoError(t, err)
+		assert.Equal(t, storedFallback, policy)
+	})
+
+	t.Run("should return an error if getting the fallback policy fails", func(t *testing.T) {
+		t.Cleanup(cleanPolicyCache)
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.MatchedBy(func(b *builder.GetItemBuilder) bool {
+			return b.Key[constants.PolicyIDField] == "*#*#*"
+		}), mock.Anything).
+			Return(commonError)
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+			Return(dynamoerror.ErrItemNotFound)
+		srv := RetryPolicyService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock}
+
+		_, err := srv.GetPolicy(context.TODO(), "ELO", "", "")
+		assert.ErrorIs(t, err, commonError)
+	})
+
+	t.Run("should return an error if getting the policy fails", func(t *testing.T) {
+		t.Cleanup(cleanPolicyCache)
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+			Return(commonError)
+		srv := RetryPolicyService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock}
+
+		_, err := srv.GetPolicy(context.TODO(), core.BrandVisa, "", "")
+		assert.Error(t, err)
+	})
+}
+
+func TestRetryPolicyService_GetShadowPolicy(t *testing.T) {
+	t.Run("should return the most specific shadow policy", func(t *testing.T) {
+		t.Cleanup(cleanPolicyCache)
+		shadowPolicy := types.RetryPolicy{
+			PolicyID: constants.ShadowPolicyPrefix + generatePolicyID(core.BrandVisa, "", ""),
+			Version:  "v2",
+			Rules:    []types.FrequencyRule{{Frequency: constants.DailyFrequency, WindowHours: 24, MaxAttempts: 3, BlockType: constants.TEMPORARY}},
+		}
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.MatchedBy(func(b *builder.GetItemBuilder) bool {
+			return b.Key[constants.PolicyIDField] == shadowPolicy.PolicyID
+		}), mock.Anything).
+			Run(func(args mock.Arguments) {
+				*args[2].(*types.RetryPolicy) = shadowPolicy
+			}).
+			Return(nil).
+			Once()
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+			Return(dynamoerror.ErrItemNotFound).
+			Once()
+		srv := RetryPolicyService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock}
+
+		policy, found, err := srv.GetShadowPolicy(context.TODO(), "visa", "", mockPolicyMerchant)
+		assert.NoError(t, err)
+		assert.True(t, found)
+		assert.Equal(t, shadowPolicy, policy)
+		dynamoMock.AssertExpectations(t)
+	})
+
+	t.Run("should not fall back to a default shadow policy", func(t *testing.T) {
+		t.Cleanup(cleanPolicyCache)
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+			Return(dynamoerror.ErrItemNotFound)
+		srv := RetryPolicyService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock}
+
+		_, found, err := srv.GetShadowPolicy(context.TODO(), core.BrandVisa, "", "")
+		assert.NoError(t, err)
+		assert.False(t, found)
+	})
+
+	t.Run("should return an error if getting the shadow policy fails", func(t *testing.T) {
+		t.Cleanup(cleanPolicyCache)
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+			Return(commonError)
+		srv := RetryPolicyService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock}
+
+		_, _, err := srv.GetShadowPolicy(context.TODO(), core.BrandVisa, "", "")
+		assert.ErrorIs(t, err, commonError)
+	})
+}
+
+func TestPolicyCandidates(t *testing.T) {
+	t.Run("should order candidates from the most specific", func(t *testing.T) {
+		res := policyCandidates(core.BrandVisa, mockPolicyProcessor, mockPolicyMerchant)
+		assert.Equal(t, []string{
+			"VISA#processor123#merchant123",
+			"VISA#*#merchant123",
+			"VISA#processor123#*",
+			"VISA#*#*",
+		}, res)
+	})
+
+	t.Run("should only use the brand policy when there is no processor or merchant", func(t *testing.T) {
+		res := policyCandidates(core.BrandVisa, "", "")
+		assert.Equal(t, []string{"VISA#*#*"}, res)
+	})
+}
+
+func cleanPolicyCache() {
+	policyCacheMutex.Lock()
+	policyCache = make(map[string]cachedPolicy)
+	policyCacheMutex.Unlock()
+}
+
+func TestValidatePolicy_DuplicatedRules(t *testing.T) {
+	rule := types.FrequencyRule{Frequency: constants.DailyFrequency, WindowHours: constants.DayHours, MaxAttempts: 7, BlockType: constants.TEMPORARY}
+	categoryRule := rule
+	categoryRule.Category = constants.VisaGenericDecline
+	duplicatedRule := categoryRule
+	duplicatedRule.Category = strings.ToLower(constants.VisaGenericDecline)
+	duplicatedRule.MaxAttempts = 3
+
+	assert.NoError(t, validatePolicy(types.RetryPolicy{Rules: []types.FrequencyRule{rule, categoryRule}}))
+	assert.ErrorIs(t, validatePolicy(types.RetryPolicy{Rules: []types.FrequencyRule{rule, rule}}), errInvalidPolicy)
+	assert.ErrorIs(t, validatePolicy(types.RetryPolicy{Rules: []types.FrequencyRule{rule, categoryRule, duplicatedRule}}), errInvalidPolicy)
+}
+
+func TestValidatePolicy_TimeZone(t *testing.T) {
+	rule := types.FrequencyRule{Frequency: constants.DailyFrequency, Calendar: constants.CalendarDay, MaxAttempts: 7, BlockType: constants.TEMPORARY}
+	zonedRule := rule
+	zonedRule.TimeZone = "America/Guayaquil"
+	unknownZoneRule := rule
+	unknownZoneRule.TimeZone = "Mars/Olympus"
+
+	assert.NoError(t, validatePolicy(types.RetryPolicy{Rules: []types.FrequencyRule{rule}}))
+	assert.NoError(t, validatePolicy(types.RetryPolicy{Rules: []types.FrequencyRule{zonedRule}}))
+	assert.ErrorIs(t, validatePolicy(types.RetryPolicy{Rules: []types.FrequencyRule{unknownZoneRule}}), errInvalidPolicy)
+}
+
+func TestValidateEscalation(t *testing.T) {
+	assert.NoError(t, validateEscalation(nil))
+	assert.NoError(t, validateEscalation(&types.BlockEscalation{BlockHours: []int{24, 72}, WindowDays: 30}))
+	assert.ErrorIs(t, validateEscalation(&types.BlockEscalation{BlockHours: []int{24, 0}}), errInvalidPolicy)
+	assert.ErrorIs(t, validateEscalation(&types.BlockEscalation{BlockHours: []int{24}, WindowDays: -1}), errInvalidPolicy)
+}
+
+func TestValidateWindow(t *testing.T) {
+	assert.NoError(t, validateWindow(types.FrequencyRule{WindowHours: constants.DayHours}))
+	assert.NoError(t, validateWindow(types.FrequencyRule{Calendar: constants.CalendarDay}))
+	assert.NoError(t, validateWindow(types.FrequencyRule{Calendar: constants.CalendarMonth, TimeZone: "America/Guayaquil"}))
+	assert.ErrorIs(t, validateWindow(types.FrequencyRule{}), errInvalidPolicy)
+	assert.ErrorIs(t, validateWindow(types.FrequencyRule{WindowHours: 1, TimeZone: "America/Guayaquil"}), errInvalidPolicy)
+	assert.ErrorIs(t, validateWindow(types.FrequencyRule{Calendar: "week"}), errInvalidPolicy)
+	assert.ErrorIs(t, validateWindow(types.FrequencyRule{Calendar: constants.CalendarDay, TimeZone: "Mars/Olympus"}), errInvalidPolicy)
+}
@@ -0,0 +1,93 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+	// the lambda runtime ships without zoneinfo.
+	_ "time/tzdata"
+
+	constants "bitbucket.org/kushki/usrv-card-control"
+	"bitbucket.org/kushki/usrv-card-control/types"
+)
+
+// getWindowEnd end of the window opened by a retry: WindowHours later for a rolling rule,
+// the start of the next day or month of the rule time zone for a calendar one.
+func getWindowEnd(rule types.FrequencyRule, retry int64) int64 {
+	if rule.Calendar == "" {
+		return retry + (time.Duration(rule.WindowHours) * time.Hour).Milliseconds()
+	}
+
+	date := time.UnixMilli(retry).In(getRuleLocation(rule))
+	if strings.EqualFold(rule.Calendar, constants.CalendarMonth) {
+		return time.Date(date.Year(), date.Month()+1, 1, 0, 0, 0, 0, date.Location()).UnixMilli()
+	}
+
+	return time.Date(date.Year(), date.Month(), date.Day()+1, 0, 0, 0, 0, date.Location()).UnixMilli()
+}
+
+// isInWindow a retry counts until the end of its window, so a calendar retry only counts in its own day or month.
+func isInWindow(rule types.FrequencyRule, retry int64, currentDate int64) bool {
+	return getWindowEnd(rule, retry) > currentDate
+}
+
+// getWindowHours hours from the retry to the end of its window rounded up, a calendar day may last 25 hours on a DST change.
+func getWindowHours(rule types.FrequencyRule, retry int64) int {
+	windowMillis := getWindowEnd(rule, retry) - retry
+	hourMillis := time.Hour.Milliseconds()
+
+	return int((windowMillis + hourMillis - 1) / hourMillis)
+}
+
+const retryWindowTag = "RetryWindow | %s"
+
+var (
+	// ruleLocations zones resolved by validatePolicy or by the first window of the rule, for the lambda lifetime.
+	ruleLocations      = make(map[string]*time.Location)
+	ruleLocationsMutex sync.RWMutex
+)
+
+// getRuleLocation validatePolicy rejects an unknown zone when a stored policy or velocity rule is loaded,
+// so only an unvalidated rule reaches here with one and it falls back to UTC.
+func getRuleLocation(rule types.FrequencyRule) *time.Location {
+	location, err := loadRuleLocation(rule.TimeZone)
+	if err != nil {
+		newKushkiLogger(context.Background()).Error(fmt.Sprintf(retryWindowTag, "getRuleLocation | falling back to UTC"), err)
+		return time.UTC
+	}
+
+	return location
+}
+
+// loadRuleLocation time.LoadLocation parses the zone on every call, so the resolved ones are cached.
+func loadRuleLocation(timeZone string) (*time.Location, error) {
+	ruleLocationsMutex.RLock()
+	location, ok := ruleLocations[timeZone]
+	ruleLocationsMutex.RUnlock()
+	if ok {
+		return location, nil
+	}
+
+	location, err := time.LoadLocation(timeZone)
+	if err != nil {
+		return nil, err
+	}
+	ruleLocationsMutex.Lock()
+	ruleLocations[timeZone] = location
+	ruleLocationsMutex.Unlock()
+
+	return location, nil
+}
+
+// getFrequencyRule first rule of the frequency.
+func getFrequencyRule(rules []types.FrequencyRule, frequency string) (types.FrequencyRule, bool) {
+	for _, rule := range rules {
+		if strings.EqualFold(rule.Frequency, frequency) {
+			return rule, true
+		}
+	}
+
+	return types.FrequencyRule{}, false
+}
@@ -0,0 +1,129 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	constants "bitbucket.org/kushki/usrv-card-control"
+	"bitbucket.org/kushki/usrv-card-control/mocks"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestGetWindowEnd(t *testing.T) {
+	guayaquil, err := time.LoadLocation("America/Guayaquil")
+	assert.NoError(t, err)
+	retry := time.Date(2026, time.March, 10, 23, 30, 0, 0, guayaquil).UnixMilli()
+
+	t.Run("should roll the window hours from the retry", func(t *testing.T) {
+		rule := types.FrequencyRule{WindowHours: constants.DayHours}
+
+		assert.Equal(t, retry+(24*time.Hour).Milliseconds(), getWindowEnd(rule, retry))
+	})
+
+	t.Run("should end the calendar day at the midnight of the time zone", func(t *testing.T) {
+		rule := types.FrequencyRule{Calendar: constants.CalendarDay, TimeZone: "America/Guayaquil"}
+
+		assert.Equal(t, time.Date(2026, time.March, 11, 0, 0, 0, 0, guayaquil).UnixMilli(), getWindowEnd(rule, retry))
+	})
+
+	t.Run("should end the calendar month at the first day of the next one", func(t *testing.T) {
+		rule := types.FrequencyRule{Calendar: constants.CalendarMonth}
+		endOfYear := time.Date(2026, time.December, 31, 12, 0, 0, 0, time.UTC).UnixMilli()
+
+		assert.Equal(t, time.Date(2027, time.January, 1, 0, 0, 0, 0, time.UTC).UnixMilli(), getWindowEnd(rule, endOfYear))
+	})
+
+	t.Run("should use UTC for an unknown time zone and log it", func(t *testing.T) {
+		t.Cleanup(clean)
+		mockLogger := mocks.GetMockLogger(t)
+		newKushkiLogger = func(context.Context) logger.KushkiLogger { return mockLogger }
+		rule := types.FrequencyRule{Calendar: constants.CalendarDay, TimeZone: "Mars/Olympus"}
+
+		assert.Equal(t, time.Date(2026, time.March, 12, 0, 0, 0, 0, time.UTC).UnixMilli(), getWindowEnd(rule, retry))
+		mockLogger.AssertCalled(t, "Error", mock.Anything, mock.Anything)
+	})
+}
+
+func TestLoadRuleLocation(t *testing.T) {
+	t.Run("should resolve a zone once", func(t *testing.T) {
+		first, err := loadRuleLocation("America/Bogota")
+		assert.NoError(t, err)
+		second, err := loadRuleLocation("America/Bogota")
+		assert.NoError(t, err)
+
+		assert.Same(t, first, second)
+	})
+
+	t.Run("should not cache an unknown zone", func(t *testing.T) {
+		_, err := loadRuleLocation("Mars/Olympus")
+
+		assert.Error(t, err)
+		assert.NotContains(t, ruleLocations, "Mars/Olympus")
+	})
+}
+
+func TestGetValidRetries_Calendar(t *testing.T) {
+	guayaquil, err := time.LoadLocation("America/Guayaquil")
+	assert.NoError(t, err)
+	currentDate := time.Date(2026, time.March, 11, 0, 10, 0, 0, guayaquil).UnixMilli()
+	yesterday := time.Date(2026, time.March, 10, 23, 50, 0, 0, guayaquil).UnixMilli()
+	today := time.Date(2026, time.March, 11, 0, 0, 0, 0, guayaquil).UnixMilli()
+	retries := []int64{yesterday, today}
+
+	assert.Equal(t, []int64{currentDate, today},
+		getValidRetries(currentDate, retries, types.FrequencyRule{Calendar: constants.CalendarDay, TimeZone: "America/Guayaquil"}))
+	assert.Equal(t, []int64{currentDate, yesterday, today},
+		getValidRetries(currentDate, retries, types.FrequencyRule{WindowHours: constants.DayHours}))
+	assert.Equal(t, []int64{currentDate, yesterday, today},
+		getValidRetries(currentDate, retries, types.FrequencyRule{Calendar: constants.CalendarMonth, TimeZone: "America/Guayaquil"}))
+}
+
+func TestGetWindowHours(t *testing.T) {
+	madrid, err := time.LoadLocation("Europe/Madrid")
+	assert.NoError(t, err)
+	// the clocks go back on October 26, 2025 in Madrid.
+	monthStart := time.Date(2025, time.October, 1, 0, 0, 0, 0, madrid).UnixMilli()
+	dayStart := time.Date(2025, time.October, 26, 0, 0, 0, 0, madrid).UnixMilli()
+	dayRule := types.FrequencyRule{Calendar: constants.CalendarDay, TimeZone: "Europe/Madrid"}
+	monthRule := types.FrequencyRule{Calendar: constants.CalendarMonth, TimeZone: "Europe/Madrid"}
+
+	assert.Equal(t, 72, getWindowHours(types.FrequencyRule{WindowHours: 72}, monthStart))
+	assert.Equal(t, constants.DayHours+1, getWindowHours(dayRule, dayStart))
+	assert.Equal(t, 1, getWindowHours(dayRule, dayStart+(constants.DayHours*time.Hour).Milliseconds()+1))
+	assert.Equal(t, 31*constants.DayHours+1, getWindowHours(monthRule, monthStart))
+	assert.Equal(t, 31*constants.DayHours+1, getLongestWindowHours([]types.FrequencyRule{
+		{WindowHours: constants.DayHours},
+		monthRule,
+	}, monthStart))
+}
+
+func TestGetBlockStatus_RetriesEnd(t *testing.T) {
+	currentDate := time.Now().UnixMilli()
+	hour := time.Hour.Milliseconds()
+
+	t.Run("should keep the retries until the end of the daily window", func(t *testing.T) {
+		blockedMerchants := map[string]types.BlockedMerchant{
+			"merchant1": {LastRetry: currentDate - 2*hour, RetriesEnd: currentDate + hour},
+			"merchant2": {LastRetry: currentDate - 2*hour, RetriesEnd: currentDate - hour},
+		}
+
+		assert.True(t, getBlockStatus(blockedMerchants, "merchant1", currentDate).HasRetries)
+		assert.False(t, getBlockStatus(blockedMerchants, "merchant2", currentDate).HasRetries)
+	})
+
+	t.Run("should keep 24 hours for the records without window end", func(t *testing.T) {
+		blockedMerchants := map[string]types.BlockedMerchant{
+			"merchant1": {LastRetry: currentDate - 23*hour},
+			"merchant2": {LastRetry: currentDate - 25*hour},
+			"merchant3": {RetriesEnd: currentDate + hour},
+		}
+
+		assert.True(t, getBlockStatus(blockedMerchants, "merchant1", currentDate).HasRetries)
+		assert.False(t, getBlockStatus(blockedMerchants, "merchant2", currentDate).HasRetries)
+		assert.False(t, getBlockStatus(blockedMerchants, "merchant3", currentDate).HasRetries)
+	})
+}
@@ -0,0 +1,65 @@
+package service
+
+import (
+	"context"
+	"fmt"
+
+	constants "bitbucket.org/kushki/usrv-card-control"
+	"bitbucket.org/kushki/usrv-card-control/gateway"
+	"bitbucket.org/kushki/usrv-card-control/types"
+)
+
+// evaluateShadowPolicy runs the shadow policy of the request next to the active one.
+// Its retries are counted apart and its decision only reaches the history and the report, so it is best effort:
+// a failure is logged and never fails the record.
+func (bs *BlockService) evaluateShadowPolicy(
+	ctx context.Context,
+	request types.BlockCardRequest,
+	category types.DeclineCategory,
+	settings types.MerchantSettings,
+	currentDate int64,
+	activeBlocked bool,
+) {
+	tag := fmt.Sprintf(blockSrvTag, "evaluateShadowPolicy")
+	policy, found, err := bs.Policies.GetShadowPolicy(ctx, request.Franchise, request.Processor, request.MerchantIdentifier)
+	if err != nil {
+		bs.Logger.Error(tag, err)
+		return
+	}
+	if !found {
+		return
+	}
+
+	ttlHours := getLongestWindowHours(policy.Rules, currentDate)
+	blockTypes := make([]string, 0)
+	trigger := blockTrigger{}
+	for _, rule := range applyLimitFactor(getApplicableRules(policy, category), settings.LimitFactor) {
+		retries, err := bs.countRetry(ctx, request, generateShadowRetryKey(request, rule), rule, currentDate, ttlHours)
+		if err != nil {
+			bs.Logger.Error(tag, err)
+			return
+		}
+		if len(retries) >= rule.MaxAttempts {
+			blockTypes = append(blockTypes, rule.BlockType)
+			if len(retries) > trigger.Retries.After {
+				trigger.Retries = retryCount{Frequency: rule.Frequency, Before: len(retries) - 1, After: len(retries)}
+			}
+		}
+	}
+
+	shadowBlocked := len(blockTypes) > 0
+	if shadowBlocked {
+		entry := newBlockEntry(request, constants.HistoryShadowPolicy, getStrongestBlockType(blockTypes), trigger)
+		entry.PolicyID = policy.PolicyID
+		bs.History.Record(ctx, entry)
+	}
+
+	if err := bs.Dynamo.UpdateItem(ctx, gateway.IncrementShadowReportBuilder(policy, request, activeBlocked, shadowBlocked)); err != nil {
+		bs.Logger.Error(tag, err)
+	}
+}
+
+// generateShadowRetryKey the shadow counters never share an item with the active ones, the frequency still closes the key.
+func generateShadowRetryKey(request types.BlockCardRequest, rule types.FrequencyRule) string {
+	return fmt.Sprintf("%s-%s", constants.ShadowRetryPrefix, generateRuleRetryKey(request, rule))
+}
@@ -0,0 +1,136 @@
+package service
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	constants "bitbucket.org/kushki/usrv-card-control"
+	"bitbucket.org/kushki/usrv-card-control/mocks"
+	coreMock "bitbucket.org/kushki/usrv-card-control/mocks/core"
+	mockService "bitbucket.org/kushki/usrv-card-control/mocks/service"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	core "bitbucket.org/kushki/usrv-go-core"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo/builder"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+func TestBlockService_EvaluateShadowPolicy(t *testing.T) {
+	request := types.BlockCardRequest{CardID: "card1", MerchantIdentifier: "merchant1", Franchise: core.BrandMasterCard}
+	category := classifyDecline(request.Franchise, request.Conditional)
+	currentDate := time.Now().UTC().UnixMilli()
+	shadowPolicy := types.RetryPolicy{
+		PolicyID: constants.ShadowPolicyPrefix + "MASTERCARD#*#*",
+		Version:  "v2",
+		Rules:    []types.FrequencyRule{{Frequency: constants.DailyFrequency, WindowHours: 24, MaxAttempts: 2, BlockType: constants.TEMPORARY}},
+	}
+	isReport := func(b *builder.UpdateItemBuilder) bool {
+		return b.Key[constants.PolicyIDField] == shadowPolicy.PolicyID
+	}
+	newScenario := func(t *testing.T, retries []int64, found bool, policyErr error) (*BlockService, *coreMock.IDynamoGateway, *mockService.IHistoryService) {
+		t.Helper()
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+			Run(func(args mock.Arguments) {
+				*args[2].(*types.CardRetry) = types.CardRetry{Retries: retries}
+			}).
+			Return(nil)
+		dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).Return(nil)
+		policiesMock := &mockService.IRetryPolicyService{}
+		policiesMock.On("GetShadowPolicy", mock.Anything, request.Franchise, "", request.MerchantIdentifier).
+			Return(shadowPolicy, found, policyErr)
+		historyMock := getHistoryMock()
+
+		return &BlockService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock, Policies: policiesMock, History: historyMock}, dynamoMock, historyMock
+	}
+
+	t.Run("should record the extra block of the shadow policy without blocking", func(t *testing.T) {
+		srv, dynamoMock, historyMock := newScenario(t, []int64{currentDate - 1000}, true, nil)
+
+		srv.evaluateShadowPolicy(context.TODO(), request, category, types.MerchantSettings{}, currentDate, false)
+
+		dynamoMock.AssertCalled(t, "GetItem", mock.Anything, mock.MatchedBy(func(b *builder.GetItemBuilder) bool {
+			return b.Key[constants.RetryKeyField] == "shadow-card1-merchant1-daily"
+		}), mock.Anything)
+		dynamoMock.AssertNotCalled(t, "UpdateItem", mock.Anything, mock.MatchedBy(func(b *builder.UpdateItemBuilder) bool {
+			return b.Key[constants.CardIdField] != nil
+		}))
+		dynamoMock.AssertCalled(t, "UpdateItem", mock.Anything, mock.MatchedBy(func(b *builder.UpdateItemBuilder) bool {
+			input, err := b.BuildInput()
+			return err == nil && isReport(b) && input.Key[constants.ReportKeyField] != nil
+		}))
+		historyMock.AssertCalled(t, "Record", mock.Anything, mock.MatchedBy(func(entry types.BlockHistoryEntry) bool {
+			return entry.Operation == constants.HistoryShadowPolicy && entry.PolicyID == shadowPolicy.PolicyID &&
+				entry.BlockType == constants.TEMPORARY && entry.RetriesAfter == 2
+		}))
+	})
+
+	t.Run("should only report while the shadow policy does not block", func(t *testing.T) {
+		srv, dynamoMock, historyMock := newScenario(t, nil, true, nil)
+
+		srv.evaluateShadowPolicy(context.TODO(), request, category, types.MerchantSettings{}, currentDate, true)
+
+		dynamoMock.AssertNumberOfCalls(t, "UpdateItem", 2)
+		historyMock.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should apply the limit factor of the merchant", func(t *testing.T) {
+		srv, _, historyMock := newScenario(t, []int64{currentDate - 1000}, true, nil)
+
+		srv.evaluateShadowPolicy(context.TODO(), request, category, types.MerchantSettings{LimitFactor: 2}, currentDate, false)
+
+		historyMock.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should skip the evaluation without shadow policy or when it cannot be read", func(t *testing.T) {
+		for _, policyErr := range []error{nil, commonError} {
+			srv, dynamoMock, historyMock := newScenario(t, nil, false, policyErr)
+
+			srv.evaluateShadowPolicy(context.TODO(), request, category, types.MerchantSettings{}, currentDate, true)
+
+			dynamoMock.AssertNotCalled(t, "GetItem", mock.Anything, mock.Anything, mock.Anything)
+			dynamoMock.AssertNotCalled(t, "UpdateItem", mock.Anything, mock.Anything)
+			historyMock.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+		}
+	})
+
+	t.Run("should not report if the shadow counter fails", func(t *testing.T) {
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).Return(commonError)
+		policiesMock := &mockService.IRetryPolicyService{}
+		policiesMock.On("GetShadowPolicy", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(shadowPolicy, true, nil)
+		srv := BlockService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock, Policies: policiesMock, History: getHistoryMock()}
+
+		assert.NotPanics(t, func() {
+			srv.evaluateShadowPolicy(context.TODO(), request, category, types.MerchantSettings{}, currentDate, false)
+		})
+		dynamoMock.AssertNotCalled(t, "UpdateItem", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should not fail if the report cannot be updated", func(t *testing.T) {
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("GetItem", mock.Anything, mock.Anything, mock.Anything).Return(dynamoerror.ErrItemNotFound)
+		dynamoMock.On("UpdateItem", mock.Anything, mock.MatchedBy(isReport)).Return(commonError).Once()
+		dynamoMock.On("UpdateItem", mock.Anything, mock.Anything).Return(nil).Once()
+		policiesMock := &mockService.IRetryPolicyService{}
+		policiesMock.On("GetShadowPolicy", mock.Anything, mock.Anything, mock.Anything, mock.Anything).
+			Return(shadowPolicy, true, nil)
+		srv := BlockService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock, Policies: policiesMock, History: getHistoryMock()}
+
+		assert.NotPanics(t, func() {
+			srv.evaluateShadowPolicy(context.TODO(), request, category, types.MerchantSettings{}, currentDate, false)
+		})
+		dynamoMock.AssertExpectations(t)
+	})
+}
+
+func TestGenerateShadowRetryKey(t *testing.T) {
+	request := types.BlockCardRequest{CardID: "card1", MerchantIdentifier: "merchant1", Franchise: core.BrandVisa, Conditional: "05"}
+	rule := types.FrequencyRule{Frequency: constants.MonthlyFrequency}
+
+	assert.Equal(t, "shadow-card1-merchant1-05-monthly", generateShadowRetryKey(request, rule))
+	assert.Equal(t, constants.MonthlyFrequency, getRetryFrequency(generateShadowRetryKey(request, rule)))
+}
@@ -0,0 +1,97 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	constants "bitbucket.org/kushki/usrv-card-control"
+	"bitbucket.org/kushki/usrv-card-control/gateway"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	errorsCore "bitbucket.org/kushki/usrv-go-core/errors"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+	"github.com/aws/aws-lambda-go/events"
+)
+
+const shadowReportSrvTag = "ShadowReportService | %s"
+
+var (
+	refNewShadowReportService = NewShadowReportService
+
+	errMissingPolicyID = errors.New("policyId is required")
+)
+
+type IShadowReportService interface {
+	GetReport(ctx context.Context, request types.ShadowReportRequest) (types.ShadowReportResponse, error)
+}
+
+// ShadowReportService differences between a shadow policy and the active one, per brand and merchant.
+type ShadowReportService struct {
+	Logger logger.KushkiLogger
+	Dynamo dynamo.IDynamoGateway
+}
+
+// NewShadowReportService function to instantiate.
+func NewShadowReportService(kskLogger logger.KushkiLogger, dynamoGtw dynamo.IDynamoGateway) IShadowReportService {
+	return &ShadowReportService{
+		Logger: kskLogger,
+		Dynamo: dynamoGtw,
+	}
+}
+
+// InitializeShadowReport init dependencies and fetch the report of the shadow policy.
+func InitializeShadowReport(ctx context.Context, event events.APIGatewayProxyRequest) (types.ShadowReportResponse, error) {
+	tag := fmt.Sprintf(shadowReportSrvTag, "InitializeShadowReport")
+
+	kskLogger := newKushkiLogger(ctx)
+
+	var request types.ShadowReportRequest
+	if err := jsonUnmarshalCaller([]byte(event.Body), &request); err != nil {
+		kskLogger.Error(tag, err)
+		return types.ShadowReportResponse{}, newServiceError(errorsCore.E001, tag, err)
+	}
+	if request.PolicyID == "" {
+		return types.ShadowReportResponse{}, newServiceError(errorsCore.E001, tag, errMissingPolicyID)
+	}
+
+	dynamoGtw, err := initializeDynamoGtw(ctx, kskLogger)
+	if err != nil {
+		kskLogger.Error(tag, err)
+		return types.ShadowReportResponse{}, newServiceError(errorsCore.E002, tag, err)
+	}
+
+	service := refNewShadowReportService(kskLogger, dynamoGtw)
+
+	response, err := service.GetReport(ctx, request)
+	if err != nil {
+		kskLogger.Error(tag, err)
+		return types.ShadowReportResponse{}, newServiceError(errorsCore.E002, tag, err)
+	}
+
+	return response, nil
+}
+
+// GetReport every row of the shadow policy, read in pages.
+func (rs *ShadowReportService) GetReport(ctx context.Context, request types.ShadowReportRequest) (types.ShadowReportResponse, error) {
+	response := types.ShadowReportResponse{
+		PolicyID: request.PolicyID,
+		Rows:     make([]types.ShadowReportRow, 0),
+	}
+
+	var lastRow types.ShadowReportRow
+	for {
+		var page []types.ShadowReportRow
+		input := gateway.QueryShadowReportBuilder(request, lastRow, constants.ShadowReportPageSize)
+		if err := rs.Dynamo.Query(ctx, input, &page); err != nil {
+			rs.Logger.Error(fmt.Sprintf(shadowReportSrvTag, "GetReport"), err)
+			return types.ShadowReportResponse{}, err
+		}
+
+		response.Rows = append(response.Rows, page...)
+		if len(page) < constants.ShadowReportPageSize {
+			return response, nil
+		}
+		lastRow = page[len(page)-1]
+	}
+}
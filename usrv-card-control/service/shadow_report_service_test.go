@@ -0,0 +1,116 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	constants "bitbucket.org/kushki/usrv-card-control"
+	"bitbucket.org/kushki/usrv-card-control/mocks"
+	coreMock "bitbucket.org/kushki/usrv-card-control/mocks/core"
+	mockService "bitbucket.org/kushki/usrv-card-control/mocks/service"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo/builder"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+	"github.com/aws/aws-lambda-go/events"
+	typesDynamo "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+const mockShadowPolicyID = "SHADOW#VISA#*#*"
+
+func TestNewShadowReportService(t *testing.T) {
+	t.Run("should no be empty", func(t *testing.T) {
+		assert.NotEmpty(t, NewShadowReportService(mocks.GetMockLogger(t), &coreMock.IDynamoGateway{}))
+	})
+}
+
+func TestShadowReportService_GetReport(t *testing.T) {
+	t.Run("should read every page of the report", func(t *testing.T) {
+		fullPage := make([]types.ShadowReportRow, constants.ShadowReportPageSize)
+		for i := range fullPage {
+			fullPage[i] = types.ShadowReportRow{PolicyID: mockShadowPolicyID, ReportKey: fmt.Sprint("v2#VISA#merchant", i)}
+		}
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("Query", mock.Anything, mock.MatchedBy(func(b *builder.QueryBuilder) bool {
+			return b.StartKey == nil
+		}), mock.Anything).Return(nil).Once().Run(func(args mock.Arguments) {
+			*args.Get(2).(*[]types.ShadowReportRow) = fullPage
+		})
+		dynamoMock.On("Query", mock.Anything, mock.MatchedBy(func(b *builder.QueryBuilder) bool {
+			key, ok := b.StartKey[constants.ReportKeyField].(*typesDynamo.AttributeValueMemberS)
+			return ok && key.Value == fullPage[len(fullPage)-1].ReportKey
+		}), mock.Anything).Return(nil).Once().Run(func(args mock.Arguments) {
+			*args.Get(2).(*[]types.ShadowReportRow) = []types.ShadowReportRow{{PolicyID: mockShadowPolicyID, ExtraBlocks: 1}}
+		})
+		srv := ShadowReportService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock}
+
+		response, err := srv.GetReport(context.TODO(), types.ShadowReportRequest{PolicyID: mockShadowPolicyID, Version: "v2"})
+
+		assert.NoError(t, err)
+		assert.Equal(t, mockShadowPolicyID, response.PolicyID)
+		assert.Len(t, response.Rows, constants.ShadowReportPageSize+1)
+		assert.Equal(t, 1, response.Rows[constants.ShadowReportPageSize].ExtraBlocks)
+		dynamoMock.AssertExpectations(t)
+	})
+
+	t.Run("should return an error if the query fails", func(t *testing.T) {
+		dynamoMock := &coreMock.IDynamoGateway{}
+		dynamoMock.On("Query", mock.Anything, mock.Anything, mock.Anything).Return(commonError)
+		srv := ShadowReportService{Logger: mocks.GetMockLogger(t), Dynamo: dynamoMock}
+
+		_, err := srv.GetReport(context.TODO(), types.ShadowReportRequest{PolicyID: mockShadowPolicyID})
+
+		assert.ErrorIs(t, err, commonError)
+	})
+}
+
+func TestInitializeShadowReport(t *testing.T) {
+	request := types.ShadowReportRequest{PolicyID: mockShadowPolicyID}
+	tests := []struct {
+		name               string
+		request            types.ShadowReportRequest
+		jsonUnmarshalError error
+		dynamoError        error
+		reportError        error
+		expectedCode       string
+	}{
+		{name: "should return the report", request: request},
+		{name: "should return E001 if unmarshal fails", jsonUnmarshalError: commonError, expectedCode: "E001"},
+		{name: "should return E001 without policyId", expectedCode: "E001"},
+		{name: "should return E002 if dynamo init fails", request: request, dynamoError: commonError, expectedCode: "E002"},
+		{name: "should return E002 if the report fails", request: request, reportError: commonError, expectedCode: "E002"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			t.Cleanup(clean)
+			newKushkiLogger = func(context.Context) logger.KushkiLogger {
+				return mocks.GetMockLogger(t)
+			}
+			jsonUnmarshalCaller = func(_ []byte, v any) error {
+				*v.(*types.ShadowReportRequest) = test.request
+				return test.jsonUnmarshalError
+			}
+			initializeDynamoGtw = func(context.Context, logger.KushkiLogger) (dynamo.IDynamoGateway, error) {
+				return &coreMock.IDynamoGateway{}, test.dynamoError
+			}
+			reportMock := &mockService.IShadowReportService{}
+			reportMock.On("GetReport", mock.Anything, test.request).
+				Return(types.ShadowReportResponse{PolicyID: test.request.PolicyID}, test.reportError)
+			refNewShadowReportService = func(logger.KushkiLogger, dynamo.IDynamoGateway) IShadowReportService {
+				return reportMock
+			}
+
+			response, err := InitializeShadowReport(context.TODO(), events.APIGatewayProxyRequest{})
+			if test.expectedCode != "" {
+				assert.ErrorContains(t, err, "Code: "+test.expectedCode)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.request.PolicyID, response.PolicyID)
+		})
+	}
+}
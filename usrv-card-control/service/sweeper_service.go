@@ -0,0 +1,263 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	constants "bitbucket.org/kushki/usrv-card-control"
+	"bitbucket.org/kushki/usrv-card-control/gateway"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+)
+
+type ISweeperService interface {
+	Sweep(ctx context.Context, request types.SweepRequest) (types.SweepResult, error)
+}
+
+// SweeperService to remove expired temporary blocks and stale retries.
+type SweeperService struct {
+	Logger   logger.KushkiLogger
+	Dynamo   dynamo.IDynamoGateway
+	History  IHistoryService
+	Policies IRetryPolicyService
+	Velocity VelocityConfig
+}
+
+const sweeperSrvTag = "SweeperService | %s"
+
+// RefNewSweeperService ref to new service.
+var RefNewSweeperService = NewSweeperService
+
+// NewSweeperService function to instantiate.
+func NewSweeperService(kskLogger logger.KushkiLogger, dynamoGtw dynamo.IDynamoGateway) (ISweeperService, error) {
+	velocity, err := loadVelocityConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return &SweeperService{
+		Logger:   kskLogger,
+		Dynamo:   dynamoGtw,
+		History:  NewHistoryService(kskLogger, dynamoGtw),
+		Policies: NewRetryPolicyService(kskLogger, dynamoGtw),
+		Velocity: velocity,
+	}, nil
+}
+
+// InitSweeperService used to initialize dependencies for service.
+func InitSweeperService(ctx context.Context, request types.SweepRequest) (types.SweepResult, error) {
+	kskLogger := newKushkiLogger(ctx)
+	dynamoGtw, err := initializeDynamoGtw(ctx, kskLogger)
+	if err != nil {
+		kskLogger.Error(fmt.Sprintf(sweeperSrvTag, "Error intializing dynamo"), err)
+		return types.SweepResult{}, err
+	}
+
+	service, err := RefNewSweeperService(kskLogger, dynamoGtw)
+	if err != nil {
+		kskLogger.Error(fmt.Sprintf(sweeperSrvTag, "Error initializing card velocity"), err)
+		return types.SweepResult{}, err
+	}
+
+	return service.Sweep(ctx, request)
+}
+
+// Sweep walks both tables in pages, a failed item is counted and skipped while a failed page stops the run.
+func (ss *SweeperService) Sweep(ctx context.Context, request types.SweepRequest) (types.SweepResult, error) {
+	dryRun, _ := strconv.ParseBool(os.Getenv(constants.EnvSweeperDryRun))
+	result := types.SweepResult{DryRun: request.DryRun || dryRun}
+	currentDate := nowCaller().UTC().UnixMilli()
+
+	err := ss.sweepBlockedCards(ctx, currentDate, &result)
+	if err == nil {
+		err = ss.sweepRetries(ctx, currentDate, &result)
+	}
+	ss.Logger.Info(fmt.Sprintf(sweeperSrvTag, "Sweep"), result)
+
+	return result, err
+}
+
+func (ss *SweeperService) sweepBlockedCards(ctx context.Context, currentDate int64, result *types.SweepResult) error {
+	lastCardID := ""
+	for {
+		var page []types.DynamoBlockedCard
+		input := gateway.ScanBlockedCardsBuilder(lastCardID, constants.SweeperPageSize)
+		if err := ss.Dynamo.ScanItems(ctx, input, &page); err != nil {
+			ss.Logger.Error(fmt.Sprintf(sweeperSrvTag, "sweepBlockedCards"), err)
+			return err
+		}
+
+		for _, blockedCard := range page {
+			result.ScannedCards++
+			ss.sweepBlockedCard(ctx, blockedCard, currentDate, result)
+		}
+		if len(page) < constants.SweeperPageSize {
+			return nil
+		}
+		lastCardID = page[len(page)-1].CardID
+	}
+}
+
+func (ss *SweeperService) sweepBlockedCard(
+	ctx context.Context,
+	blockedCard types.DynamoBlockedCard,
+	currentDate int64,
+	result *types.SweepResult,
+) {
+	// Dynamo deletes the whole card once its TTL passes.
+	if isExpiredItem(blockedCard.ExpiresAt) {
+		return
+	}
+
+	var expiredMerchants []string
+	err := retryOnConflict(ss.Logger, sweeperSrvTag, func(attempt int) error {
+		if attempt > 0 {
+			err := ss.Dynamo.GetItem(ctx, gateway.GetBlockedCardBuilder(blockedCard.CardID), &blockedCard)
+			if errors.Is(err, dynamoerror.ErrItemNotFound) {
+				expiredMerchants = nil
+				return nil
+			}
+			if err != nil {
+				return err
+			}
+		}
+
+		expiredMerchants = getExpiredMerchants(blockedCard, currentDate)
+		if len(expiredMerchants) == 0 || result.DryRun {
+			return nil
+		}
+
+		return ss.Dynamo.UpdateItem(ctx, gateway.RemoveExpiredBlocksBuilder(expiredMerchants, blockedCard))
+	})
+	if err != nil {
+		ss.Logger.Error(fmt.Sprintf(sweeperSrvTag, "sweepBlockedCard | "+blockedCard.CardID), err)
+		result.Failed++
+		return
+	}
+
+	result.ExpiredBlocks += len(expiredMerchants)
+	if result.DryRun {
+		return
+	}
+	for _, merchantID := range expiredMerchants {
+		ss.History.Record(ctx, types.BlockHistoryEntry{
+			CardID:     blockedCard.CardID,
+			MerchantID: merchantID,
+			Operation:  constants.HistoryExpired,
+			BlockType:  constants.TEMPORARY,
+		})
+	}
+}
+
+// getExpiredMerchants merchants with a temporary block already expired, sorted to build stable updates.
+func getExpiredMerchants(blockedCard types.DynamoBlockedCard, currentDate int64) []string {
+	merchants := make([]string, 0)
+	for merchantID := range blockedCard.BlockedMerchants {
+		if isExpiredBlock(blockedCard, merchantID, currentDate) {
+			merchants = append(merchants, merchantID)
+		}
+	}
+	sort.Strings(merchants)
+
+	return merchants
+}
+
+func (ss *SweeperService) sweepRetries(ctx context.Context, currentDate int64, result *types.SweepResult) error {
+	lastRetryKey := ""
+	for {
+		var page []types.CardRetry
+		input := gateway.ScanCardRetriesBuilder(lastRetryKey, constants.SweeperPageSize)
+		if err := ss.Dynamo.ScanItems(ctx, input, &page); err != nil {
+			ss.Logger.Error(fmt.Sprintf(sweeperSrvTag, "sweepRetries"), err)
+			return err
+		}
+
+		for _, cardRetry := range page {
+			result.ScannedRetries++
+			ss.sweepRetry(ctx, cardRetry, currentDate, result)
+		}
+		if len(page) < constants.SweeperPageSize {
+			return nil
+		}
+		lastRetryKey = page[len(page)-1].RetryKey
+	}
+}
+
+func (ss *SweeperService) sweepRetry(ctx context.Context, cardRetry types.CardRetry, currentDate int64, result *types.SweepResult) {
+	rule, err := ss.getRetryRule(ctx, cardRetry, currentDate)
+	if err != nil {
+		ss.Logger.Error(fmt.Sprintf(sweeperSrvTag, "getRetryRule | "+cardRetry.RetryKey), err)
+		result.Failed++
+		return
+	}
+	if !isStaleRetry(cardRetry, rule, currentDate) {
+		return
+	}
+
+	if !result.DryRun {
+		err := ss.Dynamo.DeleteItem(ctx, gateway.DeleteStaleRetryBuilder(cardRetry))
+		// a conflict means the retry was incremented meanwhile, so it is not stale anymore.
+		if isConditionalCheckFailed(err) {
+			result.Conflicts++
+			return
+		}
+		if err != nil {
+			ss.Logger.Error(fmt.Sprintf(sweeperSrvTag, "sweepRetry | "+cardRetry.RetryKey), err)
+			result.Failed++
+			return
+		}
+	}
+	result.DeletedRetries++
+}
+
+// getRetryRule the rule that counts into the retry key, taken from the same rules that wrote it.
+func (ss *SweeperService) getRetryRule(ctx context.Context, cardRetry types.CardRetry, currentDate int64) (types.FrequencyRule, error) {
+	var rules []types.FrequencyRule
+	switch {
+	case cardRetry.MerchantID == constants.VelocityMerchantID:
+		rules = ss.Velocity.Rules
+	case strings.HasPrefix(cardRetry.RetryKey, constants.ShadowRetryPrefix+"-"):
+		policy, _, err := ss.Policies.GetShadowPolicy(ctx, cardRetry.Brand, cardRetry.Processor, cardRetry.MerchantID)
+		if err != nil {
+			return types.FrequencyRule{}, err
+		}
+		rules = policy.Rules
+	default:
+		policy, err := ss.Policies.GetPolicy(ctx, cardRetry.Brand, cardRetry.Processor, cardRetry.MerchantID)
+		if err != nil {
+			return types.FrequencyRule{}, err
+		}
+		rules = policy.Rules
+	}
+
+	return findRetryRule(cardRetry.RetryKey, rules, currentDate), nil
+}
+
+// findRetryRule category keys close with the category and frequency, the generic ones only with the frequency.
+// A key no rule counts into anymore keeps the longest window of the rules, so no retry still counted is dropped.
+func findRetryRule(retryKey string, rules []types.FrequencyRule, currentDate int64) types.FrequencyRule {
+	for _, rule := range rules {
+		if rule.Category != "" && strings.HasSuffix(retryKey, "-"+getRuleCounter(rule)) {
+			return rule
+		}
+	}
+	for _, rule := range rules {
+		if rule.Category == "" && strings.EqualFold(getRetryFrequency(retryKey), rule.Frequency) {
+			return rule
+		}
+	}
+
+	return types.FrequencyRule{WindowHours: getLongestWindowHours(rules, currentDate)}
+}
+
+// isStaleRetry getValidRetries always keeps the current date, so only it remains when no retry is in the window.
+func isStaleRetry(cardRetry types.CardRetry, rule types.FrequencyRule, currentDate int64) bool {
+	return len(getValidRetries(currentDate, cardRetry.Retries, rule)) == 1
+}
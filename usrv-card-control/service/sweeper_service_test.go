@@ -0,0 +1,291 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	constants "bitbucket.org/kushki/usrv-card-control"
+	"bitbucket.org/kushki/usrv-card-control/mocks"
+	coreMock "bitbucket.org/kushki/usrv-card-control/mocks/core"
+	mockService "bitbucket.org/kushki/usrv-card-control/mocks/service"
+	"bitbucket.org/kushki/usrv-card-control/types"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo"
+	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo/builder"
+	dynamoerror "bitbucket.org/kushki/usrv-go-core/gateway/dynamo/errors"
+	"bitbucket.org/kushki/usrv-go-core/logger"
+	typesDynamo "github.com/aws/aws-sdk-go-v2/service/dynamodb/types"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+)
+
+var sweepNow = time.Date(2025, 3, 20, 12, 0, 0, 0, time.UTC)
+
+const sweepTimeStamp = 1742508191000
+
+var sweepPolicy = types.RetryPolicy{Rules: []types.FrequencyRule{
+	{Frequency: constants.DailyFrequency, WindowHours: constants.DayHours, MaxAttempts: 5, BlockType: constants.TEMPORARY},
+	{Frequency: constants.MonthlyFrequency, WindowHours: constants.MonthDays * constants.DayHours, MaxAttempts: 15, BlockType: constants.TEMPORARY},
+}}
+
+func TestInitSweeperService(t *testing.T) {
+	t.Run("should return an error if error init dynamo", func(t *testing.T) {
+		t.Cleanup(clean)
+		newKushkiLogger = func(context.Context) logger.KushkiLogger {
+			return mocks.GetMockLogger(t)
+		}
+		initializeDynamoGtw = func(context.Context, logger.KushkiLogger) (dynamo.IDynamoGateway, error) {
+			return &coreMock.IDynamoGateway{}, commonError
+		}
+		_, err := InitSweeperService(context.TODO(), types.SweepRequest{})
+		assert.Error(t, err)
+	})
+
+	t.Run("should return an error if the card velocity config is invalid", func(t *testing.T) {
+		t.Cleanup(clean)
+		t.Setenv(constants.EnvCardVelocityRules, "not json")
+		newKushkiLogger = func(context.Context) logger.KushkiLogger {
+			return mocks.GetMockLogger(t)
+		}
+		initializeDynamoGtw = func(context.Context, logger.KushkiLogger) (dynamo.IDynamoGateway, error) {
+			return &coreMock.IDynamoGateway{}, nil
+		}
+		_, err := InitSweeperService(context.TODO(), types.SweepRequest{})
+		assert.ErrorIs(t, err, errInvalidPolicy)
+	})
+
+	t.Run("should be successfully if not error on init dependencies", func(t *testing.T) {
+		t.Cleanup(clean)
+		newKushkiLogger = func(context.Context) logger.KushkiLogger {
+			return mocks.GetMockLogger(t)
+		}
+		initializeDynamoGtw = func(context.Context, logger.KushkiLogger) (dynamo.IDynamoGateway, error) {
+			return &coreMock.IDynamoGateway{}, nil
+		}
+		RefNewSweeperService = func(logger.KushkiLogger, dynamo.IDynamoGateway) (ISweeperService, error) {
+			srv := &mockService.ISweeperService{}
+			srv.On("Sweep", mock.Anything, types.SweepRequest{DryRun: true}).Return(types.SweepResult{DryRun: true}, nil)
+			return srv, nil
+		}
+		res, err := InitSweeperService(context.TODO(), types.SweepRequest{DryRun: true})
+		assert.NoError(t, err)
+		assert.True(t, res.DryRun)
+	})
+}
+
+func newSweeperScenario(t *testing.T, cards []types.DynamoBlockedCard, retries []types.CardRetry) (*SweeperService, *coreMock.IDynamoGateway, *mockService.IHistoryService) {
+	t.Helper()
+	t.Cleanup(clean)
+	nowCaller = func() time.Time { return sweepNow }
+	sleepCaller = func(time.Duration) {}
+
+	mockDynamo := &coreMock.IDynamoGateway{}
+	mockDynamo.On("ScanItems", mock.Anything, mock.Anything, mock.AnythingOfType("*[]types.DynamoBlockedCard")).
+		Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(2).(*[]types.DynamoBlockedCard) = cards
+	})
+	mockDynamo.On("ScanItems", mock.Anything, mock.Anything, mock.AnythingOfType("*[]types.CardRetry")).
+		Return(nil).Run(func(args mock.Arguments) {
+		*args.Get(2).(*[]types.CardRetry) = retries
+	})
+	history := &mockService.IHistoryService{}
+	history.On("Record", mock.Anything, mock.Anything).Return()
+	policies := &mockService.IRetryPolicyService{}
+	policies.On("GetPolicy", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(sweepPolicy, nil)
+
+	return &SweeperService{Logger: mocks.GetMockLogger(t), Dynamo: mockDynamo, History: history, Policies: policies}, mockDynamo, history
+}
+
+func TestSweeperService_Sweep(t *testing.T) {
+	expired := sweepNow.Add(-time.Hour).UnixMilli()
+	active := sweepNow.Add(time.Hour).UnixMilli()
+	cards := []types.DynamoBlockedCard{
+		{CardID: "card1", TimeStamp: sweepTimeStamp, BlockedMerchants: map[string]types.BlockedMerchant{
+			"merchantA": {BlockType: constants.TEMPORARY, ExpirationDate: expired},
+			"merchantB": {BlockType: constants.TEMPORARY, ExpirationDate: active},
+			"merchantC": {BlockType: constants.PERMANENT, ExpirationDate: expired},
+			"merchantD": {LastRetry: expired},
+		}},
+		{CardID: "card2", TimeStamp: sweepTimeStamp, BlockedMerchants: map[string]types.BlockedMerchant{
+			"merchantA": {BlockType: constants.PERMANENT},
+		}},
+	}
+	retries := []types.CardRetry{
+		{RetryKey: "card1-merchantA-stale-monthly", TimeStamp: sweepTimeStamp, Retries: []int64{sweepNow.AddDate(0, 0, -constants.MonthDays-1).UnixMilli()}},
+		{RetryKey: "card1-merchantA-empty-monthly", TimeStamp: sweepTimeStamp},
+		{RetryKey: "card1-merchantA-inWindow-monthly", TimeStamp: sweepTimeStamp, Retries: []int64{sweepNow.Add(-time.Hour).UnixMilli()}},
+	}
+
+	t.Run("should remove expired temporary blocks and stale retries", func(t *testing.T) {
+		service, mockDynamo, history := newSweeperScenario(t, cards, retries)
+		mockDynamo.On("UpdateItem", mock.Anything, mock.MatchedBy(func(b *builder.UpdateItemBuilder) bool {
+			return b.Key[constants.CardIdField] == "card1"
+		})).Return(nil).Once()
+		mockDynamo.On("DeleteItem", mock.Anything, mock.MatchedBy(func(b *builder.DeleteItemBuilder) bool {
+			return b.Key[constants.RetryKeyField] == "card1-merchantA-stale-monthly"
+		})).Return(nil).Once()
+		mockDynamo.On("DeleteItem", mock.Anything, mock.MatchedBy(func(b *builder.DeleteItemBuilder) bool {
+			return b.Key[constants.RetryKeyField] == "card1-merchantA-empty-monthly"
+		})).Return(&typesDynamo.ConditionalCheckFailedException{}).Once()
+
+		result, err := service.Sweep(context.Background(), types.SweepRequest{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, types.SweepResult{
+			ScannedCards: 2, ExpiredBlocks: 1, ScannedRetries: 3, DeletedRetries: 1, Conflicts: 1,
+		}, result)
+		history.AssertCalled(t, "Record", mock.Anything, types.BlockHistoryEntry{
+			CardID: "card1", MerchantID: "merchantA", Operation: constants.HistoryExpired, BlockType: constants.TEMPORARY,
+		})
+		mockDynamo.AssertExpectations(t)
+	})
+
+	t.Run("should only count on dry run", func(t *testing.T) {
+		t.Setenv(constants.EnvSweeperDryRun, "true")
+		service, mockDynamo, history := newSweeperScenario(t, cards, retries)
+
+		result, err := service.Sweep(context.Background(), types.SweepRequest{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, types.SweepResult{
+			DryRun: true, ScannedCards: 2, ExpiredBlocks: 1, ScannedRetries: 3, DeletedRetries: 2,
+		}, result)
+		mockDynamo.AssertNotCalled(t, "UpdateItem", mock.Anything, mock.Anything)
+		mockDynamo.AssertNotCalled(t, "DeleteItem", mock.Anything, mock.Anything)
+		history.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should re-read the card on conflict and skip it when it was deleted", func(t *testing.T) {
+		service, mockDynamo, history := newSweeperScenario(t, cards[:1], nil)
+		mockDynamo.On("UpdateItem", mock.Anything, mock.Anything).
+			Return(&typesDynamo.ConditionalCheckFailedException{}).Once()
+		mockDynamo.On("GetItem", mock.Anything, mock.Anything, mock.Anything).
+			Return(dynamoerror.ErrItemNotFound).Once()
+
+		result, err := service.Sweep(context.Background(), types.SweepRequest{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, types.SweepResult{ScannedCards: 1}, result)
+		history.AssertNotCalled(t, "Record", mock.Anything, mock.Anything)
+	})
+
+	t.Run("should count a failed card and keep sweeping", func(t *testing.T) {
+		service, mockDynamo, _ := newSweeperScenario(t, cards, nil)
+		mockDynamo.On("UpdateItem", mock.Anything, mock.Anything).Return(commonError).Once()
+
+		result, err := service.Sweep(context.Background(), types.SweepRequest{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, types.SweepResult{ScannedCards: 2, Failed: 1}, result)
+	})
+}
+
+func TestSweeperService_SweepRetryRules(t *testing.T) {
+	twoDaysAgo := []int64{sweepNow.AddDate(0, 0, -2).UnixMilli()}
+	shadowPolicy := types.RetryPolicy{Rules: []types.FrequencyRule{
+		{Frequency: constants.DailyFrequency, WindowHours: 3 * constants.DayHours, MaxAttempts: 5, BlockType: constants.TEMPORARY},
+	}}
+	categoryPolicy := types.RetryPolicy{Rules: []types.FrequencyRule{
+		{Frequency: constants.DailyFrequency, WindowHours: constants.DayHours, MaxAttempts: 5, BlockType: constants.TEMPORARY},
+		{Frequency: constants.DailyFrequency, Category: "cat", WindowHours: 3 * constants.DayHours, MaxAttempts: 5, BlockType: constants.TEMPORARY},
+	}}
+
+	tests := []struct {
+		name      string
+		cardRetry types.CardRetry
+		velocity  VelocityConfig
+		deleted   int
+	}{
+		{
+			name:      "should use the window of the daily rule",
+			cardRetry: types.CardRetry{RetryKey: "card1-merchantA-05-daily", MerchantID: "merchantA", Brand: "VISA", Retries: twoDaysAgo},
+			deleted:   1,
+		},
+		{
+			name:      "should keep the retries in the window of the monthly rule",
+			cardRetry: types.CardRetry{RetryKey: "card1-merchantA-05-monthly", MerchantID: "merchantA", Brand: "VISA", Retries: twoDaysAgo},
+		},
+		{
+			name:      "should use the rules of the shadow policy for a shadow key",
+			cardRetry: types.CardRetry{RetryKey: "shadow-card1-merchantA-05-daily", MerchantID: "merchantA", Brand: "VISA", Retries: twoDaysAgo},
+		},
+		{
+			name:      "should prefer the category rule of the key",
+			cardRetry: types.CardRetry{RetryKey: "card1-merchantB-cat-daily", MerchantID: "merchantB", Brand: "VISA", Retries: twoDaysAgo},
+		},
+		{
+			name:      "should use the card velocity rules for a velocity key",
+			cardRetry: types.CardRetry{RetryKey: "card1-*-daily", MerchantID: constants.VelocityMerchantID, Retries: twoDaysAgo},
+			velocity: VelocityConfig{Rules: []types.FrequencyRule{
+				{Frequency: constants.DailyFrequency, WindowHours: constants.DayHours, MaxAttempts: 5, BlockType: constants.TEMPORARY},
+			}},
+			deleted: 1,
+		},
+		{
+			name:      "should keep the longest window when no rule counts into the key",
+			cardRetry: types.CardRetry{RetryKey: "card1-merchantA-05-weekly", MerchantID: "merchantA", Brand: "VISA", Retries: twoDaysAgo},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service, mockDynamo, _ := newSweeperScenario(t, nil, []types.CardRetry{tt.cardRetry})
+			policies := &mockService.IRetryPolicyService{}
+			policies.On("GetPolicy", mock.Anything, "VISA", "", "merchantA").Return(sweepPolicy, nil)
+			policies.On("GetPolicy", mock.Anything, "VISA", "", "merchantB").Return(categoryPolicy, nil)
+			policies.On("GetShadowPolicy", mock.Anything, "VISA", "", "merchantA").Return(shadowPolicy, true, nil)
+			service.Policies = policies
+			service.Velocity = tt.velocity
+			mockDynamo.On("DeleteItem", mock.Anything, mock.Anything).Return(nil)
+
+			result, err := service.Sweep(context.Background(), types.SweepRequest{})
+
+			assert.NoError(t, err)
+			assert.Equal(t, types.SweepResult{ScannedRetries: 1, DeletedRetries: tt.deleted}, result)
+			mockDynamo.AssertNumberOfCalls(t, "DeleteItem", tt.deleted)
+		})
+	}
+
+	t.Run("should count a failed retry when its policy can not be read", func(t *testing.T) {
+		cardRetry := types.CardRetry{RetryKey: "card1-merchantA-05-daily", MerchantID: "merchantA", Brand: "VISA", Retries: twoDaysAgo}
+		service, mockDynamo, _ := newSweeperScenario(t, nil, []types.CardRetry{cardRetry})
+		policies := &mockService.IRetryPolicyService{}
+		policies.On("GetPolicy", mock.Anything, mock.Anything, mock.Anything, mock.Anything).Return(types.RetryPolicy{}, commonError)
+		service.Policies = policies
+
+		result, err := service.Sweep(context.Background(), types.SweepRequest{})
+
+		assert.NoError(t, err)
+		assert.Equal(t, types.SweepResult{ScannedRetries: 1, Failed: 1}, result)
+		mockDynamo.AssertNotCalled(t, "DeleteItem", mock.Anything, mock.Anything)
+	})
+}
+
+func TestSweeperService_SweepPages(t *testing.T) {
+	t.Cleanup(clean)
+	nowCaller = func() time.Time { return sweepNow }
+	fullPage := make([]types.DynamoBlockedCard, constants.SweeperPageSize)
+	for i := range fullPage {
+		fullPage[i] = types.DynamoBlockedCard{CardID: fmt.Sprint("card", i)}
+	}
+
+	mockDynamo := &coreMock.IDynamoGateway{}
+	mockDynamo.On("ScanItems", mock.Anything, mock.MatchedBy(func(b *builder.ScanBuilder) bool {
+		return b.StartKey == nil
+	}), mock.AnythingOfType("*[]types.DynamoBlockedCard")).Return(nil).Once().Run(func(args mock.Arguments) {
+		*args.Get(2).(*[]types.DynamoBlockedCard) = fullPage
+	})
+	mockDynamo.On("ScanItems", mock.Anything, mock.MatchedBy(func(b *builder.ScanBuilder) bool {
+		key, ok := b.StartKey[constants.CardIdField].(*typesDynamo.AttributeValueMemberS)
+		return ok && key.Value == fmt.Sprint("card", constants.SweeperPageSize-1)
+	}), mock.AnythingOfType("*[]types.DynamoBlockedCard")).Return(nil).Once()
+	mockDynamo.On("ScanItems", mock.Anything, mock.Anything, mock.AnythingOfType("*[]types.CardRetry")).
+		Return(commonError).Once()
+	service := &SweeperService{Logger: mocks.GetMockLogger(t), Dynamo: mockDynamo}
+
+	result, err := service.Sweep(context.Background(), types.SweepRequest{})
+
+	assert.ErrorIs(t, err, commonError)
+	assert.Equal(t, constants.SweeperPageSize, result.ScannedCards)
+	mockDynamo.AssertExpectations(t)
+}
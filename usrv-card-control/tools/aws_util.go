@@ -6,6 +6,7 @@ import (
 	"bitbucket.org/kushki/usrv-card-control/config/aws"
 	"bitbucket.org/kushki/usrv-go-core/gateway/dynamo"
 	"bitbucket.org/kushki/usrv-go-core/logger"
+	"github.com/aws/aws-sdk-go-v2/service/sqs"
 )
 
 // Definition of functions methods for testing purposes.
@@ -21,3 +22,10 @@ func InitializeDynamoGtw(ctx context.Context, logger logger.KushkiLogger) (dynam
 	dynamoGtw := dynamo.NewDynamoGateway(logger, dynamoClient)
 	return dynamoGtw, err
 }
+
+// InitializeSQSClient Initialize SQS client.
+func InitializeSQSClient(ctx context.Context, logger logger.KushkiLogger) (*sqs.Client, error) {
+	cfg, err := awsConfig(ctx, logger)
+
+	return sqs.NewFromConfig(cfg), err
+}
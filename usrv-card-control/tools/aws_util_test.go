@@ -31,6 +31,23 @@ func TestInitializeDynamoClient(t *testing.T) {
 	})
 }
 
+// TestInitializeSQSClient tests cases for initialize SQS.
+func TestInitializeSQSClient(t *testing.T) {
+	assertions := assert.New(t)
+	lgg := &mocks.KushkiLogger{}
+	t.Run("Initialize SQS client successfully", func(t *testing.T) {
+		sqsClient, err := InitializeSQSClient(context.Background(), lgg)
+		assertions.NotNil(sqsClient)
+		assertions.Nil(err)
+	})
+	t.Run("Initialize SQS client fails on awsConfig", func(t *testing.T) {
+		awsConfig = mockAwsProvideConfig(errors.New("error"))
+		_, err := InitializeSQSClient(context.Background(), lgg)
+		assertions.Error(err)
+		t.Cleanup(resetMocks)
+	})
+}
+
 func mockAwsProvideConfig(errorFake error) func(ctx context.Context, logger logger.KushkiLogger) (aws.Config, error) {
 	return func(ctx context.Context, logger logger.KushkiLogger) (aws.Config, error) {
 		return aws.Config{}, errorFake
@@ -0,0 +1,19 @@
+package types
+
+// BatchCheckCardStatusRequest card and merchant pairs checked in a single call.
+type BatchCheckCardStatusRequest struct {
+	Cards []CheckCardStatusRequest `json:"cards"`
+}
+
+// BatchCheckCardStatusItem block status of a pair, Error is set instead when it could not be resolved.
+type BatchCheckCardStatusItem struct {
+	CardID             string                   `json:"cardId"`
+	MerchantIdentifier string                   `json:"merchantIdentifier"`
+	Status             *CheckCardStatusResponse `json:"status,omitempty"`
+	Error              string                   `json:"error,omitempty"`
+}
+
+// BatchCheckCardStatusResponse results in the order of the request.
+type BatchCheckCardStatusResponse struct {
+	Results []BatchCheckCardStatusItem `json:"results"`
+}
@@ -8,6 +8,15 @@ type BlockCardRequest struct {
 	CardID             string `json:"cardId,omitempty"`
 	Processor          string `json:"processor,omitempty"`
 	Conditional        string `json:"conditional,omitempty"`
+	TransactionID      string `json:"transactionId,omitempty"`
+}
+
+// ProcessedMessage dedup record of a block message, it is ignored once ExpiresAt passes.
+type ProcessedMessage struct {
+	IdempotencyKey string `json:"idempotencyKey" dynamodbav:"idempotencyKey"`
+	Status         string `json:"status" dynamodbav:"status"`
+	ProcessedAt    int64  `json:"processedAt" dynamodbav:"processedAt"`
+	ExpiresAt      int64  `json:"expiresAt" dynamodbav:"expiresAt"`
 }
 
 // CardRetry card retry save information per merchant, code and card.
@@ -16,11 +25,26 @@ type CardRetry struct {
 	MerchantID string  `json:"merchantID" dynamodbav:"merchantID"`
 	RetryKey   string  `json:"retryKey" dynamodbav:"retryKey"`
 	Retries    []int64 `json:"retries" dynamodbav:"retries"`
+	Brand      string  `json:"brand,omitempty" dynamodbav:"brand,omitempty"`
+	Processor  string  `json:"processor,omitempty" dynamodbav:"processor,omitempty"`
 	TimeStamp  int64   `json:"timeStamp" dynamodbav:"timeStamp"`
+	ExpiresAt  int64   `json:"expiresAt,omitempty" dynamodbav:"expiresAt,omitempty"`
 }
 
-// RestoreDailyRequest info to clean daily retries and lastRetry timestamp.
+// RestoreDailyRequest info to clean retries and lastRetry timestamp, the daily retries of the card and merchant by default.
 type RestoreDailyRequest struct {
 	CardID     string `json:"cardId"`
 	MerchantID string `json:"merchantId"`
+	Scope      string `json:"scope,omitempty"`
+	Frequency  string `json:"frequency,omitempty"`
+	Brand      string `json:"brand,omitempty"`
+}
+
+// RestoreResult summary of the retries deleted by a restore.
+type RestoreResult struct {
+	Scope       string   `json:"scope"`
+	Frequency   string   `json:"frequency"`
+	CardID      string   `json:"cardId,omitempty"`
+	MerchantID  string   `json:"merchantId,omitempty"`
+	DeletedKeys []string `json:"deletedKeys"`
 }
@@ -0,0 +1,38 @@
+package types
+
+// BlockHistoryEntry append-only record of a card state transition.
+type BlockHistoryEntry struct {
+	CardID        string `json:"cardID" dynamodbav:"cardID"`
+	EventKey      string `json:"eventKey" dynamodbav:"eventKey"`
+	MerchantID    string `json:"merchantID,omitempty" dynamodbav:"merchantID,omitempty"`
+	Operation     string `json:"operation" dynamodbav:"operation"`
+	Brand         string `json:"brand,omitempty" dynamodbav:"brand,omitempty"`
+	Conditional   string `json:"conditional,omitempty" dynamodbav:"conditional,omitempty"`
+	Category      string `json:"category,omitempty" dynamodbav:"category,omitempty"`
+	Frequency     string `json:"frequency,omitempty" dynamodbav:"frequency,omitempty"`
+	BlockType     string `json:"blockType,omitempty" dynamodbav:"blockType,omitempty"`
+	PolicyID      string `json:"policyID,omitempty" dynamodbav:"policyID,omitempty"`
+	RetriesBefore int    `json:"retriesBefore" dynamodbav:"retriesBefore"`
+	RetriesAfter  int    `json:"retriesAfter" dynamodbav:"retriesAfter"`
+	Operator      string `json:"operator,omitempty" dynamodbav:"operator,omitempty"`
+	Reason        string `json:"reason,omitempty" dynamodbav:"reason,omitempty"`
+	TimeStamp     int64  `json:"timeStamp" dynamodbav:"timeStamp"`
+}
+
+// BlockHistoryRequest filters to fetch a card timeline, dates in milliseconds.
+type BlockHistoryRequest struct {
+	CardID             string `json:"cardId"`
+	MerchantIdentifier string `json:"merchantIdentifier,omitempty"`
+	From               int64  `json:"from,omitempty"`
+	To                 int64  `json:"to,omitempty"`
+	Limit              int    `json:"limit,omitempty"`
+	Cursor             string `json:"cursor,omitempty"`
+}
+
+// BlockHistoryResponse card timeline, newest events first.
+// NextCursor is set while there are older events to fetch.
+type BlockHistoryResponse struct {
+	CardID     string              `json:"cardId"`
+	Events     []BlockHistoryEntry `json:"events"`
+	NextCursor string              `json:"nextCursor,omitempty"`
+}
@@ -0,0 +1,31 @@
+package types
+
+// CardAdminRequest manual administration action over a card.
+type CardAdminRequest struct {
+	Operation          string `json:"operation"`
+	CardID             string `json:"cardId"`
+	MerchantIdentifier string `json:"merchantIdentifier,omitempty"`
+	BlockType          string `json:"blockType,omitempty"`
+	BlockHours         int    `json:"blockHours,omitempty"`
+	Reason             string `json:"reason"`
+}
+
+// CardAdminResponse result of a manual administration action.
+type CardAdminResponse struct {
+	Operation          string `json:"operation"`
+	CardID             string `json:"cardId"`
+	MerchantIdentifier string `json:"merchantIdentifier,omitempty"`
+	BlockType          string `json:"blockType,omitempty"`
+	Operator           string `json:"operator"`
+	Reason             string `json:"reason"`
+	ProcessedAt        int64  `json:"processedAt"`
+}
+
+// AdminAction operator and reason of the last manual action over a card.
+type AdminAction struct {
+	Operation  string `json:"operation" dynamodbav:"operation"`
+	MerchantID string `json:"merchantID,omitempty" dynamodbav:"merchantID,omitempty"`
+	Operator   string `json:"operator" dynamodbav:"operator"`
+	Reason     string `json:"reason" dynamodbav:"reason"`
+	Date       int64  `json:"date" dynamodbav:"date"`
+}
@@ -1,6 +1,10 @@
 package types
 
+// CheckCardStatusRequest brand enables the retry details, VISA also needs the conditional of the decline.
 type CheckCardStatusRequest struct {
 	CardID             string `json:"cardId"`
 	MerchantIdentifier string `json:"merchantIdentifier"`
+	Franchise          string `json:"brand,omitempty"`
+	Processor          string `json:"processor,omitempty"`
+	Conditional        string `json:"conditional,omitempty"`
 }
@@ -1,7 +1,36 @@
 package types
 
+// CheckCardStatusResponse Degraded is set when the block status could not be read and the merchant fail mode answered instead.
+// CardBlocked is set when the block comes from the card velocity across merchants.
+// Exempt and Shadow come from the merchant settings, a shadow merchant is never answered as blocked.
 type CheckCardStatusResponse struct {
-	BlockType  string `json:"blockType"`
-	Blocked    bool   `json:"blocked"`
-	HasRetries bool   `json:"hasRetries"`
+	BlockType         string              `json:"blockType"`
+	Blocked           bool                `json:"blocked"`
+	CardBlocked       bool                `json:"cardBlocked,omitempty"`
+	HasRetries        bool                `json:"hasRetries"`
+	Degraded          bool                `json:"degraded"`
+	Exempt            bool                `json:"exempt,omitempty"`
+	Shadow            bool                `json:"shadow,omitempty"`
+	ExpirationDate    int64               `json:"expirationDate,omitempty"`
+	RemainingAttempts []RemainingAttempts `json:"remainingAttempts,omitempty"`
+	NextAllowedDate   int64               `json:"nextAllowedDate,omitempty"`
+	Policy            *PolicyDecision     `json:"policy,omitempty"`
+}
+
+// RemainingAttempts attempts left in the current window of a policy rule.
+type RemainingAttempts struct {
+	Frequency   string `json:"frequency"`
+	Category    string `json:"category,omitempty"`
+	WindowHours int    `json:"windowHours"`
+	Calendar    string `json:"calendar,omitempty"`
+	TimeZone    string `json:"timeZone,omitempty"`
+	MaxAttempts int    `json:"maxAttempts"`
+	Remaining   int    `json:"remaining"`
+}
+
+// PolicyDecision retry policy used to evaluate the card.
+type PolicyDecision struct {
+	PolicyID string `json:"policyID"`
+	Version  string `json:"version"`
+	Category string `json:"category,omitempty"`
 }
@@ -0,0 +1,8 @@
+package types
+
+// DeclineCategory network classification of the decline reason sent in the conditional field.
+type DeclineCategory struct {
+	Network    string `json:"network"`
+	Category   string `json:"category"`
+	NeverRetry bool   `json:"neverRetry"`
+}
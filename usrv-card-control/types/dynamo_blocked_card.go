@@ -1,15 +1,23 @@
 package types
 
-// DynamoBlockedCard blocked card information.
+// DynamoBlockedCard blocked card information, CardBlock applies to every merchant.
 type DynamoBlockedCard struct {
 	CardID           string                     `json:"cardID" dynamodbav:"cardID"`
 	TimeStamp        int64                      `json:"timeStamp" dynamodbav:"timeStamp"`
 	BlockedMerchants map[string]BlockedMerchant `json:"blockedMerchants" dynamodbav:"blockedMerchants"`
+	LastAdminAction  *AdminAction               `json:"lastAdminAction,omitempty" dynamodbav:"lastAdminAction,omitempty"`
+	ExpiresAt        int64                      `json:"expiresAt,omitempty" dynamodbav:"expiresAt,omitempty"`
+	CardBlock        *BlockedMerchant           `json:"cardBlock,omitempty" dynamodbav:"cardBlock,omitempty"`
 }
 
 // BlockedMerchant saves timestamp per merchant blocking duration.
+// RetriesEnd closes the daily window of the last retry, records without it keep the 24 hours of LastRetry.
+// EscalationCount counts the temporary blocks since EscalationStart and outlives the block.
 type BlockedMerchant struct {
-	ExpirationDate int64  `json:"expirationDate" dynamodbav:"expirationDate,omitempty"`
-	BlockType      string `json:"blockType" dynamodbav:"blockType,omitempty"`
-	LastRetry      int64  `json:"lastRetry" dynamodbav:"lastRetry,omitempty"`
+	ExpirationDate  int64  `json:"expirationDate" dynamodbav:"expirationDate,omitempty"`
+	BlockType       string `json:"blockType" dynamodbav:"blockType,omitempty"`
+	LastRetry       int64  `json:"lastRetry" dynamodbav:"lastRetry,omitempty"`
+	RetriesEnd      int64  `json:"retriesEnd,omitempty" dynamodbav:"retriesEnd,omitempty"`
+	EscalationCount int    `json:"escalationCount,omitempty" dynamodbav:"escalationCount,omitempty"`
+	EscalationStart int64  `json:"escalationStart,omitempty" dynamodbav:"escalationStart,omitempty"`
 }
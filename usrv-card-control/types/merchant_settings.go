@@ -0,0 +1,11 @@
+package types
+
+// MerchantSettings overrides of the blocking engine for a merchant.
+// LimitFactor multiplies the max attempts of every rule, stricter below 1 and looser above it.
+// Shadow records the blocks the merchant would get without writing them.
+type MerchantSettings struct {
+	MerchantID  string  `json:"merchantID" dynamodbav:"merchantID"`
+	Exempt      bool    `json:"exempt,omitempty" dynamodbav:"exempt,omitempty"`
+	LimitFactor float64 `json:"limitFactor,omitempty" dynamodbav:"limitFactor,omitempty"`
+	Shadow      bool    `json:"shadow,omitempty" dynamodbav:"shadow,omitempty"`
+}
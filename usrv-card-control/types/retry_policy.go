@@ -0,0 +1,32 @@
+package types
+
+// RetryPolicy retry limits applied to a brand, optionally narrowed by processor and merchant.
+type RetryPolicy struct {
+	PolicyID   string           `json:"policyID" dynamodbav:"policyID"`
+	Brand      string           `json:"brand" dynamodbav:"brand"`
+	Processor  string           `json:"processor,omitempty" dynamodbav:"processor,omitempty"`
+	MerchantID string           `json:"merchantID,omitempty" dynamodbav:"merchantID,omitempty"`
+	Version    string           `json:"version" dynamodbav:"version"`
+	Rules      []FrequencyRule  `json:"rules" dynamodbav:"rules"`
+	Escalation *BlockEscalation `json:"escalation,omitempty" dynamodbav:"escalation,omitempty"`
+}
+
+// FrequencyRule max attempts allowed inside a window and the block applied when exceeded.
+// Rules with a category only apply to declines classified in it and keep their own counters.
+type FrequencyRule struct {
+	Frequency   string `json:"frequency" dynamodbav:"frequency"`
+	Category    string `json:"category,omitempty" dynamodbav:"category,omitempty"`
+	WindowHours int    `json:"windowHours" dynamodbav:"windowHours"`
+	MaxAttempts int    `json:"maxAttempts" dynamodbav:"maxAttempts"`
+	BlockType   string `json:"blockType" dynamodbav:"blockType"`
+	BlockHours  int    `json:"blockHours,omitempty" dynamodbav:"blockHours,omitempty"`
+	Calendar    string `json:"calendar,omitempty" dynamodbav:"calendar,omitempty"`
+	TimeZone    string `json:"timeZone,omitempty" dynamodbav:"timeZone,omitempty"`
+}
+
+// BlockEscalation durations of the consecutive temporary blocks of a merchant inside the window,
+// the block after the last one is permanent.
+type BlockEscalation struct {
+	BlockHours []int `json:"blockHours" dynamodbav:"blockHours"`
+	WindowDays int   `json:"windowDays,omitempty" dynamodbav:"windowDays,omitempty"`
+}
@@ -0,0 +1,28 @@
+package types
+
+// ShadowReportRow decisions of a shadow policy version against the active policy for a brand and merchant.
+// ExtraBlocks counts the declines only the shadow policy would block, FewerBlocks the ones only the active policy blocks.
+type ShadowReportRow struct {
+	PolicyID     string `json:"policyID" dynamodbav:"policyID"`
+	ReportKey    string `json:"-" dynamodbav:"reportKey"`
+	Version      string `json:"version" dynamodbav:"version"`
+	Brand        string `json:"brand" dynamodbav:"brand"`
+	MerchantID   string `json:"merchantID" dynamodbav:"merchantID"`
+	Evaluations  int    `json:"evaluations" dynamodbav:"evaluations"`
+	ActiveBlocks int    `json:"activeBlocks" dynamodbav:"activeBlocks"`
+	ShadowBlocks int    `json:"shadowBlocks" dynamodbav:"shadowBlocks"`
+	ExtraBlocks  int    `json:"extraBlocks" dynamodbav:"extraBlocks"`
+	FewerBlocks  int    `json:"fewerBlocks" dynamodbav:"fewerBlocks"`
+}
+
+// ShadowReportRequest report of a shadow policy, every version unless one is set.
+type ShadowReportRequest struct {
+	PolicyID string `json:"policyId"`
+	Version  string `json:"version,omitempty"`
+}
+
+// ShadowReportResponse rows ordered by version, brand and merchant.
+type ShadowReportResponse struct {
+	PolicyID string            `json:"policyId"`
+	Rows     []ShadowReportRow `json:"rows"`
+}
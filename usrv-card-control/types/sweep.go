@@ -0,0 +1,17 @@
+package types
+
+// SweepRequest scheduled sweep input, DryRun only counts what would be cleaned.
+type SweepRequest struct {
+	DryRun bool `json:"dryRun"`
+}
+
+// SweepResult counts of a sweep run.
+type SweepResult struct {
+	DryRun         bool `json:"dryRun"`
+	ScannedCards   int  `json:"scannedCards"`
+	ExpiredBlocks  int  `json:"expiredBlocks"`
+	ScannedRetries int  `json:"scannedRetries"`
+	DeletedRetries int  `json:"deletedRetries"`
+	Conflicts      int  `json:"conflicts"`
+	Failed         int  `json:"failed"`
+}